@@ -0,0 +1,432 @@
+// Package config loads the server configuration from an optional YAML or
+// TOML file, overridden by environment variables.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete server configuration. Zero values mean "use the
+// built-in default" so a file only needs the settings it changes.
+type Config struct {
+	Server          Server          `yaml:"server" toml:"server"`
+	Limits          Limits          `yaml:"limits" toml:"limits"`
+	Render          Render          `yaml:"render" toml:"render"`
+	BaseDefinitions BaseDefinitions `yaml:"baseDefinitions" toml:"baseDefinitions"`
+	Terminology     Terminology     `yaml:"terminology" toml:"terminology"`
+	Storage         Storage         `yaml:"storage" toml:"storage"`
+}
+
+// Server configures the HTTP and gRPC listeners
+type Server struct {
+	Port     string   `yaml:"port" toml:"port"`         // PORT, default 8080
+	GRPCPort string   `yaml:"grpcPort" toml:"grpcPort"` // GRPC_PORT, default off
+	CORS     CORS     `yaml:"cors" toml:"cors"`
+	Security Security `yaml:"security" toml:"security"`
+}
+
+// CORS restricts cross-origin access (see middleware.CORSOptions)
+type CORS struct {
+	Origins          []string `yaml:"origins" toml:"origins"`                   // CORS_ORIGINS (comma separated), default any
+	Methods          []string `yaml:"methods" toml:"methods"`                   // CORS_METHODS
+	Headers          []string `yaml:"headers" toml:"headers"`                   // CORS_HEADERS
+	AllowCredentials bool     `yaml:"allowCredentials" toml:"allowCredentials"` // CORS_ALLOW_CREDENTIALS
+}
+
+// Security sets the security headers (see middleware.SecurityOptions)
+type Security struct {
+	DocumentCSP    string `yaml:"documentCSP" toml:"documentCSP"`       // SECURITY_DOCUMENT_CSP
+	PageCSP        string `yaml:"pageCSP" toml:"pageCSP"`               // SECURITY_PAGE_CSP
+	FrameOptions   string `yaml:"frameOptions" toml:"frameOptions"`     // SECURITY_FRAME_OPTIONS
+	ReferrerPolicy string `yaml:"referrerPolicy" toml:"referrerPolicy"` // SECURITY_REFERRER_POLICY
+}
+
+// Limits caps the resources a single request may consume (see handlers.Limits)
+type Limits struct {
+	MaxBodyBytes    int64    `yaml:"maxBodyBytes" toml:"maxBodyBytes"`       // MAX_BODY_BYTES
+	MaxPackageBytes int64    `yaml:"maxPackageBytes" toml:"maxPackageBytes"` // MAX_PACKAGE_BYTES
+	MaxElements     int      `yaml:"maxElements" toml:"maxElements"`         // MAX_ELEMENTS
+	MaxDepth        int      `yaml:"maxDepth" toml:"maxDepth"`               // MAX_DEPTH
+	RenderTimeout   Duration `yaml:"renderTimeout" toml:"renderTimeout"`     // RENDER_TIMEOUT
+	RenderWorkers   int      `yaml:"renderWorkers" toml:"renderWorkers"`     // RENDER_WORKERS
+	RenderQueue     *int     `yaml:"renderQueue" toml:"renderQueue"`         // RENDER_QUEUE; 0 is a valid queue length
+
+	JobRetention      Duration `yaml:"jobRetention" toml:"jobRetention"`           // JOB_RETENTION
+	MaxJobResults     int      `yaml:"maxJobResults" toml:"maxJobResults"`         // MAX_JOB_RESULTS
+	MaxJobResultBytes int64    `yaml:"maxJobResultBytes" toml:"maxJobResultBytes"` // MAX_JOB_RESULT_BYTES
+}
+
+// Render configures the default look of rendered diagrams
+type Render struct {
+	FontPath        string   `yaml:"fontPath" toml:"fontPath"`               // FONT_PATH
+	TypeLinkBase    string   `yaml:"typeLinkBase" toml:"typeLinkBase"`       // TYPE_LINK_BASE
+	ElementLinkBase string   `yaml:"elementLinkBase" toml:"elementLinkBase"` // ELEMENT_LINK_BASE
+	Columns         []string `yaml:"columns" toml:"columns"`                 // RENDER_COLUMNS (comma separated), see renderer.ParseColumns
+	// RENDER_EXTRA_COLUMNS (comma separated "key:Title"), see renderer.ParseExtraColumns
+	ExtraColumns []ExtraColumn `yaml:"extraColumns" toml:"extraColumns"`
+	Flags        []Flag        `yaml:"flags" toml:"flags"`
+	Icons        []Icon        `yaml:"icons" toml:"icons"`
+	IconRules    []IconRule    `yaml:"iconRules" toml:"iconRules"`
+	Title        string        `yaml:"title" toml:"title"`             // RENDER_TITLE, see renderer.ParseTitleTemplate
+	Watermark    string        `yaml:"watermark" toml:"watermark"`     // RENDER_WATERMARK, e.g. "DRAFT"
+	Whitespace   string        `yaml:"whitespace" toml:"whitespace"`   // RENDER_WHITESPACE, "pretty" or "minified"
+	StrictLinks  bool          `yaml:"strictLinks" toml:"strictLinks"` // RENDER_STRICT_LINKS
+	Theme        Theme         `yaml:"theme" toml:"theme"`
+	ThemesPath   string        `yaml:"themesPath" toml:"themesPath"` // THEMES_PATH, see LoadThemes
+	Branding     Branding      `yaml:"branding" toml:"branding"`
+}
+
+// Branding attributes diagrams to an organization. Its fields mirror
+// renderer.Branding; Logo may also be the path of an image file, which is
+// embedded as a data URI.
+type Branding struct {
+	Logo    string `yaml:"logo" toml:"logo"`
+	OrgName string `yaml:"orgName" toml:"orgName"`
+	OrgURL  string `yaml:"orgURL" toml:"orgURL"`
+	RepoURL string `yaml:"repoURL" toml:"repoURL"`
+}
+
+// ExtraColumn adds a column showing each element's meta value for Key.
+// Its fields mirror renderer.ExtraColumn.
+type ExtraColumn struct {
+	Key   string  `yaml:"key" toml:"key"`
+	Title string  `yaml:"title" toml:"title"`
+	Width float64 `yaml:"width" toml:"width"`
+}
+
+// Flag registers a custom flag or restyles a built-in one. Its fields
+// mirror renderer.FlagStyle.
+type Flag struct {
+	Code        string `yaml:"code" toml:"code"`
+	Text        string `yaml:"text" toml:"text"`
+	Boxed       bool   `yaml:"boxed" toml:"boxed"`
+	Fill        string `yaml:"fill" toml:"fill"`
+	TextColor   string `yaml:"textColor" toml:"textColor"`
+	Description string `yaml:"description" toml:"description"`
+}
+
+// Icon registers an icon drawn from SVG path data. Its fields mirror
+// renderer.IconStyle.
+type Icon struct {
+	Name        string  `yaml:"name" toml:"name"`
+	Path        string  `yaml:"path" toml:"path"`
+	Fill        string  `yaml:"fill" toml:"fill"`
+	Size        float64 `yaml:"size" toml:"size"`
+	Description string  `yaml:"description" toml:"description"`
+}
+
+// IconRule picks an icon for element types matching Type, e.g. "Geo*".
+// Its fields mirror renderer.IconRule.
+type IconRule struct {
+	Type string `yaml:"type" toml:"type"`
+	Icon string `yaml:"icon" toml:"icon"`
+}
+
+// Theme overrides the default font family and colors, e.g. for branding.
+// Its fields mirror renderer.Theme.
+type Theme struct {
+	FontFamily          string `yaml:"fontFamily" toml:"fontFamily"`
+	HeaderBgColor       string `yaml:"headerBgColor" toml:"headerBgColor"`
+	HeaderTextColor     string `yaml:"headerTextColor" toml:"headerTextColor"`
+	RowBgColor          string `yaml:"rowBgColor" toml:"rowBgColor"`
+	AltRowBgColor       string `yaml:"altRowBgColor" toml:"altRowBgColor"`
+	BorderColor         string `yaml:"borderColor" toml:"borderColor"`
+	LinkColor           string `yaml:"linkColor" toml:"linkColor"`
+	TextColor           string `yaml:"textColor" toml:"textColor"`
+	NotUsedColor        string `yaml:"notUsedColor" toml:"notUsedColor"`
+	TodoColor           string `yaml:"todoColor" toml:"todoColor"`
+	MustSupportColor    string `yaml:"mustSupportColor" toml:"mustSupportColor"`
+	MustSupportRowColor string `yaml:"mustSupportRowColor" toml:"mustSupportRowColor"`
+	AddedRowColor       string `yaml:"addedRowColor" toml:"addedRowColor"`
+	TargetRowColor      string `yaml:"targetRowColor" toml:"targetRowColor"`
+	HoverRowColor       string `yaml:"hoverRowColor" toml:"hoverRowColor"`
+	UsedRowColor        string `yaml:"usedRowColor" toml:"usedRowColor"`
+	TodoRowColor        string `yaml:"todoRowColor" toml:"todoRowColor"`
+	NotUsedRowColor     string `yaml:"notUsedRowColor" toml:"notUsedRowColor"`
+
+	TreeLineColor string  `yaml:"treeLineColor" toml:"treeLineColor"`
+	TreeLineWidth float64 `yaml:"treeLineWidth" toml:"treeLineWidth"`
+	TreeIndent    float64 `yaml:"treeIndent" toml:"treeIndent"`
+}
+
+// BaseDefinitions configures where base StructureDefinitions are resolved
+type BaseDefinitions struct {
+	Path      string `yaml:"path" toml:"path"`           // BASE_DEFINITIONS
+	URL       string `yaml:"url" toml:"url"`             // BASE_DEFINITIONS_URL
+	CacheSize int    `yaml:"cacheSize" toml:"cacheSize"` // BASE_DEFINITIONS_CACHE_SIZE, default 500 downloaded definitions
+}
+
+// Terminology configures the server ?expandBindings=true expands bound
+// value sets with
+type Terminology struct {
+	Server      string `yaml:"server" toml:"server"`           // TERMINOLOGY_SERVER, default off
+	ExpandCount int    `yaml:"expandCount" toml:"expandCount"` // TERMINOLOGY_EXPAND_COUNT, default 10
+	CacheSize   int    `yaml:"cacheSize" toml:"cacheSize"`     // TERMINOLOGY_CACHE_SIZE, default 1000 expansions
+}
+
+// Storage selects the store behind share links and snippets
+type Storage struct {
+	Kind   string `yaml:"kind" toml:"kind"`     // SHARE_STORE: sqlite (default), memory or none
+	DBPath string `yaml:"dbPath" toml:"dbPath"` // SHARE_DB_PATH, default fhir_renderer.db
+}
+
+// Duration is a time.Duration written as a string such as "10s"
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Load reads path, if not empty, and applies the environment overrides. The
+// file format follows the extension: .yaml, .yml or .toml. Unknown keys are
+// rejected so typos do not go unnoticed.
+func Load(path string) (Config, error) {
+	var cfg Config
+	if path != "" {
+		if err := readFile(path, &cfg); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// validate rejects negative limits, credentials for any origin, unknown
+// frame options and negative tree styles
+func (c Config) validate() error {
+	cors := c.Server.CORS
+	if cors.AllowCredentials && (len(cors.Origins) == 0 || slices.Contains(cors.Origins, "*")) {
+		return errors.New("server.cors.allowCredentials requires explicit origins")
+	}
+	switch c.Server.Security.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("server.security.frameOptions must be DENY or SAMEORIGIN, not %q", c.Server.Security.FrameOptions)
+	}
+
+	l := c.Limits
+	switch {
+	case l.MaxBodyBytes < 0:
+		return errors.New("limits.maxBodyBytes must be positive")
+	case l.MaxPackageBytes < 0:
+		return errors.New("limits.maxPackageBytes must be positive")
+	case l.MaxElements < 0:
+		return errors.New("limits.maxElements must be positive")
+	case l.MaxDepth < 0:
+		return errors.New("limits.maxDepth must be positive")
+	case l.RenderTimeout < 0:
+		return errors.New("limits.renderTimeout must be positive")
+	case l.RenderWorkers < 0:
+		return errors.New("limits.renderWorkers must be positive")
+	case l.RenderQueue != nil && *l.RenderQueue < 0:
+		return errors.New("limits.renderQueue must not be negative")
+	case l.JobRetention < 0:
+		return errors.New("limits.jobRetention must be positive")
+	case l.MaxJobResults < 0:
+		return errors.New("limits.maxJobResults must be positive")
+	case l.MaxJobResultBytes < 0:
+		return errors.New("limits.maxJobResultBytes must be positive")
+	case c.BaseDefinitions.CacheSize < 0:
+		return errors.New("baseDefinitions.cacheSize must be positive")
+	case c.Terminology.CacheSize < 0:
+		return errors.New("terminology.cacheSize must be positive")
+	}
+	if err := c.Render.Theme.validate(); err != nil {
+		return fmt.Errorf("render.theme: %w", err)
+	}
+	return nil
+}
+
+// readFile decodes a YAML or TOML file into cfg
+func readFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(f)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	case ".toml":
+		return toml.NewDecoder(f).DisallowUnknownFields().Decode(cfg)
+	default:
+		return fmt.Errorf("unsupported config format %q (expected .yaml, .yml or .toml)", ext)
+	}
+}
+
+// applyEnv overrides cfg with the environment variables that are set
+func applyEnv(cfg *Config) error {
+	setString(&cfg.Server.Port, "PORT")
+	setString(&cfg.Server.GRPCPort, "GRPC_PORT")
+	setList(&cfg.Server.CORS.Origins, "CORS_ORIGINS")
+	setList(&cfg.Server.CORS.Methods, "CORS_METHODS")
+	setList(&cfg.Server.CORS.Headers, "CORS_HEADERS")
+	if v := os.Getenv("CORS_ALLOW_CREDENTIALS"); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS %q", v)
+		}
+		cfg.Server.CORS.AllowCredentials = allow
+	}
+	setString(&cfg.Server.Security.DocumentCSP, "SECURITY_DOCUMENT_CSP")
+	setString(&cfg.Server.Security.PageCSP, "SECURITY_PAGE_CSP")
+	setString(&cfg.Server.Security.FrameOptions, "SECURITY_FRAME_OPTIONS")
+	setString(&cfg.Server.Security.ReferrerPolicy, "SECURITY_REFERRER_POLICY")
+
+	if err := setInt64(&cfg.Limits.MaxBodyBytes, "MAX_BODY_BYTES"); err != nil {
+		return err
+	}
+	if err := setInt64(&cfg.Limits.MaxPackageBytes, "MAX_PACKAGE_BYTES"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Limits.MaxElements, "MAX_ELEMENTS"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Limits.MaxDepth, "MAX_DEPTH"); err != nil {
+		return err
+	}
+	if v := os.Getenv("RENDER_TIMEOUT"); v != "" {
+		if err := cfg.Limits.RenderTimeout.UnmarshalText([]byte(v)); err != nil || cfg.Limits.RenderTimeout <= 0 {
+			return fmt.Errorf("invalid RENDER_TIMEOUT %q (expected a duration like 5s)", v)
+		}
+	}
+	if err := setInt(&cfg.Limits.RenderWorkers, "RENDER_WORKERS"); err != nil {
+		return err
+	}
+	if v := os.Getenv("RENDER_QUEUE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid RENDER_QUEUE %q", v)
+		}
+		cfg.Limits.RenderQueue = &n
+	}
+	if v := os.Getenv("JOB_RETENTION"); v != "" {
+		if err := cfg.Limits.JobRetention.UnmarshalText([]byte(v)); err != nil || cfg.Limits.JobRetention <= 0 {
+			return fmt.Errorf("invalid JOB_RETENTION %q (expected a duration like 30m)", v)
+		}
+	}
+	if err := setInt(&cfg.Limits.MaxJobResults, "MAX_JOB_RESULTS"); err != nil {
+		return err
+	}
+	if err := setInt64(&cfg.Limits.MaxJobResultBytes, "MAX_JOB_RESULT_BYTES"); err != nil {
+		return err
+	}
+
+	setString(&cfg.Render.FontPath, "FONT_PATH")
+	setString(&cfg.Render.TypeLinkBase, "TYPE_LINK_BASE")
+	setString(&cfg.Render.ElementLinkBase, "ELEMENT_LINK_BASE")
+	setList(&cfg.Render.Columns, "RENDER_COLUMNS")
+	setString(&cfg.Render.Title, "RENDER_TITLE")
+	setString(&cfg.Render.Watermark, "RENDER_WATERMARK")
+	setString(&cfg.Render.Whitespace, "RENDER_WHITESPACE")
+	setString(&cfg.Render.ThemesPath, "THEMES_PATH")
+	if v := os.Getenv("RENDER_STRICT_LINKS"); v != "" {
+		strict, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid RENDER_STRICT_LINKS %q", v)
+		}
+		cfg.Render.StrictLinks = strict
+	}
+	if v := os.Getenv("RENDER_EXTRA_COLUMNS"); v != "" {
+		cfg.Render.ExtraColumns = nil
+		for _, spec := range splitList(v) {
+			key, title, _ := strings.Cut(spec, ":")
+			cfg.Render.ExtraColumns = append(cfg.Render.ExtraColumns,
+				ExtraColumn{Key: strings.TrimSpace(key), Title: strings.TrimSpace(title)})
+		}
+	}
+	setString(&cfg.BaseDefinitions.Path, "BASE_DEFINITIONS")
+	setString(&cfg.BaseDefinitions.URL, "BASE_DEFINITIONS_URL")
+	if err := setInt(&cfg.BaseDefinitions.CacheSize, "BASE_DEFINITIONS_CACHE_SIZE"); err != nil {
+		return err
+	}
+	setString(&cfg.Terminology.Server, "TERMINOLOGY_SERVER")
+	if err := setInt(&cfg.Terminology.ExpandCount, "TERMINOLOGY_EXPAND_COUNT"); err != nil {
+		return err
+	}
+	if err := setInt(&cfg.Terminology.CacheSize, "TERMINOLOGY_CACHE_SIZE"); err != nil {
+		return err
+	}
+	setString(&cfg.Storage.Kind, "SHARE_STORE")
+	setString(&cfg.Storage.DBPath, "SHARE_DB_PATH")
+	return nil
+}
+
+// setString overrides *dst with the environment variable name, if set
+func setString(dst *string, name string) {
+	if v := os.Getenv(name); v != "" {
+		*dst = v
+	}
+}
+
+// setList overrides *dst with the comma separated environment variable
+// name, if set
+func setList(dst *[]string, name string) {
+	if v := os.Getenv(name); v != "" {
+		*dst = splitList(v)
+	}
+}
+
+// setInt overrides *dst with the integer environment variable name, if set
+func setInt(dst *int, name string) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid %s %q", name, v)
+	}
+	*dst = n
+	return nil
+}
+
+// setInt64 overrides *dst with the integer environment variable name, if set
+func setInt64(dst *int64, name string) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid %s %q", name, v)
+	}
+	*dst = n
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultThemesPath is the theme directory read when THEMES_PATH and
+// render.themesPath are unset; it may be missing
+const DefaultThemesPath = "themes"
+
+// themeNamePattern restricts theme names, which come from file names and
+// are selected with ?theme=
+var themeNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// LoadThemes reads the theme packs in dir: one YAML or JSON file per theme
+// with the fields of Theme, named after the file without its extension,
+// e.g. themes/acme.yaml for ?theme=acme. Unknown keys are rejected like in
+// the configuration file; other files are skipped.
+func LoadThemes(dir string) (map[string]Theme, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	themes := make(map[string]Theme)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if !themeNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("%s: invalid theme name %q (use letters, digits, '-' and '_')", entry.Name(), name)
+		}
+		if _, ok := themes[name]; ok {
+			return nil, fmt.Errorf("%s: theme %q defined twice", entry.Name(), name)
+		}
+		theme, err := readTheme(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		themes[name] = theme
+	}
+	return themes, nil
+}
+
+// readTheme decodes a theme file. JSON is read as YAML, of which it is a
+// subset.
+func readTheme(path string) (Theme, error) {
+	var theme Theme
+	f, err := os.Open(path)
+	if err != nil {
+		return theme, err
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&theme); err != nil && !errors.Is(err, io.EOF) {
+		return theme, err
+	}
+	return theme, theme.validate()
+}
+
+// validate rejects negative tree line widths and indents
+func (t Theme) validate() error {
+	switch {
+	case t.TreeLineWidth < 0:
+		return errors.New("treeLineWidth must not be negative")
+	case t.TreeIndent < 0:
+		return errors.New("treeIndent must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build !js
+
+package convert
+
+import (
+	"context"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/sync/singleflight"
+)
+
+// lookupErrorTTL is how long a failed lookup is remembered before it is
+// tried again
+const lookupErrorTTL = time.Minute
+
+// lookupCache remembers the results of remote lookups, keeping the most
+// recently used ones. Concurrent lookups of a key share one call.
+type lookupCache[V any] struct {
+	entries *lru.Cache[string, lookupResult[V]]
+	calls   singleflight.Group
+}
+
+// lookupResult caches a lookup. Failures are cached too, until expires.
+type lookupResult[V any] struct {
+	value   V
+	err     error
+	expires time.Time
+}
+
+// newLookupCache returns a cache holding up to size results
+func newLookupCache[V any](size int) *lookupCache[V] {
+	entries, _ := lru.New[string, lookupResult[V]](max(size, 1))
+	return &lookupCache[V]{entries: entries}
+}
+
+// get returns the cached result for key, or runs lookup. The call is shared,
+// so it runs without ctx's cancellation and must be bounded by its own
+// timeout; get stops waiting for it when ctx is done.
+func (c *lookupCache[V]) get(ctx context.Context, key string, lookup func(ctx context.Context) (V, error)) (V, error) {
+	if cached, ok := c.entries.Get(key); ok && (cached.err == nil || time.Now().Before(cached.expires)) {
+		return cached.value, cached.err
+	}
+
+	call := c.calls.DoChan(key, func() (any, error) {
+		value, err := lookup(context.WithoutCancel(ctx))
+		result := lookupResult[V]{value: value, err: err}
+		if err != nil {
+			result.expires = time.Now().Add(lookupErrorTTL)
+		}
+		c.entries.Add(key, result)
+		return result, nil
+	})
+	select {
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	case done := <-call:
+		result := done.Val.(lookupResult[V])
+		return result.value, result.err
+	}
+}
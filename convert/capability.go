@@ -0,0 +1,90 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+
+	"fhir_renderer/models"
+)
+
+// capabilityStatement holds the parts of a FHIR CapabilityStatement that are rendered
+type capabilityStatement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	FHIRVersion string `json:"fhirVersion"`
+	Rest        []struct {
+		Mode        string        `json:"mode"`
+		Interaction []interaction `json:"interaction"`
+		Resource    []struct {
+			Type        string        `json:"type"`
+			Profile     string        `json:"profile"`
+			Interaction []interaction `json:"interaction"`
+			SearchParam []struct {
+				Name string `json:"name"`
+			} `json:"searchParam"`
+		} `json:"resource"`
+	} `json:"rest"`
+}
+
+// interaction is a supported REST interaction
+type interaction struct {
+	Code string `json:"code"`
+}
+
+// IsCapabilityStatement reports whether data is a FHIR CapabilityStatement
+func IsCapabilityStatement(data []byte) bool {
+	var header struct {
+		ResourceType string `json:"resourceType"`
+	}
+	return json.Unmarshal(data, &header) == nil && header.ResourceType == "CapabilityStatement"
+}
+
+// CapabilityStatement extracts the REST capability matrix from a FHIR
+// CapabilityStatement
+func CapabilityStatement(data []byte) (*models.CapabilityMatrix, error) {
+	var cs capabilityStatement
+	if err := json.Unmarshal(data, &cs); err != nil {
+		return nil, err
+	}
+
+	name := firstNonEmpty(cs.Title, cs.Name, cs.ID)
+	if name == "" {
+		return nil, errors.New("capability statement has no title, name or id")
+	}
+
+	matrix := &models.CapabilityMatrix{
+		Name:        name,
+		Version:     cs.Version,
+		FHIRVersion: cs.FHIRVersion,
+	}
+	for _, rest := range cs.Rest {
+		r := models.CapabilityRest{Mode: rest.Mode, Interactions: interactionCodes(rest.Interaction)}
+		for _, res := range rest.Resource {
+			resource := models.CapabilityResource{
+				Type:         res.Type,
+				Profile:      res.Profile,
+				Interactions: interactionCodes(res.Interaction),
+			}
+			for _, param := range res.SearchParam {
+				resource.SearchParams = append(resource.SearchParams, param.Name)
+			}
+			r.Resources = append(r.Resources, resource)
+		}
+		matrix.Rest = append(matrix.Rest, r)
+	}
+	if len(matrix.Rest) == 0 {
+		return nil, errors.New("capability statement has no rest entries")
+	}
+	return matrix, nil
+}
+
+// interactionCodes returns the codes of the interactions
+func interactionCodes(interactions []interaction) []string {
+	var codes []string
+	for _, i := range interactions {
+		codes = append(codes, i.Code)
+	}
+	return codes
+}
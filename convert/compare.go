@@ -0,0 +1,175 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"fhir_renderer/models"
+)
+
+// Compare converts a profile StructureDefinition and marks how it differs from
+// its base: elements removed (max 0), cardinalities tightened and slices or
+// elements the base does not have. base is optional; without it the profile's
+// baseDefinition is resolved through registry.
+func Compare(ctx context.Context, profileData, baseData []byte, registry Registry) (*models.ResourceDefinition, error) {
+	var profile structureDefinition
+	if err := json.Unmarshal(profileData, &profile); err != nil {
+		return nil, fmt.Errorf("profile: %w", err)
+	}
+	name := firstNonEmpty(profile.Name, profile.Title, profile.ID)
+	if name == "" {
+		return nil, errors.New("profile has no name, title or id")
+	}
+
+	if len(baseData) > 0 {
+		registry = Registries(NewMapRegistry(baseData), registry)
+	}
+
+	var base structureDefinition
+	var err error
+	if len(baseData) > 0 {
+		err = json.Unmarshal(baseData, &base)
+	} else if profile.BaseDefinition == "" {
+		err = errors.New("profile has no baseDefinition")
+	} else {
+		base, err = resolveDefinition(ctx, registry, profile.BaseDefinition, profile.FHIRVersion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("base: %w", err)
+	}
+	baseElements, err := snapshotElements(ctx, base, registry, 0)
+	if err != nil {
+		return nil, fmt.Errorf("base: %w", err)
+	}
+
+	elements, err := snapshotElements(ctx, profile, registry, 0)
+	if err != nil {
+		return nil, fmt.Errorf("profile: %w", err)
+	}
+
+	// Base ids are renamed to the profile's root type for lookups
+	baseType, profileType := rootType(base), ""
+	if len(elements) > 0 {
+		profileType, _, _ = strings.Cut(elementID(elements[0]), ".")
+	}
+	byID := make(map[string]elementDefinition, len(baseElements))
+	for _, e := range baseElements {
+		id := elementID(e)
+		if baseType != "" && profileType != "" && (id == baseType || isBelow(id, baseType)) {
+			id = profileType + strings.TrimPrefix(id, baseType)
+		}
+		byID[id] = e
+	}
+
+	annotate := func(def elementDefinition, elem *models.Element) {
+		if def.Path == "" && def.ID == "" {
+			return
+		}
+		id := elementID(def)
+		if baseID, ok := lookupBase(byID, id); ok {
+			annotateChange(byID[baseID], def, elem)
+			return
+		}
+		// Children of expanded datatypes are not in the base snapshot and
+		// cannot be compared; slices and extra elements are new
+		parentID, name := splitElementID(id)
+		baseParent, ok := lookupBase(byID, parentID)
+		if strings.Contains(name, ":") || ok && hasChildren(byID, baseParent) {
+			elem.Change = models.ChangeAdded
+		}
+	}
+	return resourceFromElements(profile, name, elements, annotate), nil
+}
+
+// lookupBase maps a profile element id to the id of its base element,
+// segment by segment. Choice elements renamed to one type, such as valueQuantity, match
+// value[x]; below a slice the sliced element's children are compared.
+func lookupBase(byID map[string]elementDefinition, id string) (string, bool) {
+	parts := strings.Split(id, ".")
+	mapped := parts[0]
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		candidate := mapped + "." + part
+		if _, ok := byID[candidate]; ok {
+			mapped = candidate
+			continue
+		}
+		if sliced, _, ok := strings.Cut(part, ":"); ok && !last {
+			part = sliced
+			if _, ok := byID[mapped+"."+part]; ok {
+				mapped += "." + part
+				continue
+			}
+		}
+		choice, ok := choiceElement(byID, mapped, part)
+		if !ok {
+			return "", false
+		}
+		mapped = choice
+	}
+	_, ok := byID[mapped]
+	return mapped, ok
+}
+
+// hasChildren reports whether the base has elements below id
+func hasChildren(byID map[string]elementDefinition, id string) bool {
+	for other := range byID {
+		if strings.HasPrefix(other, id+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// choiceElement returns the id of the choice element below parentID that
+// name is a type-specific form of
+func choiceElement(byID map[string]elementDefinition, parentID, name string) (string, bool) {
+	for id := range byID {
+		prefix, ok := strings.CutSuffix(id, "[x]")
+		if !ok || !strings.HasPrefix(prefix, parentID+".") {
+			continue
+		}
+		choiceName := strings.TrimPrefix(prefix, parentID+".")
+		typeName, ok := strings.CutPrefix(name, choiceName)
+		if ok && typeName != "" && !strings.ContainsAny(choiceName, ".:") && unicode.IsUpper(rune(typeName[0])) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// annotateChange compares the cardinality of a profile element with its base
+func annotateChange(base, def elementDefinition, elem *models.Element) {
+	if def.Max == "0" && base.Max != "0" {
+		elem.Change = models.ChangeRemoved
+		elem.BaseCardinality = elementCardinality(base)
+		return
+	}
+	if cardinalityRank(def.Max) < cardinalityRank(base.Max) || minOf(def) > minOf(base) {
+		elem.Change = models.ChangeTightened
+		elem.BaseCardinality = elementCardinality(base)
+	}
+}
+
+// cardinalityRank orders max cardinalities, with "*" and missing values
+// unbounded
+func cardinalityRank(max string) int {
+	n, err := strconv.Atoi(max)
+	if err != nil {
+		return int(^uint(0) >> 1)
+	}
+	return n
+}
+
+// minOf returns the min cardinality of an element, 0 when missing
+func minOf(def elementDefinition) int {
+	if def.Min == nil {
+		return 0
+	}
+	return int(*def.Min)
+}
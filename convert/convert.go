@@ -0,0 +1,84 @@
+// Package convert turns FHIR resources into ResourceDefinitions so they can
+// be rendered with the same table layout.
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+
+	"fhir_renderer/models"
+)
+
+// tracer records conversion spans
+var tracer = otel.Tracer("fhir_renderer/convert")
+
+// converters maps a FHIR resourceType to its converter
+var converters = map[string]func(ctx context.Context, data []byte) (*models.ResourceDefinition, error){
+	"Questionnaire": func(_ context.Context, data []byte) (*models.ResourceDefinition, error) {
+		return Questionnaire(data)
+	},
+	"StructureDefinition": StructureDefinition,
+}
+
+// HasConverter reports whether data is a FHIR resource FromFHIR converts
+func HasConverter(data []byte) bool {
+	var header struct {
+		ResourceType string `json:"resourceType"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return false
+	}
+	_, ok := converters[header.ResourceType]
+	return ok
+}
+
+// FromFHIR converts data when its resourceType has a converter. It reports
+// false when the document is not a convertible FHIR resource.
+func FromFHIR(ctx context.Context, data []byte) (*models.ResourceDefinition, bool, error) {
+	var header struct {
+		ResourceType string `json:"resourceType"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, false, nil
+	}
+	convert, ok := converters[header.ResourceType]
+	if !ok {
+		return nil, false, nil
+	}
+
+	ctx, span := tracer.Start(ctx, "convert "+header.ResourceType)
+	defer span.End()
+	resource, err := convert(ctx, data)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return resource, true, err
+}
+
+// flexBool decodes a JSON boolean, also accepting the "true"/"false"
+// strings produced by FHIR XML conversion
+type flexBool bool
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	*b = flexBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+// flexStrings decodes a JSON array of strings, also accepting the single
+// string STU3 uses for type profiles
+type flexStrings []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *flexStrings) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*s = flexStrings{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(s))
+}
@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFromFHIR(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		converted bool
+		wantErr   bool
+		want      string // Resulting definition as JSON
+	}{
+		{
+			name: "questionnaire",
+			data: `{"resourceType":"Questionnaire","name":"Intake","status":"active","item":[
+				{"linkId":"1","text":"Smoker?","type":"boolean","required":true},
+				{"linkId":"2","text":"Visits","type":"group","repeats":true,"item":[
+					{"linkId":"2.1","text":"Reason","type":"choice","answerOption":[{"valueCoding":{"code":"a"}},{"valueString":"other"}]}]}]}`,
+			converted: true,
+			want: `{"resourceType":"Questionnaire","name":"Intake","status":"active","type":"Questionnaire","elements":[
+				{"name":"1","cardinality":"1..1","type":"boolean","description":"Smoker?"},
+				{"name":"2","cardinality":"0..*","type":"group","description":"Visits","elements":[
+					{"name":"2.1","cardinality":"0..1","type":"choice","description":"Reason","notes":"Answers: a | other",
+					"binding":{"strength":"required","valueSet":"a | other"}}]}]}`,
+		},
+		{
+			name: "structure definition snapshot",
+			data: `{"resourceType":"StructureDefinition","name":"MyPatient","type":"Patient","snapshot":{"element":[
+				{"id":"Patient","path":"Patient","min":0,"max":"*"},
+				{"id":"Patient.gender","path":"Patient.gender","min":1,"max":"1","type":[{"code":"code"}],"mustSupport":true,
+				"binding":{"strength":"required","valueSet":"http://hl7.org/fhir/ValueSet/administrative-gender"}}]}}`,
+			converted: true,
+			want: `{"resourceType":"StructureDefinition","name":"MyPatient","type":"Patient","elements":[
+				{"name":"gender","flags":["MS"],"cardinality":"1..1","type":"code","binding":{"strength":"required",
+				"valueSet":"http://hl7.org/fhir/ValueSet/administrative-gender","url":"http://hl7.org/fhir/ValueSet/administrative-gender"}}]}`,
+		},
+		{
+			name:      "structure definition without name",
+			data:      `{"resourceType":"StructureDefinition","type":"Patient"}`,
+			converted: true,
+			wantErr:   true,
+		},
+		{
+			name:      "structure definition without elements",
+			data:      `{"resourceType":"StructureDefinition","name":"Empty","type":"Patient"}`,
+			converted: true,
+			wantErr:   true,
+		},
+		{
+			name: "resource without converter",
+			data: `{"resourceType":"Patient","name":[{"family":"Doe"}]}`,
+		},
+		{
+			name: "resource definition",
+			data: `{"name":"MyPatient","type":"Patient","elements":[]}`,
+		},
+		{
+			name: "not JSON",
+			data: `<Questionnaire/>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, converted, err := FromFHIR(context.Background(), []byte(tt.data))
+			if converted != tt.converted {
+				t.Fatalf("converted = %v, want %v", converted, tt.converted)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.want == "" {
+				if resource != nil && err == nil {
+					t.Errorf("got a definition for a document without converter")
+				}
+				return
+			}
+			got, err := json.Marshal(resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotValue, wantValue any
+			json.Unmarshal(got, &gotValue)
+			if err := json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+				t.Fatalf("invalid expected JSON: %v", err)
+			}
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
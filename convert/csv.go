@@ -0,0 +1,134 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// csvColumns are the columns of a CSV without header row, in order
+var csvColumns = []string{"path", "type", "cardinality", "description"}
+
+// csvHeaders maps the lower-cased header names a CSV may use to column keys;
+// they include the headers of the csv render format so exports round-trip
+var csvHeaders = map[string]string{
+	"path":             "path",
+	"type":             "type",
+	"cardinality":      "cardinality",
+	"card":             "cardinality",
+	"card.":            "cardinality",
+	"description":      "description",
+	"flags":            "flags",
+	"usage":            "usage",
+	"notes":            "notes",
+	"binding strength": "bindingStrength",
+	"value set":        "valueSet",
+}
+
+// CSV converts a spreadsheet element list into a ResourceDefinition. Each
+// row has a dotted path such as "Patient.contact.name", a type, a
+// cardinality and a description; nesting follows the paths. The first path
+// segment names the resource, and a row for the bare name sets its type and
+// description. Parents missing from the list become BackboneElements (see
+// ResourceDefinition.NestPaths).
+//
+// A header row starting with "Path" selects the columns by name (also flags,
+// usage, notes, binding strength and value set); without one the columns are
+// path, type, cardinality and description. Semicolon separated files, as
+// written by Excel in many locales, are detected from the first line.
+func CSV(data []byte) (*models.ResourceDefinition, error) {
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		cr.Comma = ';'
+	}
+
+	columns := csvColumns
+	var resource *models.ResourceDefinition
+	listed := map[string]bool{}
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "path") {
+			columns = make([]string, len(record))
+			for i, header := range record {
+				columns[i] = csvHeaders[strings.ToLower(strings.TrimSpace(header))]
+			}
+			continue
+		}
+
+		row := map[string]string{}
+		for i, value := range record {
+			if i < len(columns) && columns[i] != "" {
+				row[columns[i]] = unescapeFormula(strings.TrimSpace(value))
+			}
+		}
+		path := row["path"]
+		if path == "" {
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		segments := strings.Split(path, ".")
+		if slices.Contains(segments, "") {
+			return nil, fmt.Errorf("line %d: invalid path %q", line, path)
+		}
+
+		if resource == nil {
+			resource = &models.ResourceDefinition{Name: segments[0], Type: segments[0]}
+		}
+		if segments[0] != resource.Name {
+			return nil, fmt.Errorf("line %d: path %q does not start with %s", line, path, resource.Name)
+		}
+		if len(segments) == 1 {
+			resource.Type = firstNonEmpty(row["type"], resource.Type)
+			resource.Description = row["description"]
+			continue
+		}
+
+		if listed[path] {
+			return nil, fmt.Errorf("line %d: duplicate path %q", line, path)
+		}
+		listed[path] = true
+		elem := models.Element{
+			Path:        path,
+			Type:        firstNonEmpty(row["type"], models.ImplicitParentType),
+			Cardinality: row["cardinality"],
+			Description: row["description"],
+			Usage:       row["usage"],
+			Notes:       row["notes"],
+			Flags:       strings.Fields(strings.ReplaceAll(row["flags"], ",", " ")),
+		}
+		if row["bindingStrength"] != "" || row["valueSet"] != "" {
+			elem.Binding = &models.Binding{Strength: row["bindingStrength"], ValueSet: row["valueSet"]}
+		}
+		resource.Elements = append(resource.Elements, elem)
+	}
+	if resource == nil {
+		return nil, errors.New("no rows with a path")
+	}
+
+	return resource, resource.NestPaths()
+}
+
+// unescapeFormula drops the apostrophe the csv render format puts before
+// values spreadsheets would evaluate as formulas
+func unescapeFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
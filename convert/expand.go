@@ -0,0 +1,141 @@
+//go:build !js
+
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"fhir_renderer/models"
+)
+
+// DefaultTerminologyCacheSize is the number of expansions a terminology
+// server keeps when no cache size is configured
+const DefaultTerminologyCacheSize = 1000
+
+// TerminologyServer expands value sets with the $expand operation of a FHIR
+// terminology server and caches the results
+type TerminologyServer struct {
+	base   string
+	client *http.Client
+	cache  *lookupCache[expansion]
+}
+
+// expansion is the start of an expanded value set
+type expansion struct {
+	codes     []string
+	truncated bool
+}
+
+// NewTerminologyServer returns a client for the terminology server at base,
+// e.g. https://tx.fhir.org/r4, keeping up to cacheSize expansions;
+// cacheSize <= 0 selects DefaultTerminologyCacheSize
+func NewTerminologyServer(base string, cacheSize int) *TerminologyServer {
+	if cacheSize <= 0 {
+		cacheSize = DefaultTerminologyCacheSize
+	}
+	return &TerminologyServer{
+		base:   strings.TrimSuffix(base, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  newLookupCache[expansion](cacheSize),
+	}
+}
+
+// Expand returns the first count codes of the value set with a canonical
+// URL, optionally suffixed "|version", and whether it holds more
+func (s *TerminologyServer) Expand(ctx context.Context, canonical string, count int) ([]string, bool, error) {
+	key := canonical + "#" + strconv.Itoa(count)
+	result, err := s.cache.get(ctx, key, func(ctx context.Context) (expansion, error) {
+		codes, truncated, err := s.expand(ctx, canonical, count)
+		return expansion{codes, truncated}, err
+	})
+	return result.codes, result.truncated, err
+}
+
+// expand runs $expand, asking for one code more than count to learn
+// whether the list is complete when the server omits the total
+func (s *TerminologyServer) expand(ctx context.Context, canonical string, count int) ([]string, bool, error) {
+	vsURL, version, _ := strings.Cut(canonical, "|")
+	query := url.Values{"url": {vsURL}, "count": {strconv.Itoa(count + 1)}}
+	if version != "" {
+		query.Set("valueSetVersion", version)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.base+"/ValueSet/$expand?"+query.Encode(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/fhir+json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("expanding %s: server returned %s", vsURL, resp.Status)
+	}
+
+	var vs valueSet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&vs); err != nil {
+		return nil, false, fmt.Errorf("expanding %s: %w", vsURL, err)
+	}
+	if vs.Expansion == nil {
+		return nil, false, fmt.Errorf("expanding %s: response has no expansion", vsURL)
+	}
+	codes := expansionCodes(vs.Expansion.Contains)
+	truncated := len(codes) > count || (vs.Expansion.Total != nil && *vs.Expansion.Total > count)
+	if len(codes) > count {
+		codes = codes[:count]
+	}
+	if len(codes) == 0 {
+		return nil, false, errors.New("expanding " + vsURL + ": expansion lists no codes")
+	}
+	return codes, truncated, nil
+}
+
+// expansionCodes returns the selectable codes of an expansion in order,
+// including nested ones
+func expansionCodes(contains []expansionContains) []string {
+	var codes []string
+	for _, c := range contains {
+		if c.Code != "" && !bool(c.Abstract) {
+			codes = append(codes, c.Code)
+		}
+		codes = append(codes, expansionCodes(c.Contains)...)
+	}
+	return codes
+}
+
+// ExpandBindings lists the first count codes of each binding whose value set
+// is an http(s) canonical URL, from the terminology server. Bindings that
+// already list codes are kept, and value sets the server cannot expand are
+// left as they are.
+func ExpandBindings(ctx context.Context, resource *models.ResourceDefinition, server *TerminologyServer, count int) {
+	for _, fe := range resource.Flatten() {
+		binding := fe.Element.Binding
+		if binding == nil || len(binding.Codes) > 0 || !isCanonicalURL(binding.ValueSet) {
+			continue
+		}
+		codes, truncated, err := server.Expand(ctx, binding.ValueSet, count)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		binding.Codes, binding.Truncated = codes, truncated
+	}
+}
+
+// isCanonicalURL reports whether a binding value set is an http(s) URL
+// rather than a list of codes
+func isCanonicalURL(valueSet string) bool {
+	return strings.HasPrefix(valueSet, "http://") || strings.HasPrefix(valueSet, "https://")
+}
@@ -0,0 +1,396 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"fhir_renderer/models"
+)
+
+// DefaultCanonicalBase prefixes the url of exported StructureDefinitions
+// when no canonical base is given
+const DefaultCanonicalBase = "http://example.org/fhir"
+
+// abstractBaseTypes are the base types a definition specializes into a
+// logical model rather than constrains
+var abstractBaseTypes = []string{"Base", "Element", "BackboneElement", "Resource", "DomainResource"}
+
+// exportedDefinition is the StructureDefinition written by
+// ToStructureDefinition, in FHIR's element order
+type exportedDefinition struct {
+	ResourceType   string `json:"resourceType"`
+	ID             string `json:"id"`
+	URL            string `json:"url"`
+	Version        string `json:"version,omitempty"`
+	Name           string `json:"name"`
+	Title          string `json:"title,omitempty"`
+	Status         string `json:"status"`
+	Date           string `json:"date,omitempty"`
+	Publisher      string `json:"publisher,omitempty"`
+	Description    string `json:"description,omitempty"`
+	FHIRVersion    string `json:"fhirVersion"`
+	Kind           string `json:"kind"`
+	Abstract       bool   `json:"abstract"`
+	Type           string `json:"type"`
+	BaseDefinition string `json:"baseDefinition"`
+	Derivation     string `json:"derivation"`
+	Differential   struct {
+		Element []exportedElement `json:"element"`
+	} `json:"differential"`
+}
+
+// exportedElement is one differential element. Fixed and pattern values are
+// added under their choice-typed names by MarshalJSON.
+type exportedElement struct {
+	ID               string           `json:"id"`
+	Path             string           `json:"path"`
+	SliceName        string           `json:"sliceName,omitempty"`
+	Short            string           `json:"short,omitempty"`
+	Definition       string           `json:"definition,omitempty"`
+	Comment          string           `json:"comment,omitempty"`
+	Min              *int             `json:"min,omitempty"`
+	Max              string           `json:"max,omitempty"`
+	ContentReference string           `json:"contentReference,omitempty"`
+	Type             []exportedType   `json:"type,omitempty"`
+	MustSupport      bool             `json:"mustSupport,omitempty"`
+	IsModifier       bool             `json:"isModifier,omitempty"`
+	IsModifierReason string           `json:"isModifierReason,omitempty"`
+	IsSummary        bool             `json:"isSummary,omitempty"`
+	Binding          *exportedBinding `json:"binding,omitempty"`
+	Mapping          []models.Mapping `json:"mapping,omitempty"`
+
+	fixed   map[string]json.RawMessage
+	pattern map[string]json.RawMessage
+}
+
+// MarshalJSON implements json.Marshaler, appending fixed[x] and pattern[x]
+// after the other fields
+func (e exportedElement) MarshalJSON() ([]byte, error) {
+	type plain exportedElement
+	data, err := json.Marshal(plain(e))
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range []map[string]json.RawMessage{e.fixed, e.pattern} {
+		for key, raw := range value {
+			data = fmt.Appendf(data[:len(data)-1], `,%q:%s}`, key, raw)
+		}
+	}
+	return data, nil
+}
+
+// exportedType is a permitted type of an exported element
+type exportedType struct {
+	Code          string   `json:"code"`
+	Profile       []string `json:"profile,omitempty"`
+	TargetProfile []string `json:"targetProfile,omitempty"`
+}
+
+// exportedBinding is the value set binding of an exported element
+type exportedBinding struct {
+	Strength    string `json:"strength"`
+	Description string `json:"description,omitempty"`
+	ValueSet    string `json:"valueSet,omitempty"`
+}
+
+// invalidIDChars are the characters not allowed in FHIR resource ids
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// ToStructureDefinition converts a resource definition into a FHIR R4 or,
+// with fhirVersion R5, an R5 StructureDefinition with a differential, the
+// reverse of StructureDefinition; STU3 is not exported. Definitions based
+// on a concrete type become profiles (constraints) of it; those based on an
+// abstract type such as DomainResource become logical models. The url is
+// canonicalBase followed by /StructureDefinition/ and the id. registry,
+// when set, resolves the base definition for its kind.
+//
+// Elements keep their names as paths, with "name:slice" as a slice of name,
+// either next to or below it;
+// usage not-used becomes max 0, notes the comment and the flags mustSupport,
+// isModifier and isSummary. Constraints (the I flag) cannot be expressed and
+// are dropped.
+func ToStructureDefinition(ctx context.Context, resource *models.ResourceDefinition, canonicalBase string, registry Registry) ([]byte, error) {
+	id := strings.Trim(invalidIDChars.ReplaceAllString(resource.Name, "-"), "-")
+	if len(id) > 64 {
+		id = id[:64]
+	}
+	if id == "" {
+		return nil, fmt.Errorf("name %q yields no valid id", resource.Name)
+	}
+	version := resource.FHIRVersionOf()
+	if version == models.FHIRVersionSTU3 {
+		return nil, fmt.Errorf("cannot export FHIR %s StructureDefinitions (use %s or %s)", version, models.FHIRVersionR4, models.FHIRVersionR5)
+	}
+	canonicalBase = strings.TrimSuffix(firstNonEmpty(canonicalBase, DefaultCanonicalBase), "/")
+
+	sd := exportedDefinition{
+		ResourceType:   "StructureDefinition",
+		ID:             id,
+		URL:            canonicalBase + "/StructureDefinition/" + id,
+		Version:        resource.Version,
+		Name:           exportName(resource.Name),
+		Status:         firstNonEmpty(resource.Status, models.StatusDraft),
+		Date:           resource.Date,
+		Publisher:      resource.Publisher,
+		Description:    resource.Description,
+		FHIRVersion:    models.FHIRVersionNumber(version),
+		Kind:           "resource",
+		Type:           resource.Type,
+		BaseDefinition: coreCanonicalPrefix + resource.Type,
+		Derivation:     "constraint",
+	}
+	root := resource.Type
+	if slices.Contains(abstractBaseTypes, resource.Type) {
+		// Logical models name their own type by url
+		sd.Kind, sd.Type, sd.Derivation = "logical", sd.URL, "specialization"
+		root = sd.Name
+	} else if kind := baseKind(ctx, registry, versionedCanonical(sd.BaseDefinition, sd.FHIRVersion)); kind != "" {
+		sd.Kind = kind
+	}
+
+	rootElement := exportedElement{ID: root, Path: root, Short: resource.Description}
+	if sd.Kind == "logical" {
+		rootElement.Definition = resource.Description
+	}
+	sd.Differential.Element = append(sd.Differential.Element, rootElement)
+	sd.Differential.Element = exportElements(sd.Differential.Element, resource.Elements, root, root, sd.Kind == "logical", resource)
+	for _, ext := range resource.Extensions {
+		sd.Differential.Element = append(sd.Differential.Element, exportExtension(root, root, ext)...)
+	}
+
+	return json.MarshalIndent(sd, "", "  ")
+}
+
+// exportElements appends the differential elements of elements and their
+// children below the parent id and path
+func exportElements(out []exportedElement, elements []models.Element, parentID, parentPath string, logical bool, resource *models.ResourceDefinition) []exportedElement {
+	for _, elem := range elements {
+		name, sliceName, _ := strings.Cut(elem.Name, ":")
+		id, path := parentID+"."+elem.Name, parentPath+"."+name
+		if sliceName != "" && strings.HasSuffix(parentPath, "."+name) {
+			// Slices nested below the element they slice, as StructureDefinition
+			// converts them, are its siblings in FHIR
+			id, path = parentID+":"+sliceName, parentPath
+		}
+		e := exportedElement{
+			ID:        id,
+			Path:      path,
+			SliceName: sliceName,
+			Short:     elem.Description,
+			Comment:   elem.Notes,
+			Mapping:   elem.Mappings,
+		}
+		if logical {
+			e.Definition = elem.Description
+		}
+		e.Min, e.Max = exportCardinality(elem.Cardinality)
+		if elem.Usage == models.UsageNotUsed {
+			zero := 0
+			e.Min, e.Max = &zero, "0"
+		}
+
+		if target, ok := strings.CutPrefix(elem.Type, "see "); ok {
+			root, _, _ := strings.Cut(parentPath, ".")
+			e.ContentReference = "#" + contentReferencePath(resource, root, target)
+		} else {
+			e.Type = exportTypes(elem)
+		}
+		e.MustSupport = slices.Contains(elem.Flags, models.FlagMustSupport)
+		e.IsSummary = slices.Contains(elem.Flags, models.FlagSummary)
+		if slices.Contains(elem.Flags, models.FlagModifier) {
+			e.IsModifier, e.IsModifierReason = true, "Flagged as modifier in the source definition"
+		}
+		if b := elem.Binding; b != nil && (b.Strength != "" || b.ValueSet != "") {
+			e.Binding = &exportedBinding{Strength: firstNonEmpty(b.Strength, "example")}
+			if strings.Contains(b.ValueSet, "://") {
+				e.Binding.ValueSet = b.ValueSet
+			} else {
+				e.Binding.Description = b.ValueSet
+			}
+		}
+		if len(e.Type) == 1 {
+			e.fixed = choiceValue("fixed", e.Type[0].Code, elem.Fixed)
+			e.pattern = choiceValue("pattern", e.Type[0].Code, elem.Pattern)
+		}
+
+		out = append(out, e)
+		out = exportElements(out, elem.Elements, e.ID, e.Path, logical, resource)
+		for _, ext := range elem.Extensions {
+			out = append(out, exportExtension(e.ID, e.Path, ext)...)
+		}
+	}
+	return out
+}
+
+// exportExtension returns the extension slice for ext below an element,
+// followed by the slices of its sub-extensions. Sub-extensions are profiled
+// only when their url is absolute; relative ones are the bare slice name.
+func exportExtension(parentID, parentPath string, ext models.Extension) []exportedElement {
+	e := exportedElement{
+		ID:        parentID + ".extension:" + ext.Name,
+		Path:      parentPath + ".extension",
+		SliceName: ext.Name,
+		Short:     ext.Description,
+		Type:      []exportedType{{Code: "Extension"}},
+	}
+	if ext.URL != "" {
+		e.Type[0].Profile = []string{ext.URL}
+	}
+	e.Min, e.Max = exportCardinality(ext.Cardinality)
+	e.MustSupport = slices.Contains(ext.Flags, models.FlagMustSupport)
+	e.IsSummary = slices.Contains(ext.Flags, models.FlagSummary)
+	out := []exportedElement{e}
+	for _, sub := range ext.Extensions {
+		subElements := exportExtension(e.ID, e.Path, sub)
+		if !isURL(sub.URL) {
+			subElements[0].Type[0].Profile = nil
+		}
+		out = append(out, subElements...)
+	}
+	return out
+}
+
+// exportTypes splits "A | B" types into codes. Reference and canonical
+// elements carry their targets, from Targets or written as
+// "Reference(A | B)", as target profiles.
+func exportTypes(elem models.Element) []exportedType {
+	var types []exportedType
+	for _, name := range splitTypes(elem.Type) {
+		code, targets, _ := strings.Cut(name, "(")
+		t := exportedType{Code: strings.TrimSpace(code)}
+		if t.Code != "Reference" && t.Code != "canonical" {
+			types = append(types, t)
+			continue
+		}
+		for _, target := range strings.Split(strings.TrimSuffix(targets, ")"), "|") {
+			if target = strings.TrimSpace(target); target != "" {
+				t.TargetProfile = append(t.TargetProfile, coreCanonicalPrefix+target)
+			}
+		}
+		for _, target := range elem.Targets {
+			t.TargetProfile = append(t.TargetProfile, coreCanonicalPrefix+target.Type)
+		}
+		types = append(types, t)
+	}
+	return types
+}
+
+// splitTypes splits a type on the "|" outside parentheses
+func splitTypes(typ string) []string {
+	var names []string
+	depth, start := 0, 0
+	for i, r := range typ + "|" {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == '|' && depth == 0:
+			if name := strings.TrimSpace(typ[start:min(i, len(typ))]); name != "" {
+				names = append(names, name)
+			}
+			start = i + 1
+		}
+	}
+	return names
+}
+
+// exportCardinality parses "min..max"; malformed parts are left out
+func exportCardinality(card string) (*int, string) {
+	lower, upper, ok := strings.Cut(card, "..")
+	if !ok {
+		return nil, ""
+	}
+	var min *int
+	if n, err := strconv.Atoi(lower); err == nil && n >= 0 {
+		min = &n
+	}
+	if _, err := strconv.Atoi(upper); err != nil && upper != "*" {
+		upper = ""
+	}
+	return min, upper
+}
+
+// choiceValue returns the fixed[x] or pattern[x] field for a value of type
+// code: primitives as JSON values, complex types when the value is a JSON
+// object. Other values cannot be typed and are dropped.
+func choiceValue(prefix, code, value string) map[string]json.RawMessage {
+	if value == "" {
+		return nil
+	}
+	key := prefix + strings.ToUpper(code[:1]) + code[1:]
+	var raw json.RawMessage
+	switch {
+	case unicode.IsUpper(rune(code[0])):
+		if !strings.HasPrefix(value, "{") || !json.Valid([]byte(value)) {
+			return nil
+		}
+		raw = json.RawMessage(value)
+	case code == "boolean" && (value == "true" || value == "false"):
+		raw = json.RawMessage(value)
+	case slices.Contains([]string{"integer", "positiveInt", "unsignedInt", "decimal"}, code) && json.Valid([]byte(value)):
+		raw = json.RawMessage(value)
+	default:
+		raw, _ = json.Marshal(value)
+	}
+	return map[string]json.RawMessage{key: raw}
+}
+
+// contentReferencePath returns the path of the first element named name for
+// a "see name" type, whose definition the element reuses
+func contentReferencePath(resource *models.ResourceDefinition, root, name string) string {
+	for _, fe := range resource.Flatten()[1:] {
+		if fe.Element.Name == name {
+			return root + strings.TrimPrefix(fe.Path, resource.Name)
+		}
+	}
+	return root + "." + name
+}
+
+// exportName returns a computer-friendly name: letters and digits starting
+// with an upper-case letter, as FHIR's sdf-0 invariant requires
+func exportName(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) || r > unicode.MaxASCII {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	result := strings.TrimLeftFunc(sb.String(), unicode.IsDigit)
+	if result == "" {
+		return "Structure"
+	}
+	return result
+}
+
+// baseKind returns the kind of the base definition, or "" when it cannot be
+// resolved
+func baseKind(ctx context.Context, registry Registry, url string) string {
+	if registry == nil {
+		return ""
+	}
+	data, err := registry.Resolve(ctx, url)
+	if err != nil {
+		return ""
+	}
+	var base struct {
+		Kind string `json:"kind"`
+	}
+	if json.Unmarshal(data, &base) != nil {
+		return ""
+	}
+	return base.Kind
+}
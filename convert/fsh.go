@@ -0,0 +1,584 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// fshEntityKeywords start the entities of FHIR Shorthand
+var fshEntityKeywords = []string{
+	"Profile", "Logical", "Resource", "Extension", "Instance", "ValueSet", "CodeSystem",
+	"Invariant", "RuleSet", "Mapping",
+}
+
+// fshMetadataKeywords start the metadata lines of an entity
+var fshMetadataKeywords = []string{
+	"Parent", "Id", "Title", "Description", "Characteristics", "InstanceOf", "Usage",
+	"Severity", "Expression", "XPath", "Source", "Target", "Context",
+}
+
+// fshEntities are the FSH entities that convert to a diagram
+var fshEntities = []string{"Profile", "Extension", "Logical", "Resource"}
+
+// fshCardinality matches the cardinality of a rule, e.g. "0..1" or "1.."
+var fshCardinality = regexp.MustCompile(`^\d*\.\.(\d+|\*)?$`)
+
+// fshSliceNames matches the slice names of an element id
+var fshSliceNames = regexp.MustCompile(`:[^.]*`)
+
+// fshFlags maps the flags of FSH rules to element definition fields; TU, N
+// and D have no counterpart and are ignored
+var fshFlags = []string{"MS", "SU", "?!", "TU", "N", "D"}
+
+// IsFSH reports whether data looks like FHIR Shorthand: its first line that
+// is not blank or a comment starts with a FSH keyword such as "Profile:"
+func IsFSH(data []byte) bool {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || bytes.IndexByte([]byte("{[<"), trimmed[0]) >= 0 {
+		return false
+	}
+	for _, line := range strings.Split(stripFSHComments(string(data)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		keyword, _, ok := strings.Cut(line, ":")
+		return ok && isFSHKeyword(keyword)
+	}
+	return false
+}
+
+// isFSHKeyword reports whether keyword starts an alias, entity or metadata
+// line
+func isFSHKeyword(keyword string) bool {
+	return keyword == "Alias" || slices.Contains(fshEntityKeywords, keyword) || slices.Contains(fshMetadataKeywords, keyword)
+}
+
+// fshEntity is the Profile, Extension, Logical or Resource being converted
+type fshEntity struct {
+	kind, name, parent, id, title, description string
+}
+
+// FSH converts the first Profile, Extension, Logical or Resource of FHIR
+// Shorthand source into a ResourceDefinition, generating the snapshot from
+// the base definition in the configured base registry like
+// StructureDefinition.
+//
+// The rules understood are cardinalities and flags ("* name 1..1 MS"),
+// element definitions of logical models and resources ("* name 1..1 string
+// "Short" "Definition""), types ("only"), bindings ("from"), assignments
+// ("=", as pattern unless "(exactly)"), slices and extensions ("contains"),
+// invariants ("obeys", as the I flag) and the ^short, ^definition, ^status,
+// ^version, ^publisher and ^date caret rules. Paths may use indentation and
+// the [slice] notation. Aliases are resolved; other entities and rules are
+// skipped.
+func FSH(ctx context.Context, data []byte) (*models.ResourceDefinition, error) {
+	return FSHEntity(ctx, data, "")
+}
+
+// FSHEntity converts the Profile, Extension, Logical or Resource named name
+// like FSH, or the first one when name is empty
+func FSHEntity(ctx context.Context, data []byte, name string) (*models.ResourceDefinition, error) {
+	lines := strings.Split(stripFSHComments(string(data)), "\n")
+	aliases := map[string]string{}
+	var entity *fshEntity
+	var rules []fshLine
+	done := false
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "*") {
+			if entity != nil && !done {
+				rules = append(rules, fshLine{number: i + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: strings.TrimSpace(line[1:])})
+			}
+			continue
+		}
+
+		keyword, value, ok := strings.Cut(line, ":")
+		if !ok || !isFSHKeyword(keyword) {
+			// Rules such as "contains" may continue on the following lines
+			if entity != nil && !done && len(rules) > 0 {
+				rules[len(rules)-1].text += " " + line
+			}
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"""`) {
+			value, i = fshMultilineString(lines, i, value)
+		}
+		switch {
+		case keyword == "Alias":
+			name, url, _ := strings.Cut(value, "=")
+			aliases[strings.TrimSpace(name)] = strings.TrimSpace(url)
+		case slices.Contains(fshEntityKeywords, keyword):
+			if entity != nil {
+				done = true
+			} else if slices.Contains(fshEntities, keyword) && (name == "" || value == name) {
+				entity = &fshEntity{kind: keyword, name: value}
+			}
+		case entity == nil || done:
+		case keyword == "Parent":
+			entity.parent = value
+		case keyword == "Id":
+			entity.id = value
+		case keyword == "Title":
+			entity.title = fshString(value)
+		case keyword == "Description":
+			entity.description = fshString(value)
+		}
+	}
+	if entity == nil && name != "" {
+		return nil, fmt.Errorf("no Profile, Extension, Logical or Resource named %q", name)
+	}
+	if entity == nil {
+		return nil, errors.New("no Profile, Extension, Logical or Resource definition")
+	}
+
+	b := newFSHBuilder(ctx, entity, aliases)
+	for _, rule := range rules {
+		if err := b.apply(rule); err != nil {
+			return nil, fmt.Errorf("line %d: %w", rule.number, err)
+		}
+	}
+	return convertStructureDefinition(ctx, b.sd, baseRegistry)
+}
+
+// fshLine is a rule line with its 1-based number and indentation
+type fshLine struct {
+	number, indent int
+	text           string
+}
+
+// fshBuilder collects the differential of the entity from its rules
+type fshBuilder struct {
+	sd      structureDefinition
+	kind    string // FSH keyword of the entity
+	root    string
+	aliases map[string]string
+	index   map[string]int // Element id → index in the differential
+
+	// context holds the paths of the enclosing rules of indented rules
+	context []fshLine
+}
+
+// newFSHBuilder starts the StructureDefinition of entity. Profiles and
+// extensions constrain their parent; logical models and resources
+// specialize it and name their root after themselves.
+func newFSHBuilder(ctx context.Context, entity *fshEntity, aliases map[string]string) *fshBuilder {
+	b := &fshBuilder{kind: entity.kind, aliases: aliases, index: map[string]int{}}
+	parent := b.resolve(entity.parent)
+	if parent == "" {
+		parent = map[string]string{"Profile": "Resource", "Extension": "Extension", "Logical": "Base", "Resource": "DomainResource"}[entity.kind]
+	}
+	base := parent
+	if !isURL(base) {
+		base = coreCanonicalPrefix + parent
+	}
+
+	b.sd = structureDefinition{
+		ID:             entity.id,
+		Name:           entity.name,
+		Title:          entity.title,
+		Status:         models.StatusDraft,
+		BaseDefinition: base,
+		Derivation:     "constraint",
+		Differential:   &elementList{},
+	}
+	b.root = lastSegment(parent, "/")
+	if parentSD, err := resolveDefinition(ctx, baseRegistry, base, ""); err == nil && rootType(parentSD) != "" {
+		// Profiles of profiles constrain the type of their parent
+		b.root = rootType(parentSD)
+	}
+	if entity.kind == "Logical" || entity.kind == "Resource" {
+		b.root = entity.name
+		b.sd.Kind = "logical"
+		b.sd.Derivation = "specialization"
+	}
+	b.sd.Type = b.root
+	b.element(b.root).Short = entity.description
+	return b
+}
+
+// apply applies one rule to the differential
+func (b *fshBuilder) apply(rule fshLine) error {
+	// Indented rules continue the path of the closest less indented rule
+	for len(b.context) > 0 && b.context[len(b.context)-1].indent >= rule.indent {
+		b.context = b.context[:len(b.context)-1]
+	}
+	tokens := fshTokens(rule.text)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	path := ""
+	if !strings.HasPrefix(tokens[0], "^") && tokens[0] != "insert" && tokens[0] != "obeys" {
+		path, tokens = tokens[0], tokens[1:]
+	}
+	if len(b.context) > 0 {
+		parent := b.context[len(b.context)-1].text
+		if path == "" || path == "." {
+			path = parent
+		} else {
+			path = parent + "." + path
+		}
+	}
+	b.context = append(b.context, fshLine{indent: rule.indent, text: path})
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	id, err := b.id(path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case tokens[0] == "insert":
+		return nil
+	case strings.HasPrefix(tokens[0], "^"):
+		return b.caret(id, tokens)
+	case tokens[0] == "contains":
+		return b.contains(id, tokens[1:])
+	case tokens[0] == "obeys":
+		b.element(id).Constraint = append(b.element(id).Constraint, struct {
+			Key string `json:"key"`
+		}{Key: strings.Join(tokens[1:], " ")})
+	case tokens[0] == "only":
+		b.element(id).Type = b.types(tokens[1:])
+	case tokens[0] == "from":
+		if len(tokens) < 2 {
+			return errors.New("binding without value set")
+		}
+		strength := "required"
+		if len(tokens) > 2 {
+			strength = strings.Trim(tokens[2], "()")
+		}
+		b.element(id).Binding = &edBinding{Strength: strength, ValueSet: b.resolve(tokens[1])}
+	case tokens[0] == "=":
+		if len(tokens) < 2 {
+			return errors.New("assignment without value")
+		}
+		value := b.value(tokens[1:])
+		if slices.Contains(tokens, "(exactly)") {
+			b.element(id).Fixed = value
+		} else {
+			b.element(id).Pattern = value
+		}
+	default:
+		b.definition(id, tokens)
+	}
+	return nil
+}
+
+// definition applies a cardinality and flags rule, followed by types and
+// descriptions in logical models and resources
+func (b *fshBuilder) definition(id string, tokens []string) {
+	elem := b.element(id)
+	if len(tokens) > 0 && fshCardinality.MatchString(tokens[0]) {
+		min, max, _ := strings.Cut(tokens[0], "..")
+		if min != "" {
+			n, _ := strconv.Atoi(min)
+			elem.Min = (*flexInt)(&n)
+		}
+		elem.Max = max
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 && slices.Contains(fshFlags, tokens[0]) {
+		switch tokens[0] {
+		case "MS":
+			elem.MustSupport = true
+		case "SU":
+			elem.IsSummary = true
+		case "?!":
+			elem.IsModifier = true
+		}
+		tokens = tokens[1:]
+	}
+
+	var types, texts []string
+	for _, token := range tokens {
+		if token[0] == '"' {
+			texts = append(texts, fshString(token))
+		} else if len(texts) == 0 {
+			types = append(types, token)
+		}
+	}
+	if len(types) > 0 {
+		elem.Type = b.types(types)
+	}
+	if len(texts) > 0 {
+		elem.Short = texts[0]
+		elem.Definition = texts[len(texts)-1]
+	}
+}
+
+// caret applies the caret rules that show in the diagram
+func (b *fshBuilder) caret(id string, tokens []string) error {
+	if len(tokens) < 3 || tokens[1] != "=" {
+		return nil
+	}
+	value := b.value(tokens[2:])
+	if id == b.root {
+		switch tokens[0] {
+		case "^status":
+			b.sd.Status = value
+		case "^version":
+			b.sd.Version = value
+		case "^publisher":
+			b.sd.Publisher = value
+		case "^date":
+			b.sd.Date = value
+		case "^title":
+			b.sd.Title = value
+		}
+	}
+	switch tokens[0] {
+	case "^short":
+		b.element(id).Short = value
+	case "^definition":
+		b.element(id).Definition = value
+	}
+	return nil
+}
+
+// contains adds the slices of "contains a 0..1 MS and b 1..1"; on
+// extensions the items name the extension, optionally "named" a slice.
+// Extensions define their sub-extensions inline, unless an item names an
+// alias or url.
+func (b *fshBuilder) contains(id string, tokens []string) error {
+	extension := strings.HasSuffix(id, "xtension")
+	for _, item := range splitTokens(tokens, "and") {
+		if len(item) == 0 {
+			return errors.New("empty contains item")
+		}
+		name, profile := item[0], ""
+		item = item[1:]
+		if len(item) > 1 && item[0] == "named" {
+			profile, name, item = name, item[1], item[2:]
+		} else if extension {
+			profile = name
+		}
+
+		sliceID := id + ":" + name
+		elem := b.element(sliceID)
+		elem.SliceName = name
+		if extension && b.kind == "Extension" && !isURL(b.resolve(profile)) {
+			elem.Type = []edType{{Code: "Extension"}}
+		} else if extension {
+			elem.Type = []edType{{Code: "Extension", Profile: []string{b.resolve(profile)}}}
+		}
+		b.definition(sliceID, item)
+	}
+	return nil
+}
+
+// types parses "A or B or Reference(C or D)" into element types
+func (b *fshBuilder) types(tokens []string) []edType {
+	var types []edType
+	for _, item := range splitTokens(tokens, "or") {
+		name := strings.Join(item, " ")
+		code, targets, ok := strings.Cut(name, "(")
+		t := edType{Code: b.resolve(strings.TrimSpace(code))}
+		if ok {
+			for _, target := range strings.Split(strings.TrimSuffix(targets, ")"), " or ") {
+				target = b.resolve(strings.TrimSpace(target))
+				if !isURL(target) {
+					target = coreCanonicalPrefix + target
+				}
+				t.TargetProfile = append(t.TargetProfile, target)
+			}
+		}
+		types = append(types, t)
+	}
+	return types
+}
+
+// value returns an assigned value as shown in the diagram: codes as
+// "system#code", strings unquoted
+func (b *fshBuilder) value(tokens []string) string {
+	value := tokens[0]
+	if strings.HasPrefix(value, `"`) {
+		return fshString(value)
+	}
+	system, code, ok := strings.Cut(value, "#")
+	if !ok {
+		return b.resolve(value)
+	}
+	if system == "" {
+		return code
+	}
+	return b.resolve(system) + "#" + code
+}
+
+// id returns the element id of a FSH path relative to the root, e.g.
+// "Observation.component:systolic.code" for "component[systolic].code".
+// Numeric and soft indices select no slice.
+func (b *fshBuilder) id(path string) (string, error) {
+	id := b.root
+	if path == "" || path == "." {
+		return id, nil
+	}
+	for _, segment := range strings.Split(path, ".") {
+		name, slice, sliced := strings.Cut(segment, "[")
+		if name == "" {
+			return "", fmt.Errorf("invalid path %q", path)
+		}
+		slice = strings.TrimSuffix(slice, "]")
+		if slice == "x" {
+			// Choice elements such as value[x] are no slices
+			name, sliced = name+"[x]", false
+		}
+		id += "." + name
+		if sliced && slice != "+" && slice != "=" {
+			if _, err := strconv.Atoi(slice); err != nil {
+				id += ":" + slice
+			}
+		}
+	}
+	return id, nil
+}
+
+// element returns the differential element with id, adding it when
+// missing
+func (b *fshBuilder) element(id string) *elementDefinition {
+	i, ok := b.index[id]
+	if !ok {
+		i = len(b.sd.Differential.Element)
+		b.index[id] = i
+		b.sd.Differential.Element = append(b.sd.Differential.Element, elementDefinition{ID: id, Path: fshSliceNames.ReplaceAllString(id, "")})
+	}
+	return &b.sd.Differential.Element[i]
+}
+
+// resolve replaces an alias by its url
+func (b *fshBuilder) resolve(name string) string {
+	if url, ok := b.aliases[name]; ok {
+		return url
+	}
+	return name
+}
+
+// fshTokens splits a rule on whitespace, keeping quoted strings and
+// parenthesized groups such as "Reference(Patient or Group)" together
+func fshTokens(rule string) []string {
+	var tokens []string
+	var current strings.Builder
+	quoted, depth := false, 0
+	for i := 0; i < len(rule); i++ {
+		c := rule[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(rule):
+			current.WriteByte(c)
+			i++
+			c = rule[i]
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == '(':
+			depth++
+		case !quoted && c == ')':
+			depth--
+		case !quoted && depth == 0 && (c == ' ' || c == '\t'):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteByte(c)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// splitTokens splits tokens on a separator word such as "and"
+func splitTokens(tokens []string, sep string) [][]string {
+	var items [][]string
+	start := 0
+	for i, token := range tokens {
+		if token == sep {
+			items = append(items, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, tokens[start:])
+}
+
+// fshString unquotes a FSH string, returning other values unchanged
+func fshString(value string) string {
+	if s, err := strconv.Unquote(value); err == nil {
+		return s
+	}
+	return strings.Trim(value, `"`)
+}
+
+// fshMultilineString reads a """ string starting on line i, returning it
+// and the index of its last line
+func fshMultilineString(lines []string, i int, value string) (string, int) {
+	text := strings.TrimPrefix(value, `"""`)
+	var parts []string
+	for {
+		if before, _, ok := strings.Cut(text, `"""`); ok {
+			parts = append(parts, before)
+			break
+		}
+		parts = append(parts, text)
+		if i+1 >= len(lines) {
+			break
+		}
+		i++
+		text = strings.TrimSpace(lines[i])
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n")), i
+}
+
+// stripFSHComments removes // line comments and /* */ block comments outside
+// strings. "//" only starts a comment at the line start or after
+// whitespace, so urls such as http://loinc.org are kept.
+func stripFSHComments(source string) string {
+	var sb strings.Builder
+	quoted, block := false, false
+	for i := 0; i < len(source); i++ {
+		c := source[i]
+		switch {
+		case block:
+			if strings.HasPrefix(source[i:], "*/") {
+				block = false
+				i++
+			} else if c == '\n' {
+				sb.WriteByte(c)
+			}
+			continue
+		case quoted && c == '\\' && i+1 < len(source):
+			sb.WriteByte(c)
+			i++
+			c = source[i]
+		case c == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(source[i:], "/*"):
+			block = true
+			i++
+			continue
+		case !quoted && strings.HasPrefix(source[i:], "//") && (i == 0 || source[i-1] == ' ' || source[i-1] == '\t' || source[i-1] == '\n'):
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+			if i < len(source) {
+				sb.WriteByte('\n')
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,92 @@
+package convert
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrPackageTooLarge is returned when a package expands beyond the allowed size
+var ErrPackageTooLarge = errors.New("package contents exceed the size limit")
+
+// Package is an NPM-style FHIR package with its StructureDefinitions
+type Package struct {
+	Name        string
+	Version     string
+	Definitions []PackageFile // StructureDefinitions ordered by file name
+}
+
+// PackageFile is a resource file from a package's package/ folder
+type PackageFile struct {
+	Name string // File name without the package/ folder
+	Data []byte
+}
+
+// ReadPackage reads a gzipped package tarball and collects the
+// StructureDefinitions in its package/ folder. Examples and other sub
+// folders are ignored. maxBytes caps the total size of the extracted files.
+func ReadPackage(r io.Reader, maxBytes int64) (*Package, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a gzipped tarball: %w", err)
+	}
+	defer gz.Close()
+
+	pkg := &Package{}
+	remaining := maxBytes
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tarball: %w", err)
+		}
+
+		dir, name := path.Split(path.Clean(header.Name))
+		if header.Typeflag != tar.TypeReg || dir != "package/" || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if header.Size > remaining {
+			return nil, ErrPackageTooLarge
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, header.Size))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		remaining -= int64(len(data))
+
+		switch {
+		case name == "package.json":
+			var manifest struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			}
+			if json.Unmarshal(data, &manifest) == nil {
+				pkg.Name, pkg.Version = manifest.Name, manifest.Version
+			}
+		case isStructureDefinition(data):
+			pkg.Definitions = append(pkg.Definitions, PackageFile{Name: name, Data: data})
+		}
+	}
+
+	sort.Slice(pkg.Definitions, func(i, j int) bool {
+		return pkg.Definitions[i].Name < pkg.Definitions[j].Name
+	})
+	return pkg, nil
+}
+
+// isStructureDefinition reports whether data is a FHIR StructureDefinition
+func isStructureDefinition(data []byte) bool {
+	var header struct {
+		ResourceType string `json:"resourceType"`
+	}
+	return json.Unmarshal(data, &header) == nil && header.ResourceType == "StructureDefinition"
+}
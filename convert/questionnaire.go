@@ -0,0 +1,167 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// questionnaire holds the parts of a FHIR Questionnaire that are rendered
+type questionnaire struct {
+	ID        string              `json:"id"`
+	URL       string              `json:"url"`
+	Version   string              `json:"version"`
+	Name      string              `json:"name"`
+	Title     string              `json:"title"`
+	Status    string              `json:"status"`
+	Publisher string              `json:"publisher"`
+	Date      string              `json:"date"`
+	Item      []questionnaireItem `json:"item"`
+}
+
+// questionnaireItem is a question or group of a Questionnaire
+type questionnaireItem struct {
+	LinkID         string              `json:"linkId"`
+	Text           string              `json:"text"`
+	Type           string              `json:"type"`
+	Required       flexBool            `json:"required"`
+	Repeats        flexBool            `json:"repeats"`
+	ReadOnly       flexBool            `json:"readOnly"`
+	AnswerValueSet string              `json:"answerValueSet"`
+	AnswerOption   []answerOption      `json:"answerOption"`
+	Item           []questionnaireItem `json:"item"`
+}
+
+// answerOption is one permitted answer; only one value[x] is set
+type answerOption struct {
+	ValueCoding *struct {
+		Code    string `json:"code"`
+		Display string `json:"display"`
+	} `json:"valueCoding"`
+	ValueString  string `json:"valueString"`
+	ValueInteger any    `json:"valueInteger"`
+	ValueDate    string `json:"valueDate"`
+}
+
+// label returns the option as shown in the binding
+func (o answerOption) label() string {
+	switch {
+	case o.ValueCoding != nil && o.ValueCoding.Code != "":
+		return o.ValueCoding.Code
+	case o.ValueCoding != nil:
+		return o.ValueCoding.Display
+	case o.ValueString != "":
+		return o.ValueString
+	case o.ValueInteger != nil:
+		data, _ := json.Marshal(o.ValueInteger)
+		return strings.Trim(string(data), `"`)
+	default:
+		return o.ValueDate
+	}
+}
+
+// Questionnaire converts a FHIR Questionnaire into a ResourceDefinition.
+// Items become elements named by linkId with their text as description,
+// required/repeats as cardinality and answerValueSet or answerOption as
+// binding.
+func Questionnaire(data []byte) (*models.ResourceDefinition, error) {
+	var q questionnaire
+	if err := json.Unmarshal(data, &q); err != nil {
+		return nil, err
+	}
+
+	name := firstNonEmpty(q.Name, q.Title, q.ID)
+	if name == "" {
+		return nil, errors.New("questionnaire has no name, title or id")
+	}
+
+	return &models.ResourceDefinition{
+		ResourceType: "Questionnaire",
+		Name:         name,
+		Version:      q.Version,
+		Status:       q.Status,
+		Publisher:    q.Publisher,
+		Date:         q.Date,
+		Type:         "Questionnaire",
+		Description:  q.Title,
+		Elements:     questionnaireElements(q.Item),
+	}, nil
+}
+
+// questionnaireElements converts items and their nested items
+func questionnaireElements(items []questionnaireItem) []models.Element {
+	var elements []models.Element
+	for _, item := range items {
+		elem := models.Element{
+			Name:        firstNonEmpty(item.LinkID, item.Text),
+			Type:        item.Type,
+			Cardinality: itemCardinality(item),
+			Description: item.Text,
+			Elements:    questionnaireElements(item.Item),
+		}
+		if item.ReadOnly {
+			elem.Notes = "Read only"
+		}
+
+		if binding := itemBinding(item); binding != nil {
+			elem.Binding = binding
+			answers := "Answers: " + binding.ValueSet
+			if elem.Notes != "" {
+				answers = elem.Notes + ". " + answers
+			}
+			elem.Notes = answers
+		}
+		elements = append(elements, elem)
+	}
+	return elements
+}
+
+// itemCardinality maps required and repeats to a cardinality. Display items
+// carry no answer and have no cardinality.
+func itemCardinality(item questionnaireItem) string {
+	if item.Type == "display" {
+		return ""
+	}
+	card := "0.."
+	if item.Required {
+		card = "1.."
+	}
+	if item.Repeats {
+		return card + "*"
+	}
+	return card + "1"
+}
+
+// itemBinding returns the answer binding of an item, if it has one
+func itemBinding(item questionnaireItem) *models.Binding {
+	if item.AnswerValueSet != "" {
+		binding := &models.Binding{Strength: "required", ValueSet: item.AnswerValueSet}
+		if strings.HasPrefix(item.AnswerValueSet, "http") {
+			binding.URL = item.AnswerValueSet
+		}
+		return binding
+	}
+	if len(item.AnswerOption) == 0 {
+		return nil
+	}
+
+	labels := make([]string, 0, len(item.AnswerOption))
+	for _, option := range item.AnswerOption {
+		if label := option.label(); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return &models.Binding{Strength: "required", ValueSet: strings.Join(labels, " | ")}
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
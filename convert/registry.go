@@ -0,0 +1,135 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// ErrNotInRegistry is returned when a canonical URL cannot be resolved
+var ErrNotInRegistry = errors.New("definition not found in registry")
+
+// Registry resolves the canonical URLs of base StructureDefinitions
+type Registry interface {
+	// Resolve returns the StructureDefinition JSON for url, or
+	// ErrNotInRegistry. Registries that download definitions stop when ctx
+	// is done.
+	Resolve(ctx context.Context, url string) ([]byte, error)
+}
+
+// baseRegistry resolves base definitions for snapshot generation; nil
+// renders differentials as they are
+var baseRegistry Registry
+
+// SetBaseRegistry configures the registry used to generate snapshots
+func SetBaseRegistry(r Registry) {
+	baseRegistry = r
+}
+
+// BaseRegistry returns the configured registry, or nil
+func BaseRegistry() Registry {
+	return baseRegistry
+}
+
+// MapRegistry holds StructureDefinitions in memory, keyed by canonical URL
+type MapRegistry struct {
+	definitions map[string][]byte
+	releases    map[string][]byte // By canonical URL and FHIR version, "url|R4"
+}
+
+// NewMapRegistry indexes StructureDefinition documents by their url
+func NewMapRegistry(documents ...[]byte) *MapRegistry {
+	r := &MapRegistry{definitions: make(map[string][]byte), releases: make(map[string][]byte)}
+	for _, data := range documents {
+		r.add(data)
+	}
+	return r
+}
+
+// add indexes a StructureDefinition, or every StructureDefinition entry of a
+// Bundle, and ignores other documents
+func (r *MapRegistry) add(data []byte) {
+	var doc struct {
+		ResourceType string `json:"resourceType"`
+		URL          string `json:"url"`
+		FHIRVersion  string `json:"fhirVersion"`
+		Entry        []struct {
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	if json.Unmarshal(data, &doc) != nil {
+		return
+	}
+	switch doc.ResourceType {
+	case "StructureDefinition":
+		if doc.URL == "" {
+			break
+		}
+		r.definitions[doc.URL] = data
+		if version, ok := models.ParseFHIRVersion(doc.FHIRVersion); ok {
+			r.releases[doc.URL+"|"+version] = data
+		}
+	case "Bundle":
+		for _, entry := range doc.Entry {
+			r.add(entry.Resource)
+		}
+	}
+}
+
+// Len returns the number of indexed definitions
+func (r *MapRegistry) Len() int {
+	return len(r.definitions)
+}
+
+// Resolve implements Registry. The "|version" of a core definition selects
+// the definition of that FHIR version, and definitions held only for other
+// FHIR versions are not returned. Other versions are ignored.
+func (r *MapRegistry) Resolve(_ context.Context, url string) ([]byte, error) {
+	url, version, _ := strings.Cut(url, "|")
+	if version, ok := models.ParseFHIRVersion(version); ok && strings.HasPrefix(url, coreCanonicalPrefix) {
+		if data, ok := r.releases[url+"|"+version]; ok {
+			return data, nil
+		}
+		for _, other := range models.FHIRVersions {
+			if _, ok := r.releases[url+"|"+other]; ok {
+				return nil, ErrNotInRegistry
+			}
+		}
+	}
+	if data, ok := r.definitions[url]; ok {
+		return data, nil
+	}
+	return nil, ErrNotInRegistry
+}
+
+// coreCanonicalPrefix is the canonical URL prefix of the core definitions
+const coreCanonicalPrefix = "http://hl7.org/fhir/StructureDefinition/"
+
+// registryChain tries each registry in turn
+type registryChain []Registry
+
+// Resolve implements Registry
+func (chain registryChain) Resolve(ctx context.Context, url string) ([]byte, error) {
+	for _, r := range chain {
+		if r == nil {
+			continue
+		}
+		data, err := r.Resolve(ctx, url)
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, ErrNotInRegistry) {
+			return nil, err
+		}
+	}
+	return nil, ErrNotInRegistry
+}
+
+// Registries combines registries; earlier ones take precedence. Nil entries
+// are skipped.
+func Registries(registries ...Registry) Registry {
+	return registryChain(registries)
+}
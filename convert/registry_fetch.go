@@ -0,0 +1,94 @@
+//go:build !js
+
+package convert
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"fhir_renderer/models"
+)
+
+// DefaultBaseDefinitionsCacheSize is the number of downloaded definitions a
+// FetchRegistry keeps when no cache size is configured
+const DefaultBaseDefinitionsCacheSize = 500
+
+// maxFetchedDefinitionSize caps the size of one downloaded definition
+const maxFetchedDefinitionSize = 16 << 20
+
+// FetchRegistry downloads core definitions on demand and caches them. The
+// template is expanded like the link templates: {name} is the type name from
+// the canonical URL, {lower} its lowercase form and {version} the FHIR
+// version of the definition being resolved (default R4), for example
+// https://hl7.org/fhir/{version}/{lower}.profile.json.
+type FetchRegistry struct {
+	template string
+	client   *http.Client
+	cache    *lookupCache[[]byte]
+}
+
+// NewFetchRegistry returns a registry fetching core definitions from
+// template, keeping up to cacheSize of them; cacheSize <= 0 selects
+// DefaultBaseDefinitionsCacheSize
+func NewFetchRegistry(template string, cacheSize int) *FetchRegistry {
+	if cacheSize <= 0 {
+		cacheSize = DefaultBaseDefinitionsCacheSize
+	}
+	return &FetchRegistry{
+		template: template,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		cache:    newLookupCache[[]byte](cacheSize),
+	}
+}
+
+// Resolve implements Registry. Only core canonical URLs are fetched.
+func (r *FetchRegistry) Resolve(ctx context.Context, canonical string) ([]byte, error) {
+	canonical, version, _ := strings.Cut(canonical, "|")
+	name, ok := strings.CutPrefix(canonical, coreCanonicalPrefix)
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return nil, ErrNotInRegistry
+	}
+	version, ok = models.ParseFHIRVersion(version)
+	if !ok {
+		version = models.DefaultFHIRVersion
+	}
+
+	target := strings.NewReplacer(
+		"{name}", url.PathEscape(name),
+		"{lower}", url.PathEscape(strings.ToLower(name)),
+		"{version}", version,
+	).Replace(r.template)
+	return r.cache.get(ctx, target, func(ctx context.Context) ([]byte, error) {
+		return r.fetch(ctx, target)
+	})
+}
+
+// fetch downloads a core definition from target
+func (r *FetchRegistry) fetch(ctx context.Context, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s returned %s", ErrNotInRegistry, target, resp.Status)
+	}
+	// Read one byte past the limit to tell a full body from a cut off one
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchedDefinitionSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFetchedDefinitionSize {
+		return nil, fmt.Errorf("registry too large: %s is over %d bytes", target, maxFetchedDefinitionSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,47 @@
+//go:build !js
+
+package convert
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchRegistrySize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		wantErr bool
+	}{
+		{name: "at the limit", size: maxFetchedDefinitionSize},
+		{name: "over the limit", size: maxFetchedDefinitionSize + 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(bytes.Repeat([]byte(" "), tt.size))
+			}))
+			defer server.Close()
+
+			registry := NewFetchRegistry(server.URL+"/{name}", 1)
+			data, err := registry.Resolve(context.Background(), "http://hl7.org/fhir/StructureDefinition/Patient")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "registry too large") {
+					t.Fatalf("got %d bytes and error %v, want a registry too large error", len(data), err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != tt.size {
+				t.Errorf("got %d bytes, want %d", len(data), tt.size)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+//go:build !js
+
+package convert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadRegistry reads base definitions from a FHIR package (.tgz, such as
+// hl7.fhir.r4.core), a directory of JSON files or a single JSON file. Bundles
+// such as profiles-resources.json are indexed entry by entry.
+func LoadRegistry(path string) (*MapRegistry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewMapRegistry()
+	switch {
+	case info.IsDir():
+		files, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			r.add(data)
+		}
+	case strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz"):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		pkg, err := ReadPackage(f, info.Size()*64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, file := range pkg.Definitions {
+			r.add(file.Data)
+		}
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		r.add(data)
+	}
+
+	if r.Len() == 0 {
+		return nil, fmt.Errorf("%s contains no StructureDefinitions", path)
+	}
+	return r, nil
+}
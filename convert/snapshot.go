@@ -0,0 +1,344 @@
+package convert
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// maxBaseChain limits how many profiles-on-profiles are followed
+const maxBaseChain = 10
+
+// generateSnapshot merges the differential of sd into the snapshot of its
+// base definition, resolved through registry. Bases without a snapshot get
+// one generated first. Complex datatypes are expanded from their own
+// definitions where the differential constrains their children.
+func generateSnapshot(ctx context.Context, sd structureDefinition, registry Registry, depth int) ([]elementDefinition, error) {
+	if depth > maxBaseChain {
+		return nil, errors.New("base definition chain is too long")
+	}
+	if sd.BaseDefinition == "" {
+		return nil, errors.New("structure definition has no baseDefinition")
+	}
+
+	base, err := resolveDefinition(ctx, registry, sd.BaseDefinition, sd.FHIRVersion)
+	if err != nil {
+		return nil, fmt.Errorf("resolving base %s: %w", sd.BaseDefinition, err)
+	}
+	baseElements, err := snapshotElements(ctx, base, registry, depth+1)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &snapshotBuilder{ctx: ctx, registry: registry, fhirVersion: sd.FHIRVersion, elements: slices.Clone(baseElements)}
+	for i := range s.elements {
+		s.elements[i].ID = elementID(s.elements[i])
+	}
+
+	// Specializations (new resources and logical models) take over the base
+	// elements under their own type name
+	if baseType, ownType := rootType(base), rootType(sd); baseType != "" && ownType != "" && baseType != ownType {
+		s.rebaseAll(baseType, ownType)
+	}
+
+	if sd.Differential == nil {
+		return s.elements, nil
+	}
+	for _, diff := range sd.Differential.Element {
+		id := elementID(diff)
+		i, ok := s.ensure(id)
+		if !ok {
+			s.insertNew(diff, id)
+			continue
+		}
+		s.elements[i] = mergeElement(s.elements[i], diff)
+	}
+	return s.elements, nil
+}
+
+// resolveDefinition loads and parses the StructureDefinition for url,
+// referred to by a definition of fhirVersion. Core definitions are looked up
+// in that FHIR version, and a definition without a fhirVersion of its own
+// takes it over.
+func resolveDefinition(ctx context.Context, registry Registry, url, fhirVersion string) (structureDefinition, error) {
+	var sd structureDefinition
+	if registry == nil {
+		return sd, ErrNotInRegistry
+	}
+	data, err := registry.Resolve(ctx, versionedCanonical(url, fhirVersion))
+	if err != nil {
+		return sd, err
+	}
+	err = json.Unmarshal(data, &sd)
+	sd.FHIRVersion = cmp.Or(sd.FHIRVersion, fhirVersion)
+	return sd, err
+}
+
+// versionedCanonical adds fhirVersion to a core canonical URL without a
+// version, so registries holding several FHIR versions pick the matching
+// definition
+func versionedCanonical(url, fhirVersion string) string {
+	if fhirVersion == "" || strings.Contains(url, "|") || !strings.HasPrefix(url, coreCanonicalPrefix) {
+		return url
+	}
+	return url + "|" + fhirVersion
+}
+
+// snapshotElements returns the snapshot of sd, generating it when only a
+// differential is present
+func snapshotElements(ctx context.Context, sd structureDefinition, registry Registry, depth int) ([]elementDefinition, error) {
+	if sd.Snapshot != nil && len(sd.Snapshot.Element) > 0 {
+		return sd.Snapshot.Element, nil
+	}
+	return generateSnapshot(ctx, sd, registry, depth)
+}
+
+// rootType returns the type name of a definition's root element. Logical
+// models have a url as type and name their root after its last segment
+// unless their elements say otherwise.
+func rootType(sd structureDefinition) string {
+	for _, elements := range []*elementList{sd.Snapshot, sd.Differential} {
+		if elements != nil && len(elements.Element) > 0 {
+			root, _, _ := strings.Cut(elementID(elements.Element[0]), ".")
+			return root
+		}
+	}
+	return lastSegment(sd.Type, "/")
+}
+
+// snapshotBuilder holds the snapshot while the differential is applied
+type snapshotBuilder struct {
+	ctx         context.Context // Bounds the registry lookups of expandType
+	registry    Registry
+	fhirVersion string // Of the definition, for looking up its datatypes
+	elements    []elementDefinition
+}
+
+// indexOf returns the position of the element with id, or -1
+func (s *snapshotBuilder) indexOf(id string) int {
+	return slices.IndexFunc(s.elements, func(e elementDefinition) bool { return e.ID == id })
+}
+
+// subtreeEnd returns the position after the last descendant or slice of the
+// element at i
+func (s *snapshotBuilder) subtreeEnd(i int) int {
+	id := s.elements[i].ID
+	end := i + 1
+	for end < len(s.elements) && isBelow(s.elements[end].ID, id) {
+		end++
+	}
+	return end
+}
+
+// isBelow reports whether id is a descendant or slice of ancestor
+func isBelow(id, ancestor string) bool {
+	return strings.HasPrefix(id, ancestor+".") || strings.HasPrefix(id, ancestor+":")
+}
+
+// ensure finds the element with id, creating it when the differential refers
+// to a slice, a renamed choice type or a child of a complex datatype
+func (s *snapshotBuilder) ensure(id string) (int, bool) {
+	if i := s.indexOf(id); i >= 0 {
+		return i, true
+	}
+
+	parentID, name := splitElementID(id)
+	if parentID == "" {
+		return -1, false
+	}
+
+	// A new slice starts as a copy of the sliced element and its children
+	if _, sliceName, ok := strings.Cut(name, ":"); ok {
+		sliced, ok := s.ensure(parentID)
+		if !ok {
+			return -1, false
+		}
+		return s.addSlice(sliced, id, sliceName), true
+	}
+
+	parent, ok := s.ensure(parentID)
+	if !ok {
+		return -1, false
+	}
+	if s.subtreeEnd(parent) == parent+1 {
+		s.expandType(parent)
+	}
+	if i := s.indexOf(id); i >= 0 {
+		return i, true
+	}
+	return s.renameChoice(parentID, name)
+}
+
+// addSlice inserts a slice of the element at sliced after its existing slices
+// and returns the slice's position
+func (s *snapshotBuilder) addSlice(sliced int, id, sliceName string) int {
+	slicedID := s.elements[sliced].ID
+	end := s.subtreeEnd(sliced)
+
+	var copies []elementDefinition
+	for _, e := range s.elements[sliced:end] {
+		if strings.HasPrefix(e.ID, slicedID+":") {
+			continue
+		}
+		e.ID = id + strings.TrimPrefix(e.ID, slicedID)
+		copies = append(copies, e)
+	}
+	copies[0].SliceName = sliceName
+
+	s.elements = slices.Insert(s.elements, end, copies...)
+	return end
+}
+
+// expandType inserts the children of the single complex type of the element
+// at i from the type's own definition
+func (s *snapshotBuilder) expandType(i int) {
+	elem := s.elements[i]
+	if len(elem.Type) != 1 {
+		return
+	}
+	t := elem.Type[0]
+	if !isComplexType(t.Code) {
+		return
+	}
+
+	url := coreCanonicalPrefix + t.Code
+	if len(t.Profile) > 0 {
+		url = t.Profile[0]
+	}
+	typeDef, err := resolveDefinition(s.ctx, s.registry, url, s.fhirVersion)
+	if err != nil {
+		return
+	}
+	typeElements, err := snapshotElements(s.ctx, typeDef, s.registry, 1)
+	if err != nil || len(typeElements) < 2 {
+		return
+	}
+
+	rootID := elementID(typeElements[0])
+	children := make([]elementDefinition, 0, len(typeElements)-1)
+	for _, e := range typeElements[1:] {
+		e.ID = elem.ID + strings.TrimPrefix(elementID(e), rootID)
+		e.Path = elem.Path + strings.TrimPrefix(e.Path, typeElements[0].Path)
+		children = append(children, e)
+	}
+	s.elements = slices.Insert(s.elements, i+1, children...)
+}
+
+// isComplexType reports whether a type code names a datatype with children
+// worth expanding
+func isComplexType(code string) bool {
+	if code == "" || code == "Element" || code == "BackboneElement" || code == "Resource" {
+		return false
+	}
+	return unicode.IsUpper(rune(code[0]))
+}
+
+// renameChoice maps a type-specific choice name such as valueQuantity onto
+// the value[x] element, restricting it to that type
+func (s *snapshotBuilder) renameChoice(parentID, name string) (int, bool) {
+	for i, e := range s.elements {
+		prefix, ok := strings.CutSuffix(e.ID, "[x]")
+		if !ok || !strings.HasPrefix(prefix, parentID+".") {
+			continue
+		}
+		choiceName := strings.TrimPrefix(prefix, parentID+".")
+		if strings.ContainsAny(choiceName, ".:") {
+			continue
+		}
+		typeName, ok := strings.CutPrefix(name, choiceName)
+		if !ok || typeName == "" || !unicode.IsUpper(rune(typeName[0])) {
+			continue
+		}
+
+		oldID, end := e.ID, s.subtreeEnd(i)
+		for j := i; j < end; j++ {
+			s.elements[j].ID = parentID + "." + name + strings.TrimPrefix(s.elements[j].ID, oldID)
+		}
+		for _, t := range e.Type {
+			if strings.EqualFold(t.Code, typeName) {
+				s.elements[i].Type = []edType{t}
+				break
+			}
+		}
+		return i, true
+	}
+	return -1, false
+}
+
+// insertNew adds a differential element unknown to the base after its
+// parent's subtree, or at the end
+func (s *snapshotBuilder) insertNew(diff elementDefinition, id string) {
+	diff.ID = id
+	parentID, _ := splitElementID(id)
+	at := len(s.elements)
+	if parent := s.indexOf(parentID); parent >= 0 {
+		at = s.subtreeEnd(parent)
+	}
+	s.elements = slices.Insert(s.elements, at, diff)
+}
+
+// rebaseAll replaces the root type name of every element id and path
+func (s *snapshotBuilder) rebaseAll(from, to string) {
+	for i := range s.elements {
+		e := &s.elements[i]
+		if e.ID == from || isBelow(e.ID, from) {
+			e.ID = to + strings.TrimPrefix(e.ID, from)
+		}
+		if e.Path == from || strings.HasPrefix(e.Path, from+".") {
+			e.Path = to + strings.TrimPrefix(e.Path, from)
+		}
+	}
+}
+
+// mergeElement overlays the constraints of a differential element on its
+// snapshot element. Flags can only be switched on, constraints and mappings
+// accumulate.
+func mergeElement(base, diff elementDefinition) elementDefinition {
+	if diff.SliceName != "" {
+		base.SliceName = diff.SliceName
+	}
+	if diff.Min != nil {
+		base.Min = diff.Min
+	}
+	if diff.Max != "" {
+		base.Max = diff.Max
+	}
+	if diff.Short != "" {
+		base.Short = diff.Short
+	}
+	if diff.Definition != "" {
+		base.Definition = diff.Definition
+	}
+	if diff.ContentReference != "" {
+		base.ContentReference = diff.ContentReference
+	}
+	if len(diff.Type) > 0 {
+		base.Type = diff.Type
+	}
+	base.MustSupport = base.MustSupport || diff.MustSupport
+	base.IsModifier = base.IsModifier || diff.IsModifier
+	base.IsSummary = base.IsSummary || diff.IsSummary
+	if len(diff.Constraint) > 0 {
+		base.Constraint = append(slices.Clip(base.Constraint), diff.Constraint...)
+	}
+	if diff.Binding != nil {
+		base.Binding = diff.Binding
+	}
+	if diff.Fixed != "" {
+		base.Fixed = diff.Fixed
+	}
+	if diff.Pattern != "" {
+		base.Pattern = diff.Pattern
+	}
+	for _, mapping := range diff.Mapping {
+		if !slices.Contains(base.Mapping, mapping) {
+			base.Mapping = append(slices.Clip(base.Mapping), mapping)
+		}
+	}
+	return base
+}
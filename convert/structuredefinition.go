@@ -0,0 +1,430 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// structureDefinition holds the parts of a FHIR StructureDefinition that are
+// rendered
+type structureDefinition struct {
+	ID             string       `json:"id"`
+	URL            string       `json:"url"`
+	Version        string       `json:"version"`
+	Name           string       `json:"name"`
+	Title          string       `json:"title"`
+	Status         string       `json:"status"`
+	Publisher      string       `json:"publisher"`
+	Date           string       `json:"date"`
+	Type           string       `json:"type"`
+	BaseDefinition string       `json:"baseDefinition"`
+	Derivation     string       `json:"derivation"`
+	Kind           string       `json:"kind"`
+	FHIRVersion    string       `json:"fhirVersion"`
+	Snapshot       *elementList `json:"snapshot"`
+	Differential   *elementList `json:"differential"`
+}
+
+// elementList is the snapshot or differential of a StructureDefinition
+type elementList struct {
+	Element []elementDefinition `json:"element"`
+}
+
+// elementDefinition is one element of a StructureDefinition snapshot or
+// differential
+type elementDefinition struct {
+	ID               string   `json:"id"`
+	Path             string   `json:"path"`
+	SliceName        string   `json:"sliceName"`
+	Min              *flexInt `json:"min"`
+	Max              string   `json:"max"`
+	Short            string   `json:"short"`
+	Definition       string   `json:"definition"`
+	ContentReference string   `json:"contentReference"`
+	Type             []edType `json:"type"`
+	MustSupport      flexBool `json:"mustSupport"`
+	IsModifier       flexBool `json:"isModifier"`
+	IsSummary        flexBool `json:"isSummary"`
+	Constraint       []struct {
+		Key string `json:"key"`
+	} `json:"constraint"`
+	Binding *edBinding       `json:"binding"`
+	Mapping []models.Mapping `json:"mapping"`
+
+	// Fixed and Pattern display the fixed[x] and pattern[x] values, whatever
+	// their type (see UnmarshalJSON)
+	Fixed   string `json:"-"`
+	Pattern string `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, collecting the choice-typed
+// fixed[x] and pattern[x] values
+func (d *elementDefinition) UnmarshalJSON(data []byte) error {
+	type plain elementDefinition
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, value := range fields {
+		if isChoiceOf(key, "fixed") {
+			d.Fixed = displayValue(value)
+		} else if isChoiceOf(key, "pattern") {
+			d.Pattern = displayValue(value)
+		}
+	}
+	return nil
+}
+
+// isChoiceOf reports whether key is a choice-typed variant of name, e.g.
+// "fixedCode" of "fixed"
+func isChoiceOf(key, name string) bool {
+	typ, ok := strings.CutPrefix(key, name)
+	return ok && typ != "" && typ[0] >= 'A' && typ[0] <= 'Z'
+}
+
+// displayValue returns a JSON value as shown in the diagram: strings
+// unquoted, everything else as single-line JSON with spaces between tokens
+// so it can wrap
+func displayValue(value json.RawMessage) string {
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	// Indenting without an indent string puts each token on its own line;
+	// JSON strings cannot contain raw newlines, so joining the lines is safe
+	var indented bytes.Buffer
+	if json.Indent(&indented, value, "", "") != nil {
+		return string(value)
+	}
+	return strings.ReplaceAll(indented.String(), "\n", " ")
+}
+
+// edBinding is the value set binding of an element
+type edBinding struct {
+	Strength string `json:"strength"`
+	ValueSet string `json:"valueSet"`
+
+	// STU3 references the value set by either of these instead
+	ValueSetURI       string `json:"valueSetUri"`
+	ValueSetReference *struct {
+		Reference string `json:"reference"`
+	} `json:"valueSetReference"`
+}
+
+// edType is a permitted type of an element. STU3 gives a single profile
+// and target profile as strings.
+type edType struct {
+	Code          string      `json:"code"`
+	Profile       flexStrings `json:"profile"`
+	TargetProfile flexStrings `json:"targetProfile"`
+}
+
+// flexInt decodes a JSON number, also accepting the numeric strings produced
+// by FHIR XML conversion
+type flexInt int
+
+// UnmarshalJSON implements json.Unmarshaler
+func (n *flexInt) UnmarshalJSON(data []byte) error {
+	var v int
+	if err := json.Unmarshal([]byte(strings.Trim(string(data), `"`)), &v); err != nil {
+		return err
+	}
+	*n = flexInt(v)
+	return nil
+}
+
+// inheritedConstraints are carried by every element and do not earn the I flag
+var inheritedConstraints = []string{"ele-1", "ext-1"}
+
+// systemTypePrefix prefixes the FHIRPath system types used for primitive values
+const systemTypePrefix = "http://hl7.org/fhirpath/System."
+
+// StructureDefinition converts a FHIR StructureDefinition into a
+// ResourceDefinition, resolving base definitions through the configured base
+// registry.
+func StructureDefinition(ctx context.Context, data []byte) (*models.ResourceDefinition, error) {
+	return StructureDefinitionWithRegistry(ctx, data, baseRegistry)
+}
+
+// StructureDefinitionWithRegistry converts a FHIR StructureDefinition into a
+// ResourceDefinition. The snapshot is used when present; otherwise one is
+// generated from the differential and the base definition in registry,
+// falling back to the bare differential when the base cannot be resolved.
+// Elements are nested by their ids with slices below the element they slice.
+// Logical models (kind logical) work the same way; types naming other
+// logical models by url show their last segment and link to the url.
+func StructureDefinitionWithRegistry(ctx context.Context, data []byte, registry Registry) (*models.ResourceDefinition, error) {
+	var sd structureDefinition
+	if err := json.Unmarshal(data, &sd); err != nil {
+		return nil, err
+	}
+	return convertStructureDefinition(ctx, sd, registry)
+}
+
+// convertStructureDefinition converts a parsed StructureDefinition, see
+// StructureDefinitionWithRegistry
+func convertStructureDefinition(ctx context.Context, sd structureDefinition, registry Registry) (*models.ResourceDefinition, error) {
+	name := firstNonEmpty(sd.Name, sd.Title, sd.ID)
+	if name == "" {
+		return nil, errors.New("structure definition has no name, title or id")
+	}
+
+	var elements []elementDefinition
+	switch {
+	case sd.Snapshot != nil && len(sd.Snapshot.Element) > 0:
+		elements = sd.Snapshot.Element
+	case sd.Differential != nil && len(sd.Differential.Element) > 0:
+		elements = sd.Differential.Element
+		if snapshot, err := generateSnapshot(ctx, sd, registry, 0); err == nil {
+			elements = snapshot
+		}
+	default:
+		return nil, errors.New("structure definition has neither a snapshot nor a differential")
+	}
+
+	return resourceFromElements(sd, name, elements, nil), nil
+}
+
+// resourceFromElements builds the ResourceDefinition for a definition's
+// snapshot or differential elements. annotate, when set, is called for
+// every converted element with its definition.
+func resourceFromElements(sd structureDefinition, name string, elements []elementDefinition, annotate func(elementDefinition, *models.Element)) *models.ResourceDefinition {
+	root, children := elementTree(elements, annotate)
+	description := sd.Title
+	if description == "" && root != nil {
+		description = root.Short
+	}
+
+	return &models.ResourceDefinition{
+		ResourceType: "StructureDefinition",
+		Name:         name,
+		Version:      sd.Version,
+		Status:       sd.Status,
+		Publisher:    sd.Publisher,
+		Date:         sd.Date,
+		FHIRVersion:  fhirVersion(sd.FHIRVersion),
+		Type:         firstNonEmpty(lastSegment(sd.BaseDefinition, "/"), sd.Type, "Resource"),
+		Description:  description,
+		Elements:     children,
+	}
+}
+
+// fhirVersion returns the FHIR release of a StructureDefinition's
+// fhirVersion, e.g. "R4" for "4.0.1", or "" when it is missing or unknown
+func fhirVersion(number string) string {
+	version, _ := models.ParseFHIRVersion(number)
+	return version
+}
+
+// elementNode is an element with its children while the tree is built
+type elementNode struct {
+	def      elementDefinition
+	name     string
+	children []*elementNode
+}
+
+// elementTree nests the flat element list by id. It returns the root element
+// definition, if present, and the converted children of the root. Ancestors
+// missing from a differential are added as plain name-only elements, which
+// annotate sees with an empty definition.
+func elementTree(elements []elementDefinition, annotate func(elementDefinition, *models.Element)) (*elementDefinition, []models.Element) {
+	var root *elementDefinition
+	top := &elementNode{}
+	nodes := map[string]*elementNode{}
+
+	var ensure func(id string) *elementNode
+	ensure = func(id string) *elementNode {
+		if node, ok := nodes[id]; ok {
+			return node
+		}
+		parentID, name := splitElementID(id)
+		if parentID == "" {
+			return top
+		}
+		node := &elementNode{name: name}
+		nodes[id] = node
+		parent := ensure(parentID)
+		parent.children = append(parent.children, node)
+		return node
+	}
+
+	for i := range elements {
+		id := elementID(elements[i])
+		if !strings.Contains(id, ".") {
+			root = &elements[i]
+			nodes[id] = top
+			continue
+		}
+		ensure(id).def = elements[i]
+	}
+
+	return root, convertNodes(top.children, annotate)
+}
+
+// elementID returns the element id, deriving it from path and slice name for
+// older definitions without ids
+func elementID(def elementDefinition) string {
+	if def.ID != "" {
+		return def.ID
+	}
+	if def.SliceName != "" {
+		return def.Path + ":" + def.SliceName
+	}
+	return def.Path
+}
+
+// splitElementID returns the parent id and the element's own name. A slice
+// ("Patient.identifier:mrn") is a child of the element it slices
+// ("Patient.identifier").
+func splitElementID(id string) (parentID, name string) {
+	i := strings.LastIndex(id, ".")
+	if i < 0 {
+		return "", id
+	}
+	name = id[i+1:]
+	if base, _, ok := strings.Cut(name, ":"); ok {
+		return id[:i+1] + base, name
+	}
+	return id[:i], name
+}
+
+// convertNodes converts built nodes into model elements
+func convertNodes(nodes []*elementNode, annotate func(elementDefinition, *models.Element)) []models.Element {
+	var elements []models.Element
+	for _, node := range nodes {
+		elem := elementFromDefinition(node.def)
+		elem.Name = node.name
+		elem.Elements = convertNodes(node.children, annotate)
+		if annotate != nil {
+			annotate(node.def, &elem)
+		}
+		elements = append(elements, elem)
+	}
+	return elements
+}
+
+// elementFromDefinition maps an element definition to a model element.
+// Elements with max 0 are marked not used.
+func elementFromDefinition(def elementDefinition) models.Element {
+	elem := models.Element{
+		Flags:       elementFlags(def),
+		Cardinality: elementCardinality(def),
+		Description: firstNonEmpty(def.Short, def.Definition),
+	}
+	if def.Max == "0" {
+		elem.Usage = models.UsageNotUsed
+	}
+
+	if def.ContentReference != "" {
+		elem.Type = "see " + lastSegment(strings.TrimPrefix(def.ContentReference, "#"), ".")
+	} else {
+		var codes []string
+		for _, t := range def.Type {
+			codes = append(codes, typeName(t))
+			if len(def.Type) == 1 && isURL(t.Code) && !strings.HasPrefix(t.Code, systemTypePrefix) {
+				elem.TypeRef = t.Code
+			}
+			for _, target := range t.TargetProfile {
+				elem.Targets = append(elem.Targets, models.Target{Type: lastSegment(target, "/")})
+			}
+		}
+		elem.Type = strings.Join(codes, " | ")
+	}
+
+	if valueSet := bindingValueSet(def); valueSet != "" {
+		valueSet, _, _ := strings.Cut(valueSet, "|")
+		elem.Binding = &models.Binding{Strength: def.Binding.Strength, ValueSet: valueSet}
+		if strings.HasPrefix(valueSet, "http") {
+			elem.Binding.URL = valueSet
+		}
+	}
+	elem.Mappings = def.Mapping
+	elem.Fixed = def.Fixed
+	elem.Pattern = def.Pattern
+	return elem
+}
+
+// bindingValueSet returns the value set an element is bound to: the R4/R5
+// valueSet or the STU3 valueSetUri or valueSetReference
+func bindingValueSet(def elementDefinition) string {
+	if def.Binding == nil {
+		return ""
+	}
+	valueSet := firstNonEmpty(def.Binding.ValueSet, def.Binding.ValueSetURI)
+	if valueSet == "" && def.Binding.ValueSetReference != nil {
+		valueSet = def.Binding.ValueSetReference.Reference
+	}
+	return valueSet
+}
+
+// typeName returns the display name of a type: system types become their
+// primitive names, profiled extensions show the profile and logical model
+// types their name
+func typeName(t edType) string {
+	if name, ok := strings.CutPrefix(t.Code, systemTypePrefix); ok {
+		return strings.ToLower(name[:1]) + name[1:]
+	}
+	if t.Code == "Extension" && len(t.Profile) > 0 {
+		return "Extension(" + lastSegment(t.Profile[0], "/") + ")"
+	}
+	if isURL(t.Code) {
+		return lastSegment(t.Code, "/")
+	}
+	return t.Code
+}
+
+// isURL reports whether a type code is a url, as logical models use for
+// their own types
+func isURL(code string) bool {
+	return strings.HasPrefix(code, "http://") || strings.HasPrefix(code, "https://")
+}
+
+// elementFlags maps modifier, summary, must support and constraint markers
+func elementFlags(def elementDefinition) []string {
+	var flags []string
+	if def.IsModifier {
+		flags = append(flags, models.FlagModifier)
+	}
+	if def.IsSummary {
+		flags = append(flags, models.FlagSummary)
+	}
+	if def.MustSupport {
+		flags = append(flags, models.FlagMustSupport)
+	}
+	for _, c := range def.Constraint {
+		if !slices.Contains(inheritedConstraints, c.Key) {
+			flags = append(flags, models.FlagConstraint)
+			break
+		}
+	}
+	return flags
+}
+
+// elementCardinality formats min and max; differentials may set only one
+func elementCardinality(def elementDefinition) string {
+	if def.Min == nil && def.Max == "" {
+		return ""
+	}
+	lower := ""
+	if def.Min != nil {
+		lower = fmt.Sprint(int(*def.Min))
+	}
+	return lower + ".." + def.Max
+}
+
+// lastSegment returns the part of s after the last sep, ignoring a
+// "|version" suffix
+func lastSegment(s, sep string) string {
+	s, _, _ = strings.Cut(s, "|")
+	return s[strings.LastIndex(s, sep)+1:]
+}
@@ -0,0 +1,244 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// codeSystem holds the parts of a FHIR CodeSystem that are rendered
+type codeSystem struct {
+	ID      string              `json:"id"`
+	URL     string              `json:"url"`
+	Version string              `json:"version"`
+	Name    string              `json:"name"`
+	Title   string              `json:"title"`
+	Concept []codeSystemConcept `json:"concept"`
+}
+
+// codeSystemConcept is a concept of a CodeSystem with its child concepts
+type codeSystemConcept struct {
+	Code       string              `json:"code"`
+	Display    string              `json:"display"`
+	Definition string              `json:"definition"`
+	Property   []conceptProperty   `json:"property"`
+	Concept    []codeSystemConcept `json:"concept"`
+}
+
+// conceptProperty is a concept property; only status flags are rendered
+type conceptProperty struct {
+	Code         string   `json:"code"`
+	ValueCode    string   `json:"valueCode"`
+	ValueBoolean flexBool `json:"valueBoolean"`
+}
+
+// valueSet holds the parts of a FHIR ValueSet that are rendered
+type valueSet struct {
+	ID      string `json:"id"`
+	URL     string `json:"url"`
+	Version string `json:"version"`
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	Compose *struct {
+		Include []valueSetInclude `json:"include"`
+		Exclude []valueSetInclude `json:"exclude"`
+	} `json:"compose"`
+	Expansion *struct {
+		Total    *int                `json:"total"`
+		Contains []expansionContains `json:"contains"`
+	} `json:"expansion"`
+}
+
+// valueSetInclude is an include or exclude rule of a ValueSet compose
+type valueSetInclude struct {
+	System  string `json:"system"`
+	Version string `json:"version"`
+	Concept []struct {
+		Code    string `json:"code"`
+		Display string `json:"display"`
+	} `json:"concept"`
+	Filter []struct {
+		Property string `json:"property"`
+		Op       string `json:"op"`
+		Value    string `json:"value"`
+	} `json:"filter"`
+	ValueSet []string `json:"valueSet"`
+}
+
+// expansionContains is a code of a ValueSet expansion
+type expansionContains struct {
+	System   string              `json:"system"`
+	Code     string              `json:"code"`
+	Display  string              `json:"display"`
+	Abstract flexBool            `json:"abstract"`
+	Inactive flexBool            `json:"inactive"`
+	Contains []expansionContains `json:"contains"`
+}
+
+// IsTerminology reports whether data is a FHIR ValueSet or CodeSystem
+func IsTerminology(data []byte) bool {
+	var header struct {
+		ResourceType string `json:"resourceType"`
+	}
+	if json.Unmarshal(data, &header) != nil {
+		return false
+	}
+	return header.ResourceType == "ValueSet" || header.ResourceType == "CodeSystem"
+}
+
+// Terminology extracts the concepts of a FHIR ValueSet or CodeSystem. A
+// ValueSet's expansion is preferred; without one its compose rules are shown
+// grouped by code system.
+func Terminology(data []byte) (*models.TerminologyTable, error) {
+	var header struct {
+		ResourceType string `json:"resourceType"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	switch header.ResourceType {
+	case "CodeSystem":
+		return fromCodeSystem(data)
+	case "ValueSet":
+		return fromValueSet(data)
+	default:
+		return nil, fmt.Errorf("resourceType %q is not a ValueSet or CodeSystem", header.ResourceType)
+	}
+}
+
+// fromCodeSystem converts the concept hierarchy of a CodeSystem
+func fromCodeSystem(data []byte) (*models.TerminologyTable, error) {
+	var cs codeSystem
+	if err := json.Unmarshal(data, &cs); err != nil {
+		return nil, err
+	}
+
+	name := firstNonEmpty(cs.Title, cs.Name, cs.ID)
+	if name == "" {
+		return nil, errors.New("code system has no title, name or id")
+	}
+
+	return &models.TerminologyTable{
+		ResourceType: "CodeSystem",
+		Name:         name,
+		Version:      cs.Version,
+		URL:          cs.URL,
+		Concepts:     codeSystemConcepts(cs.Concept, cs.URL),
+	}, nil
+}
+
+// codeSystemConcepts converts concepts and their children
+func codeSystemConcepts(concepts []codeSystemConcept, system string) []models.Concept {
+	var result []models.Concept
+	for _, c := range concepts {
+		concept := models.Concept{
+			Code:       c.Code,
+			System:     system,
+			Display:    c.Display,
+			Definition: c.Definition,
+			Concepts:   codeSystemConcepts(c.Concept, system),
+		}
+		for _, p := range c.Property {
+			switch {
+			case p.Code == "inactive" && bool(p.ValueBoolean),
+				p.Code == "status" && (p.ValueCode == "retired" || p.ValueCode == "deprecated"):
+				concept.Inactive = true
+			case p.Code == "notSelectable" && bool(p.ValueBoolean):
+				concept.Abstract = true
+			}
+		}
+		result = append(result, concept)
+	}
+	return result
+}
+
+// fromValueSet converts the expansion or, without one, the compose rules
+func fromValueSet(data []byte) (*models.TerminologyTable, error) {
+	var vs valueSet
+	if err := json.Unmarshal(data, &vs); err != nil {
+		return nil, err
+	}
+
+	name := firstNonEmpty(vs.Title, vs.Name, vs.ID)
+	if name == "" {
+		return nil, errors.New("value set has no title, name or id")
+	}
+
+	table := &models.TerminologyTable{
+		ResourceType: "ValueSet",
+		Name:         name,
+		Version:      vs.Version,
+		URL:          vs.URL,
+	}
+	switch {
+	case vs.Expansion != nil && len(vs.Expansion.Contains) > 0:
+		table.Concepts = expansionConcepts(vs.Expansion.Contains)
+	case vs.Compose != nil:
+		for _, include := range vs.Compose.Include {
+			table.Concepts = append(table.Concepts, composeGroup(include, "Include"))
+		}
+		for _, exclude := range vs.Compose.Exclude {
+			table.Concepts = append(table.Concepts, composeGroup(exclude, "Exclude"))
+		}
+	}
+	if len(table.Concepts) == 0 {
+		return nil, errors.New("value set has neither an expansion nor compose rules")
+	}
+	return table, nil
+}
+
+// expansionConcepts converts expansion codes and their nested codes
+func expansionConcepts(contains []expansionContains) []models.Concept {
+	var result []models.Concept
+	for _, c := range contains {
+		result = append(result, models.Concept{
+			Code:     c.Code,
+			System:   c.System,
+			Display:  c.Display,
+			Abstract: bool(c.Abstract),
+			Inactive: bool(c.Inactive),
+			Concepts: expansionConcepts(c.Contains),
+		})
+	}
+	return result
+}
+
+// composeGroup converts an include or exclude rule into a group row with the
+// enumerated concepts below it. Filters and imported value sets are
+// summarized in the group's definition.
+func composeGroup(rule valueSetInclude, label string) models.Concept {
+	system := rule.System
+	if system != "" && rule.Version != "" {
+		system += "|" + rule.Version
+	}
+
+	var criteria []string
+	for _, f := range rule.Filter {
+		criteria = append(criteria, fmt.Sprintf("%s %s %s", f.Property, f.Op, f.Value))
+	}
+	if len(rule.ValueSet) > 0 {
+		criteria = append(criteria, "Codes from "+strings.Join(rule.ValueSet, ", "))
+	}
+
+	group := models.Concept{
+		Code:       firstNonEmpty(system, strings.Join(rule.ValueSet, ", ")),
+		Display:    label,
+		Definition: strings.Join(criteria, "; "),
+		Group:      true,
+	}
+	if group.Definition == "" && len(rule.Concept) == 0 {
+		group.Definition = "All codes"
+	}
+	for _, c := range rule.Concept {
+		group.Concepts = append(group.Concepts, models.Concept{
+			Code:    c.Code,
+			System:  rule.System,
+			Display: c.Display,
+		})
+	}
+	return group
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/renderer"
+)
+
+// AnalyzeHandler reports complexity metrics and the estimated diagram size
+// of a resource definition without rendering it. The render options apply,
+// so the estimate matches a render with the same query parameters; the
+// element count and depth limits do not, so oversized profiles can be
+// flagged too.
+// POST /analyze with JSON (or FHIR XML) body → returns renderer.Stats
+func AnalyzeHandler(c *gin.Context) {
+	_, resource, ok := readResourceBody(c)
+	if !ok {
+		return
+	}
+	if !checkResource(c, &resource, isStrict(c)) {
+		return
+	}
+
+	config := renderer.DefaultConfig()
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+	trimmed := trimResource(c, &resource)
+	respondLayoutJSON(c, func(ctx context.Context) (any, error) {
+		return renderer.AnalyzeContext(ctx, trimmed, config)
+	})
+}
+
+// RenderSizeHandler returns the size of the SVG a render with the same body
+// and query parameters would produce, so documentation generators can reserve
+// space or pick a thumbnail scale before fetching the image
+// POST /render/size with JSON (or FHIR XML) body → returns renderer.Size
+func RenderSizeHandler(c *gin.Context) {
+	_, resource, ok := readResourceBody(c)
+	if !ok {
+		return
+	}
+	if !checkResource(c, &resource, isStrict(c)) {
+		return
+	}
+
+	config := renderer.DefaultConfig()
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+	trimmed := trimResource(c, &resource)
+	if err := checkComplexity(trimmed.Flatten()); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Resource exceeds render limits",
+			"details": err.Error(),
+		})
+		return
+	}
+	respondLayoutJSON(c, func(ctx context.Context) (any, error) {
+		return renderer.MeasureContext(ctx, trimmed, config)
+	})
+}
+
+// respondLayoutJSON runs a layout pass under a render slot and the render
+// timeout and writes its result as JSON
+func respondLayoutJSON(c *gin.Context, layout func(ctx context.Context) (any, error)) {
+	release, ok := acquireRenderSlot(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), limits.RenderTimeout)
+	defer cancel()
+	result, err := layout(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Resource exceeds render limits",
+			"details": fmt.Sprintf("layout took longer than %s", limits.RenderTimeout),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Layout failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/convert"
+	"fhir_renderer/models"
+)
+
+// DefaultExpandCount is the number of codes listed per binding when the
+// server configuration does not set one
+const DefaultExpandCount = 10
+
+// terminology is the server ?expandBindings=true expands value sets with
+// (TERMINOLOGY_SERVER) and the number of codes listed per binding
+var terminology struct {
+	server *convert.TerminologyServer
+	count  int
+}
+
+// SetTerminologyServer configures the terminology server for
+// ?expandBindings=true; count <= 0 selects DefaultExpandCount
+func SetTerminologyServer(server *convert.TerminologyServer, count int) {
+	if count <= 0 {
+		count = DefaultExpandCount
+	}
+	terminology.server = server
+	terminology.count = count
+}
+
+// wantsExpandedBindings reports whether the request asks for
+// ?expandBindings=true and a terminology server is configured
+func wantsExpandedBindings(c *gin.Context) bool {
+	return terminology.server != nil && c.Query("expandBindings") == "true"
+}
+
+// expandBindings lists the codes of the bound value sets when the request
+// asks for ?expandBindings=true and a terminology server is configured
+func expandBindings(c *gin.Context, resources ...*models.ResourceDefinition) {
+	if !wantsExpandedBindings(c) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), limits.RenderTimeout)
+	defer cancel()
+	for _, resource := range resources {
+		convert.ExpandBindings(ctx, resource, terminology.server, terminology.count)
+	}
+}
+
+// bindingsCacheKey adds ?expandBindings=true to the ETag cache key of
+// resources. Bindings are expanded after the ETag check, so the resources
+// alone do not tell the two renders apart.
+func bindingsCacheKey(c *gin.Context, resources any) any {
+	if !wantsExpandedBindings(c) {
+		return resources
+	}
+	return struct {
+		Resources      any  `json:"resources"`
+		ExpandBindings bool `json:"expandBindings"`
+	}{resources, true}
+}
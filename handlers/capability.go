@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/convert"
+	"fhir_renderer/renderer"
+)
+
+// renderCapabilityAndRespond renders a FHIR CapabilityStatement as an
+// interaction matrix. compressedResource is used for the footer's edit link.
+func renderCapabilityAndRespond(c *gin.Context, body []byte, compressedResource string) {
+	format := c.DefaultQuery("format", FormatSVG)
+	if format != FormatSVG {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unsupported format",
+			"details": fmt.Sprintf("format %q does not support CapabilityStatement; use %q", format, FormatSVG),
+		})
+		return
+	}
+
+	matrix, err := convert.CapabilityStatement(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CapabilityStatement", "details": err.Error()})
+		return
+	}
+
+	config := renderer.DefaultConfig()
+	config.CompressedResource = compressedResource
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+
+	respondRendered(c, matrix, config, format, func(ctx context.Context, w io.Writer) error {
+		svg, err := renderer.RenderCapabilityContext(ctx, matrix, config)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, svg)
+		return err
+	})
+}
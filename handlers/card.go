@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/renderer"
+)
+
+// formatCard is the PNG preview card of GET /og, which ?format= does not
+// offer
+const formatCard = "card"
+
+// CardHandler renders the social preview card of a definition, a
+// renderer.CardWidth x renderer.CardHeight PNG that editor links show when
+// they unfurl in chat tools
+// GET /og?resource={brotli-base64url-json} or /og?id={share id}
+func CardHandler(c *gin.Context) {
+	resource, ok := definitionFromQuery(c, "GET /og?resource={brotli-base64url-json}")
+	if !ok {
+		return
+	}
+
+	config := renderer.DefaultConfig()
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+	respondRendered(c, resource, config, formatCard, func(ctx context.Context, w io.Writer) error {
+		return renderer.RenderCardPNGContext(ctx, w, resource, config)
+	})
+}
+
+// cardURL returns the absolute URL of the preview card for an editor link
+// to a shared ?id= or compressed ?resource=, or "" for other links
+func cardURL(c *gin.Context) string {
+	query := url.Values{}
+	switch {
+	case c.Query("id") != "":
+		query.Set("id", c.Query("id"))
+	case c.Query("resource") != "":
+		query.Set("resource", c.Query("resource"))
+	default:
+		return ""
+	}
+	return requestBaseURL(c) + "/og?" + query.Encode()
+}
+
+// requestBaseURL returns the scheme and host the request was sent to, such
+// as "https://example.org", for absolute links to this server
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/convert"
+)
+
+// compareRequest is the body of POST /render/compare
+type compareRequest struct {
+	Profile json.RawMessage `json:"profile"`
+	Base    json.RawMessage `json:"base,omitempty"` // Defaults to the profile's baseDefinition
+}
+
+// RenderCompareHandler renders a profile over its base definition, marking
+// added slices, tightened cardinalities and removed elements
+// POST /render/compare with {"profile": {...}, "base": {...}}
+func RenderCompareHandler(c *gin.Context) {
+	body, ok := readBody(c)
+	if !ok {
+		return
+	}
+
+	var req compareRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON body", "details": err.Error()})
+		return
+	}
+	if len(req.Profile) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required field 'profile'"})
+		return
+	}
+
+	_, span := tracer.Start(c.Request.Context(), "compare StructureDefinition")
+	resource, err := convert.Compare(c.Request.Context(), req.Profile, req.Base, convert.BaseRegistry())
+	span.End()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot compare profile", "details": err.Error()})
+		return
+	}
+	if err := validateResource(resource); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile", "details": err.Error()})
+		return
+	}
+
+	// The editor link opens the annotated definition
+	compressedResource := ""
+	if data, err := json.Marshal(resource); err == nil {
+		compressedResource, _ = compressBrotliBase64URL(data)
+	}
+	renderAndRespond(c, resource, compressedResource, "")
+}
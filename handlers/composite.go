@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/models"
+	"fhir_renderer/renderer"
+	"fhir_renderer/validation"
+)
+
+// isJSONArray reports whether the JSON document is an array
+func isJSONArray(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// decodeResources unmarshals a JSON array of resource definitions. In strict
+// mode unknown fields are rejected. JSON errors are located in data, like
+// those of decodeResource.
+func decodeResources(ctx context.Context, data []byte, strict bool) ([]*models.ResourceDefinition, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, validation.DescribeDecodeError(data, err)
+	}
+	resources := make([]*models.ResourceDefinition, len(raw))
+	for i, item := range raw {
+		resources[i] = &models.ResourceDefinition{}
+		if err := decodeResource(ctx, item, strict, resources[i]); err != nil {
+			var decodeErr *validation.DecodeError
+			if errors.As(err, &decodeErr) {
+				decodeErr.Within(data, fmt.Sprintf("$[%d]", i))
+				return nil, err
+			}
+			return nil, fmt.Errorf("resource %d: %w", i, err)
+		}
+	}
+	return resources, nil
+}
+
+// renderCompositeAndRespond renders an array of definitions stacked in one SVG
+func renderCompositeAndRespond(c *gin.Context, body []byte) {
+	format := c.DefaultQuery("format", FormatSVG)
+	if format != FormatSVG {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unsupported format",
+			"details": fmt.Sprintf("format %q does not support multiple resources; use %q", format, FormatSVG),
+		})
+		return
+	}
+
+	strict := isStrict(c)
+	resources, err := decodeResources(c.Request.Context(), body, strict)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, invalidJSON("Invalid JSON body", err))
+		return
+	}
+	if len(resources) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Resource array is empty"})
+		return
+	}
+
+	config := renderer.DefaultConfig()
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+
+	// Limits apply to the composite as a whole
+	var flat []models.FlatElement
+	for i, resource := range resources {
+		if !checkResource(c, resource, strict) {
+			return
+		}
+		resources[i] = trimResource(c, resource)
+		flat = append(flat, resources[i].Flatten()...)
+	}
+	if err := checkComplexity(flat); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Resource exceeds render limits",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	prepare := func() { expandBindings(c, resources...) }
+	respondPrepared(c, bindingsCacheKey(c, resources), config, format, prepare, func(ctx context.Context, w io.Writer) error {
+		return renderer.RenderCompositeToContext(ctx, w, resources, config)
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// retryAfterSeconds is the Retry-After hint sent with 429 responses
+const retryAfterSeconds = 5
+
+// errRenderBusy is returned when every render slot is taken and the wait
+// queue is full
+var errRenderBusy = errors.New("too many renders in progress")
+
+// renderSemaphore bounds the number of concurrent renders. Requests wait for
+// a free slot unless too many are already waiting.
+type renderSemaphore struct {
+	slots      chan struct{}
+	waiting    atomic.Int64
+	maxWaiting int64
+}
+
+// newRenderSemaphore allows workers concurrent renders with up to queue
+// requests waiting
+func newRenderSemaphore(workers, queue int) *renderSemaphore {
+	return &renderSemaphore{
+		slots:      make(chan struct{}, workers),
+		maxWaiting: int64(queue),
+	}
+}
+
+// renderSlots is the semaphore shared by all render requests
+var renderSlots = newRenderSemaphore(limits.RenderWorkers, limits.RenderQueue)
+
+// acquire takes a render slot. Unless always is set it fails with
+// errRenderBusy when the wait queue is full.
+func (s *renderSemaphore) acquire(ctx context.Context, always bool) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if s.waiting.Add(1) > s.maxWaiting && !always {
+		s.waiting.Add(-1)
+		return errRenderBusy
+	}
+	defer s.waiting.Add(-1)
+
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire
+func (s *renderSemaphore) release() {
+	<-s.slots
+}
+
+// jobContextKey marks the requests of background render jobs, which wait for
+// a render slot however long the queue is
+type jobContextKey struct{}
+
+// acquireRenderSlot takes a render slot for the request. On failure an error
+// response has already been written and ok is false; otherwise release must
+// be called when rendering is done.
+func acquireRenderSlot(c *gin.Context) (release func(), ok bool) {
+	slots := renderSlots
+	isJob := c.Request.Context().Value(jobContextKey{}) != nil
+	err := slots.acquire(c.Request.Context(), isJob)
+	if errors.Is(err, errRenderBusy) {
+		respondBusy(c)
+		return nil, false
+	}
+	if err != nil {
+		// The client went away while waiting
+		c.Status(http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return slots.release, true
+}
+
+// respondBusy writes a 429 response asking the client to retry later
+func respondBusy(c *gin.Context) {
+	c.Writer.Header().Del("ETag")
+	c.Writer.Header().Del("Cache-Control")
+	c.Header("Retry-After", strconv.Itoa(retryAfterSeconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":   "Server is busy",
+		"details": errRenderBusy.Error() + "; retry later",
+	})
+}
@@ -4,9 +4,16 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/renderer"
 )
 
-// EditorHandler serves the interactive editor page
+// EditorHandler serves the interactive editor page. Links to a shared or
+// compressed definition carry Open Graph tags pointing at its preview card.
 func EditorHandler(c *gin.Context) {
-	c.HTML(http.StatusOK, "editor.html", nil)
+	c.HTML(http.StatusOK, "editor.html", gin.H{
+		"CardURL":    cardURL(c),
+		"CardWidth":  renderer.CardWidth,
+		"CardHeight": renderer.CardHeight,
+	})
 }
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"fhir_renderer/renderer"
+)
+
+// computeETag derives a strong ETag from the resource (or resources), the
+// render configuration, the output format and the renderer version, so any
+// change that affects the output produces a different tag
+func computeETag(resource any, config renderer.SVGConfig, format string) (string, error) {
+	resourceJSON, err := json.Marshal(resource)
+	if err != nil {
+		return "", err
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(renderer.Version))
+	h.Write([]byte{0})
+	h.Write([]byte(format))
+	h.Write([]byte{0})
+	h.Write(resourceJSON)
+	h.Write([]byte{0})
+	h.Write(configJSON)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches the ETag.
+// If-None-Match uses weak comparison, so W/ prefixes are ignored.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
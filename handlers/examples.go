@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed example.json examples/*.json
+var exampleFiles embed.FS
+
+// starterExample is a definition of the starter library, served from file
+type starterExample struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	file        string
+}
+
+// starterExamples are the definitions listed by GET /examples, in the
+// order the editor offers them. The first is the one GET /example returns.
+var starterExamples = []starterExample{
+	{
+		Name:        "appointment",
+		Title:       "Appointment",
+		Description: "Resource with flags, bindings, usage and implementation notes",
+		file:        "example.json",
+	},
+	{
+		Name:        "patient-profile",
+		Title:       "Patient profile",
+		Description: "Profile with identifier slices, a fixed value, mappings, reference targets, an extension and a sample instance",
+		file:        "examples/patient-profile.json",
+	},
+	{
+		Name:        "observation-profile",
+		Title:       "Observation profile",
+		Description: "Blood pressure profile with systolic and diastolic component slices and LOINC patterns",
+		file:        "examples/observation-profile.json",
+	},
+	{
+		Name:        "questionnaire",
+		Title:       "Questionnaire",
+		Description: "FHIR Questionnaire resource, converted on render: items become elements and answer options bindings",
+		file:        "examples/questionnaire.json",
+	},
+	{
+		Name:        "logical-model",
+		Title:       "Logical model",
+		Description: "Abstract model with elements of its own types, mapped to FHIR",
+		file:        "examples/logical-model.json",
+	},
+	{
+		Name:        "sliced-bundle",
+		Title:       "Heavily sliced Bundle",
+		Description: "Document Bundle sliced by resource type, with Observation entries sliced again by code",
+		file:        "examples/sliced-bundle.json",
+	},
+}
+
+// exampleNames lists the names of the starter examples
+func exampleNames() []string {
+	names := make([]string, len(starterExamples))
+	for i, example := range starterExamples {
+		names[i] = example.Name
+	}
+	return names
+}
+
+// ExamplesHandler lists the starter definitions with their URLs
+// GET /examples
+func ExamplesHandler(c *gin.Context) {
+	type listedExample struct {
+		starterExample
+		URL string `json:"url"`
+	}
+	examples := make([]listedExample, len(starterExamples))
+	for i, example := range starterExamples {
+		examples[i] = listedExample{starterExample: example, URL: "/examples/" + example.Name}
+	}
+	c.JSON(http.StatusOK, gin.H{"examples": examples})
+}
+
+// ExampleByNameHandler returns a starter definition as it is stored
+// GET /examples/{name}
+func ExampleByNameHandler(c *gin.Context) {
+	name := c.Param("name")
+	for _, example := range starterExamples {
+		if example.Name != name {
+			continue
+		}
+		data, err := exampleFiles.ReadFile(example.file)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read example"})
+			return
+		}
+		c.Data(http.StatusOK, "application/json", data)
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":     "Unknown example",
+		"available": exampleNames(),
+	})
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRenderExamplesStrict renders every starter example in strict mode,
+// which must not reject the definitions the editor offers
+func TestRenderExamplesStrict(t *testing.T) {
+	router := gin.New()
+	router.POST("/render", RenderPOSTHandler)
+	for _, example := range starterExamples {
+		t.Run(example.Name, func(t *testing.T) {
+			data, err := exampleFiles.ReadFile(example.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/render?strict=true", bytes.NewReader(data))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
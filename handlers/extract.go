@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/middleware"
+	"fhir_renderer/renderer"
+)
+
+// ExtractHandler recovers the source definition from an SVG rendered with
+// ?embedSource=true
+// POST /extract with the SVG as body → returns the pretty-printed JSON
+func ExtractHandler(c *gin.Context) {
+	encoded, err := renderer.ExtractSource(http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodyBytes))
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		middleware.RespondBodyTooLarge(c, limits.MaxBodyBytes)
+		return
+	case errors.Is(err, renderer.ErrNoSource):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "No embedded source",
+			"details": "render the SVG with ?embedSource=true to embed its source",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid SVG", "details": err.Error()})
+		return
+	}
+
+	decoded, err := decompressBrotliBase64URL(c.Request.Context(), encoded)
+	if errors.Is(err, errResourceTooLarge) {
+		respondTooLarge(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Decompression failed", "details": err.Error()})
+		return
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, decoded, "", "  "); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", pretty.Bytes())
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/middleware"
+	"fhir_renderer/renderer"
+)
+
+// defaultFont is the server-wide custom font set via FONT_PATH; nil uses
+// the built-in Go Regular metrics
+var defaultFont *renderer.Font
+
+// SetDefaultFont configures the font used when a request does not upload one
+func SetDefaultFont(f *renderer.Font) {
+	defaultFont = f
+}
+
+// fontContextKey stores a font uploaded with the request in the gin context
+const fontContextKey = "font"
+
+// exampleContextKey stores an example instance uploaded with the request in
+// the gin context
+const exampleContextKey = "example"
+
+// requestFont returns the font uploaded with the request, or the default font
+func requestFont(c *gin.Context) *renderer.Font {
+	if f, ok := c.Get(fontContextKey); ok {
+		return f.(*renderer.Font)
+	}
+	return defaultFont
+}
+
+// isMultipartContentType reports whether the content type is multipart/form-data
+func isMultipartContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// readMultipartBody reads a multipart/form-data body with the resource JSON in
+// the "resource" part, an optional TTF/OTF file in the "font" part and an
+// optional example instance in the "example" part. The parsed font and the
+// example are stored in the context for the render. On failure an error
+// response has already been written and ok is false.
+func readMultipartBody(c *gin.Context) (body []byte, ok bool) {
+	_, params, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	reader := multipart.NewReader(http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodyBytes), params["boundary"])
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondMultipartError(c, err)
+			return nil, false
+		}
+
+		data, err := io.ReadAll(part)
+		if err != nil {
+			respondMultipartError(c, err)
+			return nil, false
+		}
+
+		switch part.FormName() {
+		case "resource":
+			body = data
+		case "font":
+			f, err := renderer.ParseFont(data)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid font", "details": err.Error()})
+				return nil, false
+			}
+			c.Set(fontContextKey, f)
+		case "example":
+			var instance map[string]any
+			if err := json.Unmarshal(data, &instance); err != nil || instance == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid example", "details": "the example must be a JSON object"})
+				return nil, false
+			}
+			c.Set(exampleContextKey, json.RawMessage(data))
+		}
+	}
+
+	if len(strings.TrimSpace(string(body))) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'resource' part in multipart body"})
+		return nil, false
+	}
+	return body, true
+}
+
+// respondMultipartError writes the response for a failed multipart read
+func respondMultipartError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		middleware.RespondBodyTooLarge(c, limits.MaxBodyBytes)
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart body", "details": err.Error()})
+}
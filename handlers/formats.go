@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"fhir_renderer/models"
+	"fhir_renderer/renderer"
+)
+
+// Output formats selectable via the ?format= query parameter
+const (
+	FormatSVG      = "svg"
+	FormatMermaid  = "mermaid"
+	FormatPlantUML = "plantuml"
+	FormatHTML     = "html"
+	FormatLayout   = "json-layout"
+	FormatZip      = "zip" // One SVG per page of ?maxRowsPerPage= rows
+	FormatCSV      = "csv"
+	FormatXLSX     = "xlsx"
+)
+
+// formatContentTypes maps each output format to its response content type
+var formatContentTypes = map[string]string{
+	FormatSVG:      "image/svg+xml",
+	FormatMermaid:  "text/plain; charset=utf-8",
+	FormatPlantUML: "text/plain; charset=utf-8",
+	FormatHTML:     "text/html; charset=utf-8",
+	FormatLayout:   "application/json; charset=utf-8",
+	FormatZip:      "application/zip",
+	FormatCSV:      "text/csv; charset=utf-8",
+	FormatXLSX:     "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// renderFormat writes the resource to w in the requested output format
+func renderFormat(ctx context.Context, w io.Writer, format string, resource *models.ResourceDefinition, config renderer.SVGConfig) error {
+	var output string
+	switch format {
+	case FormatMermaid:
+		output = renderer.RenderMermaid(resource)
+	case FormatPlantUML:
+		output = renderer.RenderPlantUML(resource)
+	case FormatHTML:
+		output = renderer.RenderHTML(resource, config)
+	case FormatLayout:
+		layout, err := renderer.ComputeLayout(ctx, resource, config)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(layout)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatZip:
+		pages, err := renderer.RenderPagesContext(ctx, resource, config)
+		if err != nil {
+			return err
+		}
+		return writePagesZip(w, resource.Name, pages)
+	case FormatCSV:
+		return renderer.RenderCSV(w, resource, config)
+	case FormatXLSX:
+		return renderer.RenderXLSX(w, resource, config)
+	default:
+		return renderer.RenderToContext(ctx, w, resource, config)
+	}
+	_, err := io.WriteString(w, output)
+	return err
+}
+
+// writePagesZip writes the SVG pages as name-page-N.svg files of a ZIP archive
+func writePagesZip(w io.Writer, name string, pages [][]byte) error {
+	zw := zip.NewWriter(w)
+	base := packageFileName(name, nil)
+	for i, data := range pages {
+		if err := writeZipFile(zw, fmt.Sprintf("%s-page-%d.svg", base, i+1), data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// supportedFormatNames returns the accepted format names in sorted order
+func supportedFormatNames() []string {
+	names := make([]string, 0, len(formatContentTypes))
+	for name := range formatContentTypes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// supportedFormats lists the accepted format names for error messages
+func supportedFormats() string {
+	return strings.Join(supportedFormatNames(), ", ")
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/convert"
+	"fhir_renderer/models"
+)
+
+// isFSHContentType reports whether the content type denotes FHIR Shorthand
+func isFSHContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/fsh" || mediaType == "application/fsh"
+}
+
+// fshToJSON converts the Profile, Extension, Logical or Resource named name
+// (the first one when empty) of FHIR Shorthand source into the JSON of its
+// definition
+func fshToJSON(ctx context.Context, data []byte, name string) ([]byte, error) {
+	resource, err := convert.FSHEntity(ctx, data, name)
+	if err != nil {
+		return nil, err
+	}
+	// Without the resourceType the JSON is not taken for a StructureDefinition
+	resource.ResourceType = ""
+	return json.Marshal(resource)
+}
+
+// RenderFSHHandler renders FHIR Shorthand source directly, whatever its
+// content type, so previews don't need a SUSHI build first
+// POST /render/fsh with FSH body → renders the first Profile, Extension,
+// Logical or Resource like POST /render, or the one named by ?name=
+func RenderFSHHandler(c *gin.Context) {
+	body, ok := readRawBody(c)
+	if !ok {
+		return
+	}
+
+	body, err := fshToJSON(c.Request.Context(), body, c.Query("name"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid FSH body", "details": err.Error()})
+		return
+	}
+
+	var resource models.ResourceDefinition
+	if err := decodeResource(c.Request.Context(), body, isStrict(c), &resource); err != nil {
+		c.JSON(http.StatusBadRequest, invalidJSON("Invalid FSH body", err))
+		return
+	}
+	if !checkResource(c, &resource, isStrict(c)) {
+		return
+	}
+
+	// The editor link opens the converted JSON, not the FSH
+	compressedResource, _ := compressBrotliBase64URL(body)
+	renderAndRespond(c, &resource, compressedResource, "")
+}
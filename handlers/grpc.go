@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"fhir_renderer/rendererpb"
+)
+
+// renderService implements the gRPC RenderService by running POST /render
+// in-process, like render jobs, so both APIs share options, limits and
+// render slots
+type renderService struct {
+	rendererpb.UnimplementedRenderServiceServer
+	router *gin.Engine // Serves POST /render without middleware
+}
+
+// NewGRPCServer returns a gRPC server with the RenderService registered.
+// Call it after SetLimits; messages are limited to MaxBodyBytes.
+func NewGRPCServer() *grpc.Server {
+	router := gin.New()
+	router.POST("/render", RenderPOSTHandler)
+
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(int(limits.MaxBodyBytes)))
+	rendererpb.RegisterRenderServiceServer(server, &renderService{router: router})
+	return server
+}
+
+// Render renders the request's definition as POST /render would with the
+// request's format and options as query parameters
+func (s *renderService) Render(ctx context.Context, req *rendererpb.RenderRequest) (*rendererpb.RenderResponse, error) {
+	if req.GetResource() == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing resource")
+	}
+	body, err := protojson.Marshal(req.GetResource())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	query := make(url.Values)
+	for name, value := range req.GetOptions() {
+		query.Set(name, value)
+	}
+	if req.GetFormat() != "" {
+		query.Set("format", req.GetFormat())
+	}
+	target := url.URL{Path: "/render", RawQuery: query.Encode()}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	result := &jobResponse{status: http.StatusOK, header: make(http.Header)}
+	s.router.ServeHTTP(result, request)
+	if result.status >= http.StatusBadRequest {
+		return nil, status.Error(grpcCode(result.status), jobError(result))
+	}
+	return &rendererpb.RenderResponse{
+		Output:      result.body.Bytes(),
+		ContentType: result.header.Get("Content-Type"),
+		Etag:        result.header.Get("ETag"),
+	}, nil
+}
+
+// grpcCode maps the HTTP status of a failed render to a gRPC code
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return codes.ResourceExhausted
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/convert"
+)
+
+// ImportCSVHandler converts a spreadsheet element list (see convert.CSV) into
+// a resource definition, so analysts can start from the CSV they already
+// maintain
+// POST /import/csv with CSV body → returns the definition as JSON, or
+// renders it like POST /render with ?render=true and the render options
+func ImportCSVHandler(c *gin.Context) {
+	body, ok := readRawBody(c)
+	if !ok {
+		return
+	}
+
+	resource, err := convert.CSV(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV", "details": err.Error()})
+		return
+	}
+	if !checkResource(c, resource, isStrict(c)) {
+		return
+	}
+
+	data, err := json.MarshalIndent(resource, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Encoding failed", "details": err.Error()})
+		return
+	}
+	if c.Query("render") != "true" {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+		return
+	}
+
+	// The editor link opens the converted JSON, not the CSV
+	compressedResource, _ := compressBrotliBase64URL(data)
+	renderAndRespond(c, resource, compressedResource, "")
+}
+
+// ConvertStructureDefinitionHandler converts a resource definition into a
+// FHIR StructureDefinition with a differential (see
+// convert.ToStructureDefinition), so structures prototyped in the editor can
+// move on to standard FHIR tooling
+// POST /convert/structuredefinition with JSON (or FHIR XML) body → returns
+// the StructureDefinition; ?canonical= sets the base of its url
+func ConvertStructureDefinitionHandler(c *gin.Context) {
+	_, resource, ok := readResourceBody(c)
+	if !ok {
+		return
+	}
+	if !checkResource(c, &resource, isStrict(c)) {
+		return
+	}
+
+	data, err := convert.ToStructureDefinition(c.Request.Context(), &resource, c.Query("canonical"), convert.BaseRegistry())
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Conversion failed", "details": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/fhir+json; charset=utf-8", data)
+}
@@ -0,0 +1,439 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/middleware"
+	"fhir_renderer/storage"
+)
+
+// Render job types, selected with ?type= on POST /render/jobs
+const (
+	JobTypeRender  = "render"
+	JobTypePackage = "package"
+	JobTypeCompare = "compare"
+)
+
+// Render job states
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// jobPruneInterval is how often finished jobs are checked for expiry
+const jobPruneInterval = time.Minute
+
+// jobEventsKeepAlive is how often an idle event stream sends a comment, so
+// proxies do not close it during long renders
+const jobEventsKeepAlive = 15 * time.Second
+
+// jobPaths maps job types to the endpoint that runs them
+var jobPaths = map[string]string{
+	JobTypeRender:  "/render",
+	JobTypePackage: "/render/package",
+	JobTypeCompare: "/render/compare",
+}
+
+// renderJob is a queued request to one of the render endpoints together with
+// its recorded response
+type renderJob struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	Status     string       `json:"status"`
+	Error      string       `json:"error,omitempty"` // Error message of a failed job
+	CreatedAt  time.Time    `json:"createdAt"`
+	StartedAt  *time.Time   `json:"startedAt,omitempty"`
+	FinishedAt *time.Time   `json:"finishedAt,omitempty"`
+	ResultURL  string       `json:"resultUrl,omitempty"` // Set once the job has finished
+	Progress   *jobProgress `json:"progress,omitempty"`  // Package jobs: definitions rendered so far
+
+	request *http.Request
+	result  *jobResponse
+	entries []packageEntry // Rendered package definitions, in order
+	changed chan struct{}  // Closed and replaced whenever the job changes
+}
+
+// jobProgress counts the definitions a package job has rendered. Updates
+// replace it, so snapshots can share it.
+type jobProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// progressContextKey carries the progress callback of a package job, see
+// reportPackageProgress
+type progressContextKey struct{}
+
+// reportPackageProgress tells the job running the request, if any, that the
+// package has total definitions and entry, unless nil, has been rendered
+func reportPackageProgress(c *gin.Context, entry *packageEntry, total int) {
+	if report, ok := c.Request.Context().Value(progressContextKey{}).(func(*packageEntry, int)); ok {
+		report(entry, total)
+	}
+}
+
+// jobResponse records the response of a job's endpoint
+type jobResponse struct {
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+// Header implements http.ResponseWriter
+func (r *jobResponse) Header() http.Header {
+	return r.header
+}
+
+// Write implements http.ResponseWriter
+func (r *jobResponse) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+// WriteHeader implements http.ResponseWriter
+func (r *jobResponse) WriteHeader(status int) {
+	r.status = status
+}
+
+// jobQueue holds the render jobs and feeds them to the workers
+type jobQueue struct {
+	mu          sync.Mutex
+	jobs        map[string]*renderJob
+	finished    []*renderJob // Finished jobs, oldest first
+	resultBytes int64        // Total body size of the finished jobs' results
+	pending     chan *renderJob
+	router      *gin.Engine // Serves the job endpoints without middleware
+
+	retention  time.Duration
+	maxResults int
+	maxBytes   int64
+}
+
+// jobs is nil until StartRenderJobs is called
+var jobs *jobQueue
+
+// StartRenderJobs starts workers that run render jobs in the background, with
+// up to queueSize jobs waiting. Without it POST /render/jobs returns 503. Jobs
+// share the render slots of synchronous requests but never get 429. Finished
+// jobs are kept for the JobRetention of the limits, and the oldest are
+// dropped early to stay within MaxJobResults and MaxJobResultBytes.
+func StartRenderJobs(workers, queueSize int) {
+	router := gin.New()
+	router.POST(jobPaths[JobTypeRender], RenderPOSTHandler)
+	router.POST(jobPaths[JobTypePackage], RenderPackageHandler)
+	router.POST(jobPaths[JobTypeCompare], RenderCompareHandler)
+
+	q := &jobQueue{
+		jobs:    make(map[string]*renderJob),
+		pending: make(chan *renderJob, queueSize),
+		router:  router,
+
+		retention:  limits.JobRetention,
+		maxResults: limits.MaxJobResults,
+		maxBytes:   limits.MaxJobResultBytes,
+	}
+	for range workers {
+		go q.work()
+	}
+	go q.pruneEvery(jobPruneInterval)
+	jobs = q
+}
+
+// work runs queued jobs until the process exits
+func (q *jobQueue) work() {
+	for job := range q.pending {
+		q.update(job, func() {
+			now := time.Now().UTC()
+			job.Status = JobRunning
+			job.StartedAt = &now
+		})
+
+		result := &jobResponse{status: http.StatusOK, header: make(http.Header)}
+		q.router.ServeHTTP(result, job.request)
+
+		q.update(job, func() {
+			now := time.Now().UTC()
+			job.FinishedAt = &now
+			job.ResultURL = "/render/jobs/" + job.ID + "/result"
+			job.result = result
+			job.request = nil
+			job.Status = JobDone
+			if result.status >= http.StatusBadRequest {
+				job.Status = JobFailed
+				job.Error = jobError(result)
+			}
+			q.finished = append(q.finished, job)
+			q.resultBytes += int64(result.body.Len())
+			q.prune()
+		})
+	}
+}
+
+// pruneEvery drops expired jobs every interval until the process exits
+func (q *jobQueue) pruneEvery(interval time.Duration) {
+	for range time.Tick(interval) {
+		q.mu.Lock()
+		q.prune()
+		q.mu.Unlock()
+	}
+}
+
+// update changes a job while holding the queue lock and wakes its event
+// streams
+func (q *jobQueue) update(job *renderJob, change func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change()
+	close(job.changed)
+	job.changed = make(chan struct{})
+}
+
+// progress records a rendered definition of a package job
+func (q *jobQueue) progress(job *renderJob, entry *packageEntry, total int) {
+	q.update(job, func() {
+		if entry != nil {
+			job.entries = append(job.entries, *entry)
+		}
+		job.Progress = &jobProgress{Done: len(job.entries), Total: total}
+	})
+}
+
+// add queues a job. It returns false when the queue is full.
+func (q *jobQueue) add(job *renderJob) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case q.pending <- job:
+		q.jobs[job.ID] = job
+		return true
+	default:
+		return false
+	}
+}
+
+// get returns a snapshot of the job with id
+func (q *jobQueue) get(id string) (renderJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return renderJob{}, false
+	}
+	return *job, true
+}
+
+// prune drops jobs that finished more than the retention period ago, and
+// the oldest finished jobs while more results are kept than allowed. The
+// caller holds the lock.
+func (q *jobQueue) prune() {
+	cutoff := time.Now().Add(-q.retention)
+	for len(q.finished) > 0 {
+		oldest := q.finished[0]
+		if !oldest.FinishedAt.Before(cutoff) && len(q.finished) <= q.maxResults && q.resultBytes <= q.maxBytes {
+			return
+		}
+		delete(q.jobs, oldest.ID)
+		q.resultBytes -= int64(oldest.result.body.Len())
+		q.finished[0] = nil
+		q.finished = q.finished[1:]
+	}
+}
+
+// jobError extracts the message of a JSON error response
+func jobError(result *jobResponse) string {
+	var body struct {
+		Error   string `json:"error"`
+		Details string `json:"details"`
+	}
+	if json.Unmarshal(result.body.Bytes(), &body) != nil || body.Error == "" {
+		return http.StatusText(result.status)
+	}
+	if body.Details != "" {
+		return body.Error + ": " + body.Details
+	}
+	return body.Error
+}
+
+// requireRenderJobs writes a 503 response when render jobs are not enabled
+func requireRenderJobs(c *gin.Context) bool {
+	if jobs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Render jobs are not enabled on this server"})
+		return false
+	}
+	return true
+}
+
+// CreateRenderJobHandler queues a render in the background and returns its
+// id. The body and query parameters are those of the endpoint selected by
+// ?type=render|package|compare.
+// POST /render/jobs
+func CreateRenderJobHandler(c *gin.Context) {
+	if !requireRenderJobs(c) {
+		return
+	}
+
+	jobType := c.DefaultQuery("type", JobTypeRender)
+	path, ok := jobPaths[jobType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unsupported job type",
+			"details": fmt.Sprintf("type %q is not one of %s, %s, %s", jobType, JobTypeRender, JobTypePackage, JobTypeCompare),
+		})
+		return
+	}
+
+	// The body is read now; the request is gone by the time a worker runs.
+	// The route allows package sized bodies, other jobs take less.
+	maxBytes := limits.MaxBodyBytes
+	if jobType == JobTypePackage {
+		maxBytes = limits.MaxPackageBytes
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			middleware.RespondBodyTooLarge(c, maxBytes)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		}
+		return
+	}
+
+	job := &renderJob{
+		ID:        storage.NewID(),
+		Type:      jobType,
+		Status:    JobQueued,
+		CreatedAt: time.Now().UTC(),
+		changed:   make(chan struct{}),
+	}
+
+	query := c.Request.URL.Query()
+	query.Del("type")
+	target := url.URL{Path: path, RawQuery: query.Encode()}
+	ctx := context.WithValue(context.Background(), jobContextKey{}, true)
+	ctx = context.WithValue(ctx, progressContextKey{}, func(entry *packageEntry, total int) {
+		jobs.progress(job, entry, total)
+	})
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create job", "details": err.Error()})
+		return
+	}
+	request.Header.Set("Content-Type", c.GetHeader("Content-Type"))
+	job.request = request
+
+	if !jobs.add(job) {
+		c.Header("Retry-After", strconv.Itoa(retryAfterSeconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Render job queue is full"})
+		return
+	}
+
+	snapshot, _ := jobs.get(job.ID)
+	c.Header("Location", "/render/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, snapshot)
+}
+
+// RenderJobHandler returns the status of a render job
+// GET /render/jobs/:id
+func RenderJobHandler(c *gin.Context) {
+	if !requireRenderJobs(c) {
+		return
+	}
+	job, ok := jobs.get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Render job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// jobProgressEvent is the data of a "progress" event: a rendered package
+// definition with the count so far
+type jobProgressEvent struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+	packageEntry
+}
+
+// RenderJobEventsHandler streams a render job's progress as server-sent
+// events: "status" when it starts running, "progress" for every definition
+// of a package job (with its share links for ?output=index), and "done" with
+// the final status and resultUrl, after which the stream ends. Clients that
+// connect late get the events so far first.
+// GET /render/jobs/:id/events
+func RenderJobEventsHandler(c *gin.Context) {
+	if !requireRenderJobs(c) {
+		return
+	}
+	id := c.Param("id")
+	job, ok := jobs.get(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Render job not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	sent, status := 0, ""
+	c.Stream(func(w io.Writer) bool {
+		for ; sent < len(job.entries); sent++ {
+			c.SSEvent("progress", jobProgressEvent{Done: sent + 1, Total: job.Progress.Total, packageEntry: job.entries[sent]})
+		}
+		if job.FinishedAt != nil {
+			c.SSEvent("done", job)
+			return false
+		}
+		if job.Status != status {
+			status = job.Status
+			c.SSEvent("status", job)
+		}
+
+		select {
+		case <-job.changed:
+		case <-time.After(jobEventsKeepAlive):
+			io.WriteString(w, ": keep-alive\n\n")
+		case <-c.Request.Context().Done():
+			return false
+		}
+		job, ok = jobs.get(id)
+		return ok
+	})
+}
+
+// RenderJobResultHandler returns the response of a finished render job as
+// the endpoint produced it, including error responses of failed jobs
+// GET /render/jobs/:id/result
+func RenderJobResultHandler(c *gin.Context) {
+	if !requireRenderJobs(c) {
+		return
+	}
+	job, ok := jobs.get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Render job not found"})
+		return
+	}
+	if job.result == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Render job has not finished", "status": job.Status})
+		return
+	}
+
+	for _, name := range []string{"Content-Disposition", "ETag", "Cache-Control"} {
+		if value := job.result.header.Get(name); value != "" {
+			c.Header(name, value)
+		}
+	}
+	c.Data(job.result.status, job.result.header.Get("Content-Type"), job.result.body.Bytes())
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jobsRouter serves the render job endpoints
+func jobsRouter() *gin.Engine {
+	router := gin.New()
+	router.POST("/render/jobs", CreateRenderJobHandler)
+	router.GET("/render/jobs/:id", RenderJobHandler)
+	router.GET("/render/jobs/:id/result", RenderJobResultHandler)
+	return router
+}
+
+// TestRenderJobs queues jobs and follows them to their result
+func TestRenderJobs(t *testing.T) {
+	const definition = `{"name":"MyPatient","type":"Patient","elements":[{"name":"gender","cardinality":"1..1","type":"code"}]}`
+
+	tests := []struct {
+		name       string
+		disabled   bool // Render jobs not started
+		workers    int
+		queueSize  int
+		query      string
+		body       string
+		wantStatus int    // Status of POST /render/jobs
+		wantJob    string // Final job status, when the job is accepted
+		wantResult int    // Status of the job result
+	}{
+		{
+			name:       "render",
+			workers:    1,
+			queueSize:  4,
+			body:       definition,
+			wantStatus: http.StatusAccepted,
+			wantJob:    JobDone,
+			wantResult: http.StatusOK,
+		},
+		{
+			name:       "invalid definition fails",
+			workers:    1,
+			queueSize:  4,
+			body:       `{"elements":`,
+			wantStatus: http.StatusAccepted,
+			wantJob:    JobFailed,
+			wantResult: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown type",
+			workers:    1,
+			queueSize:  4,
+			query:      "?type=thumbnail",
+			body:       definition,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "full queue",
+			body:       definition,
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "not enabled",
+			disabled:   true,
+			body:       definition,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	defer func(saved *jobQueue) { jobs = saved }(jobs)
+	router := jobsRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs = nil
+			if !tt.disabled {
+				StartRenderJobs(tt.workers, tt.queueSize)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/render/jobs"+tt.query, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+				t.Error("429 without Retry-After")
+			}
+			if rec.Code != http.StatusAccepted {
+				return
+			}
+
+			var created renderJob
+			if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+				t.Fatal(err)
+			}
+			if location := rec.Header().Get("Location"); location != "/render/jobs/"+created.ID {
+				t.Errorf("Location %q, want the job URL", location)
+			}
+
+			job := waitForJob(t, router, created.ID)
+			if job.Status != tt.wantJob {
+				t.Fatalf("job status %q, want %q (error %q)", job.Status, tt.wantJob, job.Error)
+			}
+			if (job.Error != "") != (tt.wantJob == JobFailed) {
+				t.Errorf("job error %q for a %s job", job.Error, job.Status)
+			}
+
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, job.ResultURL, nil))
+			if rec.Code != tt.wantResult {
+				t.Errorf("result status %d, want %d: %s", rec.Code, tt.wantResult, rec.Body)
+			}
+			if tt.wantResult == http.StatusOK && !strings.HasPrefix(rec.Header().Get("Content-Type"), "image/svg+xml") {
+				t.Errorf("result Content-Type %q, want an SVG", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestRenderJobNotFound(t *testing.T) {
+	defer func(saved *jobQueue) { jobs = saved }(jobs)
+	StartRenderJobs(1, 1)
+
+	router := jobsRouter()
+	for _, path := range []string{"/render/jobs/unknown", "/render/jobs/unknown/result"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+// waitForJob polls the status of a job until it has finished
+func waitForJob(t *testing.T, router *gin.Engine, id string) renderJob {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/render/jobs/"+id, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("job status: %d: %s", rec.Code, rec.Body)
+		}
+		var job renderJob
+		if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == JobDone || job.Status == JobFailed {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s after 10s", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestJobQueuePrune drops expired jobs and the oldest ones over the limits
+func TestJobQueuePrune(t *testing.T) {
+	tests := []struct {
+		name       string
+		ages       []time.Duration // Time since each job finished, oldest first
+		sizes      []int           // Result body size of each job
+		maxResults int
+		maxBytes   int64
+		want       []string // IDs of the kept jobs
+	}{
+		{
+			name:       "within limits",
+			ages:       []time.Duration{3 * time.Minute, 2 * time.Minute, time.Minute},
+			sizes:      []int{10, 10, 10},
+			maxResults: 10,
+			maxBytes:   100,
+			want:       []string{"0", "1", "2"},
+		},
+		{
+			name:       "expired",
+			ages:       []time.Duration{2 * time.Hour, 90 * time.Minute, time.Minute},
+			sizes:      []int{10, 10, 10},
+			maxResults: 10,
+			maxBytes:   100,
+			want:       []string{"2"},
+		},
+		{
+			name:       "too many results",
+			ages:       []time.Duration{3 * time.Minute, 2 * time.Minute, time.Minute},
+			sizes:      []int{10, 10, 10},
+			maxResults: 2,
+			maxBytes:   100,
+			want:       []string{"1", "2"},
+		},
+		{
+			name:       "too many bytes",
+			ages:       []time.Duration{3 * time.Minute, 2 * time.Minute, time.Minute},
+			sizes:      []int{60, 30, 30},
+			maxResults: 10,
+			maxBytes:   70,
+			want:       []string{"1", "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &jobQueue{
+				jobs:       make(map[string]*renderJob),
+				retention:  time.Hour,
+				maxResults: tt.maxResults,
+				maxBytes:   tt.maxBytes,
+			}
+			for i, age := range tt.ages {
+				finished := time.Now().Add(-age)
+				job := &renderJob{ID: strconv.Itoa(i), FinishedAt: &finished, result: &jobResponse{}}
+				job.result.body.WriteString(strings.Repeat("x", tt.sizes[i]))
+				q.jobs[job.ID] = job
+				q.finished = append(q.finished, job)
+				q.resultBytes += int64(tt.sizes[i])
+			}
+
+			q.prune()
+			var kept []string
+			for _, job := range q.finished {
+				kept = append(kept, job.ID)
+			}
+			if !slices.Equal(kept, tt.want) || len(q.jobs) != len(tt.want) {
+				t.Errorf("kept %v (%d jobs), want %v", kept, len(q.jobs), tt.want)
+			}
+		})
+	}
+}
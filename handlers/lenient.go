@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isLenient reports whether ?lenient=true asks for relaxed JSON parsing
+func isLenient(c *gin.Context) bool {
+	return c.Query("lenient") == "true"
+}
+
+// relaxedJSONToJSON rewrites hand-edited JSON into strict JSON: it drops
+// // and /* */ comments and trailing commas, quotes unquoted object keys and
+// turns single-quoted strings into double-quoted ones. Comments and dropped
+// commas become spaces, so line numbers in later errors still match. Other
+// mistakes are left for the JSON decoder to report.
+func relaxedJSONToJSON(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data)+len(data)/8)
+	for i := 0; i < len(data); {
+		switch ch := data[i]; {
+		case ch == '"' || ch == '\'':
+			end, err := relaxedStringEnd(data, i)
+			if err != nil {
+				return nil, err
+			}
+			if ch == '"' {
+				out = append(out, data[i:end]...)
+			} else {
+				out = appendSingleQuoted(out, data[i+1:end-1])
+			}
+			i = end
+		case ch == '/' && i+1 < len(data) && (data[i+1] == '/' || data[i+1] == '*'):
+			end, err := relaxedCommentEnd(data, i)
+			if err != nil {
+				return nil, err
+			}
+			out = appendBlank(out, data[i:end])
+			i = end
+		case ch == ',':
+			if next := relaxedSkip(data, i+1); next < len(data) && (data[next] == '}' || data[next] == ']') {
+				out = append(out, ' ')
+			} else {
+				out = append(out, ',')
+			}
+			i++
+		case isIdentifierStart(ch):
+			end := i + 1
+			for end < len(data) && isIdentifierPart(data[end]) {
+				end++
+			}
+			if next := relaxedSkip(data, end); next < len(data) && data[next] == ':' {
+				out = strconv.AppendQuote(out, string(data[i:end]))
+			} else {
+				out = append(out, data[i:end]...) // true, false, null or a mistake
+			}
+			i = end
+		default:
+			out = append(out, ch)
+			i++
+		}
+	}
+	return out, nil
+}
+
+// relaxedStringEnd returns the offset just past the string starting at
+// start, which is quoted with data[start]
+func relaxedStringEnd(data []byte, start int) (int, error) {
+	quote := data[start]
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, nil
+		case '\n':
+			return 0, fmt.Errorf("line %d: unterminated string", lineAt(data, start))
+		}
+	}
+	return 0, fmt.Errorf("line %d: unterminated string", lineAt(data, start))
+}
+
+// relaxedCommentEnd returns the offset just past the comment starting at
+// start; line comments end before their newline
+func relaxedCommentEnd(data []byte, start int) (int, error) {
+	if data[start+1] == '/' {
+		if end := bytes.IndexByte(data[start:], '\n'); end >= 0 {
+			return start + end, nil
+		}
+		return len(data), nil
+	}
+	if end := bytes.Index(data[start+2:], []byte("*/")); end >= 0 {
+		return start + 2 + end + 2, nil
+	}
+	return 0, fmt.Errorf("line %d: unterminated comment", lineAt(data, start))
+}
+
+// relaxedSkip returns the offset of the next character after i that is
+// neither whitespace nor part of a comment
+func relaxedSkip(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		case '/':
+			if i+1 >= len(data) || (data[i+1] != '/' && data[i+1] != '*') {
+				return i
+			}
+			end, err := relaxedCommentEnd(data, i)
+			if err != nil {
+				return i
+			}
+			i = end
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// appendSingleQuoted appends the body of a single-quoted string as a JSON
+// string: \' loses its backslash and " gains one
+func appendSingleQuoted(out, body []byte) []byte {
+	out = append(out, '"')
+	for i := 0; i < len(body); i++ {
+		switch ch := body[i]; {
+		case ch == '\\' && i+1 < len(body) && body[i+1] == '\'':
+			out = append(out, '\'')
+			i++
+		case ch == '\\' && i+1 < len(body):
+			out = append(out, ch, body[i+1])
+			i++
+		case ch == '"':
+			out = append(out, '\\', '"')
+		default:
+			out = append(out, ch)
+		}
+	}
+	return append(out, '"')
+}
+
+// appendBlank appends spaces in place of skipped text, keeping its newlines
+func appendBlank(out, skipped []byte) []byte {
+	for len(skipped) > 0 {
+		r, size := utf8.DecodeRune(skipped)
+		if r == '\n' {
+			out = append(out, '\n')
+		} else {
+			out = append(out, ' ')
+		}
+		skipped = skipped[size:]
+	}
+	return out
+}
+
+// lineAt returns the 1-based line number of offset in data
+func lineAt(data []byte, offset int) int {
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+func isIdentifierStart(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+func isIdentifierPart(ch byte) bool {
+	return isIdentifierStart(ch) || ch >= '0' && ch <= '9'
+}
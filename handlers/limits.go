@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"fhir_renderer/models"
+)
+
+// Limits caps the resources a single render request may consume
+type Limits struct {
+	MaxBodyBytes    int64         // Maximum request body (and decompressed resource) size
+	MaxPackageBytes int64         // Maximum package upload and extracted definitions size
+	MaxElements     int           // Maximum number of flattened rows
+	MaxDepth        int           // Maximum element nesting depth
+	RenderTimeout   time.Duration // Maximum time spent rendering
+	RenderWorkers   int           // Concurrent renders, also the number of job workers
+	RenderQueue     int           // Requests waiting for a render slot, and queued jobs
+
+	JobRetention      time.Duration // How long finished render jobs and their results are kept
+	MaxJobResults     int           // Finished render jobs kept; the oldest are dropped first
+	MaxJobResultBytes int64         // Total size of the kept render job results
+}
+
+// DefaultLimits returns the limits used when no overrides are configured
+func DefaultLimits() Limits {
+	return Limits{
+		MaxBodyBytes:    2 << 20,  // 2 MiB
+		MaxPackageBytes: 50 << 20, // 50 MiB
+		MaxElements:     5000,
+		MaxDepth:        20,
+		RenderTimeout:   10 * time.Second,
+		RenderWorkers:   runtime.NumCPU(),
+		RenderQueue:     100,
+
+		JobRetention:      time.Hour,
+		MaxJobResults:     1000,
+		MaxJobResultBytes: 256 << 20, // 256 MiB
+	}
+}
+
+// limits is the active configuration used by the handlers
+var limits = DefaultLimits()
+
+// SetLimits replaces the limits applied to incoming requests
+func SetLimits(l Limits) {
+	limits = l
+	renderSlots = newRenderSemaphore(l.RenderWorkers, l.RenderQueue)
+}
+
+// checkComplexity verifies the flattened resource stays within the element
+// count and depth limits
+func checkComplexity(flat []models.FlatElement) error {
+	if len(flat) > limits.MaxElements {
+		return fmt.Errorf("resource has %d elements, limit is %d", len(flat), limits.MaxElements)
+	}
+	for _, fe := range flat {
+		if fe.Depth > limits.MaxDepth {
+			return fmt.Errorf("element %q is nested %d levels deep, limit is %d", fe.Path, fe.Depth, limits.MaxDepth)
+		}
+	}
+	return nil
+}
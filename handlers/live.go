@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"fhir_renderer/models"
+	"fhir_renderer/renderer"
+	"fhir_renderer/validation"
+)
+
+// liveOrigins are the browser origins allowed to open /ws; empty or "*"
+// allows any, like CORS_ORIGINS
+var liveOrigins []string
+
+// SetLiveOrigins configures the origins allowed to open /ws
+func SetLiveOrigins(origins []string) {
+	liveOrigins = slices.Clone(origins)
+}
+
+// liveEdit is a definition received on /ws, numbered in arrival order
+type liveEdit struct {
+	seq  int
+	data []byte
+}
+
+// liveResult answers a liveEdit with the rendered SVG or an error
+type liveResult struct {
+	Seq         int                     `json:"seq"`
+	SVG         string                  `json:"svg,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	Details     string                  `json:"details,omitempty"`
+	Diagnostics []validation.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// LiveRenderHandler streams previews over a WebSocket: every text message is
+// a definition, answered with {"seq", "svg"} or {"seq", "error", "details"}.
+// Edits that arrive while a render is running replace each other, so only
+// the newest is rendered and a fast typist never waits for stale previews.
+// The render options are taken from the query string, as for /render.
+// GET /ws
+func LiveRenderHandler(c *gin.Context) {
+	config := renderer.DefaultConfig()
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+
+	server := websocket.Server{
+		Handshake: checkLiveOrigin,
+		Handler: func(ws *websocket.Conn) {
+			serveLive(c, ws, config)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkLiveOrigin rejects browsers on origins that CORS_ORIGINS does not
+// allow; clients that send no Origin are not browsers and are accepted
+func checkLiveOrigin(_ *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" || len(liveOrigins) == 0 || slices.Contains(liveOrigins, "*") || slices.Contains(liveOrigins, origin) {
+		return nil
+	}
+	return fmt.Errorf("origin %q is not allowed", origin)
+}
+
+// serveLive renders the newest edit whenever the previous render is done,
+// until the client disconnects
+func serveLive(c *gin.Context, ws *websocket.Conn, config renderer.SVGConfig) {
+	ws.MaxPayloadBytes = int(limits.MaxBodyBytes)
+
+	edits := make(chan liveEdit, 1)
+	go func() {
+		defer close(edits)
+		for seq := 1; ; seq++ {
+			var data []byte
+			if err := websocket.Message.Receive(ws, &data); err != nil {
+				return
+			}
+			// Drop the edit still waiting, if any; this is the only sender
+			select {
+			case <-edits:
+			default:
+			}
+			edits <- liveEdit{seq: seq, data: data}
+		}
+	}()
+
+	for edit := range edits {
+		if err := websocket.JSON.Send(ws, renderLive(c, edit, config)); err != nil {
+			return
+		}
+	}
+}
+
+// renderLive decodes, checks and renders one edit like POST /render
+func renderLive(c *gin.Context, edit liveEdit, config renderer.SVGConfig) liveResult {
+	result := liveResult{Seq: edit.seq}
+	ctx := c.Request.Context()
+
+	strict := isStrict(c)
+	var resource models.ResourceDefinition
+	if err := decodeResource(ctx, edit.data, strict, &resource); err != nil {
+		result.Error, result.Details = "Invalid JSON", err.Error()
+		var decodeErr *validation.DecodeError
+		if errors.As(err, &decodeErr) {
+			result.Diagnostics = []validation.Diagnostic{decodeErr.Diagnostic}
+		}
+		return result
+	}
+	applyFHIRVersion(c, &resource)
+	if err := validateResource(&resource); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if strict {
+		if diagnostics := validation.StrictViolations(&resource); len(diagnostics) > 0 {
+			result.Error, result.Diagnostics = "Strict validation failed", diagnostics
+			return result
+		}
+	}
+
+	trimmed := trimResource(c, &resource)
+	if err := checkComplexity(trimmed.Flatten()); err != nil {
+		result.Error, result.Details = "Resource exceeds render limits", err.Error()
+		return result
+	}
+
+	if err := renderSlots.acquire(ctx, false); err != nil {
+		result.Error, result.Details = "Server is busy", err.Error()
+		return result
+	}
+	defer renderSlots.release()
+	expandBindings(c, trimmed)
+
+	ctx, cancel := context.WithTimeout(ctx, limits.RenderTimeout)
+	defer cancel()
+	var svg strings.Builder
+	err := renderer.RenderToContext(ctx, &svg, trimmed, config)
+	if errors.Is(err, context.DeadlineExceeded) {
+		result.Error, result.Details = "Resource exceeds render limits", fmt.Sprintf("rendering took longer than %s", limits.RenderTimeout)
+		return result
+	}
+	if err != nil {
+		result.Error, result.Details = "Render failed", err.Error()
+		return result
+	}
+	result.SVG = svg.String()
+	return result
+}
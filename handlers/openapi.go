@@ -0,0 +1,981 @@
+package handlers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/convert"
+	"fhir_renderer/models"
+	"fhir_renderer/renderer"
+	"fhir_renderer/storage"
+	"fhir_renderer/validation"
+)
+
+//go:embed overview.md
+var apiOverviewMarkdown string
+
+// OpenAPIVersion is the version of the published API description
+const OpenAPIVersion = "1.0.0"
+
+// schemaDescriptions documents model properties, keyed by "Type.property"
+var schemaDescriptions = map[string]string{
+	"ResourceDefinition.resourceType": "Optional identifier, usually \"ResourceDefinition\"",
+	"ResourceDefinition.name":         "Resource name",
+	"ResourceDefinition.type":         "Base type, e.g. \"DomainResource\"",
+	"ResourceDefinition.version":      "Business version of the definition",
+	"ResourceDefinition.status":       "Publication status, shown as a colored badge in the title bar",
+	"ResourceDefinition.publisher":    "Organization or individual that published the definition (metadata footer)",
+	"ResourceDefinition.date":         "Publication date, e.g. \"2024-05-01\" (metadata footer)",
+	"ResourceDefinition.flags":        "Metadata flags (see Flags)",
+	"ResourceDefinition.fhirVersion":  "FHIR version whose types are linked and checked: STU3, R4 (default) or R5, or a version number such as 4.0.1",
+	"ResourceDefinition.elements":     "Child elements",
+	"ResourceDefinition.extensions":   "Root-level FHIR extensions",
+	"ResourceDefinition.annotations":  "Review annotations that tint matching rows and add margin notes",
+	"ResourceDefinition.example":      "Example instance; its values appear in a \"Sample value\" column, matched by element path",
+	"Annotation.path":                 "Element path, with or without the resource name, e.g. \"Patient.identifier\"",
+	"Annotation.color":                "Row tint as #RGB or #RRGGBB; defaults to a light yellow",
+	"Annotation.note":                 "Note shown in the margin column",
+	"Element.name":                    "Field name; required unless path is set",
+	"Element.path":                    "Dotted path such as \"Patient.contact.name\" for top-level elements given flat; the server nests them, creating missing parents as BackboneElements",
+	"Element.type":                    "Data type",
+	"Element.cardinality":             "Cardinality such as \"0..1\", \"1..1\", \"0..*\"",
+	"Element.flags":                   "FHIR flags (see Flags)",
+	"Element.typeRef":                 "Link to the type documentation",
+	"Element.usage":                   "Implementation status",
+	"Element.notes":                   "Custom implementation notes",
+	"Element.elements":                "Nested children (BackboneElement)",
+	"Element.targets":                 "Reference target types, rendered as Reference(A | B) with each target linked",
+	"Target.type":                     "Target resource type, e.g. \"Patient\"",
+	"Target.url":                      "Link to the target documentation",
+	"Element.fixed":                   "Value the element must have exactly, shown as a \"Fixed Value:\" line",
+	"Element.pattern":                 "Value the element must match, shown as a \"Required Pattern:\" line",
+	"Element.mappings":                "Mappings to other specifications, shown in the optional map column",
+	"Mapping.identity":                "Target specification, e.g. \"v2\" or \"rim\"",
+	"Mapping.map":                     "Mapping expression, e.g. \"PID-3\"",
+	"Element.extensions":              "Extensions on this element",
+	"Element.meta":                    "Free-form values shown in the configured extra columns, keyed by column key",
+	"Element.highlight":               "Row tint, e.g. by owning subsystem: #RGB, #RRGGBB or one of " + strings.Join(models.HighlightNames(), ", "),
+	"Binding.strength":                "Binding strength",
+	"Binding.valueSet":                "Allowed values (pipe-delimited) or value set URL",
+	"Binding.url":                     "Link to the value set documentation",
+	"Binding.codes":                   "Allowed codes listed below the description, filled in by ?expandBindings=true",
+	"Binding.truncated":               "The value set holds more codes than listed, shown as \"…\"",
+	"Extension.url":                   "Extension URL, shown and linked below the name",
+	"Extension.flags":                 "FHIR flags (see Flags)",
+	"Extension.context":               "Where the extension applies, shown below the url",
+	"Extension.extensions":            "Sub-extensions of a complex extension, rendered as a subtree; their url may be the slice name",
+	"Snippet.id":                      "Generated snippet id",
+	"Snippet.tags":                    "Free-form labels used to filter the library",
+	"Snippet.resource":                "Saved definition; omitted from GET /snippets listings",
+	"Report.valid":                    "True when no errors were found (warnings are allowed)",
+	"Diagnostic.path":                 "JSON path of the offending value, e.g. $.elements[2].cardinality",
+	"Diagnostic.line":                 "1-based line of the offending key or value in the request body, for problems found while reading it",
+	"Diagnostic.column":               "1-based column, in characters, of the offending key or value",
+}
+
+// schemaEnums lists the allowed values of enumerated model properties
+var schemaEnums = map[string][]string{
+	"Element.usage":             {models.UsageUsed, models.UsageNotUsed, models.UsageTodo, models.UsageOptional},
+	"Binding.strength":          validation.KnownBindingStrengths,
+	"ResourceDefinition.status": validation.KnownStatuses,
+	"Diagnostic.severity":       {validation.SeverityError, validation.SeverityWarning},
+}
+
+// openAPISpec is generated once at startup from the models and route table
+var openAPISpec = buildOpenAPISpec()
+
+// OpenAPIHandler returns the OpenAPI 3.0 description of the API
+// GET /openapi.json
+func OpenAPIHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPISpec)
+}
+
+// DocsHandler serves the Swagger UI page for the OpenAPI description
+// GET /docs
+func DocsHandler(c *gin.Context) {
+	c.HTML(http.StatusOK, "docs.html", gin.H{"SpecURL": "/openapi.json"})
+}
+
+// HelpHandler redirects to the interactive API documentation
+func HelpHandler(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, "/docs")
+}
+
+// buildOpenAPISpec assembles the OpenAPI document
+func buildOpenAPISpec() gin.H {
+	schemas := gin.H{}
+	generateSchema(reflect.TypeOf(models.ResourceDefinition{}), schemas)
+	generateSchema(reflect.TypeOf(validation.Report{}), schemas)
+	generateSchema(reflect.TypeOf(renderer.Layout{}), schemas)
+	generateSchema(reflect.TypeOf(renderer.Stats{}), schemas)
+	generateSchema(reflect.TypeOf(renderer.Size{}), schemas)
+	generateSchema(reflect.TypeOf(storage.Snippet{}), schemas)
+	// Snippet resources are stored as raw JSON but hold a ResourceDefinition
+	schemas["Snippet"].(gin.H)["properties"].(gin.H)["resource"] = gin.H{
+		"allOf":       []gin.H{schemaRef("ResourceDefinition")},
+		"description": schemaDescriptions["Snippet.resource"],
+	}
+	schemas["RenderJob"] = gin.H{
+		"type":     "object",
+		"required": []string{"id", "type", "status", "createdAt"},
+		"properties": gin.H{
+			"id":         gin.H{"type": "string"},
+			"type":       gin.H{"type": "string", "enum": []string{JobTypeRender, JobTypePackage, JobTypeCompare}},
+			"status":     gin.H{"type": "string", "enum": []string{JobQueued, JobRunning, JobDone, JobFailed}},
+			"error":      gin.H{"type": "string", "description": "Error message of a failed job"},
+			"createdAt":  gin.H{"type": "string", "format": "date-time"},
+			"startedAt":  gin.H{"type": "string", "format": "date-time"},
+			"finishedAt": gin.H{"type": "string", "format": "date-time"},
+			"resultUrl":  gin.H{"type": "string", "description": "Result link, set once the job has finished"},
+			"progress": gin.H{
+				"type":        "object",
+				"description": "Package jobs: definitions rendered so far",
+				"properties": gin.H{
+					"done":  gin.H{"type": "integer"},
+					"total": gin.H{"type": "integer"},
+				},
+			},
+		},
+	}
+	schemas["Error"] = gin.H{
+		"type":     "object",
+		"required": []string{"error"},
+		"properties": gin.H{
+			"error":    gin.H{"type": "string", "description": "Human readable error message"},
+			"details":  gin.H{"type": "string", "description": "Underlying parser or decoder error"},
+			"usage":    gin.H{"type": "string", "description": "Correct usage hint"},
+			"maxBytes": gin.H{"type": "integer", "description": "Size limit that was exceeded, in bytes (413 responses)"},
+			"diagnostics": gin.H{
+				"type":        "array",
+				"items":       schemaRef("Diagnostic"),
+				"description": "Strict validation failures, or where a body that is no valid definition JSON goes wrong",
+			},
+		},
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "FHIR Renderer API",
+			"version":     OpenAPIVersion,
+			"description": apiOverviewMarkdown,
+		},
+		"paths": buildOpenAPIPaths(),
+		"components": gin.H{
+			"schemas": schemas,
+			"responses": gin.H{
+				"BadRequest":          errorResponse("Invalid input"),
+				"PayloadTooLarge":     errorResponse("Request body or decompressed resource exceeds the size limit"),
+				"UnprocessableEntity": errorResponse("Resource exceeds element count, depth or render time limits"),
+				"NotFound":            errorResponse("No shared resource exists for the id"),
+				"ServiceUnavailable":  errorResponse("Sharing is disabled on this server"),
+				"SnippetsUnavailable": errorResponse("The snippet library is disabled on this server"),
+				"JobsUnavailable":     errorResponse("Render jobs are disabled on this server"),
+				"TooManyRequests":     errorResponse("All render slots are busy and the wait queue is full; retry after the Retry-After seconds"),
+			},
+		},
+	}
+}
+
+// buildOpenAPIPaths describes every route registered in main.go
+func buildOpenAPIPaths() gin.H {
+	svgResponse := gin.H{
+		"description": "Rendered SVG diagram",
+		"headers": gin.H{
+			"Cache-Control": gin.H{"schema": gin.H{"type": "string"}, "description": "public, max-age=3600"},
+			"ETag":          gin.H{"schema": gin.H{"type": "string"}, "description": "Strong validator for If-None-Match"},
+		},
+		"content": gin.H{
+			"image/svg+xml": gin.H{"schema": gin.H{"type": "string"}},
+			"text/plain":    gin.H{"schema": gin.H{"type": "string"}, "description": "Mermaid or PlantUML class diagram"},
+			"text/html":     gin.H{"schema": gin.H{"type": "string"}, "description": "HTML table version of the diagram"},
+			"application/json": gin.H{
+				"schema":      schemaRef("Layout"),
+				"description": "Computed layout (format=json-layout)",
+			},
+			"text/csv": gin.H{"schema": gin.H{"type": "string"}, "description": "Element table (format=csv)"},
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": gin.H{
+				"schema":      gin.H{"type": "string", "format": "binary"},
+				"description": "Element table as an Excel workbook (format=xlsx)",
+			},
+		},
+	}
+	fshBody := gin.H{"schema": gin.H{"type": "string"}, "description": "FHIR Shorthand; the first Profile, Extension, Logical or Resource is converted"}
+	yamlBody := gin.H{"schema": gin.H{"type": "string"}, "description": "YAML form of the JSON body; the first document is read"}
+	resourceBody := gin.H{
+		"required": true,
+		"content": gin.H{
+			"application/json":     gin.H{"schema": schemaRef("ResourceDefinition")},
+			"application/yaml":     yamlBody,
+			"application/fhir+xml": gin.H{"schema": gin.H{"type": "string"}},
+			"text/fsh":             fshBody,
+		},
+	}
+	renderBody := gin.H{
+		"required": true,
+		"content": gin.H{
+			"application/json": gin.H{"schema": gin.H{"oneOf": []gin.H{
+				schemaRef("ResourceDefinition"),
+				{
+					"type":        "array",
+					"items":       schemaRef("ResourceDefinition"),
+					"description": "Definitions stacked in one SVG, each with its own title bar (svg format only)",
+				},
+				{
+					"type":        "object",
+					"description": "FHIR CapabilityStatement, rendered as an interaction matrix (svg format only)",
+				},
+				{
+					"type":        "object",
+					"description": "FHIR ValueSet or CodeSystem, rendered as a concept table (svg format only)",
+				},
+			}}},
+			"application/yaml":     yamlBody,
+			"application/fhir+xml": gin.H{"schema": gin.H{"type": "string"}},
+			"text/fsh":             fshBody,
+			"multipart/form-data": gin.H{"schema": gin.H{
+				"type":     "object",
+				"required": []string{"resource"},
+				"properties": gin.H{
+					"resource": gin.H{"type": "string", "description": "ResourceDefinition JSON (or an array of them)"},
+					"font":     gin.H{"type": "string", "format": "binary", "description": "TTF or OTF font used to measure and render this request"},
+					"example":  gin.H{"type": "string", "description": "Example instance JSON shown in a \"Sample value\" column; overrides the definition's example"},
+				},
+			}},
+		},
+	}
+	badRequest := gin.H{"$ref": "#/components/responses/BadRequest"}
+	tooLarge := gin.H{"$ref": "#/components/responses/PayloadTooLarge"}
+	tooComplex := gin.H{"$ref": "#/components/responses/UnprocessableEntity"}
+	notModified := gin.H{"description": "Not modified; the If-None-Match ETag is still current"}
+	notFound := gin.H{"$ref": "#/components/responses/NotFound"}
+	shareDisabled := gin.H{"$ref": "#/components/responses/ServiceUnavailable"}
+	busy := gin.H{"$ref": "#/components/responses/TooManyRequests"}
+	idParameter := gin.H{
+		"name":        "id",
+		"in":          "path",
+		"required":    true,
+		"description": "Short id returned by POST /share",
+		"schema":      gin.H{"type": "string"},
+	}
+	snippetsDisabled := gin.H{"$ref": "#/components/responses/SnippetsUnavailable"}
+	snippetNotFound := errorResponse("No snippet exists for the id")
+	jobsDisabled := gin.H{"$ref": "#/components/responses/JobsUnavailable"}
+	jobNotFound := errorResponse("No render job exists for the id, or it expired")
+	jobIDParameter := gin.H{
+		"name":        "id",
+		"in":          "path",
+		"required":    true,
+		"description": "Job id returned by POST /render/jobs",
+		"schema":      gin.H{"type": "string"},
+	}
+	snippetIDParameter := gin.H{
+		"name":        "id",
+		"in":          "path",
+		"required":    true,
+		"description": "Snippet id",
+		"schema":      gin.H{"type": "string"},
+	}
+	snippetBody := gin.H{
+		"required": true,
+		"content": gin.H{"application/json": gin.H{"schema": gin.H{
+			"type":     "object",
+			"required": []string{"name", "resource"},
+			"properties": gin.H{
+				"name":     gin.H{"type": "string"},
+				"tags":     gin.H{"type": "array", "items": gin.H{"type": "string"}},
+				"resource": schemaRef("ResourceDefinition"),
+			},
+		}}},
+	}
+	renderParameters := []gin.H{
+		{
+			"name":        "If-None-Match",
+			"in":          "header",
+			"required":    false,
+			"description": "ETag from a previous response; a match returns 304 without rendering",
+			"schema":      gin.H{"type": "string"},
+		},
+		withEnum(queryParameter("format", "Output format (default svg)", false), supportedFormatNames()),
+		queryParameter("lang", "Language of titles, column headers, labels and the legend, one of "+strings.Join(renderer.Languages(), ", ")+" (default en); regional tags such as de-CH use their language", false),
+		queryParameter("highlightMS", "\"true\" tints rows flagged MS (must support)", false),
+		queryParameter("theme", "Name of a theme pack loaded from the server's themes directory, e.g. acme for themes/acme.yaml; unknown names are rejected with 400", false),
+		queryParameter("hover", "\"true\" highlights the row under the pointer, e.g. when the SVG is inlined in an HTML page", false),
+		withEnum(queryParameter("media", "print renders the print-friendly variant: white rows and header without alternating striping, and black borders (default screen)", false), []string{renderer.MediaScreen, renderer.MediaPrint}),
+		queryParameter("legend", "\"true\" appends a legend explaining icons, flags and usage styling", false),
+		queryParameter("maxRowsPerPage", "Split structure diagrams with more rows into pages that repeat the title bar and header row (at least "+strconv.Itoa(renderer.MinRowsPerPage)+"): stacked in one SVG, or one SVG per page with format=zip", false),
+		queryParameter("rowNumbers", "\"true\" adds a leading # column numbering the rows; each number links to the row's path-derived anchor, e.g. #Patient.identifier", false),
+		queryParameter("groupHeaders", "\"true\" draws a band with the name above each top-level element with children, e.g. Claim.item, to split long resources into sections", false),
+		queryParameter("rollupUsage", "\"true\" greys out parents whose children are all not used and appends \"(no children used)\" to their description", false),
+		queryParameter("metadata", "\"true\" adds a footer row with resource version, generation time, renderer version and a source link", false),
+		queryParameter("columns", "Comma separated columns to show, in order, from name, flags, card, type, desc and map (name is required), e.g. name,card,type,desc or name,flags,card,type,desc,map to add mappings", false),
+		queryParameter("extraColumns", "Comma separated extra columns as key or key:Title, showing each element's meta value for the key after the built-in columns, e.g. owner:Owner,ticket:Ticket", false),
+		queryParameter("title", "Title bar template in Go text/template syntax, executed with the definition, e.g. Structure: {{.Name}} ({{.Type}}){{with .Version}} v{{.}}{{end}}; replaces \"Structure\" (invalid templates are rejected with 400)", false),
+		queryParameter("watermark", "Text drawn diagonally over the diagram, e.g. DRAFT or INTERNAL (at most 40 characters); \"none\" removes the server's default watermark", false),
+		queryParameter("pretty", "\"true\" puts every SVG element on its own line, indented by nesting, for reading and diffing; \"false\" drops the whitespace between elements for the smallest output (default: the server's RENDER_WHITESPACE)", false),
+		queryParameter("width", "Target width in pixels; the name, type and description columns shrink proportionally and text re-wraps to fit (at least 485)", false),
+		queryParameter("responsive", "\"true\" emits width=\"100%\" with a viewBox and preserveAspectRatio so the SVG scales with its container", false),
+		queryParameter("minFontSize", "With responsive=true, the smallest font size in pixels the diagram may shrink to (sets a CSS min-width)", false),
+		queryParameter("maxFontSize", "With responsive=true, the largest font size in pixels the diagram may grow to (sets a CSS max-width)", false),
+		queryParameter("embedSource", "\"true\" embeds the compressed definition in the SVG's <metadata>, so POST /extract can recover it from the image (single definitions only)", false),
+		queryParameter("deterministic", "\"true\" leaves out the generation time and renderer version so identical input gives byte-identical output", false),
+		withEnum(queryParameter("view", "summary keeps only elements flagged S (\u03A3) and their ancestors; coverage colors rows by usage and adds an implementation summary (default full)", false), []string{ViewFull, ViewSummary, ViewCoverage}),
+		queryParameter("include", "Comma separated element paths to keep, relative to the resource (e.g. name,identifier.*); ancestors are kept", false),
+		queryParameter("excludeUsage", "Comma separated usages whose elements (and their children) are dropped, e.g. not-used", false),
+		queryParameter("onlyFlags", "Comma separated flags; only elements carrying one of them (and their ancestors) are kept, e.g. MS", false),
+		queryParameter("maxDepth", "Collapse elements nested deeper than this (top-level elements are depth 1) into one \"… n more elements\" row per branch", false),
+		queryParameter("typeLinkBase", "Link template for type names, e.g. https://hl7.org/fhir/R4/{lower}.html ({name} is the type, {lower} its lowercase form, {version} the FHIR version; without name placeholders the name is appended)", false),
+		queryParameter("fhirVersion", "FHIR version whose types are linked and checked (STU3, R4 or R5, or a version number such as 4.0.1), overriding the definition's fhirVersion", false),
+		queryParameter("fhirLinks", "\"false\" stops linking FHIR data types and resources to the FHIR specification when no typeLinkBase is set", false),
+		queryParameter("expandBindings", "\"true\" lists the first codes of bound value sets, expanded by the configured terminology server (TERMINOLOGY_SERVER)", false),
+		queryParameter("elementLinkBase", "Link template for element names, e.g. https://hl7.org/fhir/R4/patient-definitions.html#{name} ({name} is the element path)", false),
+		queryParameter("embedFont", "\"true\" embeds the custom font (FONT_PATH or uploaded) as a base64 @font-face", false),
+		queryParameter("spacing", "Gap in pixels between resources when rendering an array (default 16, max 200)", false),
+		queryParameter("strict", "\"true\" rejects unknown fields, malformed cardinalities and invalid binding strengths", false),
+	}
+	// POST bodies can be hand-edited JSON
+	lenientParameter := queryParameter("lenient", "\"true\" accepts // and /* */ comments, trailing commas, unquoted keys and single-quoted strings in a JSON body", false)
+
+	// Package renders always produce SVG and apply the view and style options
+	var packageParameters []gin.H
+	for _, param := range renderParameters {
+		switch param["name"] {
+		case "If-None-Match", "format", "spacing", "strict":
+		default:
+			packageParameters = append(packageParameters, param)
+		}
+	}
+
+	// Comparisons render a single converted profile
+	var compareParameters []gin.H
+	for _, param := range renderParameters {
+		switch param["name"] {
+		case "spacing", "strict":
+		default:
+			compareParameters = append(compareParameters, param)
+		}
+	}
+
+	// Thumbnails and preview cards keep the style options; the width of a
+	// thumbnail scales the drawing
+	sourceParameters := []gin.H{
+		queryParameter("resource", "Brotli compressed, Base64URL encoded ResourceDefinition JSON (or use id)", false),
+		queryParameter("id", "Id of a definition stored with POST /share", false),
+	}
+	var cardParameters []gin.H
+	for _, param := range renderParameters {
+		switch param["name"] {
+		case "If-None-Match", "lang", "theme", "fhirVersion", "strict":
+			cardParameters = append(cardParameters, param)
+		}
+	}
+	thumbParameters := []gin.H{
+		queryParameter("width", fmt.Sprintf("Thumbnail width in pixels (default %d, %d to %d); the height follows the aspect ratio", renderer.DefaultThumbnailWidth, renderer.MinThumbnailWidth, renderer.MaxThumbnailWidth), false),
+	}
+	for _, param := range renderParameters {
+		switch param["name"] {
+		case "If-None-Match", "lang", "theme", "title", "watermark", "fhirVersion", "embedFont", "strict":
+			thumbParameters = append(thumbParameters, param)
+		}
+	}
+
+	return gin.H{
+		"/health": gin.H{
+			"get": operation("Health check", gin.H{
+				"200": jsonResponse("Service is healthy", gin.H{
+					"type": "object",
+					"properties": gin.H{
+						"status":  gin.H{"type": "string", "example": "ok"},
+						"service": gin.H{"type": "string", "example": "fhir-renderer"},
+					},
+				}),
+			}),
+		},
+		"/example": gin.H{
+			"get": operation("Example ResourceDefinition", gin.H{
+				"200": jsonResponse("Example definition", schemaRef("ResourceDefinition")),
+			}),
+		},
+		"/example/random": gin.H{
+			"get": withParameters(operation("Generate a synthetic definition of a given size for demos, load tests and layout stress tests", gin.H{
+				"200": gin.H{
+					"description": "Generated definition; the same parameters and seed give the same definition",
+					"headers": gin.H{
+						"X-Random-Seed": gin.H{"description": "Seed the definition was generated with", "schema": gin.H{"type": "integer"}},
+					},
+					"content": gin.H{"application/json": gin.H{"schema": schemaRef("ResourceDefinition")}},
+				},
+				"400": badRequest,
+			}), []gin.H{
+				queryParameter("elements", "Number of elements, from 1 to one less than MAX_ELEMENTS (default "+strconv.Itoa(defaultRandomElements)+")", false),
+				queryParameter("depth", "Nesting depth of the elements, from 1 to MAX_DEPTH (default "+strconv.Itoa(defaultRandomDepth)+")", false),
+				queryParameter("seed", "Non-negative integer seed for a reproducible definition; random when left out", false),
+			}),
+		},
+		"/examples": gin.H{
+			"get": operation("List the starter definitions", gin.H{
+				"200": jsonResponse("Starter definitions", gin.H{
+					"type": "object",
+					"properties": gin.H{
+						"examples": gin.H{
+							"type": "array",
+							"items": gin.H{
+								"type": "object",
+								"properties": gin.H{
+									"name":        gin.H{"type": "string", "example": "patient-profile"},
+									"title":       gin.H{"type": "string"},
+									"description": gin.H{"type": "string"},
+									"url":         gin.H{"type": "string", "example": "/examples/patient-profile"},
+								},
+							},
+						},
+					},
+				}),
+			}),
+		},
+		"/examples/{name}": gin.H{
+			"get": withParameters(operation("A starter definition: a ResourceDefinition or a FHIR resource such as a Questionnaire", gin.H{
+				"200": jsonResponse("Starter definition", gin.H{"type": "object"}),
+				"404": notFound,
+			}), []gin.H{{
+				"name":     "name",
+				"in":       "path",
+				"required": true,
+				"schema":   gin.H{"type": "string", "enum": exampleNames()},
+			}}),
+		},
+		"/render": gin.H{
+			"get": withParameters(operation("Render a compressed definition to SVG", gin.H{
+				"200": svgResponse,
+				"304": notModified,
+				"400": badRequest,
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+			}), append([]gin.H{
+				queryParameter("resource", "Brotli compressed, Base64URL encoded ResourceDefinition JSON", true),
+			}, renderParameters...)),
+			"post": withParameters(withBody(operation("Render a definition (or an array of definitions) from the request body to SVG", gin.H{
+				"200": svgResponse,
+				"304": notModified,
+				"400": badRequest,
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+			}), renderBody), append([]gin.H{lenientParameter}, renderParameters...)),
+		},
+		"/thumb": gin.H{
+			"get": withParameters(operation("Render a small, simplified SVG of a compressed or shared definition for gallery and list views: the root and its first-level elements with cardinality and type", gin.H{
+				"200": svgResponse,
+				"304": notModified,
+				"400": badRequest,
+				"404": errorResponse("No shared definition with this id"),
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+				"503": shareDisabled,
+			}), append(slices.Clone(sourceParameters), thumbParameters...)),
+		},
+		"/og": gin.H{
+			"get": withParameters(operation("Render a 1200x630 PNG social preview card of a compressed or shared definition: name, type, description, element count and the first rows of its structure. Editor links with ?id= or ?resource= point their og:image at it", gin.H{
+				"200": gin.H{
+					"description": "PNG preview card",
+					"content":     gin.H{"image/png": gin.H{"schema": gin.H{"type": "string", "format": "binary"}}},
+				},
+				"304": notModified,
+				"400": badRequest,
+				"404": errorResponse("No shared definition with this id"),
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+				"503": shareDisabled,
+			}), append(slices.Clone(sourceParameters), cardParameters...)),
+		},
+		"/ws": gin.H{
+			"get": withParameters(operation("Live preview over a WebSocket: send definitions as text messages and receive {\"seq\", \"svg\"} or {\"seq\", \"error\", \"details\"} for the newest one; edits sent during a render replace each other", gin.H{
+				"101": gin.H{"description": "Switching to the WebSocket protocol"},
+				"403": gin.H{"description": "The Origin is not one of CORS_ORIGINS"},
+			}), renderParameters),
+		},
+		"/render/package": gin.H{
+			"post": withParameters(withBody(operation("Render every StructureDefinition of an NPM-style FHIR package", gin.H{
+				"200": gin.H{
+					"description": "ZIP of SVG diagrams with an index.json (output=zip), or the index with share links (output=index)",
+					"content": gin.H{
+						"application/zip": gin.H{"schema": gin.H{"type": "string", "format": "binary"}},
+						"application/json": gin.H{"schema": gin.H{
+							"type": "object",
+							"properties": gin.H{
+								"package": gin.H{"type": "string"},
+								"version": gin.H{"type": "string"},
+								"resources": gin.H{"type": "array", "items": gin.H{
+									"type": "object",
+									"properties": gin.H{
+										"file":      gin.H{"type": "string", "description": "File name within the package"},
+										"name":      gin.H{"type": "string"},
+										"svg":       gin.H{"type": "string", "description": "SVG file name within the ZIP"},
+										"url":       gin.H{"type": "string", "description": "Render link, /d/{id}"},
+										"editorUrl": gin.H{"type": "string", "description": "Editor link, /editor?id={id}"},
+										"error":     gin.H{"type": "string", "description": "Why the definition was skipped"},
+									},
+								}},
+							},
+						}},
+					},
+				},
+				"400": badRequest,
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+				"503": shareDisabled,
+			}), gin.H{
+				"required": true,
+				"content":  gin.H{"application/gzip": gin.H{"schema": gin.H{"type": "string", "format": "binary"}}},
+			}), append([]gin.H{
+				withEnum(queryParameter("output", "zip returns the SVGs as a ZIP, index stores each definition and returns share links (default zip)", false), []string{PackageOutputZip, PackageOutputIndex}),
+			}, packageParameters...)),
+		},
+		"/render/size": gin.H{
+			"post": withParameters(withBody(operation("Return the width, height and row count of the SVG a render would produce, without rendering it", gin.H{
+				"200": jsonResponse("Diagram size in pixels", schemaRef("Size")),
+				"400": badRequest,
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+			}), resourceBody), append([]gin.H{lenientParameter}, renderParameters...)),
+		},
+		"/render/compare": gin.H{
+			"post": withParameters(withBody(operation("Render a profile StructureDefinition over its base, marking added slices, tightened cardinalities and removed elements", gin.H{
+				"200": svgResponse,
+				"304": notModified,
+				"400": badRequest,
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+			}), gin.H{
+				"required": true,
+				"content": gin.H{"application/json": gin.H{"schema": gin.H{
+					"type":     "object",
+					"required": []string{"profile"},
+					"properties": gin.H{
+						"profile": gin.H{"type": "object", "description": "Profile StructureDefinition"},
+						"base":    gin.H{"type": "object", "description": "Base StructureDefinition; defaults to the profile's baseDefinition resolved from the base definitions"},
+					},
+				}}},
+			}), compareParameters),
+		},
+		"/render/fsh": gin.H{
+			"post": withParameters(withBody(operation("Render FHIR Shorthand source: the first Profile, Extension, Logical or Resource, or the one named by ?name=", gin.H{
+				"200": svgResponse,
+				"304": notModified,
+				"400": badRequest,
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+			}), gin.H{
+				"required": true,
+				"content":  gin.H{"text/fsh": gin.H{"schema": gin.H{"type": "string"}}},
+			}), append([]gin.H{
+				queryParameter("name", "Name of the entity to render when the source defines several", false),
+			}, renderParameters...)),
+		},
+		"/render/jobs": gin.H{
+			"post": withParameters(operation("Queue a render, package or compare request and return immediately", gin.H{
+				"202": jsonResponse("Queued job; poll the Location header", schemaRef("RenderJob")),
+				"400": badRequest,
+				"413": tooLarge,
+				"429": busy,
+				"503": jobsDisabled,
+			}), []gin.H{
+				withEnum(queryParameter("type", "Endpoint to run: render (POST /render), package (POST /render/package) or compare (POST /render/compare); default render. The body and other query parameters are those of that endpoint", false), []string{JobTypeRender, JobTypePackage, JobTypeCompare}),
+			}),
+		},
+		"/render/jobs/{id}": gin.H{
+			"get": withParameters(operation("Render job status", gin.H{
+				"200": jsonResponse("Job", schemaRef("RenderJob")),
+				"404": jobNotFound,
+				"503": jobsDisabled,
+			}), []gin.H{jobIDParameter}),
+		},
+		"/render/jobs/{id}/result": gin.H{
+			"get": withParameters(operation("Response of a finished render job, exactly as the endpoint returned it (including errors of failed jobs)", gin.H{
+				"200": gin.H{"description": "Rendered output in the requested format, ZIP or package index"},
+				"404": jobNotFound,
+				"409": errorResponse("The job has not finished yet"),
+				"503": jobsDisabled,
+			}), []gin.H{jobIDParameter}),
+		},
+		"/render/jobs/{id}/events": gin.H{
+			"get": withParameters(operation("Server-sent events of a render job: \"status\" when it starts running, \"progress\" per package definition ({done, total} with the index entry) and \"done\" with the final job, after which the stream ends", gin.H{
+				"200": gin.H{
+					"description": "Event stream",
+					"content":     gin.H{"text/event-stream": gin.H{"schema": gin.H{"type": "string"}}},
+				},
+				"404": jobNotFound,
+				"503": jobsDisabled,
+			}), []gin.H{jobIDParameter}),
+		},
+		"/validate": gin.H{
+			"post": withParameters(withBody(operation("Lint a definition and report errors and warnings without rendering", gin.H{
+				"200": jsonResponse("Validation report", schemaRef("Report")),
+				"400": badRequest,
+				"413": tooLarge,
+			}), resourceBody), []gin.H{lenientParameter}),
+		},
+		"/validate-schema": gin.H{
+			"post": withParameters(withBody(operation("Validate a body against the ResourceDefinition JSON Schema without decoding or rendering it", gin.H{
+				"200": jsonResponse("Schema violations with their paths, lines and columns", schemaRef("Report")),
+				"400": badRequest,
+				"413": tooLarge,
+			}), resourceBody), []gin.H{lenientParameter}),
+		},
+		"/schema.json": gin.H{
+			"get": operation("JSON Schema (draft 2020-12) of ResourceDefinition, for editors and CI", gin.H{
+				"200": gin.H{
+					"description": "JSON Schema; its $id is the versioned URL",
+					"content":     gin.H{"application/schema+json": gin.H{"schema": gin.H{"type": "object"}}},
+				},
+			}),
+		},
+		"/schema/{version}.json": gin.H{
+			"get": withParameters(operation("A version of the ResourceDefinition JSON Schema; this server serves "+SchemaVersion, gin.H{
+				"200": gin.H{
+					"description": "JSON Schema",
+					"content":     gin.H{"application/schema+json": gin.H{"schema": gin.H{"type": "object"}}},
+				},
+				"404": notFound,
+			}), []gin.H{{
+				"name":     "version",
+				"in":       "path",
+				"required": true,
+				"schema":   gin.H{"type": "string", "example": SchemaVersion},
+			}}),
+		},
+		"/analyze": gin.H{
+			"post": withParameters(withBody(operation("Report element, depth, extension, cardinality and flag counts and the estimated diagram size without rendering", gin.H{
+				"200": jsonResponse("Complexity metrics", schemaRef("Stats")),
+				"400": badRequest,
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+			}), resourceBody), append([]gin.H{lenientParameter}, renderParameters...)),
+		},
+		"/compress": gin.H{
+			"post": withBody(operation("Compress JSON to Brotli+Base64URL", gin.H{
+				"200": jsonResponse("Compressed data", gin.H{
+					"type":       "object",
+					"properties": gin.H{"compressed": gin.H{"type": "string"}},
+				}),
+				"400": badRequest,
+				"413": tooLarge,
+			}), gin.H{
+				"required": true,
+				"content":  gin.H{"application/json": gin.H{"schema": gin.H{"type": "object"}}},
+			}),
+		},
+		"/decompress": gin.H{
+			"post": withBody(operation("Decompress Brotli+Base64URL to JSON", gin.H{
+				"200": jsonResponse("Original JSON document", gin.H{"type": "object"}),
+				"400": badRequest,
+				"413": tooLarge,
+			}), gin.H{
+				"required": true,
+				"content": gin.H{"application/json": gin.H{"schema": gin.H{
+					"type":       "object",
+					"required":   []string{"data"},
+					"properties": gin.H{"data": gin.H{"type": "string"}},
+				}}},
+			}),
+		},
+		"/source": gin.H{
+			"get": withParameters(operation("View a compressed definition as pretty-printed JSON", gin.H{
+				"200": jsonResponse("Original JSON document", schemaRef("ResourceDefinition")),
+				"400": badRequest,
+				"413": tooLarge,
+			}), []gin.H{
+				queryParameter("resource", "Brotli compressed, Base64URL encoded ResourceDefinition JSON", true),
+			}),
+		},
+		"/extract": gin.H{
+			"post": withBody(operation("Recover the definition embedded in an SVG rendered with ?embedSource=true", gin.H{
+				"200": jsonResponse("Original JSON document", schemaRef("ResourceDefinition")),
+				"400": badRequest,
+				"413": tooLarge,
+				"422": errorResponse("The SVG has no embedded source"),
+			}), gin.H{
+				"required": true,
+				"content":  gin.H{"image/svg+xml": gin.H{"schema": gin.H{"type": "string"}}},
+			}),
+		},
+		"/import/csv": gin.H{
+			"post": withParameters(withBody(operation("Convert a path,type,cardinality,description CSV into a definition, nesting elements by their dotted paths", gin.H{
+				"200": jsonResponse("Converted definition; with ?render=true the output of POST /render instead", schemaRef("ResourceDefinition")),
+				"400": badRequest,
+				"413": tooLarge,
+				"422": tooComplex,
+				"429": busy,
+			}), gin.H{
+				"required": true,
+				"content":  gin.H{"text/csv": gin.H{"schema": gin.H{"type": "string"}}},
+			}), append([]gin.H{
+				queryParameter("render", "\"true\" renders the converted definition with the render options instead of returning it", false),
+			}, renderParameters...)),
+		},
+		"/convert/structuredefinition": gin.H{
+			"post": withParameters(withBody(operation("Convert a definition into a FHIR R4 StructureDefinition with a differential: a profile of a concrete base type, or a logical model for abstract ones such as DomainResource", gin.H{
+				"200": gin.H{
+					"description": "StructureDefinition",
+					"content":     gin.H{"application/fhir+json": gin.H{"schema": gin.H{"type": "object"}}},
+				},
+				"400": badRequest,
+				"413": tooLarge,
+				"422": errorResponse("The name yields no valid id"),
+			}), resourceBody), []gin.H{
+				queryParameter("canonical", "Base of the canonical url, followed by /StructureDefinition/{id}; defaults to "+convert.DefaultCanonicalBase, false),
+				queryParameter("strict", "\"true\" rejects unknown fields, malformed cardinalities and invalid binding strengths", false),
+				lenientParameter,
+			}),
+		},
+		"/convert/yaml": gin.H{
+			"post": withParameters(withBody(operation("Convert a definition into YAML, keeping the key order", gin.H{
+				"200": gin.H{
+					"description": "YAML document",
+					"content":     gin.H{"application/yaml": gin.H{"schema": gin.H{"type": "string"}}},
+				},
+				"400": badRequest,
+				"413": tooLarge,
+			}), resourceBody), []gin.H{lenientParameter}),
+		},
+		"/convert/json": gin.H{
+			"post": withParameters(withBody(operation("Convert a YAML definition into indented JSON", gin.H{
+				"200": jsonResponse("JSON document", gin.H{"type": "object"}),
+				"400": badRequest,
+				"413": tooLarge,
+			}), resourceBody), []gin.H{lenientParameter}),
+		},
+		"/share": gin.H{
+			"post": withParameters(withBody(operation("Store a definition and return a short link", gin.H{
+				"200": jsonResponse("Short links for the stored definition", gin.H{
+					"type": "object",
+					"properties": gin.H{
+						"id":        gin.H{"type": "string"},
+						"url":       gin.H{"type": "string", "description": "Render link, /d/{id}"},
+						"editorUrl": gin.H{"type": "string", "description": "Editor link, /editor?id={id}"},
+					},
+				}),
+				"400": badRequest,
+				"413": tooLarge,
+				"503": shareDisabled,
+			}), resourceBody), []gin.H{lenientParameter}),
+		},
+		"/share/{id}": gin.H{
+			"get": withParameters(operation("View a shared definition as pretty-printed JSON", gin.H{
+				"200": jsonResponse("Original JSON document", schemaRef("ResourceDefinition")),
+				"404": notFound,
+				"503": shareDisabled,
+			}), []gin.H{idParameter}),
+		},
+		"/d/{id}": gin.H{
+			"get": withParameters(operation("Render a shared definition to SVG", gin.H{
+				"200": svgResponse,
+				"304": notModified,
+				"400": badRequest,
+				"404": notFound,
+				"422": tooComplex,
+				"429": busy,
+				"503": shareDisabled,
+			}), append([]gin.H{idParameter}, renderParameters...)),
+		},
+		"/snippets": gin.H{
+			"get": withParameters(operation("List saved snippets (without their resources)", gin.H{
+				"200": jsonResponse("Snippets ordered by name", gin.H{"type": "array", "items": schemaRef("Snippet")}),
+				"503": snippetsDisabled,
+			}), []gin.H{
+				queryParameter("tag", "Only list snippets carrying this tag", false),
+			}),
+			"post": withBody(operation("Save a named, tagged definition to the snippet library", gin.H{
+				"201": jsonResponse("Saved snippet", schemaRef("Snippet")),
+				"400": badRequest,
+				"413": tooLarge,
+				"503": snippetsDisabled,
+			}), snippetBody),
+		},
+		"/snippets/{id}": gin.H{
+			"get": withParameters(operation("Load a snippet including its definition", gin.H{
+				"200": jsonResponse("Snippet", schemaRef("Snippet")),
+				"404": snippetNotFound,
+				"503": snippetsDisabled,
+			}), []gin.H{snippetIDParameter}),
+			"put": withParameters(withBody(operation("Replace a snippet's name, tags and definition", gin.H{
+				"200": jsonResponse("Updated snippet", schemaRef("Snippet")),
+				"400": badRequest,
+				"404": snippetNotFound,
+				"413": tooLarge,
+				"503": snippetsDisabled,
+			}), snippetBody), []gin.H{snippetIDParameter}),
+			"delete": withParameters(operation("Delete a snippet", gin.H{
+				"204": gin.H{"description": "Snippet deleted"},
+				"404": snippetNotFound,
+				"503": snippetsDisabled,
+			}), []gin.H{snippetIDParameter}),
+		},
+		"/editor": gin.H{
+			"get": operation("Interactive editor page", gin.H{
+				"200": gin.H{"description": "HTML page", "content": gin.H{"text/html": gin.H{}}},
+			}),
+		},
+		"/openapi.json": gin.H{
+			"get": operation("This OpenAPI description", gin.H{
+				"200": jsonResponse("OpenAPI 3.0 document", gin.H{"type": "object"}),
+			}),
+		},
+		"/docs": gin.H{
+			"get": operation("Swagger UI for this API", gin.H{
+				"200": gin.H{"description": "HTML page", "content": gin.H{"text/html": gin.H{}}},
+			}),
+		},
+	}
+}
+
+// operation creates an OpenAPI operation object
+func operation(summary string, responses gin.H) gin.H {
+	return gin.H{"summary": summary, "responses": responses}
+}
+
+// withParameters attaches parameters to an operation
+func withParameters(op gin.H, params []gin.H) gin.H {
+	op["parameters"] = params
+	return op
+}
+
+// withBody attaches a request body to an operation
+func withBody(op gin.H, body gin.H) gin.H {
+	op["requestBody"] = body
+	return op
+}
+
+// queryParameter describes a string query parameter
+func queryParameter(name, description string, required bool) gin.H {
+	return gin.H{
+		"name":        name,
+		"in":          "query",
+		"required":    required,
+		"description": description,
+		"schema":      gin.H{"type": "string"},
+	}
+}
+
+// withEnum restricts a parameter to the given values
+func withEnum(param gin.H, values []string) gin.H {
+	param["schema"].(gin.H)["enum"] = values
+	return param
+}
+
+// jsonResponse describes a JSON response with the given schema
+func jsonResponse(description string, schema gin.H) gin.H {
+	return gin.H{
+		"description": description,
+		"content":     gin.H{"application/json": gin.H{"schema": schema}},
+	}
+}
+
+// errorResponse describes the standard JSON error body
+func errorResponse(description string) gin.H {
+	return jsonResponse(description, schemaRef("Error"))
+}
+
+// schemaRef returns a reference to a component schema
+func schemaRef(name string) gin.H {
+	return gin.H{"$ref": "#/components/schemas/" + name}
+}
+
+// generateSchema registers a component schema for a struct type (and the
+// struct types it references) derived from its JSON tags
+func generateSchema(t reflect.Type, schemas gin.H) gin.H {
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return gin.H{"type": "string", "format": "date-time"}
+	case reflect.TypeOf(json.RawMessage{}):
+		return gin.H{"type": "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return generateSchema(t.Elem(), schemas)
+	case reflect.Slice:
+		return gin.H{"type": "array", "items": generateSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": generateSchema(t.Elem(), schemas)}
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return gin.H{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.Struct:
+	default:
+		return gin.H{"type": "string"}
+	}
+
+	name := t.Name()
+	if _, ok := schemas[name]; ok {
+		return schemaRef(name)
+	}
+
+	properties := gin.H{}
+	var required []string
+	schema := gin.H{"type": "object", "properties": properties}
+	// Register before walking fields so recursive types resolve to a $ref
+	schemas[name] = schema
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		propName := parts[0]
+		if propName == "" {
+			propName = field.Name
+		}
+
+		prop := generateSchema(field.Type, schemas)
+		key := name + "." + propName
+		if desc, ok := schemaDescriptions[key]; ok {
+			// Siblings of $ref are ignored in OpenAPI 3.0, so wrap references
+			if _, isRef := prop["$ref"]; isRef {
+				prop = gin.H{"allOf": []gin.H{prop}}
+			}
+			prop["description"] = desc
+		}
+		if values, ok := schemaEnums[key]; ok {
+			prop["enum"] = values
+		}
+		properties[propName] = prop
+
+		omitEmpty := false
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitEmpty = true
+			}
+		}
+		if !omitEmpty {
+			required = append(required, propName)
+		}
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schemaRef(name)
+}
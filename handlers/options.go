@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/models"
+	"fhir_renderer/renderer"
+)
+
+// MaxCompositeSpacing caps the ?spacing= gap between composite sections
+const MaxCompositeSpacing = 200
+
+// linkDefaults are the server-wide documentation link templates
+// (TYPE_LINK_BASE and ELEMENT_LINK_BASE), overridable per request
+var linkDefaults struct {
+	typeBase    string
+	elementBase string
+}
+
+// SetLinkDefaults configures the default type and element link templates
+func SetLinkDefaults(typeBase, elementBase string) {
+	linkDefaults.typeBase = typeBase
+	linkDefaults.elementBase = elementBase
+}
+
+// ValidLinkBase reports whether a link template is an absolute http(s) URL
+func ValidLinkBase(base string) bool {
+	u, err := url.Parse(strings.NewReplacer("{name}", "x", "{lower}", "x", "{version}", "x").Replace(base))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// applyRenderOptions adjusts the render configuration from query parameters.
+// Invalid option values are answered with a 400 response and ok is false.
+func applyRenderOptions(c *gin.Context, config *renderer.SVGConfig) (ok bool) {
+	if err := parseRenderOptions(c, config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid render option", "details": err.Error()})
+		return false
+	}
+	return true
+}
+
+// parseRenderOptions adjusts the render configuration from query
+// parameters, failing on the first invalid option value
+func parseRenderOptions(c *gin.Context, config *renderer.SVGConfig) error {
+	config.TypeLinkBase = linkDefaults.typeBase
+	if base := c.Query("typeLinkBase"); base != "" {
+		if !ValidLinkBase(base) {
+			return fmt.Errorf("typeLinkBase %q must be an http(s) URL", base)
+		}
+		config.TypeLinkBase = base
+	}
+	config.ElementLinkBase = linkDefaults.elementBase
+	if base := c.Query("elementLinkBase"); base != "" {
+		if !ValidLinkBase(base) {
+			return fmt.Errorf("elementLinkBase %q must be an http(s) URL", base)
+		}
+		config.ElementLinkBase = base
+	}
+	config.FHIRLinks = c.Query("fhirLinks") != "false"
+
+	if title := c.Query("title"); title != "" {
+		if _, err := renderer.ParseTitleTemplate(title); err != nil {
+			return fmt.Errorf("title: %w", err)
+		}
+		config.TitleTemplate = title
+	}
+	switch c.Query("pretty") {
+	case "true":
+		config.Whitespace = renderer.WhitespacePretty
+	case "false":
+		config.Whitespace = renderer.WhitespaceMinified
+	}
+	switch watermark := c.Query("watermark"); watermark {
+	case "":
+	case "none":
+		config.Watermark = ""
+	default:
+		config.Watermark = renderer.NormalizeWatermark(watermark)
+	}
+	if tag := c.Query("lang"); tag != "" {
+		lang, ok := renderer.ParseLang(tag)
+		if !ok {
+			return fmt.Errorf("lang %q is not a supported language", tag)
+		}
+		config.Lang = lang
+	}
+	config.HighlightMustSupport = c.Query("highlightMS") == "true"
+	config.HoverRows = c.Query("hover") == "true"
+	if theme := c.Query("theme"); theme != "" && !config.UseTheme(theme) {
+		return fmt.Errorf("theme %q is not one of %s", theme, strings.Join(renderer.ThemeNames(), ", "))
+	}
+	switch media := c.Query("media"); media {
+	case "", renderer.MediaScreen, renderer.MediaPrint:
+		config.UseMedia(media)
+	default:
+		return fmt.Errorf("media %q is not %s or %s", media, renderer.MediaScreen, renderer.MediaPrint)
+	}
+	config.ShowLegend = c.Query("legend") == "true"
+	config.RowNumbers = c.Query("rowNumbers") == "true"
+	config.GroupHeaders = c.Query("groupHeaders") == "true"
+	config.RollupUsage = c.Query("rollupUsage") == "true"
+	switch view := c.Query("view"); view {
+	case "", ViewFull, ViewSummary, ViewCoverage:
+		config.Coverage = view == ViewCoverage
+	default:
+		return fmt.Errorf("view %q is not one of %s, %s or %s", view, ViewFull, ViewSummary, ViewCoverage)
+	}
+	config.Deterministic = c.Query("deterministic") == "true"
+	config.EmbedSource = c.Query("embedSource") == "true"
+	if c.Query("metadata") == "true" {
+		config.ShowMetadataFooter = true
+		config.GeneratedAt = time.Now()
+	}
+	if font := requestFont(c); font != nil {
+		config.UseFont(font)
+		config.EmbedFont = c.Query("embedFont") == "true"
+	}
+	if value := c.Query("spacing"); value != "" {
+		spacing, err := strconv.ParseFloat(value, 64)
+		if err != nil || spacing < 0 || spacing > MaxCompositeSpacing {
+			return fmt.Errorf("spacing %q must be a number from 0 to %d", value, MaxCompositeSpacing)
+		}
+		config.CompositeSpacing = spacing
+	}
+	if value := c.Query("maxRowsPerPage"); value != "" {
+		perPage, err := strconv.Atoi(value)
+		if err != nil || perPage < renderer.MinRowsPerPage {
+			return fmt.Errorf("maxRowsPerPage %q must be a whole number of at least %d", value, renderer.MinRowsPerPage)
+		}
+		config.MaxRowsPerPage = perPage
+	}
+	// Applied by trimResource
+	if value := c.Query("maxDepth"); value != "" {
+		if maxDepth, err := strconv.Atoi(value); err != nil || maxDepth < 1 {
+			return fmt.Errorf("maxDepth %q must be a whole number of at least 1", value)
+		}
+	}
+	if columns := c.Query("columns"); columns != "" {
+		parsed, err := renderer.ParseColumns(strings.Split(columns, ","))
+		if err != nil {
+			return fmt.Errorf("columns: %w", err)
+		}
+		config.Columns = parsed
+	}
+	if c.Query("extraColumns") != "" {
+		parsed, err := renderer.ParseExtraColumns(queryList(c, "extraColumns"))
+		if err != nil {
+			return fmt.Errorf("extraColumns: %w", err)
+		}
+		config.ExtraColumns = parsed
+	}
+	if value := c.Query("width"); value != "" {
+		width, err := strconv.ParseFloat(value, 64)
+		if err != nil || width <= 0 {
+			return fmt.Errorf("width %q must be a positive number", value)
+		}
+		config.MaxTotalWidth = width
+	}
+	if c.Query("responsive") == "true" {
+		config.Responsive = true
+		if value := c.Query("minFontSize"); value != "" {
+			size, err := strconv.ParseFloat(value, 64)
+			if err != nil || size <= 0 {
+				return fmt.Errorf("minFontSize %q must be a positive number", value)
+			}
+			config.MinFontSize = size
+		}
+		if value := c.Query("maxFontSize"); value != "" {
+			size, err := strconv.ParseFloat(value, 64)
+			if err != nil || size < config.MinFontSize {
+				return fmt.Errorf("maxFontSize %q must be a number of at least minFontSize (%g)", value, config.MinFontSize)
+			}
+			config.MaxFontSize = size
+		}
+	}
+	return nil
+}
+
+// Views selectable via the ?view= query parameter
+const (
+	ViewFull    = "full"
+	ViewSummary = "summary"
+
+	// ViewCoverage keeps every element and colors the rows by usage, with
+	// an implementation summary below them
+	ViewCoverage = "coverage"
+)
+
+// trimResource applies the summary view, element filter and ?maxDepth=
+// truncation requested by the query parameters, which applyRenderOptions
+// has already checked
+func trimResource(c *gin.Context, resource *models.ResourceDefinition) *models.ResourceDefinition {
+	if c.Query("view") == ViewSummary {
+		resource = resource.Filter(models.SummaryFilter())
+	}
+	if filter := elementFilter(c); !filter.IsEmpty() {
+		resource = resource.Filter(filter)
+	}
+	if maxDepth, err := strconv.Atoi(c.Query("maxDepth")); err == nil {
+		resource = resource.Truncate(maxDepth)
+	}
+	return resource
+}
+
+// elementFilter builds the element filter from the include, excludeUsage and
+// onlyFlags query parameters (each a comma separated list)
+func elementFilter(c *gin.Context) models.ElementFilter {
+	return models.ElementFilter{
+		Include:       queryList(c, "include"),
+		ExcludeUsages: queryList(c, "excludeUsage"),
+		OnlyFlags:     queryList(c, "onlyFlags"),
+	}
+}
+
+// queryList splits a comma separated query parameter, dropping empty entries
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"fhir_renderer/convert"
+	"fhir_renderer/middleware"
+	"fhir_renderer/models"
+	"fhir_renderer/renderer"
+)
+
+// Package render outputs
+const (
+	PackageOutputZip   = "zip"
+	PackageOutputIndex = "index"
+)
+
+// packageEntry describes one StructureDefinition of a rendered package
+type packageEntry struct {
+	File      string `json:"file"`
+	Name      string `json:"name,omitempty"`
+	SVG       string `json:"svg,omitempty"`       // File name within the ZIP
+	URL       string `json:"url,omitempty"`       // Share link of the rendered diagram
+	EditorURL string `json:"editorUrl,omitempty"` // Share link opening the editor
+	Error     string `json:"error,omitempty"`     // Why the definition was skipped
+}
+
+// packageIndex is the JSON index returned for ?output=index and included in
+// the ZIP as index.json
+type packageIndex struct {
+	Package   string         `json:"package,omitempty"`
+	Version   string         `json:"version,omitempty"`
+	Resources []packageEntry `json:"resources"`
+}
+
+// unsafeFileChars matches characters not kept in generated file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// RenderPackageHandler renders every StructureDefinition of an NPM-style
+// FHIR package
+// POST /render/package?output=zip|index with a .tgz body
+func RenderPackageHandler(c *gin.Context) {
+	output := c.DefaultQuery("output", PackageOutputZip)
+	if output != PackageOutputZip && output != PackageOutputIndex {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unsupported output",
+			"details": fmt.Sprintf("output %q is not one of %s, %s", output, PackageOutputZip, PackageOutputIndex),
+		})
+		return
+	}
+	if output == PackageOutputIndex && !requireShareStore(c) {
+		return
+	}
+
+	pkg, err := convert.ReadPackage(http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxPackageBytes), limits.MaxPackageBytes)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, convert.ErrPackageTooLarge) {
+		middleware.RespondBodyTooLarge(c, limits.MaxPackageBytes)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid package", "details": err.Error()})
+		return
+	}
+	if len(pkg.Definitions) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Package contains no StructureDefinitions"})
+		return
+	}
+
+	config := renderer.DefaultConfig()
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+
+	// The whole package renders in one slot
+	release, ok := acquireRenderSlot(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Profiles in the package may build on each other
+	documents := make([][]byte, len(pkg.Definitions))
+	for i, file := range pkg.Definitions {
+		documents[i] = file.Data
+	}
+	registry := convert.Registries(convert.NewMapRegistry(documents...), convert.BaseRegistry())
+
+	reportPackageProgress(c, nil, len(pkg.Definitions))
+	index := packageIndex{Package: pkg.Name, Version: pkg.Version}
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	seen := make(map[string]int)
+
+	for _, file := range pkg.Definitions {
+		if err := c.Request.Context().Err(); err != nil {
+			return
+		}
+		entry := packageEntry{File: file.Name}
+		resource, svg, err := renderPackageFile(c, file, registry, config)
+		if resource != nil {
+			entry.Name = resource.Name
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			index.Resources = append(index.Resources, entry)
+			reportPackageProgress(c, &entry, len(pkg.Definitions))
+			continue
+		}
+
+		if output == PackageOutputIndex {
+			data, err := json.Marshal(resource)
+			if err == nil {
+				var id string
+				if id, err = shareStore.Put(c.Request.Context(), data); err == nil {
+					entry.URL = "/d/" + id
+					entry.EditorURL = "/editor?id=" + id
+				}
+			}
+			if err != nil {
+				entry.Error = "storing share link: " + err.Error()
+			}
+		} else {
+			entry.SVG = packageFileName(resource.Name, seen) + ".svg"
+			if err := writeZipFile(zw, entry.SVG, []byte(svg)); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build ZIP", "details": err.Error()})
+				return
+			}
+		}
+		index.Resources = append(index.Resources, entry)
+		reportPackageProgress(c, &entry, len(pkg.Definitions))
+	}
+
+	if output == PackageOutputIndex {
+		c.JSON(http.StatusOK, index)
+		return
+	}
+
+	indexJSON, err := json.MarshalIndent(index, "", "  ")
+	if err == nil {
+		err = writeZipFile(zw, "index.json", indexJSON)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build ZIP", "details": err.Error()})
+		return
+	}
+
+	archiveName := "diagrams.zip"
+	if pkg.Name != "" {
+		archiveName = packageFileName(strings.Trim(pkg.Name+"-"+pkg.Version, "-"), nil) + "-diagrams.zip"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, archiveName))
+	c.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// renderPackageFile converts and renders one StructureDefinition, resolving
+// bases through registry, with the request's view options and the usual
+// limits. The converted resource is
+// returned whenever conversion succeeded.
+func renderPackageFile(c *gin.Context, file convert.PackageFile, registry convert.Registry, config renderer.SVGConfig) (*models.ResourceDefinition, string, error) {
+	ctx, span := tracer.Start(c.Request.Context(), "convert StructureDefinition", trace.WithAttributes(attribute.String("package.file", file.Name)))
+	resource, err := convert.StructureDefinitionWithRegistry(ctx, file.Data, registry)
+	span.End()
+	if err != nil {
+		return nil, "", err
+	}
+	applyFHIRVersion(c, resource)
+	if err := validateResource(resource); err != nil {
+		return resource, "", err
+	}
+
+	trimmed := trimResource(c, resource)
+	if err := checkComplexity(trimmed.Flatten()); err != nil {
+		return resource, "", err
+	}
+	expandBindings(c, trimmed)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), limits.RenderTimeout)
+	defer cancel()
+	svg, err := renderer.RenderContext(ctx, trimmed, config)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("rendering took longer than %s", limits.RenderTimeout)
+	}
+	return resource, svg, err
+}
+
+// writeZipFile adds a file to the archive
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// packageFileName turns name into a safe file name. Names already in seen get
+// a numeric suffix; seen may be nil.
+func packageFileName(name string, seen map[string]int) string {
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "._")
+	if name == "" {
+		name = "resource"
+	}
+	if seen == nil {
+		return name
+	}
+	if n := seen[name]; n > 0 {
+		seen[name] = n + 1
+		name = fmt.Sprintf("%s-%d", name, n+1)
+	}
+	seen[name]++
+	return name
+}
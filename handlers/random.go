@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/models"
+)
+
+// Defaults of GET /example/random
+const (
+	defaultRandomElements = 50
+	defaultRandomDepth    = 4
+)
+
+// Vocabulary of the generated definitions
+var (
+	randomNames = []string{
+		"identifier", "status", "category", "code", "subject", "encounter", "period", "issued",
+		"performer", "value", "note", "method", "device", "component", "reason", "priority",
+		"author", "target", "detail", "amount", "quantity", "dosage", "schedule", "location",
+		"contact", "telecom", "address", "comment", "outcome", "basedOn", "partOf", "supportingInfo",
+	}
+	randomTypes = []string{
+		"string", "boolean", "code", "dateTime", "integer", "decimal", "uri", "markdown",
+		"CodeableConcept", "Identifier", "Quantity", "Period", "Coding", "Annotation", "Reference",
+	}
+	randomTargets     = []string{"Patient", "Practitioner", "Organization", "Encounter", "Device", "Location"}
+	randomCardinality = []string{"0..1", "0..1", "1..1", "0..*", "1..*"}
+	randomUsages      = []string{"used", "used", "optional", "not-used", "todo"}
+	randomStrengths   = []string{"required", "extensible", "preferred", "example"}
+	randomWords       = []string{
+		"the", "clinical", "record", "of", "a", "measurement", "taken", "for", "patient", "when",
+		"available", "and", "reported", "by", "device", "or", "practitioner", "with", "its", "coded",
+		"value", "according", "to", "local", "policy", "identifier", "assigned", "during", "admission",
+	}
+)
+
+// RandomExampleHandler generates a synthetic definition of the requested
+// size for demos, load tests and layout stress tests. ?elements= sets the
+// number of elements (default 50), ?depth= how deep they nest (default 4)
+// and ?seed= makes the output reproducible; the seed used is returned in
+// X-Random-Seed.
+// GET /example/random?elements=50&depth=4&seed=42
+func RandomExampleHandler(c *gin.Context) {
+	// The root takes a row, so the result stays within the render limits
+	maxElements := limits.MaxElements - 1
+	elements, ok := randomParameter(c, "elements", defaultRandomElements, maxElements)
+	if !ok {
+		return
+	}
+	depth, ok := randomParameter(c, "depth", defaultRandomDepth, limits.MaxDepth)
+	if !ok {
+		return
+	}
+	seed := rand.Uint64() >> 11 // Exact as a JavaScript number
+	if value := c.Query("seed"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid seed", "details": "seed must be a non-negative integer"})
+			return
+		}
+		seed = parsed
+	}
+
+	c.Header("X-Random-Seed", strconv.FormatUint(seed, 10))
+	c.JSON(http.StatusOK, randomDefinition(rand.New(rand.NewPCG(seed, seed)), elements, depth, seed))
+}
+
+// randomParameter reads a positive integer query parameter, answering 400
+// when it is malformed or above max
+func randomParameter(c *gin.Context, name string, fallback, max int) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return min(fallback, max), true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > max {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid " + name,
+			"details": fmt.Sprintf("%s must be a whole number from 1 to %d", name, max),
+		})
+		return 0, false
+	}
+	return n, true
+}
+
+// randomNode is a generated element whose children are still being added
+type randomNode struct {
+	element  models.Element
+	depth    int
+	children []*randomNode
+}
+
+// randomDefinition generates a definition with count elements nested up
+// to depth levels. The first elements form a chain reaching that depth;
+// the others go below the root or a random BackboneElement above it.
+func randomDefinition(rng *rand.Rand, count, depth int, seed uint64) *models.ResourceDefinition {
+	depth = min(depth, count)
+	root := &randomNode{}
+	parents := []*randomNode{root}
+	for i := range count {
+		parent := parents[rng.IntN(len(parents))]
+		if i < depth {
+			parent = parents[len(parents)-1] // Extend the chain
+		}
+		node := &randomNode{depth: parent.depth + 1}
+		// Chain links and some others can take children
+		backbone := node.depth < depth && (i < depth-1 || rng.IntN(4) == 0)
+		node.element = randomElement(rng, parent, backbone)
+		parent.children = append(parent.children, node)
+		if backbone {
+			parents = append(parents, node)
+		}
+	}
+
+	return &models.ResourceDefinition{
+		ResourceType: "ResourceDefinition",
+		Name:         "SyntheticResource",
+		Status:       models.StatusDraft,
+		Type:         "DomainResource",
+		Description:  fmt.Sprintf("Synthetic definition generated with elements=%d, depth=%d and seed=%d", count, depth, seed),
+		Elements:     randomElements(root),
+	}
+}
+
+// randomElement generates an element below parent with a name its
+// siblings do not use
+func randomElement(rng *rand.Rand, parent *randomNode, backbone bool) models.Element {
+	name := randomPick(rng, randomNames)
+	for _, sibling := range parent.children {
+		if sibling.element.Name == name {
+			name += strconv.Itoa(len(parent.children) + 1)
+			break
+		}
+	}
+
+	element := models.Element{
+		Name:        name,
+		Cardinality: randomPick(rng, randomCardinality),
+		Type:        randomPick(rng, randomTypes),
+		Description: randomSentence(rng, 2+rng.IntN(14)),
+		Usage:       randomPick(rng, randomUsages),
+	}
+	switch {
+	case backbone:
+		element.Type = "BackboneElement"
+	case rng.IntN(8) == 0:
+		element.Name += "[x]"
+		element.Type = randomPick(rng, randomTypes) + " | " + randomPick(rng, randomTypes)
+	}
+	if rng.IntN(3) == 0 {
+		element.Flags = append(element.Flags, models.FlagSummary)
+	}
+	if rng.IntN(3) == 0 {
+		element.Flags = append(element.Flags, models.FlagMustSupport)
+	}
+	if rng.IntN(10) == 0 {
+		element.Flags = append(element.Flags, models.FlagModifier)
+	}
+
+	switch element.Type {
+	case "code", "CodeableConcept", "Coding":
+		if rng.IntN(2) == 0 {
+			element.Binding = &models.Binding{
+				Strength: randomPick(rng, randomStrengths),
+				ValueSet: "http://example.org/fhir/ValueSet/" + strings.ToLower(name),
+			}
+		}
+	case "Reference":
+		for _, i := range rng.Perm(len(randomTargets))[:1+rng.IntN(3)] {
+			element.Targets = append(element.Targets, models.Target{Type: randomTargets[i]})
+		}
+	}
+	if rng.IntN(5) == 0 {
+		element.Notes = randomSentence(rng, 3+rng.IntN(10))
+	}
+	return element
+}
+
+// randomElements converts the children of node into elements
+func randomElements(node *randomNode) []models.Element {
+	if len(node.children) == 0 {
+		return nil
+	}
+	elements := make([]models.Element, len(node.children))
+	for i, child := range node.children {
+		elements[i] = child.element
+		elements[i].Elements = randomElements(child)
+	}
+	return elements
+}
+
+// randomSentence returns a capitalized sentence of random words
+func randomSentence(rng *rand.Rand, words int) string {
+	parts := make([]string, words)
+	for i := range parts {
+		parts[i] = randomPick(rng, randomWords)
+	}
+	sentence := strings.Join(parts, " ")
+	return strings.ToUpper(sentence[:1]) + sentence[1:]
+}
+
+func randomPick(rng *rand.Rand, values []string) string {
+	return values[rng.IntN(len(values))]
+}
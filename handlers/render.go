@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
@@ -12,16 +13,22 @@ import (
 
 	"github.com/andybalholm/brotli"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"fhir_renderer/convert"
+	"fhir_renderer/middleware"
 	"fhir_renderer/models"
 	"fhir_renderer/renderer"
+	"fhir_renderer/validation"
 )
 
 //go:embed example.json
 var exampleJSON []byte
 
-//go:embed help.md
-var helpMarkdown string
+// tracer records handler spans; the request span comes from middleware.Tracing
+var tracer = otel.Tracer("fhir_renderer/handlers")
 
 // SVGCacheTTLSeconds is the cache duration for rendered SVGs
 const SVGCacheTTLSeconds = 3600
@@ -37,6 +44,87 @@ func validateResource(resource *models.ResourceDefinition) error {
 	return nil
 }
 
+// decodeResource unmarshals resource JSON. FHIR resources with a converter
+// (such as Questionnaire) are converted first, and elements given as flat
+// paths are nested. In strict mode unknown fields are rejected instead of
+// being silently ignored. JSON errors are returned as
+// *validation.DecodeError, located in data.
+func decodeResource(ctx context.Context, data []byte, strict bool, resource *models.ResourceDefinition) error {
+	if converted, ok, err := convert.FromFHIR(ctx, data); ok {
+		if err != nil {
+			return err
+		}
+		*resource = *converted
+		return nil
+	}
+
+	_, span := tracer.Start(ctx, "parse JSON", trace.WithAttributes(attribute.Int("body.bytes", len(data))))
+	defer span.End()
+
+	if !strict {
+		if err := json.Unmarshal(data, resource); err != nil {
+			return validation.DescribeDecodeError(data, err)
+		}
+		return resource.NestPaths()
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(resource); err != nil {
+		return validation.DescribeDecodeError(data, err)
+	}
+	return resource.NestPaths()
+}
+
+// invalidJSON returns the 400 response body for a definition that failed
+// to decode. Errors located in the source add a diagnostic with their
+// line, column and JSON path.
+func invalidJSON(message string, err error) gin.H {
+	body := gin.H{"error": message, "details": err.Error()}
+	var decodeErr *validation.DecodeError
+	if errors.As(err, &decodeErr) {
+		body["diagnostics"] = []validation.Diagnostic{decodeErr.Diagnostic}
+	}
+	return body
+}
+
+// applyFHIRVersion sets the definition's FHIR version from ?fhirVersion=
+// ("STU3", "R4", "R5" or a version number), which takes precedence over
+// its fhirVersion field. Invalid values are ignored.
+func applyFHIRVersion(c *gin.Context, resource *models.ResourceDefinition) {
+	if version, ok := models.ParseFHIRVersion(c.Query("fhirVersion")); ok {
+		resource.FHIRVersion = version
+	}
+}
+
+// checkResource applies ?fhirVersion=, validates required fields and, in
+// strict mode, rejects malformed cardinalities and binding strengths. It
+// writes the error response and returns false if the resource must not be
+// rendered.
+func checkResource(c *gin.Context, resource *models.ResourceDefinition, strict bool) bool {
+	applyFHIRVersion(c, resource)
+	if err := validateResource(resource); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if !strict {
+		return true
+	}
+
+	if diagnostics := validation.StrictViolations(resource); len(diagnostics) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":       "Strict validation failed",
+			"diagnostics": diagnostics,
+		})
+		return false
+	}
+	return true
+}
+
+// isStrict reports whether the request asked for strict validation
+func isStrict(c *gin.Context) bool {
+	return c.Query("strict") == "true"
+}
+
 // compressBrotliBase64URL compresses JSON bytes to Brotli and encodes as Base64URL
 func compressBrotliBase64URL(jsonBytes []byte) (string, error) {
 	var buf bytes.Buffer
@@ -50,25 +138,169 @@ func compressBrotliBase64URL(jsonBytes []byte) (string, error) {
 	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
 }
 
+// errResourceTooLarge is returned when a decompressed resource exceeds the body limit
+var errResourceTooLarge = errors.New("decompressed resource exceeds size limit")
+
 // decompressBrotliBase64URL decodes Base64URL and decompresses Brotli
-func decompressBrotliBase64URL(encoded string) ([]byte, error) {
+func decompressBrotliBase64URL(ctx context.Context, encoded string) ([]byte, error) {
+	_, span := tracer.Start(ctx, "decompress", trace.WithAttributes(attribute.Int("encoded.bytes", len(encoded))))
+	defer span.End()
+
 	compressed, err := base64.RawURLEncoding.DecodeString(encoded)
 	if err != nil {
 		return nil, err
 	}
 	r := brotli.NewReader(bytes.NewReader(compressed))
-	return io.ReadAll(r)
+	// Guard against decompression bombs by reading at most one byte past the limit
+	data, err := io.ReadAll(io.LimitReader(r, limits.MaxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limits.MaxBodyBytes {
+		return nil, errResourceTooLarge
+	}
+	return data, nil
 }
 
-// renderAndRespond renders the resource to SVG and writes the response
-func renderAndRespond(c *gin.Context, resource *models.ResourceDefinition, compressedResource string) {
+// renderAndRespond renders the resource to SVG and writes the response.
+// compressedResource or shareID (when stored via /share) are used for the
+// footer's edit and source links.
+func renderAndRespond(c *gin.Context, resource *models.ResourceDefinition, compressedResource, shareID string) {
+	// An example uploaded as a multipart part overrides the embedded one
+	if example, ok := c.Get(exampleContextKey); ok {
+		resource.Example = example.(json.RawMessage)
+	}
 	config := renderer.DefaultConfig()
 	config.CompressedResource = compressedResource
-	svg := renderer.Render(resource, config)
+	config.ShareID = shareID
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+	resource = trimResource(c, resource)
 
-	c.Header("Content-Type", "image/svg+xml")
-	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", SVGCacheTTLSeconds))
-	c.String(http.StatusOK, svg)
+	if err := checkComplexity(resource.Flatten()); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Resource exceeds render limits",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", FormatSVG)
+	if _, ok := formatContentTypes[format]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unsupported format",
+			"details": fmt.Sprintf("format %q is not one of %s", format, supportedFormats()),
+		})
+		return
+	}
+
+	if format == FormatZip {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-pages.zip"`, packageFileName(resource.Name, nil)))
+	}
+	if format == FormatCSV || format == FormatXLSX {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, packageFileName(resource.Name, nil), format))
+	}
+
+	prepare := func() { expandBindings(c, resource) }
+	respondPrepared(c, bindingsCacheKey(c, resource), config, format, prepare, func(ctx context.Context, w io.Writer) error {
+		return renderFormat(ctx, w, format, resource, config)
+	})
+}
+
+// respondRendered answers conditional requests from the ETag of cacheKey and
+// otherwise runs render under the render timeout, streaming its output to the
+// client
+func respondRendered(c *gin.Context, cacheKey any, config renderer.SVGConfig, format string, render func(ctx context.Context, w io.Writer) error) {
+	respondPrepared(c, cacheKey, config, format, nil, render)
+}
+
+// respondPrepared is respondRendered with a prepare step, such as expanding
+// bindings, that runs once the request holds a render slot and before the
+// render timeout starts. prepare may be nil.
+func respondPrepared(c *gin.Context, cacheKey any, config renderer.SVGConfig, format string, prepare func(), render func(ctx context.Context, w io.Writer) error) {
+	// Answer conditional requests without rendering when the client is up to date
+	etag, err := computeETag(cacheKey, config, format)
+	if err == nil {
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", SVGCacheTTLSeconds))
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	release, ok := acquireRenderSlot(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	if prepare != nil {
+		prepare()
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), limits.RenderTimeout)
+	defer cancel()
+	out := &renderedWriter{c: c, format: format}
+	err = render(ctx, out)
+	if err != nil && out.started {
+		// The status is already sent; the client gets a truncated body
+		_ = c.Error(err)
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Resource exceeds render limits",
+			"details": fmt.Sprintf("rendering took longer than %s", limits.RenderTimeout),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Render failed", "details": err.Error()})
+		return
+	}
+
+	out.start()
+	c.Writer.WriteHeaderNow()
+}
+
+// renderedWriter sends the success headers on the first write, so errors
+// raised before any output can still be answered with a JSON error
+type renderedWriter struct {
+	c       *gin.Context
+	format  string
+	started bool
+}
+
+func (w *renderedWriter) Write(data []byte) (int, error) {
+	w.start()
+	return w.c.Writer.Write(data)
+}
+
+// start sets the content type, cache headers and 200 status once
+func (w *renderedWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	contentType := formatContentTypes[w.format]
+	if w.format == formatCard {
+		contentType = "image/png"
+	}
+	w.c.Header("Content-Type", contentType)
+	w.c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", SVGCacheTTLSeconds))
+	w.c.Status(http.StatusOK)
+}
+
+// respondTooLarge writes a 413 response for a decompressed resource, such as
+// a ?resource= parameter, over the body size limit. Request bodies over the
+// limit are answered with middleware.RespondBodyTooLarge.
+func respondTooLarge(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":    "Resource too large",
+		"details":  fmt.Sprintf("maximum size is %d bytes", limits.MaxBodyBytes),
+		"maxBytes": limits.MaxBodyBytes,
+	})
 }
 
 // RenderHandler handles the /render endpoint
@@ -83,7 +315,11 @@ func RenderHandler(c *gin.Context) {
 		return
 	}
 
-	decodedJSON, err := decompressBrotliBase64URL(resourceParam)
+	decodedJSON, err := decompressBrotliBase64URL(c.Request.Context(), resourceParam)
+	if errors.Is(err, errResourceTooLarge) {
+		respondTooLarge(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid encoding (expected Brotli + Base64URL)",
@@ -92,43 +328,174 @@ func RenderHandler(c *gin.Context) {
 		return
 	}
 
+	if convert.IsCapabilityStatement(decodedJSON) {
+		renderCapabilityAndRespond(c, decodedJSON, resourceParam)
+		return
+	}
+	if convert.IsTerminology(decodedJSON) {
+		renderTerminologyAndRespond(c, decodedJSON, resourceParam)
+		return
+	}
+
+	strict := isStrict(c)
 	var resource models.ResourceDefinition
-	if err := json.Unmarshal(decodedJSON, &resource); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid JSON",
-			"details": err.Error(),
-		})
+	if err := decodeResource(c.Request.Context(), decodedJSON, strict, &resource); err != nil {
+		c.JSON(http.StatusBadRequest, invalidJSON("Invalid JSON", err))
 		return
 	}
 
-	if err := validateResource(&resource); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !checkResource(c, &resource, strict) {
 		return
 	}
 
-	renderAndRespond(c, &resource, resourceParam)
+	renderAndRespond(c, &resource, resourceParam, "")
+}
+
+// readBody reads a JSON, YAML, FHIR XML, FHIR Shorthand or multipart request body
+// and returns its JSON form.
+// On failure an error response has already been written and ok is false.
+func readBody(c *gin.Context) (body []byte, ok bool) {
+	return readBodyAs(c, false)
+}
+
+// readBodyAs is readBody; with yamlByDefault, bodies that are neither FHIR
+// XML nor FSH are read as YAML whatever their content type. YAML is a
+// superset of JSON, so JSON bodies still work.
+func readBodyAs(c *gin.Context, yamlByDefault bool) (body []byte, ok bool) {
+	if isMultipartContentType(c.GetHeader("Content-Type")) {
+		return readMultipartBody(c)
+	}
+
+	body, ok = readRawBody(c)
+	if !ok {
+		return nil, false
+	}
+
+	var err error
+	contentType := c.GetHeader("Content-Type")
+	isXML := isXMLContentType(contentType) || (yamlByDefault && bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")))
+	isYAML := isYAMLContentType(contentType) ||
+		(yamlByDefault && !isXML && !isFSHContentType(contentType) && !convert.IsFSH(body))
+
+	// Convert FHIR XML to JSON so the rest of the pipeline is format agnostic
+	if isXML {
+		body, err = fhirXMLToJSON(body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid XML body",
+				"details": err.Error(),
+			})
+			return nil, false
+		}
+	}
+
+	// YAML is converted too, so definitions can be authored by hand
+	if isYAML {
+		body, err = yamlToJSON(body)
+		if errors.Is(err, errResourceTooLarge) {
+			middleware.RespondBodyTooLarge(c, limits.MaxBodyBytes)
+			return nil, false
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid YAML body",
+				"details": err.Error(),
+			})
+			return nil, false
+		}
+	}
+
+	// ?lenient=true forgives the comments, trailing commas and unquoted keys
+	// of hand-edited JSON
+	if isLenient(c) && !convert.IsFSH(body) {
+		body, err = relaxedJSONToJSON(body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid JSON body",
+				"details": err.Error(),
+			})
+			return nil, false
+		}
+	}
+
+	// FHIR Shorthand is converted to JSON too, so edit links open the result
+	if isFSHContentType(contentType) || convert.IsFSH(body) {
+		body, err = fshToJSON(c.Request.Context(), body, "")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid FSH body",
+				"details": err.Error(),
+			})
+			return nil, false
+		}
+	}
+
+	return body, true
+}
+
+// readRawBody reads the request body up to the body size limit.
+// On failure an error response has already been written and ok is false.
+func readRawBody(c *gin.Context) (body []byte, ok bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			middleware.RespondBodyTooLarge(c, limits.MaxBodyBytes)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		}
+		return nil, false
+	}
+	return body, true
+}
+
+// readResourceBody reads a JSON or FHIR XML request body and decodes it.
+// It returns the JSON form of the body; on failure an error response has
+// already been written and ok is false.
+func readResourceBody(c *gin.Context) (body []byte, resource models.ResourceDefinition, ok bool) {
+	body, ok = readBody(c)
+	if !ok {
+		return nil, resource, false
+	}
+
+	if err := decodeResource(c.Request.Context(), body, isStrict(c), &resource); err != nil {
+		c.JSON(http.StatusBadRequest, invalidJSON("Invalid JSON body", err))
+		return nil, resource, false
+	}
+
+	return body, resource, true
 }
 
 // RenderPOSTHandler handles POST requests with JSON body
-// POST /render with JSON body
+// POST /render with JSON body (a single definition or an array of them)
 func RenderPOSTHandler(c *gin.Context) {
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+	body, ok := readBody(c)
+	if !ok {
+		return
+	}
+
+	if isJSONArray(body) {
+		renderCompositeAndRespond(c, body)
+		return
+	}
+	if convert.IsCapabilityStatement(body) {
+		compressedResource, _ := compressBrotliBase64URL(body)
+		renderCapabilityAndRespond(c, body, compressedResource)
+		return
+	}
+	if convert.IsTerminology(body) {
+		compressedResource, _ := compressBrotliBase64URL(body)
+		renderTerminologyAndRespond(c, body, compressedResource)
 		return
 	}
 
 	var resource models.ResourceDefinition
-	if err := json.Unmarshal(body, &resource); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid JSON body",
-			"details": err.Error(),
-		})
+	if err := decodeResource(c.Request.Context(), body, isStrict(c), &resource); err != nil {
+		c.JSON(http.StatusBadRequest, invalidJSON("Invalid JSON body", err))
 		return
 	}
 
-	if err := validateResource(&resource); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !checkResource(c, &resource, isStrict(c)) {
 		return
 	}
 
@@ -136,11 +503,11 @@ func RenderPOSTHandler(c *gin.Context) {
 	compressedResource, err := compressBrotliBase64URL(body)
 	if err != nil {
 		// If compression fails, render without the edit link
-		renderAndRespond(c, &resource, "")
+		renderAndRespond(c, &resource, "", "")
 		return
 	}
 
-	renderAndRespond(c, &resource, compressedResource)
+	renderAndRespond(c, &resource, compressedResource, "")
 }
 
 // HealthHandler returns health status
@@ -160,9 +527,14 @@ func ExampleHandler(c *gin.Context) {
 // CompressHandler compresses JSON to Brotli+Base64URL
 // POST /compress with JSON body → returns {"compressed": "..."}
 func CompressHandler(c *gin.Context) {
-	body, err := io.ReadAll(c.Request.Body)
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodyBytes))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			middleware.RespondBodyTooLarge(c, limits.MaxBodyBytes)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		}
 		return
 	}
 
@@ -186,12 +558,22 @@ func DecompressHandler(c *gin.Context) {
 	var req struct {
 		Data string `json:"data"`
 	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodyBytes)
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			middleware.RespondBodyTooLarge(c, limits.MaxBodyBytes)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		}
 		return
 	}
 
-	decompressed, err := decompressBrotliBase64URL(req.Data)
+	decompressed, err := decompressBrotliBase64URL(c.Request.Context(), req.Data)
+	if errors.Is(err, errResourceTooLarge) {
+		respondTooLarge(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Decompression failed", "details": err.Error()})
 		return
@@ -201,8 +583,34 @@ func DecompressHandler(c *gin.Context) {
 	c.String(http.StatusOK, string(decompressed))
 }
 
-// HelpHandler returns API documentation in markdown format
-func HelpHandler(c *gin.Context) {
-	c.Header("Content-Type", "text/markdown; charset=utf-8")
-	c.String(http.StatusOK, helpMarkdown)
+// SourceHandler returns the pretty-printed JSON of a compressed resource
+// GET /source?resource={brotli-base64url-json}
+func SourceHandler(c *gin.Context) {
+	resourceParam := c.Query("resource")
+	if resourceParam == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Missing 'resource' query parameter",
+			"usage": "GET /source?resource={brotli-base64url-json}",
+		})
+		return
+	}
+
+	decoded, err := decompressBrotliBase64URL(c.Request.Context(), resourceParam)
+	if errors.Is(err, errResourceTooLarge) {
+		respondTooLarge(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Decompression failed", "details": err.Error()})
+		return
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, decoded, "", "  "); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", SVGCacheTTLSeconds))
+	c.Data(http.StatusOK, "application/json; charset=utf-8", pretty.Bytes())
 }
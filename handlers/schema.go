@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/models"
+	"fhir_renderer/validation"
+)
+
+// SchemaVersion is the version of the ResourceDefinition JSON Schema; it
+// changes whenever the definition format does
+const SchemaVersion = "1.0.0"
+
+// schemaRequired lists the properties a definition object cannot do
+// without, those /validate reports as errors when missing. Elements need a
+// name or a path, see buildJSONSchema.
+var schemaRequired = map[string][]string{
+	"ResourceDefinition": {"name", "type"},
+	"Extension":          {"name"},
+	"Target":             {"type"},
+	"Mapping":            {"identity", "map"},
+}
+
+// schemaPatterns lists the patterns of string properties
+var schemaPatterns = map[string]string{
+	"Element.cardinality":   validation.CardinalityPattern.String(),
+	"Extension.cardinality": validation.CardinalityPattern.String(),
+	"Annotation.color":      models.HexColorPattern.String(),
+}
+
+// resourceSchema is generated once at startup, like the OpenAPI description
+var resourceSchema = buildJSONSchema()
+
+// buildJSONSchema derives the JSON Schema (draft 2020-12) of a
+// ResourceDefinition from the OpenAPI component schemas. Unlike those it
+// rejects unknown properties, as strict rendering does, and only requires
+// what a definition cannot do without. The result holds plain maps and
+// slices, as decoded from JSON, for validation.ValidateSchema.
+func buildJSONSchema() map[string]any {
+	defs := gin.H{}
+	generateSchema(reflect.TypeOf(models.ResourceDefinition{}), defs)
+	for name, def := range defs {
+		def := def.(gin.H)
+		def["additionalProperties"] = false
+		delete(def, "required")
+		if required, ok := schemaRequired[name]; ok {
+			def["required"] = required
+		}
+		properties := def["properties"].(gin.H)
+		for key, pattern := range schemaPatterns {
+			if typeName, property, _ := strings.Cut(key, "."); typeName == name {
+				properties[property].(gin.H)["pattern"] = pattern
+			}
+		}
+	}
+	defs["Element"].(gin.H)["anyOf"] = []gin.H{{"required": []string{"name"}}, {"required": []string{"path"}}}
+
+	data, err := json.Marshal(gin.H{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "ResourceDefinition",
+		"description": "Definition rendered by the FHIR renderer, version " + SchemaVersion,
+		"$ref":        "#/$defs/ResourceDefinition",
+		"$defs":       defs,
+	})
+	if err != nil {
+		panic(err)
+	}
+	data = bytes.ReplaceAll(data, []byte(`"#/components/schemas/`), []byte(`"#/$defs/`))
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// SchemaHandler returns the JSON Schema of a ResourceDefinition. Its $id is
+// the versioned URL, which keeps serving this version of the schema.
+// GET /schema.json or /schema/{version}.json
+func SchemaHandler(c *gin.Context) {
+	if version := c.Param("version"); version != "" && version != SchemaVersion+".json" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Unknown schema version",
+			"current": "/schema/" + SchemaVersion + ".json",
+		})
+		return
+	}
+	schema := maps.Clone(resourceSchema)
+	schema["$id"] = requestBaseURL(c) + "/schema/" + SchemaVersion + ".json"
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode schema"})
+		return
+	}
+	c.Data(http.StatusOK, "application/schema+json", data)
+}
+
+// ValidateSchemaHandler checks a body against the ResourceDefinition JSON
+// Schema without decoding or rendering it, listing every violation with
+// its path, line and column
+// POST /validate-schema with JSON body → returns a validation report
+func ValidateSchemaHandler(c *gin.Context) {
+	body, ok := readBody(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, validation.ValidateSchema(resourceSchema, body))
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/models"
+	"fhir_renderer/storage"
+)
+
+// shareStore holds definitions saved via POST /share; nil disables sharing
+var shareStore storage.Store
+
+// SetShareStore configures the store used for short share links
+func SetShareStore(s storage.Store) {
+	shareStore = s
+}
+
+// requireShareStore writes a 503 response when sharing is disabled
+func requireShareStore(c *gin.Context) bool {
+	if shareStore == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sharing is not enabled on this server"})
+		return false
+	}
+	return true
+}
+
+// loadShared reads the definition stored under the :id path parameter.
+// On failure an error response has already been written and ok is false.
+func loadShared(c *gin.Context) (id string, data []byte, ok bool) {
+	return loadSharedID(c, c.Param("id"))
+}
+
+// loadSharedID is loadShared for an id taken from elsewhere than the path,
+// e.g. a query parameter
+func loadSharedID(c *gin.Context, id string) (string, []byte, bool) {
+	if !requireShareStore(c) {
+		return "", nil, false
+	}
+
+	if !storage.ValidID(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shared resource not found"})
+		return "", nil, false
+	}
+
+	data, err := shareStore.Get(c.Request.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shared resource not found"})
+		return "", nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load shared resource", "details": err.Error()})
+		return "", nil, false
+	}
+	return id, data, true
+}
+
+// ShareHandler stores a definition and returns its short links
+// POST /share with JSON body → returns {"id": "...", "url": "/d/...", "editorUrl": "/editor?id=..."}
+func ShareHandler(c *gin.Context) {
+	if !requireShareStore(c) {
+		return
+	}
+
+	body, resource, ok := readResourceBody(c)
+	if !ok {
+		return
+	}
+	if !checkResource(c, &resource, isStrict(c)) {
+		return
+	}
+
+	id, err := shareStore.Put(c.Request.Context(), body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store resource", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":        id,
+		"url":       "/d/" + id,
+		"editorUrl": "/editor?id=" + id,
+	})
+}
+
+// SharedRenderHandler renders a stored definition
+// GET /d/{id}
+func SharedRenderHandler(c *gin.Context) {
+	id, data, ok := loadShared(c)
+	if !ok {
+		return
+	}
+
+	strict := isStrict(c)
+	var resource models.ResourceDefinition
+	if err := decodeResource(c.Request.Context(), data, strict, &resource); err != nil {
+		c.JSON(http.StatusBadRequest, invalidJSON("Invalid JSON", err))
+		return
+	}
+	if !checkResource(c, &resource, strict) {
+		return
+	}
+
+	// Links use the share id; the source is only compressed for embedding
+	compressedResource := ""
+	if c.Query("embedSource") == "true" {
+		compressedResource, _ = compressBrotliBase64URL(data)
+	}
+	renderAndRespond(c, &resource, compressedResource, id)
+}
+
+// SharedSourceHandler returns the pretty-printed JSON of a stored definition
+// GET /share/{id}
+func SharedSourceHandler(c *gin.Context) {
+	_, data, ok := loadShared(c)
+	if !ok {
+		return
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, data, "", "  "); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Stored resource is not valid JSON", "details": err.Error()})
+		return
+	}
+
+	// Shared entries are content addressed and never change
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", SVGCacheTTLSeconds))
+	c.Data(http.StatusOK, "application/json; charset=utf-8", pretty.Bytes())
+}
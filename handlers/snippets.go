@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/middleware"
+	"fhir_renderer/models"
+	"fhir_renderer/storage"
+)
+
+// snippetStore holds the snippet library; nil disables the /snippets endpoints
+var snippetStore storage.SnippetStore
+
+// SetSnippetStore configures the store backing the snippet library
+func SetSnippetStore(s storage.SnippetStore) {
+	snippetStore = s
+}
+
+// snippetRequest is the body of POST and PUT /snippets
+type snippetRequest struct {
+	Name     string          `json:"name"`
+	Tags     []string        `json:"tags"`
+	Resource json.RawMessage `json:"resource"`
+}
+
+// requireSnippetStore writes a 503 response when the library is disabled
+func requireSnippetStore(c *gin.Context) bool {
+	if snippetStore == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Snippet library is not enabled on this server"})
+		return false
+	}
+	return true
+}
+
+// respondSnippetError maps store errors to responses
+func respondSnippetError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snippet not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Snippet storage failed", "details": err.Error()})
+}
+
+// readSnippetRequest reads and validates a snippet body. On failure an error
+// response has already been written and ok is false.
+func readSnippetRequest(c *gin.Context) (snippet storage.Snippet, ok bool) {
+	var req snippetRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodyBytes))
+	if err := decoder.Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			middleware.RespondBodyTooLarge(c, limits.MaxBodyBytes)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON body", "details": err.Error()})
+		}
+		return snippet, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required field 'name'"})
+		return snippet, false
+	}
+	if len(req.Resource) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required field 'resource'"})
+		return snippet, false
+	}
+
+	// Only renderable definitions are worth keeping in the library
+	var resource models.ResourceDefinition
+	if err := decodeResource(c.Request.Context(), req.Resource, isStrict(c), &resource); err != nil {
+		c.JSON(http.StatusBadRequest, invalidJSON("Invalid resource", err))
+		return snippet, false
+	}
+	if !checkResource(c, &resource, isStrict(c)) {
+		return snippet, false
+	}
+
+	return storage.Snippet{Name: req.Name, Tags: req.Tags, Resource: req.Resource}, true
+}
+
+// ListSnippetsHandler lists the saved snippets without their resources
+// GET /snippets?tag={tag}
+func ListSnippetsHandler(c *gin.Context) {
+	if !requireSnippetStore(c) {
+		return
+	}
+
+	snippets, err := snippetStore.ListSnippets(c.Request.Context(), c.Query("tag"))
+	if err != nil {
+		respondSnippetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snippets)
+}
+
+// CreateSnippetHandler saves a new snippet
+// POST /snippets with {"name": "...", "tags": [...], "resource": {...}}
+func CreateSnippetHandler(c *gin.Context) {
+	if !requireSnippetStore(c) {
+		return
+	}
+
+	snippet, ok := readSnippetRequest(c)
+	if !ok {
+		return
+	}
+
+	snippet, err := snippetStore.CreateSnippet(c.Request.Context(), snippet)
+	if err != nil {
+		respondSnippetError(c, err)
+		return
+	}
+	c.Header("Location", "/snippets/"+snippet.ID)
+	c.JSON(http.StatusCreated, snippet)
+}
+
+// GetSnippetHandler returns a snippet including its resource
+// GET /snippets/{id}
+func GetSnippetHandler(c *gin.Context) {
+	if !requireSnippetStore(c) {
+		return
+	}
+
+	snippet, err := snippetStore.GetSnippet(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondSnippetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snippet)
+}
+
+// UpdateSnippetHandler replaces a snippet's name, tags and resource
+// PUT /snippets/{id}
+func UpdateSnippetHandler(c *gin.Context) {
+	if !requireSnippetStore(c) {
+		return
+	}
+
+	snippet, ok := readSnippetRequest(c)
+	if !ok {
+		return
+	}
+	snippet.ID = c.Param("id")
+
+	snippet, err := snippetStore.UpdateSnippet(c.Request.Context(), snippet)
+	if err != nil {
+		respondSnippetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snippet)
+}
+
+// DeleteSnippetHandler removes a snippet
+// DELETE /snippets/{id}
+func DeleteSnippetHandler(c *gin.Context) {
+	if !requireSnippetStore(c) {
+		return
+	}
+
+	if err := snippetStore.DeleteSnippet(c.Request.Context(), c.Param("id")); err != nil {
+		respondSnippetError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/convert"
+	"fhir_renderer/models"
+	"fhir_renderer/renderer"
+)
+
+// checkConceptComplexity applies the element and depth limits to concepts
+func checkConceptComplexity(flat []models.FlatConcept) error {
+	if len(flat) > limits.MaxElements {
+		return fmt.Errorf("terminology has %d concepts, limit is %d", len(flat), limits.MaxElements)
+	}
+	for _, fc := range flat {
+		if fc.Depth > limits.MaxDepth {
+			return fmt.Errorf("concept %q is nested %d levels deep, limit is %d", fc.Concept.Code, fc.Depth, limits.MaxDepth)
+		}
+	}
+	return nil
+}
+
+// renderTerminologyAndRespond renders a FHIR ValueSet or CodeSystem as a
+// concept table. compressedResource is used for the footer's edit link.
+func renderTerminologyAndRespond(c *gin.Context, body []byte, compressedResource string) {
+	format := c.DefaultQuery("format", FormatSVG)
+	if format != FormatSVG {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unsupported format",
+			"details": fmt.Sprintf("format %q does not support ValueSet or CodeSystem; use %q", format, FormatSVG),
+		})
+		return
+	}
+
+	table, err := convert.Terminology(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid terminology resource", "details": err.Error()})
+		return
+	}
+	if err := checkConceptComplexity(table.Flatten()); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Resource exceeds render limits",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	config := renderer.DefaultConfig()
+	config.CompressedResource = compressedResource
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+
+	respondRendered(c, table, config, format, func(ctx context.Context, w io.Writer) error {
+		svg, err := renderer.RenderTerminologyContext(ctx, table, config)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, svg)
+		return err
+	})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/convert"
+	"fhir_renderer/models"
+	"fhir_renderer/renderer"
+)
+
+// ThumbnailHandler renders a small, simplified SVG of a definition for
+// gallery and list views: the root and its first-level elements, drawn
+// ?width= pixels wide (default 320)
+// GET /thumb?resource={brotli-base64url-json}&width=320 or /thumb?id={share id}
+func ThumbnailHandler(c *gin.Context) {
+	resource, ok := definitionFromQuery(c, "GET /thumb?resource={brotli-base64url-json}&width=320")
+	if !ok {
+		return
+	}
+
+	width, err := strconv.Atoi(c.Query("width"))
+	if err != nil || width <= 0 {
+		width = renderer.DefaultThumbnailWidth
+	}
+
+	config := renderer.DefaultConfig()
+	if !applyRenderOptions(c, &config) {
+		return
+	}
+
+	// The width is not part of the config, so it joins the cache key
+	cacheKey := struct {
+		Resource *models.ResourceDefinition
+		Width    int
+	}{resource, width}
+	respondRendered(c, cacheKey, config, FormatSVG, func(ctx context.Context, w io.Writer) error {
+		return renderer.RenderThumbnailToContext(ctx, w, resource, width, config)
+	})
+}
+
+// definitionFromQuery loads the definition a thumbnail or preview card
+// shows: the compressed ?resource= or the shared ?id=. It applies
+// ?fhirVersion=, validates the definition and checks the render limits.
+// On failure an error response has already been written and ok is false.
+func definitionFromQuery(c *gin.Context, usage string) (*models.ResourceDefinition, bool) {
+	var data []byte
+	switch resourceParam, id := c.Query("resource"), c.Query("id"); {
+	case id != "":
+		var ok bool
+		if _, data, ok = loadSharedID(c, id); !ok {
+			return nil, false
+		}
+	case resourceParam != "":
+		var err error
+		data, err = decompressBrotliBase64URL(c.Request.Context(), resourceParam)
+		if errors.Is(err, errResourceTooLarge) {
+			respondTooLarge(c)
+			return nil, false
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid encoding (expected Brotli + Base64URL)",
+				"details": err.Error(),
+			})
+			return nil, false
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Missing 'resource' or 'id' query parameter",
+			"usage": usage,
+		})
+		return nil, false
+	}
+	if convert.IsCapabilityStatement(data) || convert.IsTerminology(data) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Only resource definitions and profiles are supported",
+		})
+		return nil, false
+	}
+
+	strict := isStrict(c)
+	var resource models.ResourceDefinition
+	if err := decodeResource(c.Request.Context(), data, strict, &resource); err != nil {
+		c.JSON(http.StatusBadRequest, invalidJSON("Invalid JSON", err))
+		return nil, false
+	}
+	if !checkResource(c, &resource, strict) {
+		return nil, false
+	}
+	if err := checkComplexity(resource.Flatten()); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Resource exceeds render limits",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return &resource, true
+}
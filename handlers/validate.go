@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fhir_renderer/convert"
+	"fhir_renderer/validation"
+)
+
+// ValidateHandler lints a resource definition without rendering it
+// POST /validate with JSON (or FHIR XML) body → returns a validation report
+func ValidateHandler(c *gin.Context) {
+	body, resource, ok := readResourceBody(c)
+	if !ok {
+		return
+	}
+
+	applyFHIRVersion(c, &resource)
+	// Converted FHIR resources have their own schema
+	if convert.HasConverter(body) {
+		c.JSON(http.StatusOK, validation.Lint(&resource))
+		return
+	}
+	c.JSON(http.StatusOK, validation.LintSource(body, &resource))
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"strings"
+)
+
+// xmlArrayFields lists, by root resourceType, the fields that are always
+// decoded as JSON arrays, even when the XML document contains a single
+// occurrence. A field may be qualified by its parent element, such as
+// "element.code", where the name alone means something else elsewhere.
+// Documents of other types are read as ResourceDefinitions.
+var xmlArrayFields = map[string]map[string]bool{
+	"ResourceDefinition": {
+		"flags":       true,
+		"elements":    true,
+		"extensions":  true,
+		"mappings":    true,
+		"targets":     true,
+		"annotations": true,
+		"codes":       true,
+	},
+	"Questionnaire": {
+		"item":         true,
+		"answerOption": true,
+	},
+	"CapabilityStatement": {
+		"rest":        true,
+		"resource":    true,
+		"interaction": true,
+		"searchParam": true,
+	},
+	"ValueSet": {
+		"include":  true,
+		"exclude":  true,
+		"concept":  true,
+		"filter":   true,
+		"valueSet": true,
+		"contains": true,
+	},
+	"StructureDefinition": {
+		"element":            true,
+		"element.type":       true, // The definition's own type is a single code
+		"element.code":       true, // So is type.code
+		"type.profile":       true,
+		"type.targetProfile": true,
+		"constraint":         true,
+		"mapping":            true,
+	},
+	"CodeSystem": {
+		"concept":  true,
+		"property": true,
+	},
+}
+
+// isXMLContentType reports whether the content type denotes an XML body
+func isXMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/fhir+xml", "application/xml", "text/xml":
+		return true
+	}
+	return false
+}
+
+// xmlNode is a generic XML element used during FHIR XML decoding
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+}
+
+// fhirXMLToJSON converts a FHIR-style XML document into the equivalent JSON.
+// Primitive values are read from the "value" attribute, other attributes
+// (such as an extension's "url") become string properties, and the root
+// element name is used as the resourceType.
+func fhirXMLToJSON(data []byte) ([]byte, error) {
+	root, err := parseXMLTree(data)
+	if err != nil {
+		return nil, err
+	}
+
+	arrayFields, ok := xmlArrayFields[root.name]
+	if !ok {
+		arrayFields = xmlArrayFields["ResourceDefinition"]
+	}
+	obj := xmlNodeToObject(root, arrayFields)
+	if _, ok := obj["resourceType"]; !ok {
+		obj["resourceType"] = root.name
+	}
+	return json.Marshal(obj)
+}
+
+// parseXMLTree reads the XML document into a tree of xmlNodes
+func parseXMLTree(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*xmlNode
+	var root *xmlNode
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("empty XML document")
+	}
+	return root, nil
+}
+
+// xmlNodeToObject converts an XML element with children into a JSON object,
+// making arrays of the arrayFields of the document type
+func xmlNodeToObject(node *xmlNode, arrayFields map[string]bool) map[string]any {
+	obj := make(map[string]any)
+
+	for _, attr := range node.attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" || attr.Name.Local == "value" {
+			continue
+		}
+		obj[attr.Name.Local] = attr.Value
+	}
+
+	for _, child := range node.children {
+		// Narrative XHTML is not part of the renderer model
+		if child.name == "div" {
+			continue
+		}
+
+		value := xmlNodeToValue(child, arrayFields)
+		if existing, ok := obj[child.name]; ok {
+			if list, isList := existing.([]any); isList {
+				obj[child.name] = append(list, value)
+			} else {
+				obj[child.name] = []any{existing, value}
+			}
+		} else if arrayFields[child.name] || arrayFields[node.name+"."+child.name] {
+			obj[child.name] = []any{value}
+		} else {
+			obj[child.name] = value
+		}
+	}
+
+	return obj
+}
+
+// xmlNodeToValue converts an XML element into a primitive or object value
+func xmlNodeToValue(node *xmlNode, arrayFields map[string]bool) any {
+	if len(node.children) == 0 {
+		for _, attr := range node.attrs {
+			if attr.Name.Local == "value" {
+				return strings.TrimSpace(attr.Value)
+			}
+		}
+	}
+	return xmlNodeToObject(node, arrayFields)
+}
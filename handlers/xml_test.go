@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// TestFHIRXMLToJSON checks which fields become arrays for each resource type
+func TestFHIRXMLToJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		want string
+	}{
+		{
+			name: "definition binding value set stays a string",
+			xml: `<ResourceDefinition xmlns="http://hl7.org/fhir"><name value="P"/><type value="Patient"/>
+				<elements><name value="gender"/><type value="code"/>
+				<binding><strength value="required"/><valueSet value="male | female"/></binding></elements></ResourceDefinition>`,
+			want: `{"resourceType":"ResourceDefinition","name":"P","type":"Patient",
+				"elements":[{"name":"gender","type":"code","binding":{"strength":"required","valueSet":"male | female"}}]}`,
+		},
+		{
+			name: "single reference target",
+			xml: `<ResourceDefinition><name value="P"/><type value="Patient"/>
+				<elements><name value="managingOrganization"/><type value="Reference"/><targets><type value="Organization"/></targets></elements></ResourceDefinition>`,
+			want: `{"resourceType":"ResourceDefinition","name":"P","type":"Patient",
+				"elements":[{"name":"managingOrganization","type":"Reference","targets":[{"type":"Organization"}]}]}`,
+		},
+		{
+			name: "single annotation and binding code",
+			xml: `<ResourceDefinition><name value="P"/><type value="Patient"/>
+				<elements><name value="gender"/><type value="code"/><binding><valueSet value="vs"/><codes value="male"/></binding></elements>
+				<annotations><path value="gender"/><note value="Check"/></annotations></ResourceDefinition>`,
+			want: `{"resourceType":"ResourceDefinition","name":"P","type":"Patient",
+				"elements":[{"name":"gender","type":"code","binding":{"valueSet":"vs","codes":["male"]}}],
+				"annotations":[{"path":"gender","note":"Check"}]}`,
+		},
+		{
+			name: "unknown root is read as a definition",
+			xml:  `<Definition><name value="P"/><type value="Patient"/><flags value="MS"/></Definition>`,
+			want: `{"resourceType":"Definition","name":"P","type":"Patient","flags":["MS"]}`,
+		},
+		{
+			name: "value set compose",
+			xml: `<ValueSet xmlns="http://hl7.org/fhir"><compose><include><system value="http://loinc.org"/>
+				<filter><property value="class"/><op value="="/><value value="CHEM"/></filter>
+				<valueSet value="http://example.org/vs"/></include></compose></ValueSet>`,
+			want: `{"resourceType":"ValueSet","compose":{"include":[{"system":"http://loinc.org",
+				"filter":[{"property":"class","op":"=","value":"CHEM"}],"valueSet":["http://example.org/vs"]}]}}`,
+		},
+		{
+			name: "code system concept properties",
+			xml: `<CodeSystem xmlns="http://hl7.org/fhir"><concept><code value="a"/>
+				<property><code value="status"/><valueCode value="retired"/></property></concept></CodeSystem>`,
+			want: `{"resourceType":"CodeSystem","concept":[{"code":"a","property":[{"code":"status","valueCode":"retired"}]}]}`,
+		},
+		{
+			name: "capability statement",
+			xml: `<CapabilityStatement xmlns="http://hl7.org/fhir"><rest><mode value="server"/>
+				<resource><type value="Patient"/><interaction><code value="read"/></interaction></resource></rest></CapabilityStatement>`,
+			want: `{"resourceType":"CapabilityStatement","rest":[{"mode":"server",
+				"resource":[{"type":"Patient","interaction":[{"code":"read"}]}]}]}`,
+		},
+		{
+			name: "questionnaire",
+			xml: `<Questionnaire xmlns="http://hl7.org/fhir"><name value="Q"/>
+				<item><linkId value="a"/><answerOption><valueString value="yes"/></answerOption></item></Questionnaire>`,
+			want: `{"resourceType":"Questionnaire","name":"Q","item":[{"linkId":"a","answerOption":[{"valueString":"yes"}]}]}`,
+		},
+		{
+			name: "structure definition with single occurrences",
+			xml: `<StructureDefinition xmlns="http://hl7.org/fhir"><name value="MyObservation"/>
+				<differential><element id="Observation.subject"><path value="Observation.subject"/>
+				<code><system value="http://loinc.org"/><code value="8480-6"/></code>
+				<type><code value="Reference"/><targetProfile value="http://hl7.org/fhir/StructureDefinition/Patient"/></type>
+				<constraint><key value="obs-1"/></constraint><mapping><identity value="v2"/><map value="PID-3"/></mapping>
+				</element></differential></StructureDefinition>`,
+			want: `{"resourceType":"StructureDefinition","name":"MyObservation","differential":{"element":[{
+				"id":"Observation.subject","path":"Observation.subject",
+				"code":[{"system":"http://loinc.org","code":"8480-6"}],
+				"type":[{"code":"Reference","targetProfile":["http://hl7.org/fhir/StructureDefinition/Patient"]}],
+				"constraint":[{"key":"obs-1"}],"mapping":[{"identity":"v2","map":"PID-3"}]}]}}`,
+		},
+		{
+			name: "narrative is skipped",
+			xml:  `<Questionnaire><text><div xmlns="http://www.w3.org/1999/xhtml"><p>Hi</p></div></text></Questionnaire>`,
+			want: `{"resourceType":"Questionnaire","text":{}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fhirXMLToJSON([]byte(tt.xml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestFHIRXMLToJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{name: "empty", xml: ""},
+		{name: "unclosed root", xml: `<ResourceDefinition><name value="P"/>`},
+		{name: "not XML", xml: "not xml"},
+		{name: "mismatched end tag", xml: `<ResourceDefinition><name value="P"></type></ResourceDefinition>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := fhirXMLToJSON([]byte(tt.xml)); err == nil {
+				t.Errorf("fhirXMLToJSON(%q) succeeded, want an error", tt.xml)
+			}
+		})
+	}
+}
+
+// TestRenderXML posts XML definitions to POST /render
+func TestRenderXML(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{
+			name: "binding",
+			xml: `<ResourceDefinition xmlns="http://hl7.org/fhir"><name value="MyPatient"/><type value="Patient"/>
+				<elements><name value="gender"/><cardinality value="1..1"/><type value="code"/>
+				<binding><strength value="required"/><valueSet value="http://hl7.org/fhir/ValueSet/administrative-gender"/></binding>
+				</elements></ResourceDefinition>`,
+		},
+		{
+			name: "single target",
+			xml: `<ResourceDefinition xmlns="http://hl7.org/fhir"><name value="MyPatient"/><type value="Patient"/>
+				<elements><name value="managingOrganization"/><type value="Reference"/>
+				<targets><type value="Organization"/><url value="https://hl7.org/fhir/organization.html"/></targets>
+				</elements></ResourceDefinition>`,
+		},
+		{
+			name: "single annotation",
+			xml: `<ResourceDefinition xmlns="http://hl7.org/fhir"><name value="MyPatient"/><type value="Patient"/>
+				<elements><name value="gender"/><type value="code"/></elements>
+				<annotations><path value="gender"/><color value="#fde68a"/><note value="Confirm with HIS team"/></annotations>
+				</ResourceDefinition>`,
+		},
+		{
+			name: "structure definition",
+			xml: `<StructureDefinition xmlns="http://hl7.org/fhir"><name value="MyPatient"/><type value="Patient"/>
+				<kind value="resource"/><derivation value="constraint"/>
+				<snapshot>
+				<element id="Patient"><path value="Patient"/><min value="0"/><max value="*"/><type><code value="Patient"/></type></element>
+				<element id="Patient.managingOrganization"><path value="Patient.managingOrganization"/><min value="1"/><max value="1"/>
+				<type><code value="Reference"/><targetProfile value="http://hl7.org/fhir/StructureDefinition/Organization"/></type></element>
+				</snapshot></StructureDefinition>`,
+		},
+	}
+
+	router := gin.New()
+	router.POST("/render", RenderPOSTHandler)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/render", strings.NewReader(tt.xml))
+			req.Header.Set("Content-Type", "application/fhir+xml")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			if !strings.HasPrefix(rec.Header().Get("Content-Type"), "image/svg+xml") {
+				t.Errorf("Content-Type %q, want an SVG", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+// assertJSONEqual compares JSON documents regardless of formatting and key
+// order
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("invalid expected JSON: %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
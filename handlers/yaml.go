@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// isYAMLContentType reports whether the content type denotes a YAML body
+func isYAMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return true
+	}
+	return false
+}
+
+// yamlToJSON converts the first document of a YAML body into JSON, keeping
+// the order of keys. Scalars keep their YAML type, except that timestamps
+// such as dates stay strings. Aliases are expanded up to the body size
+// limit.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, errors.New("empty YAML document")
+	}
+	var buf bytes.Buffer
+	if err := writeYAMLAsJSON(&buf, doc.Content[0], limits.MaxBodyBytes); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeYAMLAsJSON writes a YAML node as JSON, failing once the output
+// exceeds maxBytes
+func writeYAMLAsJSON(buf *bytes.Buffer, node *yaml.Node, maxBytes int64) error {
+	if int64(buf.Len()) > maxBytes {
+		return errResourceTooLarge
+	}
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeYAMLAsJSON(buf, node.Alias, maxBytes)
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeYAMLAsJSON(buf, item, maxBytes); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(node.Content[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeYAMLAsJSON(buf, node.Content[i+1], maxBytes); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.ScalarNode:
+		var value any = node.Value
+		switch node.ShortTag() {
+		case "!!null":
+			value = nil
+		case "!!int", "!!float":
+			// Literals that are valid JSON numbers are kept as written, so
+			// 1.0 stays 1.0; others such as 0x10 or 1_000 are decoded
+			if json.Valid([]byte(node.Value)) {
+				buf.WriteString(node.Value)
+				return nil
+			}
+			if err := node.Decode(&value); err != nil {
+				return err
+			}
+		case "!!bool":
+			if err := node.Decode(&value); err != nil {
+				return err
+			}
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		buf.Write(encoded)
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+	return nil
+}
+
+// jsonToYAML converts a JSON document into YAML, keeping the order of keys
+func jsonToYAML(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := readJSONAsYAML(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON document")
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readJSONAsYAML reads the next JSON value from decoder as a YAML node
+func readJSONAsYAML(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		if token == '{' {
+			node.Kind = yaml.MappingNode
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			value, err := readJSONAsYAML(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		if _, err := decoder.Token(); err != nil { // Closing delimiter
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(token.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: token.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(token)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// ConvertToYAMLHandler converts a definition into YAML, which is easier to
+// write and review by hand than JSON
+// POST /convert/yaml with JSON (or FHIR XML or FSH) body → returns YAML
+func ConvertToYAMLHandler(c *gin.Context) {
+	body, ok := readBody(c)
+	if !ok {
+		return
+	}
+	out, err := jsonToYAML(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", out)
+}
+
+// ConvertToJSONHandler converts a definition written in YAML (or FHIR XML
+// or FSH) into indented JSON. The body is read as YAML whatever its content
+// type, unless it is FHIR XML or FSH.
+// POST /convert/json with YAML body → returns JSON
+func ConvertToJSONHandler(c *gin.Context) {
+	body, ok := readBodyAs(c, true)
+	if !ok {
+		return
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON", "details": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", pretty.Bytes())
+}
@@ -1,21 +1,230 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 
+	"fhir_renderer/config"
+	"fhir_renderer/convert"
 	"fhir_renderer/handlers"
+	"fhir_renderer/middleware"
+	"fhir_renderer/renderer"
+	"fhir_renderer/storage"
+	"fhir_renderer/validation"
 )
 
 func main() {
-	// Get port from environment or default to 8080
-	port := os.Getenv("PORT")
+	// Load the configuration file (-config or CONFIG_FILE) with environment overrides
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file")
+	flag.Parse()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Port defaults to 8080
+	port := cfg.Server.Port
 	if port == "" {
 		port = "8080"
 	}
 
+	// Request limits
+	limits := limitsFromConfig(cfg.Limits)
+	handlers.SetLimits(limits)
+
+	// Default colors and font family, e.g. for branding
+	renderer.SetDefaultTheme(renderer.Theme(cfg.Render.Theme))
+
+	// Named theme packs, e.g. customer palettes selected with ?theme=
+	themesPath := cfg.Render.ThemesPath
+	if themesPath == "" {
+		if info, err := os.Stat(config.DefaultThemesPath); err == nil && info.IsDir() {
+			themesPath = config.DefaultThemesPath
+		}
+	}
+	if themesPath != "" {
+		themes, err := config.LoadThemes(themesPath)
+		if err != nil {
+			log.Fatalf("Failed to load themes: %v", err)
+		}
+		for name, theme := range themes {
+			renderer.RegisterTheme(name, renderer.Theme(theme))
+		}
+		log.Printf("Loaded %d themes from %s", len(themes), themesPath)
+	}
+
+	// Default column order and visibility
+	if len(cfg.Render.Columns) > 0 {
+		columns, err := renderer.ParseColumns(cfg.Render.Columns)
+		if err != nil {
+			log.Fatalf("Invalid render.columns: %v", err)
+		}
+		renderer.SetDefaultColumns(columns)
+	}
+	if len(cfg.Render.ExtraColumns) > 0 {
+		extraColumns := make([]renderer.ExtraColumn, len(cfg.Render.ExtraColumns))
+		for i, extra := range cfg.Render.ExtraColumns {
+			extraColumns[i] = renderer.ExtraColumn(extra)
+		}
+		if err := renderer.ValidateExtraColumns(extraColumns); err != nil {
+			log.Fatalf("Invalid render.extraColumns: %v", err)
+		}
+		renderer.SetDefaultExtraColumns(extraColumns)
+	}
+
+	// Custom flags, e.g. "PII", and restyled built-in ones
+	if len(cfg.Render.Flags) > 0 {
+		flags := make([]renderer.FlagStyle, len(cfg.Render.Flags))
+		for i, style := range cfg.Render.Flags {
+			flags[i] = renderer.FlagStyle(style)
+		}
+		if err := renderer.ValidateFlags(flags); err != nil {
+			log.Fatalf("Invalid render.flags: %v", err)
+		}
+		renderer.SetDefaultFlags(flags)
+		for _, style := range flags {
+			validation.RegisterFlags(style.Code)
+		}
+	}
+
+	// Custom icons and icons per element type, e.g. for logical models
+	if len(cfg.Render.Icons) > 0 || len(cfg.Render.IconRules) > 0 {
+		icons := make([]renderer.IconStyle, len(cfg.Render.Icons))
+		for i, icon := range cfg.Render.Icons {
+			icons[i] = renderer.IconStyle(icon)
+		}
+		rules := make([]renderer.IconRule, len(cfg.Render.IconRules))
+		for i, rule := range cfg.Render.IconRules {
+			rules[i] = renderer.IconRule(rule)
+		}
+		if err := renderer.ValidateIcons(icons, rules); err != nil {
+			log.Fatalf("Invalid render.icons: %v", err)
+		}
+		renderer.SetDefaultIcons(icons, rules)
+	}
+
+	// Default title bar, e.g. with the resource name
+	if title := cfg.Render.Title; title != "" {
+		if _, err := renderer.ParseTitleTemplate(title); err != nil {
+			log.Fatalf("Invalid render.title: %v", err)
+		}
+		renderer.SetDefaultTitleTemplate(title)
+	}
+
+	// Organization logo, name and repository link
+	branding := renderer.Branding(cfg.Render.Branding)
+	if logo := branding.Logo; logo != "" && !strings.HasPrefix(logo, "data:") && !strings.Contains(logo, "://") {
+		data, err := renderer.LoadLogoFile(logo)
+		if err != nil {
+			log.Fatalf("Failed to load render.branding.logo: %v", err)
+		}
+		branding.Logo = data
+	}
+	if err := renderer.ValidateBranding(branding); err != nil {
+		log.Fatalf("Invalid render.branding: %v", err)
+	}
+	renderer.SetDefaultBranding(branding)
+
+	// Default watermark, e.g. "DRAFT" on staging servers
+	renderer.SetDefaultWatermark(cfg.Render.Watermark)
+
+	// SVG whitespace, e.g. minified in production
+	whitespace, err := renderer.ParseWhitespace(cfg.Render.Whitespace)
+	if err != nil {
+		log.Fatalf("Invalid RENDER_WHITESPACE: %v", err)
+	}
+	renderer.SetDefaultWhitespace(whitespace)
+
+	// Only link absolute http(s) URLs from definitions
+	renderer.SetDefaultStrictLinks(cfg.Render.StrictLinks)
+
+	// Load the custom font used for measurement and rendering
+	if path := cfg.Render.FontPath; path != "" {
+		font, err := renderer.LoadFontFile(path)
+		if err != nil {
+			log.Fatalf("Failed to load font: %v", err)
+		}
+		handlers.SetDefaultFont(font)
+		log.Printf("Using font %q from %s", font.Family, path)
+	}
+
+	// Documentation link templates for types and element names
+	typeLinkBase, elementLinkBase := cfg.Render.TypeLinkBase, cfg.Render.ElementLinkBase
+	for _, base := range []string{typeLinkBase, elementLinkBase} {
+		if base != "" && !handlers.ValidLinkBase(base) {
+			log.Fatalf("Invalid link base %q: must be an http(s) URL", base)
+		}
+	}
+	handlers.SetLinkDefaults(typeLinkBase, elementLinkBase)
+
+	// Browsers may open the /ws live preview from the CORS origins only
+	handlers.SetLiveOrigins(cfg.Server.CORS.Origins)
+
+	// Base definitions for generating snapshots from differentials
+	var registries []convert.Registry
+	if path := cfg.BaseDefinitions.Path; path != "" {
+		registry, err := convert.LoadRegistry(path)
+		if err != nil {
+			log.Fatalf("Failed to load base definitions: %v", err)
+		}
+		registries = append(registries, registry)
+		log.Printf("Loaded %d base definitions from %s", registry.Len(), path)
+	}
+	if template := cfg.BaseDefinitions.URL; template != "" {
+		if !handlers.ValidLinkBase(template) {
+			log.Fatalf("Invalid BASE_DEFINITIONS_URL %q: must be an http(s) URL", template)
+		}
+		registries = append(registries, convert.NewFetchRegistry(template, cfg.BaseDefinitions.CacheSize))
+	}
+	if len(registries) > 0 {
+		convert.SetBaseRegistry(convert.Registries(registries...))
+	}
+
+	// Terminology server listing the codes of bound value sets
+	if server := cfg.Terminology.Server; server != "" {
+		if !handlers.ValidLinkBase(server) {
+			log.Fatalf("Invalid TERMINOLOGY_SERVER %q: must be an http(s) URL", server)
+		}
+		handlers.SetTerminologyServer(convert.NewTerminologyServer(server, cfg.Terminology.CacheSize), cfg.Terminology.ExpandCount)
+	}
+
+	// Open the store backing short share links and the snippet library
+	store, err := openShareStore(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open share store: %v", err)
+	}
+	if store != nil {
+		defer store.Close()
+		handlers.SetShareStore(store)
+		if snippets, ok := store.(storage.SnippetStore); ok {
+			handlers.SetSnippetStore(snippets)
+		}
+	}
+
+	// Export traces when an OTLP endpoint is configured
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Background workers for POST /render/jobs
+	handlers.StartRenderJobs(limits.RenderWorkers, limits.RenderQueue)
+
 	// Create gin router
 	router := gin.Default()
 
@@ -23,7 +232,22 @@ func main() {
 	router.LoadHTMLGlob("templates/*")
 
 	// Enable CORS
-	router.Use(corsMiddleware())
+	router.Use(middleware.CORS(middleware.CORSOptions(cfg.Server.CORS)))
+
+	// Content-Security-Policy and related headers; the pages get their own policy
+	security := middleware.SecurityOptions(cfg.Server.Security)
+	router.Use(middleware.SecurityHeaders(security))
+	pageSecurity := middleware.PageSecurityHeaders(security)
+
+	// Trace requests; spans are only exported when setupTracing installed an exporter
+	router.Use(middleware.Tracing())
+
+	// Compress SVG and JSON responses
+	router.Use(middleware.Compression())
+
+	// Cap request bodies before the handlers read them
+	bodyLimit := middleware.BodyLimit(limits.MaxBodyBytes)
+	packageLimit := middleware.BodyLimit(limits.MaxPackageBytes)
 
 	// Routes
 	router.GET("/", func(c *gin.Context) {
@@ -31,41 +255,194 @@ func main() {
 	})
 	router.GET("/health", handlers.HealthHandler)
 	router.GET("/help", handlers.HelpHandler)
+	router.GET("/openapi.json", handlers.OpenAPIHandler)
+	router.GET("/docs", pageSecurity, handlers.DocsHandler)
 	router.GET("/render", handlers.RenderHandler)
-	router.POST("/render", handlers.RenderPOSTHandler)
+	router.GET("/thumb", handlers.ThumbnailHandler)
+	router.GET("/og", handlers.CardHandler)
+	router.POST("/render", bodyLimit, handlers.RenderPOSTHandler)
+	router.GET("/ws", handlers.LiveRenderHandler)
+	router.POST("/render/package", packageLimit, handlers.RenderPackageHandler)
+	router.POST("/render/compare", bodyLimit, handlers.RenderCompareHandler)
+	router.POST("/render/fsh", bodyLimit, handlers.RenderFSHHandler)
+	router.POST("/render/size", bodyLimit, handlers.RenderSizeHandler)
+	router.POST("/render/jobs", packageLimit, handlers.CreateRenderJobHandler)
+	router.GET("/render/jobs/:id", handlers.RenderJobHandler)
+	router.GET("/render/jobs/:id/result", handlers.RenderJobResultHandler)
+	router.GET("/render/jobs/:id/events", handlers.RenderJobEventsHandler)
+	router.POST("/validate", bodyLimit, handlers.ValidateHandler)
+	router.POST("/validate-schema", bodyLimit, handlers.ValidateSchemaHandler)
+	router.POST("/analyze", bodyLimit, handlers.AnalyzeHandler)
 	router.GET("/example", handlers.ExampleHandler)
-	router.GET("/editor", handlers.EditorHandler)
-	router.POST("/compress", handlers.CompressHandler)
-	router.POST("/decompress", handlers.DecompressHandler)
+	router.GET("/example/random", handlers.RandomExampleHandler)
+	router.GET("/examples", handlers.ExamplesHandler)
+	router.GET("/examples/:name", handlers.ExampleByNameHandler)
+	router.GET("/editor", pageSecurity, handlers.EditorHandler)
+	router.Static("/static", "static")
+	router.POST("/compress", bodyLimit, handlers.CompressHandler)
+	router.POST("/decompress", bodyLimit, handlers.DecompressHandler)
+	router.GET("/source", handlers.SourceHandler)
+	router.POST("/extract", bodyLimit, handlers.ExtractHandler)
+	router.POST("/import/csv", bodyLimit, handlers.ImportCSVHandler)
+	router.POST("/convert/structuredefinition", bodyLimit, handlers.ConvertStructureDefinitionHandler)
+	router.POST("/convert/yaml", bodyLimit, handlers.ConvertToYAMLHandler)
+	router.POST("/convert/json", bodyLimit, handlers.ConvertToJSONHandler)
+	router.GET("/schema.json", handlers.SchemaHandler)
+	router.GET("/schema/:version", handlers.SchemaHandler)
+	router.POST("/share", bodyLimit, handlers.ShareHandler)
+	router.GET("/share/:id", handlers.SharedSourceHandler)
+	router.GET("/d/:id", handlers.SharedRenderHandler)
+	router.GET("/snippets", handlers.ListSnippetsHandler)
+	router.POST("/snippets", bodyLimit, handlers.CreateSnippetHandler)
+	router.GET("/snippets/:id", handlers.GetSnippetHandler)
+	router.PUT("/snippets/:id", bodyLimit, handlers.UpdateSnippetHandler)
+	router.DELETE("/snippets/:id", handlers.DeleteSnippetHandler)
 
 	// Start server
 	log.Printf("FHIR Renderer starting on port %s", port)
 	log.Printf("Endpoints:")
 	log.Printf("  GET  /health     - Health check")
-	log.Printf("  GET  /help       - API documentation (markdown)")
+	log.Printf("  GET  /help       - Redirects to /docs")
+	log.Printf("  GET  /openapi.json - OpenAPI 3.0 specification")
+	log.Printf("  GET  /docs       - API documentation (Swagger UI)")
 	log.Printf("  GET  /render?resource={brotli-base64url}  - Render SVG from compressed query param")
 	log.Printf("  POST /render     - Render SVG from JSON body")
+	log.Printf("  GET  /thumb?resource={brotli-base64url}&width=320 - Simplified SVG thumbnail of the root and first-level elements")
+	log.Printf("  GET  /og?resource={brotli-base64url} - 1200x630 PNG preview card for link unfurling")
+	log.Printf("  GET  /ws         - WebSocket live preview: send JSON, receive SVG")
+	log.Printf("  POST /render/package - Render all StructureDefinitions of a FHIR package (.tgz) to a ZIP or share index")
+	log.Printf("  POST /render/compare - Render a profile over its base definition with the changes marked")
+	log.Printf("  POST /render/fsh - Render a FHIR Shorthand profile, extension, logical model or resource")
+	log.Printf("  POST /render/size - Return the width, height and row count of a render without rendering")
+	log.Printf("  POST /render/jobs - Queue a render in the background and return a job id")
+	log.Printf("  GET  /render/jobs/{id} - Render job status")
+	log.Printf("  GET  /render/jobs/{id}/result - Output of a finished render job")
+	log.Printf("  GET  /render/jobs/{id}/events - Server-sent progress events of a render job")
+	log.Printf("  POST /validate   - Lint JSON body and report diagnostics")
+	log.Printf("  POST /validate-schema - Validate JSON body against the JSON Schema")
+	log.Printf("  POST /analyze    - Report complexity metrics and estimated diagram size")
 	log.Printf("  GET  /example    - Get example JSON schema")
+	log.Printf("  GET  /example/random?elements=50&depth=4 - Generate a synthetic definition")
+	log.Printf("  GET  /examples   - List the starter definitions")
+	log.Printf("  GET  /examples/{name} - Get a starter definition")
 	log.Printf("  GET  /editor     - Interactive editor page")
+	log.Printf("  GET  /static/*   - Editor assets, including the WebAssembly renderer built with make wasm")
 	log.Printf("  POST /compress   - Compress JSON to Brotli+Base64URL")
 	log.Printf("  POST /decompress - Decompress Brotli+Base64URL to JSON")
+	log.Printf("  GET  /source?resource={brotli-base64url} - View compressed resource as JSON")
+	log.Printf("  POST /extract    - Recover the source JSON embedded in an SVG")
+	log.Printf("  POST /import/csv - Convert a path,type,cardinality,description CSV to JSON, or render it")
+	log.Printf("  POST /convert/structuredefinition - Convert JSON body to a FHIR StructureDefinition (differential)")
+	log.Printf("  POST /convert/yaml - Convert JSON body to YAML")
+	log.Printf("  POST /convert/json - Convert YAML body to JSON")
+	log.Printf("  GET  /schema.json - JSON Schema of ResourceDefinition (also /schema/%s.json)", handlers.SchemaVersion)
+	log.Printf("  POST /share      - Store JSON body and return a short link")
+	log.Printf("  GET  /share/{id} - View shared resource as JSON")
+	log.Printf("  GET  /d/{id}     - Render SVG from a short link")
+	log.Printf("  GET  /snippets?tag={tag} - List saved snippets")
+	log.Printf("  POST /snippets   - Save a named, tagged snippet")
+	log.Printf("  GET|PUT|DELETE /snippets/{id} - Load, update or delete a snippet")
+
+	// Optional gRPC RenderService on its own port
+	if grpcPort := cfg.Server.GRPCPort; grpcPort != "" {
+		listener, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			log.Fatalf("Failed to listen on gRPC port: %v", err)
+		}
+		grpcServer := handlers.NewGRPCServer()
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+		log.Printf("gRPC RenderService listening on :%s", grpcPort)
+	}
 
 	if err := router.Run(":" + port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
 
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+// openShareStore creates the configured share store ("sqlite" by default,
+// "memory" or "none")
+func openShareStore(cfg config.Storage) (storage.Store, error) {
+	switch kind := cfg.Kind; kind {
+	case "", "sqlite":
+		path := cfg.DBPath
+		if path == "" {
+			path = "fhir_renderer.db"
 		}
+		return storage.NewSQLiteStore(path)
+	case "memory":
+		return storage.NewMemoryStore(), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown SHARE_STORE %q", kind)
+	}
+}
 
-		c.Next()
+// limitsFromConfig applies the configured limits over handlers.DefaultLimits
+func limitsFromConfig(cfg config.Limits) handlers.Limits {
+	limits := handlers.DefaultLimits()
+	if cfg.MaxBodyBytes > 0 {
+		limits.MaxBodyBytes = cfg.MaxBodyBytes
+	}
+	if cfg.MaxPackageBytes > 0 {
+		limits.MaxPackageBytes = cfg.MaxPackageBytes
+	}
+	if cfg.MaxElements > 0 {
+		limits.MaxElements = cfg.MaxElements
+	}
+	if cfg.MaxDepth > 0 {
+		limits.MaxDepth = cfg.MaxDepth
+	}
+	if cfg.RenderTimeout > 0 {
+		limits.RenderTimeout = time.Duration(cfg.RenderTimeout)
+	}
+	if cfg.RenderWorkers > 0 {
+		limits.RenderWorkers = cfg.RenderWorkers
+	}
+	if cfg.RenderQueue != nil {
+		limits.RenderQueue = *cfg.RenderQueue
+	}
+	if cfg.JobRetention > 0 {
+		limits.JobRetention = time.Duration(cfg.JobRetention)
 	}
+	if cfg.MaxJobResults > 0 {
+		limits.MaxJobResults = cfg.MaxJobResults
+	}
+	if cfg.MaxJobResultBytes > 0 {
+		limits.MaxJobResultBytes = cfg.MaxJobResultBytes
+	}
+	return limits
+}
+
+// setupTracing exports spans via OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT
+// or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set. The exporter reads the other
+// standard OTEL_* variables (headers, protocol options, OTEL_SERVICE_NAME).
+// The returned function flushes pending spans.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "fhir-renderer"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	log.Printf("Exporting traces via OTLP")
+	return provider.Shutdown, nil
 }
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit caps request bodies at maxBytes. A declared Content-Length over
+// the cap is answered with a 413 before the handler runs; bodies of unknown
+// length are wrapped in http.MaxBytesReader, so handlers reading past the cap
+// get an *http.MaxBytesError and answer with RespondBodyTooLarge.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			RespondBodyTooLarge(c, maxBytes)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// RespondBodyTooLarge aborts the request with the 413 response for a body
+// over maxBytes
+func RespondBodyTooLarge(c *gin.Context, maxBytes int64) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":    "Request body too large",
+		"details":  fmt.Sprintf("maximum size is %d bytes", maxBytes),
+		"maxBytes": maxBytes,
+	})
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Default CORS methods and request headers
+var (
+	DefaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	DefaultCORSHeaders = []string{"Content-Type", "If-None-Match", "traceparent", "tracestate"}
+)
+
+// corsExposedHeaders are the response headers scripts may read
+const corsExposedHeaders = "ETag, Location, Retry-After"
+
+// CORSOptions configures cross-origin access
+type CORSOptions struct {
+	Origins          []string // Allowed origins; empty or "*" allows any origin
+	Methods          []string // Allowed methods; empty uses DefaultCORSMethods
+	Headers          []string // Allowed request headers; empty uses DefaultCORSHeaders
+	AllowCredentials bool     // Allow cookies and auth headers; needs explicit origins
+}
+
+// CORS answers preflight requests and adds the CORS headers for allowed
+// origins. Requests from other origins get no CORS headers, so browsers block
+// them; the server itself still handles them.
+func CORS(opts CORSOptions) gin.HandlerFunc {
+	anyOrigin := len(opts.Origins) == 0 || slices.Contains(opts.Origins, "*")
+	methods := opts.Methods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	headers := opts.Headers
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+	allowMethods, allowHeaders := strings.Join(methods, ", "), strings.Join(headers, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		allowed := true
+		if anyOrigin {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Writer.Header().Add("Vary", "Origin")
+			allowed = slices.Contains(opts.Origins, origin)
+			if allowed {
+				c.Header("Access-Control-Allow-Origin", origin)
+			}
+		}
+
+		if allowed {
+			c.Header("Access-Control-Allow-Methods", allowMethods)
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+			c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
+			if opts.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Default security header values
+const (
+	// DefaultDocumentCSP locks down rendered diagrams and API responses:
+	// diagrams are user-generated markup, so opened directly they may style
+	// themselves and show embedded fonts and images but never run scripts.
+	// The sandbox still lets links open in a new tab.
+	DefaultDocumentCSP = "default-src 'none'; style-src 'unsafe-inline'; img-src data: http: https:; font-src data:; " +
+		"sandbox allow-popups allow-popups-to-escape-sandbox allow-top-navigation-by-user-activation"
+
+	// DefaultPageCSP covers the editor and API docs: their inline scripts,
+	// the WebAssembly renderer, same-origin API calls and the live preview
+	// socket, and the Swagger UI assets from unpkg.com
+	DefaultPageCSP = "default-src 'self'; script-src 'self' 'unsafe-inline' 'wasm-unsafe-eval' https://unpkg.com; " +
+		"style-src 'self' 'unsafe-inline' https://unpkg.com; img-src 'self' data: http: https:; font-src 'self' data:; " +
+		"connect-src 'self' ws: wss:; object-src 'none'; base-uri 'none'"
+
+	DefaultFrameOptions   = "SAMEORIGIN"
+	DefaultReferrerPolicy = "strict-origin-when-cross-origin"
+)
+
+// SecurityOptions configures the security headers
+type SecurityOptions struct {
+	DocumentCSP    string // Content-Security-Policy of all other responses; empty uses DefaultDocumentCSP
+	PageCSP        string // Content-Security-Policy of the editor and docs pages; empty uses DefaultPageCSP
+	FrameOptions   string // X-Frame-Options of the pages, DENY or SAMEORIGIN; empty uses DefaultFrameOptions
+	ReferrerPolicy string // Referrer-Policy of all responses; empty uses DefaultReferrerPolicy
+}
+
+// withDefaults fills in the empty options
+func (opts SecurityOptions) withDefaults() SecurityOptions {
+	if opts.DocumentCSP == "" {
+		opts.DocumentCSP = DefaultDocumentCSP
+	}
+	if opts.PageCSP == "" {
+		opts.PageCSP = DefaultPageCSP
+	}
+	if opts.FrameOptions == "" {
+		opts.FrameOptions = DefaultFrameOptions
+	}
+	if opts.ReferrerPolicy == "" {
+		opts.ReferrerPolicy = DefaultReferrerPolicy
+	}
+	return opts
+}
+
+// SecurityHeaders adds the document policy, nosniff and the referrer policy
+// to every response. Diagrams stay embeddable in other sites, so no frame
+// options are set; the document policy keeps them from running scripts.
+func SecurityHeaders(opts SecurityOptions) gin.HandlerFunc {
+	opts = opts.withDefaults()
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Content-Security-Policy", opts.DocumentCSP)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", opts.ReferrerPolicy)
+		c.Next()
+	}
+}
+
+// PageSecurityHeaders replaces the document policy with the page policy and
+// adds the frame options, for the HTML pages served by the application itself
+func PageSecurityHeaders(opts SecurityOptions) gin.HandlerFunc {
+	opts = opts.withDefaults()
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Content-Security-Policy", opts.PageCSP)
+		h.Set("X-Frame-Options", opts.FrameOptions)
+		c.Next()
+	}
+}
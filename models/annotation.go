@@ -0,0 +1,65 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Annotation calls out an element in design reviews: its row is tinted and
+// the note is shown in the margin
+type Annotation struct {
+	Path  string `json:"path"`            // Element path, e.g. "Patient.identifier" or "identifier"
+	Color string `json:"color,omitempty"` // Row tint as #RGB or #RRGGBB
+	Note  string `json:"note,omitempty"`  // Margin note
+}
+
+// HexColorPattern matches #RGB and #RRGGBB colors
+var HexColorPattern = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+
+// IsHexColor reports whether color is a #RGB or #RRGGBB color
+func IsHexColor(color string) bool {
+	return HexColorPattern.MatchString(color)
+}
+
+// HasAnnotationNotes reports whether any annotation carries a note
+func (r *ResourceDefinition) HasAnnotationNotes() bool {
+	for _, a := range r.Annotations {
+		if a.Note != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// annotate links the flattened rows to their annotations. Annotation paths
+// may start with the resource name or type, or be relative to the resource;
+// the last annotation for a path wins.
+func (r *ResourceDefinition) annotate(rows []FlatElement) {
+	if len(r.Annotations) == 0 {
+		return
+	}
+	byPath := make(map[string]*Annotation, len(r.Annotations))
+	for i := range r.Annotations {
+		byPath[r.relativePath(r.Annotations[i].Path)] = &r.Annotations[i]
+	}
+	for i := range rows {
+		rows[i].Annotation = byPath[r.relativePath(rows[i].Path)]
+	}
+}
+
+// relativePath strips the resource name or type from the start of path; the
+// root element is ""
+func (r *ResourceDefinition) relativePath(path string) string {
+	for _, prefix := range []string{r.Name, r.Type} {
+		if prefix == "" {
+			continue
+		}
+		if path == prefix {
+			return ""
+		}
+		if rest, ok := strings.CutPrefix(path, prefix+"."); ok {
+			return rest
+		}
+	}
+	return path
+}
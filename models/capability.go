@@ -0,0 +1,31 @@
+package models
+
+// CapabilityMatrix is the REST capability of a FHIR CapabilityStatement,
+// rendered as a matrix of resource types by interactions
+type CapabilityMatrix struct {
+	Name        string           `json:"name"`
+	Version     string           `json:"version,omitempty"`
+	FHIRVersion string           `json:"fhirVersion,omitempty"`
+	Rest        []CapabilityRest `json:"rest"`
+}
+
+// CapabilityRest is one rest entry (server or client mode)
+type CapabilityRest struct {
+	Mode         string               `json:"mode"`
+	Interactions []string             `json:"interactions,omitempty"` // System-level interactions
+	Resources    []CapabilityResource `json:"resources"`
+}
+
+// CapabilityResource lists what is supported for one resource type
+type CapabilityResource struct {
+	Type         string   `json:"type"`
+	Profile      string   `json:"profile,omitempty"`
+	Interactions []string `json:"interactions"`
+	SearchParams []string `json:"searchParams,omitempty"`
+}
+
+// Interaction codes in the order they appear as matrix columns
+var TypeInteractions = []string{
+	"read", "vread", "update", "patch", "delete",
+	"history-instance", "history-type", "create", "search-type",
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// Kinds of FHIR types, see DatatypeKind
+const (
+	DatatypePrimitive = "primitive" // e.g. "dateTime"
+	DatatypeComplex   = "complex"   // e.g. "CodeableConcept"
+	DatatypeSpecial   = "special"   // Reference, Extension and abstract bases
+	DatatypeResource  = "resource"  // e.g. "Patient"
+)
+
+// FHIRType is a type of the embedded FHIR type registry in one FHIR version
+type FHIRType struct {
+	Name string
+	Kind string // DatatypePrimitive, DatatypeComplex, DatatypeSpecial or DatatypeResource
+	URL  string // Page of the type in the specification of that version
+}
+
+// registryType is a registry entry: its kind, the FHIR versions defining
+// it and its specification page in each version
+type registryType struct {
+	kind         string
+	since, until string   // First and last version defining the type
+	urls         []string // Page in the specification of each of FHIRVersions
+}
+
+// datatypesJSON lists the FHIR STU3 to R5 types by kind, the versions that
+// introduced or dropped them and the specification pages that differ from
+// the usual ones
+//
+//go:embed datatypes.json
+var datatypesJSON []byte
+
+// fhirTypes maps each FHIR type name to its registry entry. The page urls
+// are joined up front since LookupType runs for every row of a rendering.
+var fhirTypes = func() map[string]registryType {
+	var registry struct {
+		Spec      map[string]string
+		Primitive []string
+		Complex   []string
+		Special   []string
+		Resource  []string
+		Since     map[string][]string
+		Until     map[string][]string
+		Pages     map[string]string
+	}
+	if err := json.Unmarshal(datatypesJSON, &registry); err != nil {
+		panic("models: invalid datatypes.json: " + err.Error())
+	}
+	types := make(map[string]registryType)
+	add := func(kind string, names []string) {
+		for _, name := range names {
+			// Data types are documented on the data types page, the
+			// others on a page of their own
+			page := strings.ToLower(name) + ".html"
+			if kind == DatatypePrimitive || kind == DatatypeComplex {
+				page = "datatypes.html#" + name
+			}
+			page = cmp.Or(registry.Pages[name], page)
+			urls := make([]string, len(FHIRVersions))
+			for i, version := range FHIRVersions {
+				urls[i] = registry.Spec[version] + page
+			}
+			types[name] = registryType{
+				kind:  kind,
+				since: FHIRVersions[0],
+				until: FHIRVersions[len(FHIRVersions)-1],
+				urls:  urls,
+			}
+		}
+	}
+	add(DatatypePrimitive, registry.Primitive)
+	add(DatatypeComplex, registry.Complex)
+	add(DatatypeSpecial, registry.Special)
+	add(DatatypeResource, registry.Resource)
+	for version, names := range registry.Since {
+		for _, name := range names {
+			t := types[name]
+			t.since = version
+			types[name] = t
+		}
+	}
+	for version, names := range registry.Until {
+		for _, name := range names {
+			t := types[name]
+			t.until = version
+			types[name] = t
+		}
+	}
+	return types
+}()
+
+// in reports whether the type is defined in a FHIR version
+func (t registryType) in(version string) bool {
+	i := slices.Index(FHIRVersions, version)
+	return i >= 0 && i >= slices.Index(FHIRVersions, t.since) && i <= slices.Index(FHIRVersions, t.until)
+}
+
+// LookupType returns a FHIR type such as "CodeableConcept" or "Patient" as
+// defined in a FHIR version, e.g. models.FHIRVersionR4. It reports false
+// for names that version does not define.
+func LookupType(name, version string) (FHIRType, bool) {
+	t, ok := fhirTypes[name]
+	if !ok || !t.in(version) {
+		return FHIRType{}, false
+	}
+	return FHIRType{Name: name, Kind: t.kind, URL: t.urls[slices.Index(FHIRVersions, version)]}, true
+}
+
+// TypeVersions returns the FHIR versions defining a type name, oldest
+// first, or nil for names outside the registry
+func TypeVersions(name string) []string {
+	t, ok := fhirTypes[name]
+	if !ok {
+		return nil
+	}
+	return slices.DeleteFunc(slices.Clone(FHIRVersions), func(v string) bool { return !t.in(v) })
+}
+
+// DatatypeKind returns the kind of a FHIR type name in any version, e.g.
+// DatatypePrimitive for "dateTime" and DatatypeComplex for
+// "CodeableConcept", or "" for names outside the registry such as logical
+// model types
+func DatatypeKind(typeName string) string {
+	return fhirTypes[typeName].kind
+}
+
+// SuggestType returns the type of a FHIR version a name outside the
+// registry is most likely a misspelling of, e.g. "CodeableConcept" for
+// "CodableConcept", or "" when the name is known or not close to any type.
+// Short names only match with a single edit so custom types are not
+// flagged.
+func SuggestType(name, version string) string {
+	if _, ok := fhirTypes[name]; ok || len(name) < 4 {
+		return ""
+	}
+	maxDistance := 1
+	if len(name) >= 8 {
+		maxDistance = 2
+	}
+	best, bestDistance := "", maxDistance+1
+	lower := strings.ToLower(name)
+	for known, t := range fhirTypes {
+		if !t.in(version) {
+			continue
+		}
+		if d := editDistance(lower, strings.ToLower(known)); d < bestDistance || (d == bestDistance && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// MaxSampleLength caps the compact JSON shown for complex sample values the
+// summary does not recognize
+const MaxSampleLength = 80
+
+// HasExample reports whether the definition carries an example instance
+func (r *ResourceDefinition) HasExample() bool {
+	return len(r.Example) > 0 && !bytes.Equal(r.Example, []byte("null"))
+}
+
+// ExampleObject returns the example instance as a JSON object, or false when
+// there is none or it is not an object
+func (r *ResourceDefinition) ExampleObject() (map[string]any, bool) {
+	if !r.HasExample() {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(r.Example))
+	decoder.UseNumber()
+	var instance map[string]any
+	if err := decoder.Decode(&instance); err != nil || instance == nil {
+		return nil, false
+	}
+	return instance, true
+}
+
+// sample fills in the sample values of the flattened rows from the example
+// instance. Rows are matched by path, with choice elements such as
+// value[x] matching valueQuantity and repeating values joined. Rows with
+// children, slices and extensions get no sample; their children show the
+// values instead.
+func (r *ResourceDefinition) sample(rows []FlatElement) {
+	instance, ok := r.ExampleObject()
+	if !ok {
+		return
+	}
+	for i := range rows {
+		relative := r.relativePath(rows[i].Path)
+		if relative == "" || len(rows[i].Element.Elements) > 0 || strings.Contains(relative, ":") {
+			continue
+		}
+		values := []any{instance}
+		for _, name := range strings.Split(relative, ".") {
+			values = exampleChildren(values, name)
+		}
+		rows[i].Sample = joinSamples(values, ", ")
+	}
+}
+
+// exampleChildren returns the values of the named child of each object in
+// values, flattening arrays. A choice name such as "value[x]" matches
+// "valueString", "valueQuantity" and so on.
+func exampleChildren(values []any, name string) []any {
+	choice, isChoice := strings.CutSuffix(name, "[x]")
+	var children []any
+	for _, value := range values {
+		object, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		for key, child := range object {
+			if key != name && !(isChoice && isChoiceKey(key, choice)) {
+				continue
+			}
+			if items, ok := child.([]any); ok {
+				children = append(children, items...)
+			} else {
+				children = append(children, child)
+			}
+		}
+	}
+	return children
+}
+
+// isChoiceKey reports whether key is the choice element prefix followed by a
+// type name, e.g. "valueQuantity" for "value"
+func isChoiceKey(key, prefix string) bool {
+	rest, ok := strings.CutPrefix(key, prefix)
+	return ok && rest != "" && unicode.IsUpper(rune(rest[0]))
+}
+
+// sampleText returns a value as shown in the sample column: primitives as
+// they are, common data types summarized (a CodeableConcept by its text or
+// codings, a Quantity by value and unit, a Reference by its reference,
+// a HumanName by its given and family names, a Period by its bounds) and
+// other objects as shortened compact JSON
+func sampleText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case []any:
+		return joinSamples(v, ", ")
+	case map[string]any:
+		return objectSampleText(v)
+	}
+	return ""
+}
+
+// objectSampleText summarizes a complex value for sampleText
+func objectSampleText(v map[string]any) string {
+	text := func(key string) string { return sampleText(v[key]) }
+	switch {
+	case text("text") != "":
+		return text("text")
+	case v["coding"] != nil:
+		return text("coding")
+	case text("code") != "" && text("value") == "":
+		if display := text("display"); display != "" {
+			return text("code") + " (" + display + ")"
+		}
+		return text("code")
+	case text("value") != "":
+		return strings.TrimSpace(text("value") + " " + firstNonEmpty(text("unit"), text("code")))
+	case text("reference") != "":
+		return text("reference")
+	case text("display") != "":
+		return text("display")
+	case v["family"] != nil || v["given"] != nil:
+		given, _ := v["given"].([]any)
+		return strings.TrimSpace(joinSamples(given, " ") + " " + text("family"))
+	case v["start"] != nil || v["end"] != nil:
+		return text("start") + " – " + text("end")
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	if runes := []rune(string(data)); len(runes) > MaxSampleLength {
+		return string(runes[:MaxSampleLength-1]) + "…"
+	}
+	return string(data)
+}
+
+// joinSamples joins the sample texts of values, leaving out empty ones
+func joinSamples(values []any, sep string) string {
+	texts := make([]string, 0, len(values))
+	for _, value := range values {
+		if text := sampleText(value); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, sep)
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	if i := slices.IndexFunc(values, func(v string) bool { return v != "" }); i >= 0 {
+		return values[i]
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// FHIR versions the type registry and the converters know, oldest first
+const (
+	FHIRVersionSTU3 = "STU3"
+	FHIRVersionR4   = "R4"
+	FHIRVersionR5   = "R5"
+)
+
+// FHIRVersions lists the supported FHIR versions, oldest first
+var FHIRVersions = []string{FHIRVersionSTU3, FHIRVersionR4, FHIRVersionR5}
+
+// DefaultFHIRVersion is assumed for definitions without a fhirVersion
+const DefaultFHIRVersion = FHIRVersionR4
+
+// fhirVersionNumbers are the release numbers written to exported definitions
+var fhirVersionNumbers = map[string]string{
+	FHIRVersionSTU3: "3.0.2",
+	FHIRVersionR4:   "4.0.1",
+	FHIRVersionR5:   "5.0.0",
+}
+
+// ParseFHIRVersion returns the FHIR version named by a release name such
+// as "R5" or a version number such as "4.0.1", as StructureDefinitions
+// carry it. R4B (4.3) counts as R4.
+func ParseFHIRVersion(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if i := slices.IndexFunc(FHIRVersions, func(v string) bool { return strings.EqualFold(v, s) }); i >= 0 {
+		return FHIRVersions[i], true
+	}
+	switch major, _, _ := strings.Cut(s, "."); {
+	case strings.EqualFold(s, "R4B"), major == "4" && s != "4":
+		return FHIRVersionR4, true
+	case major == "3" && s != "3":
+		return FHIRVersionSTU3, true
+	case major == "5" && s != "5":
+		return FHIRVersionR5, true
+	}
+	return "", false
+}
+
+// FHIRVersionNumber returns the release number of a FHIR version, e.g.
+// "4.0.1" for R4
+func FHIRVersionNumber(version string) string {
+	return fhirVersionNumbers[version]
+}
+
+// FHIRVersionOf returns the FHIR version of the definition: its fhirVersion
+// when valid, else DefaultFHIRVersion
+func (r *ResourceDefinition) FHIRVersionOf() string {
+	if version, ok := ParseFHIRVersion(r.FHIRVersion); ok {
+		return version
+	}
+	return DefaultFHIRVersion
+}
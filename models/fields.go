@@ -0,0 +1,104 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldNames returns the JSON field names of the object reached from a
+// ResourceDefinition by following the object keys in keys (array indices
+// left out), e.g. the Binding fields for ["elements", "binding"]. It
+// returns nil where the schema allows any key, such as in "meta" or
+// "example", or when keys leave the schema.
+func FieldNames(keys []string) []string {
+	t := reflect.TypeFor[ResourceDefinition]()
+	for _, key := range keys {
+		field, ok := jsonField(t, key)
+		if !ok {
+			return nil
+		}
+		t = field.Type
+	}
+	t = elemType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for i := range t.NumField() {
+		if name := jsonName(t.Field(i)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SuggestField returns the field of the object at keys (see FieldNames) a
+// name outside it is most likely a misspelling of, e.g. "cardinality" for
+// "cardinalty", or "" when the name is a field or not close to any
+func SuggestField(keys []string, name string) string {
+	return ClosestName(name, FieldNames(keys))
+}
+
+// ClosestName returns the name among names that name is most likely a
+// misspelling of, ignoring case, or "" when name is one of them or none is
+// close: within one edit, or two for names of six characters or more.
+// Names differing only in case are closest.
+func ClosestName(name string, names []string) string {
+	maxDistance := 1
+	if len(name) >= 6 {
+		maxDistance = 2
+	}
+	best, bestDistance := "", maxDistance+1
+	lower := strings.ToLower(name)
+	for _, known := range names {
+		if known == name {
+			return ""
+		}
+		if d := editDistance(lower, strings.ToLower(known)); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// jsonField returns the struct field of t (after slices and pointers)
+// decoded from key
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	t = elemType(t)
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := range t.NumField() {
+		if field := t.Field(i); jsonName(field) == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// elemType strips slices and pointers from t, e.g. []Element to Element
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Slice || t.Kind() == reflect.Pointer {
+		if t.Elem().Kind() == reflect.Uint8 {
+			break // json.RawMessage
+		}
+		t = t.Elem()
+	}
+	return t
+}
+
+// jsonName returns the JSON name of an exported field, or "" for fields
+// JSON skips
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ElementFilter selects which elements are kept when rendering a summary of
+// a large resource. Empty fields do not filter.
+type ElementFilter struct {
+	// Include keeps elements whose path (relative to the resource, e.g.
+	// "identifier.system") matches one of the patterns. A "*" segment matches
+	// any single name; a trailing "*" matches everything below the prefix.
+	Include []string
+	// ExcludeUsages drops elements, and everything below them, whose usage is listed
+	ExcludeUsages []string
+	// OnlyFlags keeps elements carrying at least one of the flags
+	OnlyFlags []string
+}
+
+// SummaryFilter keeps the elements flagged S (Σ), replicating the FHIR
+// specification's "Summary" view
+func SummaryFilter() ElementFilter {
+	return ElementFilter{OnlyFlags: []string{FlagSummary}}
+}
+
+// IsEmpty reports whether the filter keeps every element
+func (f ElementFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.ExcludeUsages) == 0 && len(f.OnlyFlags) == 0
+}
+
+// Filter returns a copy of the resource with only the elements selected by f.
+// Ancestors of selected elements are kept so the tree stays connected.
+func (r *ResourceDefinition) Filter(f ElementFilter) *ResourceDefinition {
+	filtered := *r
+	filtered.Elements = filterElements(r.Elements, nil, f)
+	filtered.Extensions = filterExtensions(r.Extensions, nil, f)
+	return &filtered
+}
+
+// filterElements filters a list of sibling elements below parentPath
+func filterElements(elements []Element, parentPath []string, f ElementFilter) []Element {
+	var kept []Element
+	for _, elem := range elements {
+		if slices.Contains(f.ExcludeUsages, elem.Usage) {
+			continue
+		}
+
+		path := append(slices.Clip(parentPath), elem.Name)
+		children := filterElements(elem.Elements, path, f)
+		extensions := filterExtensions(elem.Extensions, path, f)
+		if !f.selects(path, elem.Flags) && len(children) == 0 && len(extensions) == 0 {
+			continue
+		}
+
+		elem.Elements = children
+		elem.Extensions = extensions
+		kept = append(kept, elem)
+	}
+	return kept
+}
+
+// filterExtensions filters the extensions declared below parentPath.
+// Complex extensions are kept for their selected sub-extensions.
+func filterExtensions(extensions []Extension, parentPath []string, f ElementFilter) []Extension {
+	var kept []Extension
+	for _, ext := range extensions {
+		path := append(slices.Clip(parentPath), ext.Name)
+		subExtensions := filterExtensions(ext.Extensions, path, f)
+		if !f.selects(path, nil) && len(subExtensions) == 0 {
+			continue
+		}
+		ext.Extensions = subExtensions
+		kept = append(kept, ext)
+	}
+	return kept
+}
+
+// selects reports whether an element matches the include and flag criteria
+func (f ElementFilter) selects(path []string, flags []string) bool {
+	if len(f.Include) > 0 && !slices.ContainsFunc(f.Include, func(pattern string) bool {
+		return matchPath(strings.Split(pattern, "."), path)
+	}) {
+		return false
+	}
+	if len(f.OnlyFlags) > 0 && !slices.ContainsFunc(flags, func(flag string) bool {
+		return slices.Contains(f.OnlyFlags, flag)
+	}) {
+		return false
+	}
+	return true
+}
+
+// matchPath matches path segments against a dotted pattern split into segments
+func matchPath(pattern, path []string) bool {
+	for i, segment := range pattern {
+		if segment == "*" && i == len(pattern)-1 {
+			return len(path) >= len(pattern)
+		}
+		if i >= len(path) || (segment != "*" && segment != path[i]) {
+			return false
+		}
+	}
+	return len(path) == len(pattern)
+}
+
+// Truncate returns a copy of the resource with elements nested deeper than
+// maxDepth (top-level elements have depth 1) collapsed into a single
+// "… n more elements" placeholder per branch. maxDepth must be at least 1.
+func (r *ResourceDefinition) Truncate(maxDepth int) *ResourceDefinition {
+	truncated := *r
+	truncated.Elements = truncateElements(r.Elements, 1, maxDepth)
+	return &truncated
+}
+
+// truncateElements collapses the children of elements at maxDepth
+func truncateElements(elements []Element, depth, maxDepth int) []Element {
+	result := make([]Element, len(elements))
+	for i, elem := range elements {
+		if depth >= maxDepth {
+			if n := countElements(elem.Elements) + CountExtensions(elem.Extensions); n > 0 {
+				elem.Elements = []Element{truncationPlaceholder(n)}
+				elem.Extensions = nil
+			}
+		} else {
+			elem.Elements = truncateElements(elem.Elements, depth+1, maxDepth)
+		}
+		result[i] = elem
+	}
+	return result
+}
+
+// countElements counts elements and extensions in a subtree
+func countElements(elements []Element) int {
+	n := len(elements)
+	for _, elem := range elements {
+		n += countElements(elem.Elements) + CountExtensions(elem.Extensions)
+	}
+	return n
+}
+
+// CountExtensions counts extensions including their sub-extensions
+func CountExtensions(extensions []Extension) int {
+	n := len(extensions)
+	for _, ext := range extensions {
+		n += CountExtensions(ext.Extensions)
+	}
+	return n
+}
+
+// truncationPlaceholder creates the row standing in for n hidden elements
+func truncationPlaceholder(n int) Element {
+	name := fmt.Sprintf("\u2026 %d more elements", n)
+	if n == 1 {
+		name = "\u2026 1 more element"
+	}
+	return Element{Name: name, Usage: UsageTruncated}
+}
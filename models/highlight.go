@@ -0,0 +1,38 @@
+package models
+
+import (
+	"maps"
+	"slices"
+)
+
+// HighlightPalette names the light row tints an element highlight may use
+// instead of a hex color, e.g. to color-code elements by owning subsystem.
+// All keep body text at the WCAG AA contrast.
+var HighlightPalette = map[string]string{
+	"red":    "#FDE2E1",
+	"orange": "#FFE8CC",
+	"yellow": "#FFF6BF",
+	"green":  "#E3F4E1",
+	"teal":   "#DDF3F1",
+	"blue":   "#E0ECFB",
+	"purple": "#EEE4F7",
+	"pink":   "#FCE4EF",
+	"grey":   "#EEEEEE",
+}
+
+// HighlightNames returns the names of HighlightPalette, sorted
+func HighlightNames() []string {
+	return slices.Sorted(maps.Keys(HighlightPalette))
+}
+
+// HighlightColor returns the row tint of an element highlight, a palette
+// name or a #RGB or #RRGGBB color; it reports false for anything else
+func HighlightColor(highlight string) (string, bool) {
+	if color, ok := HighlightPalette[highlight]; ok {
+		return color, true
+	}
+	if IsHexColor(highlight) {
+		return highlight, true
+	}
+	return "", false
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// SafeLinkSchemes are the URL schemes a definition may link to. Other
+// schemes, such as javascript: or data:, can run script when a link in a
+// hosted SVG is followed.
+var SafeLinkSchemes = []string{"http", "https", "mailto"}
+
+// LinkScheme returns the lowercase scheme of a URL as a browser reads it,
+// ignoring the tabs, line breaks and leading control characters browsers
+// strip, or "" for relative URLs
+func LinkScheme(url string) string {
+	url = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, url)
+	url = strings.TrimLeft(url, "\x00\x01\x02\x03\x04\x05\x06\x07\x08\x0b\x0c\x0e\x0f\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19\x1a\x1b\x1c\x1d\x1e\x1f ")
+	if i := strings.IndexAny(url, ":/?#"); i > 0 && url[i] == ':' {
+		return strings.ToLower(url[:i])
+	}
+	return ""
+}
+
+// IsSafeLink reports whether url is relative or uses one of SafeLinkSchemes
+func IsSafeLink(url string) bool {
+	scheme := LinkScheme(url)
+	return scheme == "" || slices.Contains(SafeLinkSchemes, scheme)
+}
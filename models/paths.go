@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ImplicitParentType is the type of the parents NestPaths creates for paths
+// whose parent is not listed
+const ImplicitParentType = "BackboneElement"
+
+// NestPaths rebuilds the element tree from top-level elements given with a
+// dotted path instead of nesting, e.g. {"path": "Patient.contact.name"}. The
+// first segment is the resource name or type, the last one the element name
+// unless Name is set. Parents may be listed after their children; missing
+// ones are created as BackboneElements. Elements without a path stay at the
+// top level, and definitions without paths are left unchanged.
+func (r *ResourceDefinition) NestPaths() error {
+	if !slices.ContainsFunc(r.Elements, func(e Element) bool { return e.Path != "" }) {
+		return nil
+	}
+
+	var root Element
+	listed := map[string]bool{}
+	for i, elem := range r.Elements {
+		if elem.Path == "" {
+			root.Elements = append(root.Elements, elem)
+			continue
+		}
+		segments := strings.Split(elem.Path, ".")
+		if len(segments) < 2 || slices.Contains(segments, "") {
+			return fmt.Errorf("elements[%d]: invalid path %q", i, elem.Path)
+		}
+		if segments[0] != r.Name && segments[0] != r.Type {
+			return fmt.Errorf("elements[%d]: path %q does not start with %s or %s", i, elem.Path, r.Name, r.Type)
+		}
+		relative := strings.Join(segments[1:], ".")
+		if listed[relative] {
+			return fmt.Errorf("elements[%d]: duplicate path %q", i, elem.Path)
+		}
+		listed[relative] = true
+
+		parent := &root
+		for _, name := range segments[1 : len(segments)-1] {
+			parent = parent.child(name)
+		}
+		if elem.Name == "" {
+			elem.Name = segments[len(segments)-1]
+		}
+		elem.Path = ""
+		target := parent.child(segments[len(segments)-1])
+		// Keep the children of a parent created before it was listed
+		elem.Elements = append(elem.Elements, target.Elements...)
+		*target = elem
+	}
+	r.Elements = root.Elements
+	return nil
+}
+
+// child returns the last child named name, appending an implicit parent when
+// there is none
+func (e *Element) child(name string) *Element {
+	for i := len(e.Elements) - 1; i >= 0; i-- {
+		if e.Elements[i].Name == name {
+			return &e.Elements[i]
+		}
+	}
+	e.Elements = append(e.Elements, Element{Name: name, Type: ImplicitParentType})
+	return &e.Elements[len(e.Elements)-1]
+}
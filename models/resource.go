@@ -1,19 +1,46 @@
 package models
 
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
 // ResourceDefinition represents a FHIR resource definition with its elements
 type ResourceDefinition struct {
 	ResourceType string      `json:"resourceType,omitempty"`
 	Name         string      `json:"name"`
+	Version      string      `json:"version,omitempty"`
+	Status       string      `json:"status,omitempty"`    // "draft", "active", "retired" or "unknown"
+	Publisher    string      `json:"publisher,omitempty"` // Organization or individual that published the definition
+	Date         string      `json:"date,omitempty"`      // Publication date, e.g. "2024-05-01"
 	Flags        []string    `json:"flags,omitempty"`
 	Type         string      `json:"type"`
 	Description  string      `json:"description,omitempty"`
 	Elements     []Element   `json:"elements,omitempty"`
 	Extensions   []Extension `json:"extensions,omitempty"`
+
+	// FHIRVersion selects the FHIR release whose types and specification
+	// pages apply: "STU3", "R4" or "R5", or a version number such as
+	// "4.0.1"; empty for DefaultFHIRVersion
+	FHIRVersion string `json:"fhirVersion,omitempty"`
+
+	// Annotations tint rows and add margin notes for design reviews
+	Annotations []Annotation `json:"annotations,omitempty"`
+
+	// Example is an instance of the definition whose values are shown in
+	// a sample value column, matched by path
+	Example json.RawMessage `json:"example,omitempty"`
+
+	// Schema names the JSON Schema of the document for editors, e.g.
+	// "/schema/1.0.0.json"; it is not rendered
+	Schema string `json:"$schema,omitempty"`
 }
 
 // Element represents a single element/field in the resource definition
 type Element struct {
-	Name        string      `json:"name"`
+	Name        string      `json:"name,omitempty"` // Required unless Path is set
+	Path        string      `json:"path,omitempty"` // Dotted path in flat input, nested by NestPaths
 	Flags       []string    `json:"flags,omitempty"`
 	Cardinality string      `json:"cardinality,omitempty"`
 	Type        string      `json:"type"`
@@ -22,8 +49,24 @@ type Element struct {
 	Usage       string      `json:"usage,omitempty"`       // "used", "not-used", "todo", "optional"
 	Notes       string      `json:"notes,omitempty"`       // Custom implementation notes
 	Binding     *Binding    `json:"binding,omitempty"`     // Value set binding
+	Fixed       string      `json:"fixed,omitempty"`       // Value the element must have exactly
+	Pattern     string      `json:"pattern,omitempty"`     // Value the element must match
+	Targets     []Target    `json:"targets,omitempty"`     // Reference target types
+	Mappings    []Mapping   `json:"mappings,omitempty"`    // Mappings to other specifications
 	Elements    []Element   `json:"elements,omitempty"`    // Nested child elements
 	Extensions  []Extension `json:"extensions,omitempty"`  // Extensions on this element
+
+	// Meta holds free-form values shown in the configured extra columns,
+	// keyed by column key, e.g. {"owner": "Team A"}
+	Meta map[string]string `json:"meta,omitempty"`
+
+	// Highlight tints the row, e.g. by owning subsystem: a name from
+	// HighlightPalette or a #RGB or #RRGGBB color
+	Highlight string `json:"highlight,omitempty"`
+
+	// Comparison against a base definition (see convert.Compare)
+	Change          string `json:"change,omitempty"`          // "added", "tightened" or "removed"
+	BaseCardinality string `json:"baseCardinality,omitempty"` // Cardinality in the base definition
 }
 
 // Binding represents a value set binding for coded elements
@@ -31,25 +74,89 @@ type Binding struct {
 	Strength string `json:"strength,omitempty"` // "required", "extensible", "preferred", "example"
 	ValueSet string `json:"valueSet,omitempty"` // Value set URL or pipe-delimited values
 	URL      string `json:"url,omitempty"`      // Link to value set documentation
+
+	// Codes lists allowed codes shown below the description, e.g. from
+	// expanding ValueSet against a terminology server; Truncated marks
+	// that the value set holds more codes than listed
+	Codes     []string `json:"codes,omitempty"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// Target represents an allowed target type of a Reference element
+type Target struct {
+	Type string `json:"type"`          // Target resource type, e.g. "Patient"
+	URL  string `json:"url,omitempty"` // Link to the target documentation
+}
+
+// Mapping relates an element to another specification, like the mapping
+// tabs of the FHIR specification
+type Mapping struct {
+	Identity string `json:"identity"` // Target specification, e.g. "v2"
+	Map      string `json:"map"`      // Mapping expression, e.g. "PID-3"
+}
+
+// DisplayType returns the type as shown in the diagram. Reference elements
+// with targets are shown as "Reference(Patient | Practitioner)".
+func (e Element) DisplayType() string {
+	if len(e.Targets) == 0 {
+		return e.Type
+	}
+
+	base, _, _ := strings.Cut(e.Type, "(")
+	base = strings.TrimSpace(base)
+	if base == "" {
+		base = "Reference"
+	}
+	names := make([]string, len(e.Targets))
+	for i, t := range e.Targets {
+		names[i] = t.Type
+	}
+	return base + "(" + strings.Join(names, " | ") + ")"
 }
 
 // Extension represents a FHIR extension definition
 type Extension struct {
-	Name        string `json:"name"`
-	URL         string `json:"url"`
-	Context     string `json:"context,omitempty"`     // Where extension can be used
-	Type        string `json:"type"`
-	Cardinality string `json:"cardinality,omitempty"` // Cardinality like "0..1"
-	Description string `json:"description,omitempty"`
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Context     string   `json:"context,omitempty"` // Where extension can be used
+	Type        string   `json:"type"`
+	Cardinality string   `json:"cardinality,omitempty"` // Cardinality like "0..1"
+	Flags       []string `json:"flags,omitempty"`
+	Description string   `json:"description,omitempty"`
+
+	// Extensions are the sub-extensions of a complex extension (its
+	// extension.extension slices), e.g. "city" and "country" of a birth
+	// place. Their url may be the bare slice name.
+	Extensions []Extension `json:"extensions,omitempty"`
+}
+
+// element returns the row element of an extension
+func (e Extension) element() Element {
+	return Element{
+		Name:        e.Name,
+		Flags:       e.Flags,
+		Type:        e.Type,
+		Cardinality: e.Cardinality,
+		Description: e.Description,
+	}
 }
 
 // Flag constants for FHIR element flags
 const (
-	FlagSummary    = "S"   // Σ - Summary element
-	FlagModifier   = "?!"  // Modifier element
-	FlagConstraint = "I"   // Has constraint
-	FlagTrialUse   = "TU"  // Trial use
-	FlagNormative  = "N"   // Normative
+	FlagSummary     = "S"   // Σ - Summary element
+	FlagModifier    = "?!"  // Modifier element
+	FlagConstraint  = "I"   // Has constraint
+	FlagTrialUse    = "TU"  // Trial use
+	FlagNormative   = "N"   // Normative
+	FlagMustSupport = "MS"  // Must support
+)
+
+// Publication status constants, as in FHIR's PublicationStatus
+const (
+	StatusDraft   = "draft"
+	StatusActive  = "active"
+	StatusRetired = "retired"
+	StatusUnknown = "unknown"
 )
 
 // Usage constants
@@ -58,8 +165,25 @@ const (
 	UsageNotUsed = "not-used"
 	UsageTodo    = "todo"
 	UsageOptional = "optional"
+
+	// UsageTruncated marks the placeholder rows inserted by Truncate
+	UsageTruncated = "truncated"
+)
+
+// Change constants for profile-vs-base comparisons
+const (
+	ChangeAdded     = "added"     // Slice or element not in the base
+	ChangeTightened = "tightened" // Narrower cardinality than the base
+	ChangeRemoved   = "removed"   // Prohibited (max 0) by the profile
 )
 
+// HasChanges reports whether any element carries a comparison change
+func (r *ResourceDefinition) HasChanges() bool {
+	return slices.ContainsFunc(r.Flatten(), func(fe FlatElement) bool {
+		return fe.Element.Change != ""
+	})
+}
+
 // FlatElement represents a flattened element with depth info for rendering
 type FlatElement struct {
 	Element     Element
@@ -67,11 +191,21 @@ type FlatElement struct {
 	IsLast      bool     // Is this the last child at its depth
 	ParentLasts []bool   // Track if ancestors were last children (for tree lines)
 	Path        string   // Full path like "participant.type"
+
+	// Annotation is the review annotation matching Path, or nil
+	Annotation *Annotation
+
+	// Sample is the value of the example instance at Path, or ""
+	Sample string
+
+	// Extension is the extension the row shows, or nil for elements
+	Extension *Extension
 }
 
 // Flatten recursively flattens the element hierarchy for rendering
 func (r *ResourceDefinition) Flatten() []FlatElement {
-	var result []FlatElement
+	// One row for the root, each element and each extension
+	result := make([]FlatElement, 0, 1+countElements(r.Elements)+CountExtensions(r.Extensions))
 
 	// Add root element
 	rootElement := Element{
@@ -90,22 +224,21 @@ func (r *ResourceDefinition) Flatten() []FlatElement {
 
 	// Flatten children
 	flattenElements(r.Elements, 1, &result, []bool{}, r.Name, false)
+	r.annotate(result)
+	r.sample(result)
 
 	// Add extensions at the end
 	for i, ext := range r.Extensions {
-		extElement := Element{
-			Name:        ext.Name,
-			Type:        ext.Type,
-			Description: ext.Description,
-		}
 		isLast := i == len(r.Extensions)-1
 		result = append(result, FlatElement{
-			Element:     extElement,
+			Element:     ext.element(),
 			Depth:       1,
 			IsLast:      isLast,
 			ParentLasts: []bool{len(r.Elements) == 0},
 			Path:        ext.Context,
+			Extension:   &r.Extensions[i],
 		})
+		flattenExtensions(ext.Extensions, 2, &result, []bool{isLast}, r.Name+"."+ext.Name)
 	}
 
 	return result
@@ -134,20 +267,34 @@ func flattenElements(elements []Element, depth int, result *[]FlatElement, paren
 
 		// Add extensions nested under this element
 		for j, ext := range elem.Extensions {
-			extElement := Element{
-				Name:        ext.Name,
-				Type:        ext.Type,
-				Cardinality: ext.Cardinality,
-				Description: ext.Description,
-			}
 			extIsLast := j == len(elem.Extensions)-1 && isLast
 			*result = append(*result, FlatElement{
-				Element:     extElement,
+				Element:     ext.element(),
 				Depth:       depth + 1,
 				IsLast:      extIsLast,
 				ParentLasts: newParentLasts,
 				Path:        path + "." + ext.Name,
+				Extension:   &elem.Extensions[j],
 			})
+			flattenExtensions(ext.Extensions, depth+2, result, append(slices.Clip(newParentLasts), extIsLast), path+"."+ext.Name)
 		}
 	}
 }
+
+// flattenExtensions adds the sub-extensions of a complex extension below
+// its row, recursively
+func flattenExtensions(extensions []Extension, depth int, result *[]FlatElement, parentLasts []bool, parentPath string) {
+	for i, ext := range extensions {
+		isLast := i == len(extensions)-1
+		path := parentPath + "." + ext.Name
+		*result = append(*result, FlatElement{
+			Element:     ext.element(),
+			Depth:       depth,
+			IsLast:      isLast,
+			ParentLasts: parentLasts,
+			Path:        path,
+			Extension:   &extensions[i],
+		})
+		flattenExtensions(ext.Extensions, depth+1, result, append(slices.Clip(parentLasts), isLast), path)
+	}
+}
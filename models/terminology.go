@@ -0,0 +1,63 @@
+package models
+
+import "slices"
+
+// TerminologyTable is the content of a FHIR ValueSet or CodeSystem, rendered
+// as a code/display/definition table
+type TerminologyTable struct {
+	ResourceType string    `json:"resourceType"` // "ValueSet" or "CodeSystem"
+	Name         string    `json:"name"`
+	Version      string    `json:"version,omitempty"`
+	URL          string    `json:"url,omitempty"`
+	Concepts     []Concept `json:"concepts"`
+}
+
+// Concept is a code with its nested concepts. Group rows (such as the code
+// systems of a ValueSet compose) have no code of their own.
+type Concept struct {
+	Code       string    `json:"code,omitempty"`
+	System     string    `json:"system,omitempty"`
+	Display    string    `json:"display,omitempty"`
+	Definition string    `json:"definition,omitempty"`
+	Inactive   bool      `json:"inactive,omitempty"`
+	Abstract   bool      `json:"abstract,omitempty"`
+	Group      bool      `json:"group,omitempty"`
+	Concepts   []Concept `json:"concepts,omitempty"`
+}
+
+// FlatConcept is a concept with its position in the hierarchy
+type FlatConcept struct {
+	Concept     Concept
+	Depth       int
+	IsLast      bool   // Is this the last child at its depth
+	ParentLasts []bool // Track if ancestors were last children (for tree lines)
+}
+
+// Flatten returns the concepts in display order. Top-level concepts have
+// depth 0.
+func (t *TerminologyTable) Flatten() []FlatConcept {
+	var result []FlatConcept
+	flattenConcepts(t.Concepts, 0, []bool{}, &result)
+	return result
+}
+
+func flattenConcepts(concepts []Concept, depth int, parentLasts []bool, result *[]FlatConcept) {
+	for i, concept := range concepts {
+		isLast := i == len(concepts)-1
+		*result = append(*result, FlatConcept{
+			Concept:     concept,
+			Depth:       depth,
+			IsLast:      isLast,
+			ParentLasts: parentLasts,
+		})
+		if len(concept.Concepts) == 0 {
+			continue
+		}
+		// Top-level concepts draw no connector, so there is no line to continue
+		childLasts := parentLasts
+		if depth > 0 {
+			childLasts = append(slices.Clip(parentLasts), isLast)
+		}
+		flattenConcepts(concept.Concepts, depth+1, childLasts, result)
+	}
+}
@@ -0,0 +1,67 @@
+package renderer
+
+import (
+	"fmt"
+
+	"fhir_renderer/models"
+)
+
+// ColumnAnnotations is the margin column holding annotation notes. It is
+// appended automatically when a definition has annotations with notes and
+// cannot be selected via Columns.
+const ColumnAnnotations = "annotations"
+
+// Annotation styling
+const (
+	// DefaultAnnotationColor tints annotated rows that set no valid color
+	DefaultAnnotationColor = "#FFF3CD"
+
+	// AnnotationColWidth is the width of the margin note column
+	AnnotationColWidth = 200.0
+
+	// AnnotationMarkerWidth is the colored bar in front of a margin note
+	AnnotationMarkerWidth = 3.0
+)
+
+// annotationColor returns the row tint of an annotation
+func annotationColor(a *models.Annotation) string {
+	if models.IsHexColor(a.Color) {
+		return a.Color
+	}
+	return DefaultAnnotationColor
+}
+
+// renderAnnotationColumn renders the margin note of an annotated row behind
+// a marker bar
+func renderAnnotationColumn(row RowData, x, y, baseTextY float64, config SVGConfig) *node {
+	if len(row.NoteLines) == 0 {
+		return nil
+	}
+	group := newNode("g").attr("clip-path", "url(#clip-"+ColumnAnnotations+")").append(
+		newNode("rect").fixed("x", x, 0).fixed("y", y+RowTopMargin, 0).fixed("width", AnnotationMarkerWidth, 0).
+			fixed("height", row.RowHeight-RowTopMargin-RowBottomMargin, 0).attr("fill", config.TextColor),
+	)
+	for i, line := range row.NoteLines {
+		lineY := baseTextY + float64(i)*config.LineHeight
+		group.append(newNode("text").fixed("x", x+config.Padding, 0).fixed("y", lineY, 0).attr("class", "cell-text").
+			attr("font-style", "italic").content(line))
+	}
+	return group
+}
+
+// htmlAnnotationStyle returns the row style of an annotated HTML row, or ""
+func htmlAnnotationStyle(fe models.FlatElement) string {
+	if fe.Annotation == nil {
+		return ""
+	}
+	return fmt.Sprintf(` style="background: %s"`, annotationColor(fe.Annotation))
+}
+
+// htmlAnnotationCell renders the margin note cell of an HTML row
+func htmlAnnotationCell(fe models.FlatElement) string {
+	if fe.Annotation == nil || fe.Annotation.Note == "" {
+		return "<td></td>"
+	}
+	return fmt.Sprintf(`<td style="font-style: italic; border-left: %.0fpx solid currentColor;">%s</td>`,
+		AnnotationMarkerWidth, escapeXML(fe.Annotation.Note))
+}
@@ -0,0 +1,260 @@
+package renderer
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"fhir_renderer/models"
+)
+
+// Run "make bench" to print the benchmarks and "make bench-check" to compare
+// them against testdata/bench_baseline.json. After an intended slowdown or
+// speedup, refresh the thresholds with "make bench-baseline".
+var (
+	checkBaseline  = flag.Bool("baseline", false, "compare benchmarks against testdata/bench_baseline.json")
+	updateBaseline = flag.Bool("update-baseline", false, "rewrite testdata/bench_baseline.json from this machine")
+)
+
+const baselinePath = "testdata/bench_baseline.json"
+
+// Headroom applied when recording thresholds. Allocations are stable across
+// machines; timings are not, so they get a wider margin.
+const (
+	timeHeadroom  = 2.0
+	allocHeadroom = 1.1
+)
+
+// benchSizes names the fixtures: the editor example and 10 and 100 copies
+// of it nested under backbone elements
+var benchSizes = []struct {
+	name   string
+	copies int
+}{
+	{"small", 1},
+	{"medium", 10},
+	{"huge", 100},
+}
+
+// benchResource loads handlers/example.json and repeats its elements copies
+// times; a single copy returns the example unchanged
+func benchResource(tb testing.TB, copies int) *models.ResourceDefinition {
+	tb.Helper()
+	data, err := os.ReadFile("../handlers/example.json")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var resource models.ResourceDefinition
+	if err := json.Unmarshal(data, &resource); err != nil {
+		tb.Fatal(err)
+	}
+	if copies == 1 {
+		return &resource
+	}
+
+	elements := make([]models.Element, copies)
+	for i := range elements {
+		elements[i] = models.Element{
+			Name:        fmt.Sprintf("group%d", i+1),
+			Cardinality: "0..*",
+			Type:        "BackboneElement",
+			Description: "Repeated copy of the example elements",
+			Elements:    resource.Elements,
+		}
+	}
+	resource.Elements = elements
+	return &resource
+}
+
+// benchMeasurer returns the measurer of the default config
+func benchMeasurer(tb testing.TB) (*TextMeasurer, SVGConfig) {
+	tb.Helper()
+	config := DefaultConfig()
+	tm, err := newTextMeasurer(config)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(tm.Close)
+	config.textMeasurer = tm
+	return tm, config
+}
+
+// benchCase is one measured operation, named "<group>/<size>"
+type benchCase struct {
+	name string
+	run  func(b *testing.B)
+}
+
+// flattenCases flattens each fixture
+func flattenCases(tb testing.TB) []benchCase {
+	var cases []benchCase
+	for _, size := range benchSizes {
+		resource := benchResource(tb, size.copies)
+		cases = append(cases, benchCase{"Flatten/" + size.name, func(b *testing.B) {
+			for b.Loop() {
+				resource.Flatten()
+			}
+		}})
+	}
+	return cases
+}
+
+// wrapTextCases wraps a short and a long description to the column width
+func wrapTextCases(tb testing.TB) []benchCase {
+	tm, config := benchMeasurer(tb)
+	maxWidth := config.DescriptionColWidth - config.Padding*2
+	texts := []struct{ name, text string }{
+		{"short", "Identifies this patient across multiple systems"},
+		{"long", strings.Repeat("A name associated with the individual, used for display and matching. ", 20)},
+	}
+	var cases []benchCase
+	for _, text := range texts {
+		cases = append(cases, benchCase{"WrapText/" + text.name, func(b *testing.B) {
+			for b.Loop() {
+				tm.WrapText(text.text, maxWidth)
+			}
+		}})
+	}
+	return cases
+}
+
+// prepareRowsCases measures and wraps the rows of each fixture
+func prepareRowsCases(tb testing.TB) []benchCase {
+	tm, config := benchMeasurer(tb)
+	var cases []benchCase
+	for _, size := range benchSizes {
+		resource := benchResource(tb, size.copies)
+		flat := resource.Flatten()
+		rowConfig := config
+		rowConfig.NameColWidth = calculateNameColumnWidth(resource.Name, flat, tm, config)
+		cases = append(cases, benchCase{"PrepareRows/" + size.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := prepareRows(context.Background(), flat, tm, rowConfig); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}})
+	}
+	return cases
+}
+
+// renderCases renders each fixture to SVG, discarding the output
+func renderCases(tb testing.TB) []benchCase {
+	config := DefaultConfig()
+	var cases []benchCase
+	for _, size := range benchSizes {
+		resource := benchResource(tb, size.copies)
+		cases = append(cases, benchCase{"Render/" + size.name, func(b *testing.B) {
+			for b.Loop() {
+				if err := RenderTo(io.Discard, resource, config); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}})
+	}
+	return cases
+}
+
+// runCases runs each case as a sub-benchmark named after its size
+func runCases(b *testing.B, cases []benchCase) {
+	for _, bc := range cases {
+		_, name, _ := strings.Cut(bc.name, "/")
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			bc.run(b)
+		})
+	}
+}
+
+func BenchmarkFlatten(b *testing.B)     { runCases(b, flattenCases(b)) }
+func BenchmarkWrapText(b *testing.B)    { runCases(b, wrapTextCases(b)) }
+func BenchmarkPrepareRows(b *testing.B) { runCases(b, prepareRowsCases(b)) }
+func BenchmarkRender(b *testing.B)      { runCases(b, renderCases(b)) }
+
+// baseline holds the upper limits for one benchmark
+type baseline struct {
+	NsPerOp     int64 `json:"nsPerOp"`
+	AllocsPerOp int64 `json:"allocsPerOp"`
+	BytesPerOp  int64 `json:"bytesPerOp"`
+}
+
+// TestBenchmarkBaseline fails when a benchmark exceeds its recorded
+// threshold. It only runs with -baseline or -update-baseline since timings
+// depend on the machine.
+func TestBenchmarkBaseline(t *testing.T) {
+	if !*checkBaseline && !*updateBaseline {
+		t.Skip("run with -baseline to compare against " + baselinePath)
+	}
+
+	var cases []benchCase
+	for _, group := range []func(testing.TB) []benchCase{flattenCases, wrapTextCases, prepareRowsCases, renderCases} {
+		cases = append(cases, group(t)...)
+	}
+	measured := make(map[string]baseline, len(cases))
+	for _, bc := range cases {
+		result := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			bc.run(b)
+		})
+		measured[bc.name] = baseline{
+			NsPerOp:     result.NsPerOp(),
+			AllocsPerOp: result.AllocsPerOp(),
+			BytesPerOp:  result.AllocedBytesPerOp(),
+		}
+	}
+
+	if *updateBaseline {
+		thresholds := make(map[string]baseline, len(measured))
+		for name, m := range measured {
+			thresholds[name] = baseline{
+				NsPerOp:     int64(float64(m.NsPerOp) * timeHeadroom),
+				AllocsPerOp: int64(float64(m.AllocsPerOp)*allocHeadroom) + 1,
+				BytesPerOp:  int64(float64(m.BytesPerOp) * allocHeadroom),
+			}
+		}
+		data, err := json.MarshalIndent(thresholds, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(baselinePath, append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(baselinePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var thresholds map[string]baseline
+	if err := json.Unmarshal(data, &thresholds); err != nil {
+		t.Fatalf("%s: %v", baselinePath, err)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(measured)) {
+		m := measured[name]
+		limit, ok := thresholds[name]
+		if !ok {
+			t.Errorf("%s: no baseline; run make bench-baseline", name)
+			continue
+		}
+		t.Logf("%s: %d ns/op (max %d), %d allocs/op (max %d), %d B/op (max %d)",
+			name, m.NsPerOp, limit.NsPerOp, m.AllocsPerOp, limit.AllocsPerOp, m.BytesPerOp, limit.BytesPerOp)
+		if m.NsPerOp > limit.NsPerOp {
+			t.Errorf("%s: %d ns/op exceeds baseline %d", name, m.NsPerOp, limit.NsPerOp)
+		}
+		if m.AllocsPerOp > limit.AllocsPerOp {
+			t.Errorf("%s: %d allocs/op exceeds baseline %d", name, m.AllocsPerOp, limit.AllocsPerOp)
+		}
+		if m.BytesPerOp > limit.BytesPerOp {
+			t.Errorf("%s: %d B/op exceeds baseline %d", name, m.BytesPerOp, limit.BytesPerOp)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package renderer
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Branding attributes diagrams to an organization: a logo and name in the
+// title bar and a repository link with the GitHub icon in the footer. Empty
+// fields are left out.
+type Branding struct {
+	Logo    string // http(s) URL or data:image/...;base64 URI
+	OrgName string
+	OrgURL  string // Link of the logo and name
+	RepoURL string // Repository of the definitions, e.g. https://github.com/org/ig
+}
+
+// Branding layout
+const (
+	BrandingLogoSize = 20.0
+	BrandingGap      = 6.0
+)
+
+// dataImagePattern matches the base64 image data URIs accepted as logos
+var dataImagePattern = regexp.MustCompile(`^data:image/(png|jpeg|gif|webp|svg\+xml);base64,[A-Za-z0-9+/]+=*$`)
+
+// defaultBranding is the Branding of DefaultConfig; see SetDefaultBranding
+var defaultBranding Branding
+
+// SetDefaultBranding sets the branding DefaultConfig uses. It must pass
+// ValidateBranding.
+func SetDefaultBranding(b Branding) {
+	defaultBranding = b
+}
+
+// ValidateBranding checks that the logo is an http(s) URL or a base64 image
+// data URI and that the links are http(s) URLs
+func ValidateBranding(b Branding) error {
+	if b.Logo != "" && !dataImagePattern.MatchString(b.Logo) && !isHTTPURL(b.Logo) {
+		return fmt.Errorf("logo must be an http(s) URL or a base64 image data URI")
+	}
+	if b.OrgURL != "" && !isHTTPURL(b.OrgURL) {
+		return fmt.Errorf("invalid organization URL %q", b.OrgURL)
+	}
+	if b.RepoURL != "" && !isHTTPURL(b.RepoURL) {
+		return fmt.Errorf("invalid repository URL %q", b.RepoURL)
+	}
+	return nil
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// buildTitleBarExtras draws the right side of a structure title bar at y:
+// the status badge and, to its left, the branding logo and organization name
+func buildTitleBarExtras(status string, y, totalWidth float64, config SVGConfig) string {
+	right := totalWidth - config.Padding
+	badge := buildStatusBadge(status, y, totalWidth, config)
+	if label := statusLabel(status, config); label != "" {
+		right -= statusBadgeWidth(label, config) + BrandingGap
+	}
+	return buildBranding(y, right, config) + badge
+}
+
+// buildBranding draws the logo and organization name right-aligned at right,
+// linked to the organization URL when one is set
+func buildBranding(y, right float64, config SVGConfig) string {
+	b := config.Branding
+	if b.Logo == "" && b.OrgName == "" {
+		return ""
+	}
+
+	group := newNode("g").attr("class", "branding")
+	if b.OrgURL != "" {
+		group = newNode("a").attr("class", "branding").attr("xlink:href", b.OrgURL).attr("target", "_blank")
+	}
+	x := right
+	if b.OrgName != "" {
+		x -= config.textMeasurer.MeasureString(b.OrgName)
+		group.append(newNode("text").fixed("x", x, 1).fixed("y", y+config.TitleHeight/2+TextVerticalOffset, 1).
+			attr("class", "cell-text").content(b.OrgName))
+		x -= BrandingGap
+	}
+	if b.Logo != "" {
+		x -= BrandingLogoSize
+		group.append(newNode("image").fixed("x", x, 1).fixed("y", y+(config.TitleHeight-BrandingLogoSize)/2, 1).
+			fixed("width", BrandingLogoSize, 0).fixed("height", BrandingLogoSize, 0).
+			attr("href", b.Logo).attr("xlink:href", b.Logo).attr("preserveAspectRatio", "xMidYMid meet"))
+	}
+	return group.String()
+}
+
+// buildRepoLink draws the GitHub icon and repository link at the left of the
+// footer, or returns "" when no repository is configured
+func buildRepoLink(footerY float64, config SVGConfig) string {
+	repo := config.Branding.RepoURL
+	if repo == "" {
+		return ""
+	}
+	iconSize := 12.0
+	textY := footerY + FooterHeight/2 + 3 // Vertically centered text
+	label := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(repo, "https://"), "http://"), "/")
+
+	return footerLink(repo,
+		rawNode(RenderGitHubIcon(config.Padding, textY-iconSize+2, iconSize, config.LinkColor)),
+		footerText(label, config.Padding+iconSize+4, textY, config).attr("style", "cursor: pointer;"),
+	).String()
+}
+
+// htmlBranding returns the logo and organization name for the HTML caption,
+// or ""
+func htmlBranding(config SVGConfig) string {
+	b := config.Branding
+	if b.Logo == "" && b.OrgName == "" {
+		return ""
+	}
+	var sb strings.Builder
+	if b.Logo != "" {
+		sb.WriteString(fmt.Sprintf(`<img src="%s" alt="" height="%.0f" style="vertical-align: middle; margin-right: 6px;">`, escapeXML(b.Logo), BrandingLogoSize))
+	}
+	sb.WriteString(escapeXML(b.OrgName))
+	content := sb.String()
+	if b.OrgURL != "" {
+		content = fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener">%s</a>`, escapeXML(b.OrgURL), content)
+	}
+	return `<span class="branding" style="float: right; font-weight: normal;">` + content + `</span>`
+}
+
+// htmlRepoLink returns the repository link below the HTML table, or ""
+func htmlRepoLink(config SVGConfig) string {
+	repo := config.Branding.RepoURL
+	if repo == "" {
+		return ""
+	}
+	return fmt.Sprintf("<p class=\"repository\"><a href=\"%s\" target=\"_blank\" rel=\"noopener\">%s</a></p>\n", escapeXML(repo), escapeXML(repo))
+}
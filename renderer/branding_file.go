@@ -0,0 +1,32 @@
+//go:build !js
+
+package renderer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadLogoFile reads an image file and returns it as a base64 data URI for
+// Branding.Logo
+func LoadLogoFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	contentType, _, _ = strings.Cut(contentType, ";")
+	logo := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	if !dataImagePattern.MatchString(logo) {
+		return "", fmt.Errorf("%s: unsupported image type %s", path, contentType)
+	}
+	return logo, nil
+}
@@ -0,0 +1,230 @@
+package renderer
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// Capability matrix layout
+const (
+	CapabilitySearchColWidth = 320.0
+	CapabilityMinTypeWidth   = 140.0
+	CapabilityCheckMark      = "✓"
+)
+
+// tableColumn is a titled column of the matrix and terminology tables
+type tableColumn struct {
+	title string
+	width float64
+}
+
+// capabilityRow is a resource type with its wrapped search parameters
+type capabilityRow struct {
+	resource    models.CapabilityResource
+	searchLines []string
+	height      float64
+}
+
+// RenderCapabilityContext renders a CapabilityStatement's REST capability as
+// a matrix of resource types by interactions, with the supported search
+// parameters in the last column. Each rest entry gets its own section.
+func RenderCapabilityContext(ctx context.Context, matrix *models.CapabilityMatrix, config SVGConfig) (string, error) {
+	tm, err := newTextMeasurer(config)
+	if err != nil {
+		return "", err
+	}
+	defer tm.Close()
+	config.textMeasurer = tm
+	if version, ok := models.ParseFHIRVersion(matrix.FHIRVersion); ok {
+		config.fhirVersion = version
+	}
+
+	columns := capabilityColumns(matrix, tm, config)
+	totalWidth := 0.0
+	for _, col := range columns {
+		totalWidth += col.width
+	}
+
+	// Lay out every section before writing so the total height is known
+	sections := make([][]capabilityRow, len(matrix.Rest))
+	contentHeight := 0.0
+	for i, rest := range matrix.Rest {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		for _, res := range rest.Resources {
+			row := capabilityRow{
+				resource:    res,
+				searchLines: tm.WrapText(strings.Join(res.SearchParams, ", "), CapabilitySearchColWidth-config.Padding*2-FontRenderingBuffer),
+			}
+			row.height = max(config.MinRowHeight, RowTopMargin+float64(len(row.searchLines))*config.LineHeight+RowBottomMargin)
+			sections[i] = append(sections[i], row)
+			contentHeight += row.height
+		}
+		contentHeight += config.TitleHeight + config.HeaderHeight + systemRowHeight(rest, config)
+		if i > 0 {
+			contentHeight += config.CompositeSpacing
+		}
+	}
+	footerY := contentHeight
+	totalHeight := footerY + FooterHeight + SVGHeightPadding
+
+	var sb strings.Builder
+	sb.WriteString(buildSVGHeader(totalWidth, totalHeight, capabilityTitle(matrix, models.CapabilityRest{}), capabilitySummary(matrix), false, config))
+
+	y := 0.0
+	for i, rest := range matrix.Rest {
+		sb.WriteString(buildTitleBar(capabilityTitle(matrix, rest), y, totalWidth, config))
+		sb.WriteString(buildBranding(y, totalWidth-config.Padding, config))
+		y += config.TitleHeight
+		sb.WriteString(renderTableHeader(columns, y, totalWidth, config))
+		y += config.HeaderHeight
+
+		for j, row := range sections[i] {
+			sb.WriteString(renderCapabilityRow(row, j%2 == 1, columns, y, totalWidth, config))
+			y += row.height
+		}
+
+		if len(rest.Interactions) > 0 {
+			text := config.text("System interactions:") + " " + strings.Join(rest.Interactions, ", ")
+			sb.WriteString(newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).
+				fixed("height", config.MinRowHeight, 0).attr("fill", config.HeaderBgColor).attr("stroke", config.BorderColor).String())
+			sb.WriteString(newNode("text").fixed("x", config.Padding, 0).fixed("y", y+config.MinRowHeight/2+TextVerticalOffset, 0).
+				attr("class", "cell-text").content(text).String())
+			y += config.MinRowHeight
+		}
+		y += config.CompositeSpacing
+	}
+
+	sb.WriteString(buildFooter(totalWidth, footerY, config))
+	sb.WriteString(buildRepoLink(footerY, config))
+	sb.WriteString(buildWatermark(totalWidth, totalHeight, config))
+	sb.WriteString(svgEnd)
+	return formatWhitespace(sb.String(), config), nil
+}
+
+// capabilityColumns sizes the resource, interaction and search columns
+func capabilityColumns(matrix *models.CapabilityMatrix, tm *TextMeasurer, config SVGConfig) []tableColumn {
+	typeWidth := CapabilityMinTypeWidth
+	for _, rest := range matrix.Rest {
+		for _, res := range rest.Resources {
+			typeWidth = max(typeWidth, tm.MeasureString(res.Type)+config.Padding*2+FontRenderingBuffer)
+		}
+	}
+
+	columns := []tableColumn{{title: "Resource", width: typeWidth}}
+	headerScale := config.HeaderFontSize / config.FontSize
+	for _, code := range models.TypeInteractions {
+		columns = append(columns, tableColumn{
+			title: code,
+			width: tm.MeasureString(code)*headerScale + config.Padding*2 + FontRenderingBuffer,
+		})
+	}
+	return append(columns, tableColumn{title: "Search parameters", width: CapabilitySearchColWidth})
+}
+
+// capabilityTitle names a section after the statement and, when there are
+// several rest entries, its mode
+func capabilityTitle(matrix *models.CapabilityMatrix, rest models.CapabilityRest) string {
+	title := matrix.Name
+	if matrix.Version != "" {
+		title += " v" + matrix.Version
+	}
+	if rest.Mode != "" {
+		title += " (" + rest.Mode + ")"
+	}
+	return title
+}
+
+// capabilitySummary lists the resource types of each rest entry for screen
+// readers
+func capabilitySummary(matrix *models.CapabilityMatrix) string {
+	lines := make([]string, len(matrix.Rest))
+	for i, rest := range matrix.Rest {
+		types := make([]string, len(rest.Resources))
+		for j, res := range rest.Resources {
+			types[j] = res.Type
+		}
+		lines[i] = capabilityTitle(matrix, rest) + ": " + strings.Join(types, ", ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// systemRowHeight returns the height of the system interactions row, if any
+func systemRowHeight(rest models.CapabilityRest, config SVGConfig) float64 {
+	if len(rest.Interactions) == 0 {
+		return 0
+	}
+	return config.MinRowHeight
+}
+
+// renderTableHeader renders the column titles of a custom table
+func renderTableHeader(columns []tableColumn, y, totalWidth float64, config SVGConfig) string {
+	var sb strings.Builder
+	sb.WriteString(newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", config.HeaderHeight, 0).
+		attr("fill", config.HeaderBgColor).attr("stroke", config.BorderColor).String())
+
+	x := 0.0
+	textY := y + config.HeaderHeight/2 + TitleVerticalOffset
+	for i, col := range columns {
+		sb.WriteString(newNode("text").fixed("x", x+config.Padding, 0).fixed("y", textY, 0).
+			attr("class", "header-text").content(config.text(col.title)).String())
+		x += col.width
+		if i < len(columns)-1 {
+			sb.WriteString(renderColumnSeparator(x, y, config.HeaderHeight, config).String())
+		}
+	}
+	return sb.String()
+}
+
+// renderCapabilityRow renders one resource type with a check mark per
+// supported interaction
+func renderCapabilityRow(row capabilityRow, isAlt bool, columns []tableColumn, y, totalWidth float64, config SVGConfig) string {
+	bgColor := config.RowBgColor
+	if isAlt {
+		bgColor = config.AltRowBgColor
+	}
+	label := row.resource.Type
+	if len(row.resource.Interactions) > 0 {
+		label += ": " + strings.Join(row.resource.Interactions, ", ")
+	}
+	group := newNode("g").attr("id", row.resource.Type).attr("class", "row").attr("aria-label", label).append(
+		newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", row.height, 0).attr("fill", bgColor),
+		renderRowBorder(y, row.height, totalWidth, config),
+	)
+
+	textY := y + RowTopMargin + config.FontSize
+	x := 0.0
+	for i, col := range columns {
+		switch {
+		case i == 0:
+			text := newNode("text").fixed("x", x+config.Padding, 0).fixed("y", textY, 0).attr("class", "link-text").
+				content(row.resource.Type)
+			if url := config.typeURL(row.resource.Type); url != "" {
+				group.append(newNode("a").attr("xlink:href", url).attr("target", "_blank").append(text))
+			} else {
+				group.append(text)
+			}
+		case i == len(columns)-1:
+			for j, line := range row.searchLines {
+				group.append(newNode("text").fixed("x", x+config.Padding, 0).fixed("y", textY+float64(j)*config.LineHeight, 0).
+					attr("class", "cell-text").content(line))
+			}
+		case slices.Contains(row.resource.Interactions, col.title):
+			group.append(newNode("text").fixed("x", x+col.width/2, 0).fixed("y", textY, 0).attr("class", "link-text").
+				attr("text-anchor", "middle").append(
+				newNode("title").content(row.resource.Type+" "+col.title),
+				textNode(CapabilityCheckMark),
+			))
+		}
+		x += col.width
+		if i < len(columns)-1 {
+			group.append(renderColumnSeparator(x, y, row.height, config))
+		}
+	}
+
+	return group.String()
+}
@@ -0,0 +1,337 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"fhir_renderer/models"
+)
+
+// Social preview card size in pixels, the 1.91:1 image Open Graph and
+// Twitter cards expect
+const (
+	CardWidth  = 1200
+	CardHeight = 630
+)
+
+// Card layout in pixels
+const (
+	cardMargin        = 64
+	cardAccentHeight  = 12  // Band in the link color along the top edge
+	cardTextWidth     = 512 // Width of the name, type and description
+	cardPreviewX      = 640 // Left edge of the structure preview
+	cardPreviewHeader = 44
+	cardPreviewRow    = 34
+	cardPreviewIndent = 22 // Indent per nesting level in the preview
+	cardPreviewIcon   = 14
+	cardPreviewTypeX  = 930 // Left edge of the type column in the preview
+)
+
+// cardIconColors are the main colors of the built-in icons, which the
+// structure preview draws as plain squares
+var cardIconColors = map[string]string{
+	IconResource:        "#FDB813",
+	IconBackboneElement: "#FDB813",
+	IconElement:         "#005EB8",
+	IconPrimitive:       "#3B7DD8",
+	IconDatatype:        "#D35400",
+	IconExtension:       "#FF8C00",
+	IconChoice:          "#28A745",
+	IconReference:       "#005EB8",
+}
+
+// RenderCardPNGContext writes a CardWidth x CardHeight PNG preview card of
+// a resource definition, for links that unfurl in chat tools: its name,
+// type, description and element count beside a miniature of the first rows
+// of its structure. The card uses the configured colors, language and font.
+func RenderCardPNGContext(ctx context.Context, w io.Writer, resource *models.ResourceDefinition, config SVGConfig) error {
+	fonts, err := newCardFonts(config)
+	if err != nil {
+		return err
+	}
+	defer fonts.close()
+	config.fhirVersion = resource.FHIRVersionOf()
+
+	background := hexColor(config.RowBgColor, color.White)
+	text := hexColor(config.TextColor, color.Black)
+	muted := mixColors(text, background, 0.6)
+
+	img := image.NewRGBA(image.Rect(0, 0, CardWidth, CardHeight))
+	fillRect(img, img.Bounds(), background)
+	fillRect(img, image.Rect(0, 0, CardWidth, cardAccentHeight), hexColor(config.LinkColor, color.Black))
+
+	// Name, type and description on the left
+	drawCardText(img, fonts.face(26, true), "FHIR "+config.version(), cardMargin, 108, cardTextWidth, hexColor(config.LinkColor, text))
+	nameFace := fonts.fit(resource.Name, cardTextWidth, true, 64, 52, 44, 36)
+	drawCardText(img, nameFace, resource.Name, cardMargin, 184, cardTextWidth, text)
+	drawCardText(img, fonts.face(30, false), resource.Type, cardMargin, 236, cardTextWidth, muted)
+	descFace := fonts.face(24, false)
+	for i, line := range wrapCardText(descFace, resource.Description, cardTextWidth, 3) {
+		drawCardText(img, descFace, line, cardMargin, 296+i*34, cardTextWidth, text)
+	}
+
+	// Element and extension counts
+	flat := resource.Flatten()
+	stats := []struct {
+		value int
+		label string
+	}{{len(flat) - 1, config.text("Elements")}}
+	if n := countExtensions(resource); n > 0 {
+		stats = append(stats, struct {
+			value int
+			label string
+		}{n, config.text("Extensions")})
+	}
+	statX := cardMargin
+	for _, stat := range stats {
+		width := drawCardText(img, fonts.face(54, true), strconv.Itoa(stat.value), statX, 500, cardTextWidth, text)
+		labelWidth := drawCardText(img, fonts.face(22, false), stat.label, statX, 530, cardTextWidth, muted)
+		statX += max(width, labelWidth) + 48
+	}
+	drawCardText(img, fonts.face(18, false), config.text("Generated by nuuner/fhir-resource-svg-renderer"), cardMargin, CardHeight-44, cardTextWidth, muted)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	drawCardPreview(img, flat, fonts, config)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// drawCardPreview draws the first rows of the structure as a small table of
+// names and types with tree lines, and a row counting the rest
+func drawCardPreview(img *image.RGBA, flat []models.FlatElement, fonts *cardFonts, config SVGConfig) {
+	background := hexColor(config.RowBgColor, color.White)
+	alternate := hexColor(config.AltRowBgColor, background)
+	text := hexColor(config.TextColor, color.Black)
+	muted := mixColors(text, background, 0.6)
+	border := hexColor(config.BorderColor, muted)
+	treeColor := hexColor(config.TreeStyle.Color, border)
+
+	top, bottom, right := cardMargin, CardHeight-cardMargin, CardWidth-cardMargin
+	fillRect(img, image.Rect(cardPreviewX, top, right, bottom), border)
+	fillRect(img, image.Rect(cardPreviewX+1, top+1, right-1, bottom-1), background)
+	fillRect(img, image.Rect(cardPreviewX+1, top+1, right-1, top+cardPreviewHeader), hexColor(config.HeaderBgColor, alternate))
+	headerText := hexColor(config.HeaderTextColor, text)
+	headerFace := fonts.face(18, true)
+	drawCardText(img, headerFace, config.text("Name"), cardPreviewX+16, top+29, cardPreviewTypeX-cardPreviewX-32, headerText)
+	drawCardText(img, headerFace, config.text("Type"), cardPreviewTypeX, top+29, right-cardPreviewTypeX-16, headerText)
+
+	rows := (bottom - top - cardPreviewHeader) / cardPreviewRow
+	shown := flat
+	if len(flat) > rows {
+		shown = flat[:rows-1]
+	}
+	nameFace, typeFace := fonts.face(19, false), fonts.face(17, false)
+	for i, fe := range shown {
+		y := top + cardPreviewHeader + i*cardPreviewRow
+		if i%2 == 1 {
+			fillRect(img, image.Rect(cardPreviewX+1, y, right-1, y+cardPreviewRow), alternate)
+		}
+		centerY := y + cardPreviewRow/2
+		x := cardPreviewX + 16 + fe.Depth*cardPreviewIndent
+		drawCardTreeLines(img, fe, cardPreviewX+16, y, treeColor)
+
+		icon := config.iconFor(fe.Element.Type, fe.Depth == 0, len(fe.Element.Elements) > 0 || len(fe.Element.Extensions) > 0)
+		fillRect(img, image.Rect(x, centerY-cardPreviewIcon/2, x+cardPreviewIcon, centerY+cardPreviewIcon/2), cardIconColor(icon, config))
+		nameX := x + cardPreviewIcon + 8
+		drawCardText(img, nameFace, fe.Element.Name, nameX, centerY+7, cardPreviewTypeX-nameX-12, text)
+		drawCardText(img, typeFace, fe.Element.DisplayType(), cardPreviewTypeX, centerY+6, right-cardPreviewTypeX-16, muted)
+	}
+	if more := len(flat) - len(shown); more > 0 {
+		y := top + cardPreviewHeader + len(shown)*cardPreviewRow
+		drawCardText(img, nameFace, fmt.Sprintf(config.text("… %d more"), more), cardPreviewX+16, y+cardPreviewRow/2+7, right-cardPreviewX-32, muted)
+	}
+}
+
+// drawCardTreeLines draws the tree lines of a preview row like
+// RenderTreeLines, with x the left edge of the tree
+func drawCardTreeLines(img *image.RGBA, fe models.FlatElement, x, y int, c color.Color) {
+	if fe.Depth == 0 {
+		return
+	}
+	centerY := y + cardPreviewRow/2
+	for i := 0; i < fe.Depth-1; i++ {
+		if i < len(fe.ParentLasts) && !fe.ParentLasts[i] {
+			lineX := x + i*cardPreviewIndent + cardPreviewIcon/2
+			fillRect(img, image.Rect(lineX, y, lineX+1, y+cardPreviewRow), c)
+		}
+	}
+	connectorX := x + (fe.Depth-1)*cardPreviewIndent + cardPreviewIcon/2
+	end := centerY + 1
+	if !fe.IsLast {
+		end = y + cardPreviewRow
+	}
+	fillRect(img, image.Rect(connectorX, y, connectorX+1, end), c)
+	fillRect(img, image.Rect(connectorX, centerY, x+fe.Depth*cardPreviewIndent-3, centerY+1), c)
+}
+
+// cardIconColor returns the color an icon is drawn in: a custom icon's
+// fill, else the main color of the built-in icon
+func cardIconColor(name string, config SVGConfig) color.Color {
+	for _, icon := range config.Icons {
+		if icon.Name == name {
+			return hexColor(icon.Fill, hexColor("#005EB8", color.Black))
+		}
+	}
+	return hexColor(cardIconColors[name], hexColor("#005EB8", color.Black))
+}
+
+// cardFonts creates the faces the card is drawn with, from the configured
+// font or else the Go fonts
+type cardFonts struct {
+	regular, bold *opentype.Font
+	faces         []font.Face
+}
+
+// newCardFonts parses the card fonts; a configured font is used for bold
+// text too
+func newCardFonts(config SVGConfig) (*cardFonts, error) {
+	if config.Font != nil {
+		return &cardFonts{regular: config.Font.parsed, bold: config.Font.parsed}, nil
+	}
+	regular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	bold, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	return &cardFonts{regular: regular, bold: bold}, nil
+}
+
+// face returns a face of the given size in pixels, or nil when it cannot
+// be created; text in a nil face is left out
+func (f *cardFonts) face(size float64, bold bool) font.Face {
+	parsed := f.regular
+	if bold {
+		parsed = f.bold
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil
+	}
+	f.faces = append(f.faces, face)
+	return face
+}
+
+// fit returns the face of the largest size at which s fits in maxWidth, or
+// of the smallest size
+func (f *cardFonts) fit(s string, maxWidth int, bold bool, sizes ...float64) font.Face {
+	var face font.Face
+	for _, size := range sizes {
+		face = f.face(size, bold)
+		if face == nil || font.MeasureString(face, s).Ceil() <= maxWidth {
+			break
+		}
+	}
+	return face
+}
+
+// close releases the faces created by face
+func (f *cardFonts) close() {
+	for _, face := range f.faces {
+		face.Close()
+	}
+}
+
+// drawCardText draws s with its baseline at x, y, shortened with "…" to fit
+// maxWidth, and returns the drawn width
+func drawCardText(img draw.Image, face font.Face, s string, x, y, maxWidth int, c color.Color) int {
+	if face == nil || s == "" {
+		return 0
+	}
+	s = fitCardText(face, s, maxWidth)
+	d := font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+	return d.Dot.X.Ceil() - x
+}
+
+// fitCardText shortens s with "…" until it fits maxWidth
+func fitCardText(face font.Face, s string, maxWidth int) string {
+	if font.MeasureString(face, s).Ceil() <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if shortened := strings.TrimRight(string(runes), " ") + "…"; font.MeasureString(face, shortened).Ceil() <= maxWidth {
+			return shortened
+		}
+	}
+	return ""
+}
+
+// wrapCardText breaks text into at most maxLines lines no wider than
+// maxWidth, ending the last in "…" when text does not fit
+func wrapCardText(face font.Face, text string, maxWidth, maxLines int) []string {
+	if face == nil {
+		return nil
+	}
+	var lines []string
+	line := ""
+	words := strings.Fields(text)
+	for i, word := range words {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line != "" && font.MeasureString(face, candidate).Ceil() > maxWidth {
+			if len(lines) == maxLines-1 {
+				return append(lines, fitCardText(face, strings.Join(append([]string{line}, words[i:]...), " ")+"…", maxWidth))
+			}
+			lines = append(lines, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, fitCardText(face, line, maxWidth))
+	}
+	return lines
+}
+
+// fillRect fills a rectangle of img with c
+func fillRect(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// hexColor parses a #RGB or #RRGGBB color, returning fallback for other
+// values
+func hexColor(s string, fallback color.Color) color.Color {
+	if !models.IsHexColor(s) {
+		return fallback
+	}
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return fallback
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}
+}
+
+// mixColors blends a toward b by t (0 keeps a, 1 gives b)
+func mixColors(a, b color.Color, t float64) color.Color {
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	mix := func(x, y uint32) uint8 {
+		return uint8((float64(x)*(1-t) + float64(y)*t) / 257)
+	}
+	return color.RGBA{R: mix(ar, br), G: mix(ag, bg), B: mix(ab, bb), A: 0xFF}
+}
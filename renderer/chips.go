@@ -0,0 +1,121 @@
+package renderer
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// codeEllipsis follows the chips of a value set holding more codes than
+// listed
+const codeEllipsis = "…"
+
+// bindingCodeList returns the codes of a binding: its listed codes, else the
+// values of a pipe-delimited value set such as "male|female|other". It
+// reports whether the value set holds more codes than returned.
+func bindingCodeList(binding *models.Binding) ([]string, bool) {
+	if binding == nil {
+		return nil, false
+	}
+	if len(binding.Codes) > 0 {
+		return binding.Codes, binding.Truncated
+	}
+	if !strings.Contains(binding.ValueSet, "|") || strings.Contains(binding.ValueSet, "://") {
+		return nil, false
+	}
+	var codes []string
+	for _, code := range strings.Split(binding.ValueSet, "|") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes, false
+}
+
+// bindingCodes returns the codes of a binding separated by commas, ending
+// in "…" when the value set holds more
+func bindingCodes(binding *models.Binding) string {
+	codes, truncated := bindingCodeList(binding)
+	if len(codes) == 0 {
+		return ""
+	}
+	text := strings.Join(codes, ", ")
+	if truncated {
+		text += ", " + codeEllipsis
+	}
+	return text
+}
+
+// codeLabelWidth returns the width of the bold "Codes:" label and the gap
+// before the first chip
+func codeLabelWidth(config SVGConfig) float64 {
+	return math.Ceil(config.textMeasurer.MeasureString(config.text(BindingCodesLabel))/BoldTextWidthFactor) + CodeChipGap
+}
+
+// codeChipWidth returns the drawn width of a chip, or of the unboxed
+// ellipsis
+func codeChipWidth(code string, config SVGConfig) float64 {
+	width := math.Ceil(config.textMeasurer.MeasureString(code) * FlagFontSize / config.FontSize)
+	if code != codeEllipsis {
+		width += CodeChipPadding
+	}
+	return width
+}
+
+// codeChipsWidth returns the width of chips drawn on one line
+func codeChipsWidth(codes []string, config SVGConfig) float64 {
+	width := 0.0
+	for i, code := range codes {
+		if i > 0 {
+			width += CodeChipGap
+		}
+		width += codeChipWidth(code, config)
+	}
+	return width
+}
+
+// wrapCodeChips breaks the codes of a binding into lines of chips no wider
+// than maxWidth, the first line following the "Codes:" label. A chip wider
+// than a line gets one of its own, which renderCodeChips shrinks to fit.
+func wrapCodeChips(binding *models.Binding, maxWidth float64, config SVGConfig) [][]string {
+	codes, truncated := bindingCodeList(binding)
+	if len(codes) == 0 {
+		return nil
+	}
+	if truncated {
+		codes = append(slices.Clip(codes), codeEllipsis)
+	}
+	var lines [][]string
+	var current []string
+	available := maxWidth - codeLabelWidth(config)
+	for _, code := range codes {
+		if len(current) > 0 && codeChipsWidth(append(slices.Clip(current), code), config) > available {
+			lines = append(lines, current)
+			current, available = nil, maxWidth
+		}
+		current = append(current, code)
+	}
+	return append(lines, current)
+}
+
+// renderCodeChips draws one line of code chips starting at x 0 and
+// centered on y 0, shrinking them when they are wider than maxWidth
+func renderCodeChips(codes []string, maxWidth float64, config SVGConfig) []*node {
+	var nodes []*node
+	x := 0.0
+	for _, code := range codes {
+		width := codeChipWidth(code, config)
+		if code == codeEllipsis {
+			nodes = append(nodes, newNode("text").fixed("x", x, 0).attr("y", "3").attr("class", "flag-box").content(codeEllipsis))
+		} else {
+			nodes = append(nodes,
+				newNode("rect").fixed("x", x, 0).attr("y", "-7").fixed("width", width, 0).attr("height", "14").
+					fixed("rx", CodeChipRadius, 0).attr("fill", config.HeaderBgColor).attr("stroke", config.BorderColor),
+				newNode("text").fixed("x", x+CodeChipPadding/2, 0).attr("y", "3").attr("class", "flag-box").content(code))
+		}
+		x += width + CodeChipGap
+	}
+	return scaleToFit(nodes, x-CodeChipGap, maxWidth)
+}
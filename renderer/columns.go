@@ -0,0 +1,291 @@
+package renderer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Column keys for SVGConfig.Columns, also used as the json-layout column keys
+// and clip path ids
+const (
+	ColumnName        = "name"
+	ColumnFlags       = "flags"
+	ColumnCardinality = "card"
+	ColumnType        = "type"
+	ColumnDescription = "desc"
+	ColumnMappings    = "map"
+)
+
+// ColumnRowNumber is the leading row number column. It is prepended when
+// SVGConfig.RowNumbers is set and cannot be selected via Columns.
+const ColumnRowNumber = "row"
+
+// MinRowNumberColWidth keeps the row number column wide enough for its header
+const MinRowNumberColWidth = 28.0
+
+// DefaultColumns is the column order of the FHIR specification's tables
+var DefaultColumns = []string{ColumnName, ColumnFlags, ColumnCardinality, ColumnType, ColumnDescription}
+
+// columnKeys lists every built-in column; the mappings column is optional
+var columnKeys = append(slices.Clone(DefaultColumns), ColumnMappings)
+
+// columnTitles are the header texts of the columns
+var columnTitles = map[string]string{
+	ColumnName:        "Name",
+	ColumnFlags:       "Flags",
+	ColumnCardinality: "Card.",
+	ColumnType:        "Type",
+	ColumnDescription: "Description & Constraints",
+	ColumnMappings:    "Mappings",
+	ColumnAnnotations: "Review notes",
+	ColumnSample:      "Sample value",
+	ColumnRowNumber:   "#",
+}
+
+// DefaultExtraColumnWidth is the width of extra columns that set none
+const DefaultExtraColumnWidth = 120.0
+
+// ExtraColumn is an additional column showing the element's Meta value for
+// Key, e.g. the owning team or a ticket reference
+type ExtraColumn struct {
+	Key   string
+	Title string  // Header text; empty uses Key
+	Width float64 // Pixels; 0 uses DefaultExtraColumnWidth
+}
+
+// extraColumnKeyPattern restricts keys to characters that are safe in clip
+// path ids
+var extraColumnKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// defaultColumns is the column list of DefaultConfig; see SetDefaultColumns
+var defaultColumns []string
+
+// defaultExtraColumns are the extra columns of DefaultConfig; see
+// SetDefaultExtraColumns
+var defaultExtraColumns []ExtraColumn
+
+// SetDefaultColumns sets the columns DefaultConfig uses, e.g. from the server
+// configuration. The list must be valid for ParseColumns.
+func SetDefaultColumns(columns []string) {
+	defaultColumns = slices.Clone(columns)
+}
+
+// SetDefaultExtraColumns sets the extra columns DefaultConfig uses. The list
+// must pass ValidateExtraColumns.
+func SetDefaultExtraColumns(columns []ExtraColumn) {
+	defaultExtraColumns = slices.Clone(columns)
+}
+
+// ParseColumns validates a list of column keys. Columns are drawn in the
+// given order and missing ones are hidden; the name column carries the tree
+// and must be present.
+func ParseColumns(keys []string) ([]string, error) {
+	columns := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if !slices.Contains(columnKeys, key) {
+			return nil, fmt.Errorf("unknown column %q (expected one of %s)", key, strings.Join(columnKeys, ", "))
+		}
+		if slices.Contains(columns, key) {
+			return nil, fmt.Errorf("column %q listed twice", key)
+		}
+		columns = append(columns, key)
+	}
+	if !slices.Contains(columns, ColumnName) {
+		return nil, fmt.Errorf("the %q column is required", ColumnName)
+	}
+	return columns, nil
+}
+
+// ValidateExtraColumns checks that the keys are unique, use only letters,
+// digits, '-' and '_', and do not clash with the built-in columns
+func ValidateExtraColumns(columns []ExtraColumn) error {
+	seen := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		switch {
+		case !extraColumnKeyPattern.MatchString(col.Key):
+			return fmt.Errorf("invalid extra column key %q (use letters, digits, '-' and '_')", col.Key)
+		case columnTitles[col.Key] != "":
+			return fmt.Errorf("extra column %q clashes with a built-in column", col.Key)
+		case seen[col.Key]:
+			return fmt.Errorf("extra column %q listed twice", col.Key)
+		case col.Width < 0:
+			return fmt.Errorf("extra column %q has a negative width", col.Key)
+		}
+		seen[col.Key] = true
+	}
+	return nil
+}
+
+// ParseExtraColumns parses extra columns written as "key" or "key:Title"
+func ParseExtraColumns(specs []string) ([]ExtraColumn, error) {
+	columns := make([]ExtraColumn, 0, len(specs))
+	for _, spec := range specs {
+		key, title, _ := strings.Cut(spec, ":")
+		columns = append(columns, ExtraColumn{Key: strings.TrimSpace(key), Title: strings.TrimSpace(title)})
+	}
+	if err := ValidateExtraColumns(columns); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// columns returns the visible columns in drawing order: the row numbers,
+// the built-in ones, then the extra columns, the sample values and the
+// annotation notes
+func (c SVGConfig) columns() []string {
+	columns := c.Columns
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+	if len(c.ExtraColumns) == 0 && !c.showSample && !c.showAnnotations && !c.RowNumbers {
+		return columns
+	}
+	if c.RowNumbers {
+		columns = append([]string{ColumnRowNumber}, columns...)
+	}
+	columns = slices.Clip(columns)
+	for _, extra := range c.ExtraColumns {
+		columns = append(columns, extra.Key)
+	}
+	if c.showSample {
+		columns = append(columns, ColumnSample)
+	}
+	if c.showAnnotations {
+		columns = append(columns, ColumnAnnotations)
+	}
+	return columns
+}
+
+// extraColumn returns the extra column with the key
+func (c SVGConfig) extraColumn(key string) (ExtraColumn, bool) {
+	i := slices.IndexFunc(c.ExtraColumns, func(extra ExtraColumn) bool { return extra.Key == key })
+	if i < 0 {
+		return ExtraColumn{}, false
+	}
+	return c.ExtraColumns[i], true
+}
+
+// columnTitle returns the header text of a column
+func (c SVGConfig) columnTitle(key string) string {
+	if title, ok := columnTitles[key]; ok {
+		return c.text(title)
+	}
+	if extra, ok := c.extraColumn(key); ok && extra.Title != "" {
+		return extra.Title
+	}
+	return key
+}
+
+// showsColumn reports whether the column is visible
+func (c SVGConfig) showsColumn(key string) bool {
+	return slices.Contains(c.columns(), key)
+}
+
+// columnWidth returns the configured width of a column
+func (c SVGConfig) columnWidth(key string) float64 {
+	switch key {
+	case ColumnName:
+		return c.NameColWidth
+	case ColumnFlags:
+		return c.FlagsColWidth
+	case ColumnCardinality:
+		return c.CardinalityColWidth
+	case ColumnType:
+		return c.TypeColWidth
+	case ColumnDescription:
+		return c.DescriptionColWidth
+	case ColumnMappings:
+		return c.MappingsColWidth
+	case ColumnAnnotations:
+		return AnnotationColWidth
+	case ColumnSample:
+		return SampleColWidth
+	case ColumnRowNumber:
+		return c.rowNumberWidth()
+	}
+	if extra, ok := c.extraColumn(key); ok && extra.Width > 0 {
+		return extra.Width
+	}
+	return DefaultExtraColumnWidth
+}
+
+// withHiddenColumns returns the config with the widths of hidden columns set
+// to zero, so totals, fitting and the legend only count visible columns
+func (c SVGConfig) withHiddenColumns() SVGConfig {
+	for key, width := range map[string]*float64{
+		ColumnFlags:       &c.FlagsColWidth,
+		ColumnCardinality: &c.CardinalityColWidth,
+		ColumnType:        &c.TypeColWidth,
+		ColumnDescription: &c.DescriptionColWidth,
+		ColumnMappings:    &c.MappingsColWidth,
+	} {
+		if !c.showsColumn(key) {
+			*width = 0
+		}
+	}
+	return c
+}
+
+// tableWidth returns the combined width of the visible columns
+func (c SVGConfig) tableWidth() float64 {
+	width := 0.0
+	for _, col := range c.columnSpans() {
+		width += col.width
+	}
+	return width
+}
+
+// extraColumnsWidth returns the combined width of the extra columns
+func (c SVGConfig) extraColumnsWidth() float64 {
+	width := 0.0
+	for _, extra := range c.ExtraColumns {
+		width += c.columnWidth(extra.Key)
+	}
+	return width
+}
+
+// annotationsWidth returns the width of the margin note column, or 0
+func (c SVGConfig) annotationsWidth() float64 {
+	if !c.showAnnotations {
+		return 0
+	}
+	return AnnotationColWidth
+}
+
+// rowNumberWidth returns the width of the row number column, or 0
+func (c SVGConfig) rowNumberWidth() float64 {
+	if !c.RowNumbers {
+		return 0
+	}
+	return max(c.rowNumberColWidth, MinRowNumberColWidth)
+}
+
+// calculateRowNumberWidth sizes the row number column for rows numbered up
+// to count
+func calculateRowNumberWidth(count int, tm *TextMeasurer, config SVGConfig) float64 {
+	return math.Ceil(tm.MeasureString(strconv.Itoa(count)) + config.Padding*2 + FontRenderingBuffer)
+}
+
+// columnSpan is the horizontal extent of a visible column
+type columnSpan struct {
+	key   string
+	x     float64
+	width float64
+}
+
+// columnSpans returns the visible columns with their positions, left to right
+func (c SVGConfig) columnSpans() []columnSpan {
+	columns := c.columns()
+	spans := make([]columnSpan, len(columns))
+	x := 0.0
+	for i, key := range columns {
+		spans[i] = columnSpan{key: key, x: x, width: c.columnWidth(key)}
+		x += spans[i].width
+	}
+	return spans
+}
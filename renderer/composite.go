@@ -0,0 +1,148 @@
+package renderer
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// compositeSection is one resource of a composite SVG with its prepared rows
+type compositeSection struct {
+	resource *models.ResourceDefinition
+	rows     []RowData
+}
+
+// rowsHeight returns the combined height of the section's data rows
+func (s compositeSection) rowsHeight() float64 {
+	height := 0.0
+	for _, row := range s.rows {
+		height += row.RowHeight
+	}
+	return height
+}
+
+// height returns the vertical space the section occupies, excluding spacing
+func (s compositeSection) height(config SVGConfig) float64 {
+	return config.TitleHeight + config.HeaderHeight + s.rowsHeight() + metadataFooterHeight(config)
+}
+
+// RenderCompositeContext stacks several resource definitions in one SVG.
+// Each resource gets its own title bar and header row, separated by
+// config.CompositeSpacing; all sections share the same column widths so
+// their columns line up. The legend and footer are rendered once at the end.
+func RenderCompositeContext(ctx context.Context, resources []*models.ResourceDefinition, config SVGConfig) (string, error) {
+	var sb strings.Builder
+	if err := RenderCompositeToContext(ctx, &sb, resources, config); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// RenderCompositeToContext writes the composite SVG to w like RenderToContext
+func RenderCompositeToContext(ctx context.Context, w io.Writer, resources []*models.ResourceDefinition, config SVGConfig) error {
+	tm, err := newTextMeasurer(config)
+	if err != nil {
+		return err
+	}
+	defer tm.Close()
+	config.textMeasurer = tm
+
+	measureCtx, span := tracer.Start(ctx, "measure text")
+	config = config.withHiddenColumns()
+
+	// Use the widest columns so the sections align
+	for _, resource := range resources {
+		config.showAnnotations = config.showAnnotations || resource.HasAnnotationNotes()
+		config.showSample = config.showSample || resource.HasExample()
+		if config.RowNumbers {
+			config.rowNumberColWidth = max(config.rowNumberColWidth, calculateRowNumberWidth(len(resource.Flatten()), tm, config))
+		}
+	}
+	config = sizeColumns(resources, tm, config)
+	config = fitColumns(config)
+
+	sections := make([]compositeSection, len(resources))
+	rowIDs := make(map[string]int)
+	for i, resource := range resources {
+		sectionConfig := config
+		sectionConfig.fhirVersion = resource.FHIRVersionOf()
+		rows, err := prepareRows(measureCtx, config.flatten(resource), tm, sectionConfig)
+		if err != nil {
+			span.End()
+			return err
+		}
+		assignRowIDs(rows, rowIDs)
+		sections[i] = compositeSection{resource: resource, rows: rows}
+	}
+	span.End()
+
+	_, span = tracer.Start(ctx, "build SVG")
+	defer span.End()
+	colWidths := ColumnWidths{
+		Name:        config.NameColWidth,
+		Flags:       config.FlagsColWidth,
+		Cardinality: config.CardinalityColWidth,
+		Type:        config.TypeColWidth,
+		Description: config.DescriptionColWidth,
+		Mappings:    config.MappingsColWidth,
+		Extra:       config.extraColumnsWidth(),
+		Sample:      config.sampleWidth(),
+		Annotations: config.annotationsWidth(),
+		RowNumbers:  config.rowNumberWidth(),
+	}
+	totalWidth := colWidths.Total()
+
+	contentHeight := 0.0
+	for i, section := range sections {
+		if i > 0 {
+			contentHeight += config.CompositeSpacing
+		}
+		contentHeight += section.height(config)
+	}
+	legendY := contentHeight
+	footerY := legendY + legendHeight(config)
+	totalHeight := footerY + FooterHeight + SVGHeightPadding
+
+	names := make([]string, len(sections))
+	summaries := make([]string, len(sections))
+	for i, section := range sections {
+		names[i] = compositeTitleBar(section.resource, config)
+		summaries[i] = section.resource.Name + ": " + structureSummary(section.resource, section.rows, config)
+	}
+
+	bw, flush := newSVGWriter(w, config)
+	bw.WriteString(buildSVGHeader(totalWidth, totalHeight, strings.Join(names, ", "), strings.Join(summaries, "\n"), true, config))
+
+	y := 0.0
+	for _, section := range sections {
+		bw.WriteString(buildTitleBar(compositeTitleBar(section.resource, config), y, totalWidth, config))
+		bw.WriteString(buildTitleBarExtras(section.resource.Status, y, totalWidth, config))
+		bw.WriteString(renderHeaderRow(config, y+config.TitleHeight, totalWidth))
+		rowsY := y + config.TitleHeight + config.HeaderHeight
+		writeDataRows(bw, section.rows, rowsY, totalWidth, config)
+		if config.ShowMetadataFooter {
+			bw.WriteString(buildMetadataFooter(section.resource, totalWidth, rowsY+section.rowsHeight(), config))
+		}
+		y += section.height(config) + config.CompositeSpacing
+	}
+
+	if config.ShowLegend {
+		bw.WriteString(buildLegend(totalWidth, legendY, config))
+	}
+	bw.WriteString(buildFooter(totalWidth, footerY, config))
+	bw.WriteString(buildRepoLink(footerY, config))
+	bw.WriteString(buildWatermark(totalWidth, totalHeight, config))
+	bw.WriteString(svgEnd)
+	return flush()
+}
+
+// compositeTitleBar titles a composite section with the resource name, or
+// the expanded title template when one is set
+func compositeTitleBar(resource *models.ResourceDefinition, config SVGConfig) string {
+	if title, err := expandTitle(resource, config.TitleTemplate); err == nil {
+		return title
+	}
+	return resource.Name
+}
@@ -1,5 +1,15 @@
 package renderer
 
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Version identifies the renderer output format; bump it whenever a change
+// alters the generated SVG so cached responses are invalidated
+const Version = "1.12.2"
+
 // Layout constants
 const (
 	// Row margins
@@ -10,25 +20,52 @@ const (
 	MinNameColWidth = 180.0
 	MaxNameColWidth = 300.0
 
+	// Flags, cardinality and type columns are sized to their content within
+	// these bounds; wider flags and types wrap
+	MinFlagsColWidth       = 40.0
+	MaxFlagsColWidth       = 120.0
+	MinCardinalityColWidth = 40.0
+	MaxCardinalityColWidth = 90.0
+	MinTypeColWidth        = 100.0
+	MaxTypeColWidth        = 300.0
+
+	// MinDescriptionColWidth bounds the description column when wide flags,
+	// cardinality and type columns take its width
+	MinDescriptionColWidth = 240.0
+
+	// Narrowest name, type and description columns when fitting MaxTotalWidth
+	MinFitNameColWidth        = 120.0
+	MinFitTypeColWidth        = 100.0
+	MinFitDescriptionColWidth = 160.0
+
 	// Footer
 	FooterHeight = 24.0
 
 	// Labels
-	UnusedElementLabel = "Not used"
+	UnusedElementLabel   = "Not used"
+	NoChildrenUsedLabel  = "(no children used)"
+	FixedValueLabel      = "Fixed Value:"
+	RequiredPatternLabel = "Required Pattern:"
+	BindingCodesLabel    = "Codes:"
+	TodoPrefix           = "TODO:"
+
+	// ExtensionContextLabel precedes the context of an extension below its
+	// name
+	ExtensionContextLabel = "Context:"
 )
 
 // SVGConfig contains configuration for SVG rendering
 type SVGConfig struct {
-	FontFamily       string
-	FontSize         float64
-	HeaderFontSize   float64
-	MinRowHeight     float64 // Minimum row height
-	LineHeight       float64 // Height per line of text
-	HeaderHeight     float64
-	TitleHeight      float64
-	IconSize         float64
-	Padding          float64
-	TreeStyle        TreeLineStyle
+	FontFamily     string
+	FontSize       float64
+	HeaderFontSize float64
+	MinRowHeight   float64 // Minimum row height
+	LineHeight     float64 // Height per line of text
+	HeaderHeight   float64
+	TitleHeight    float64
+	IconSize       float64
+	Padding        float64
+	TreeStyle      TreeLineStyle
 
 	// Column widths
 	NameColWidth        float64
@@ -36,8 +73,60 @@ type SVGConfig struct {
 	CardinalityColWidth float64
 	TypeColWidth        float64
 	DescriptionColWidth float64
+	MappingsColWidth    float64
+
+	// Columns lists the visible columns in drawing order (see ParseColumns);
+	// empty uses DefaultColumns
+	Columns []string
+
+	// ExtraColumns are drawn after Columns and show each element's Meta
+	// value for their key; empty for none
+	ExtraColumns []ExtraColumn
+
+	// Flags registers custom flags such as "PII" and restyles built-in ones
+	// with the same code (see BuiltinFlags and ValidateFlags)
+	Flags []FlagStyle
+
+	// Icons registers icons drawn from SVG paths and IconRules picks icons
+	// by element type, e.g. "Money" or "Geo*" (see ValidateIcons)
+	Icons     []IconStyle
+	IconRules []IconRule
+
+	// MaxTotalWidth shrinks the name, type and description columns so the
+	// table fits this many pixels; 0 keeps the natural widths
+	MaxTotalWidth float64
+
+	// Responsive emits width="100%" so the diagram scales with its container.
+	// MinFontSize and MaxFontSize (pixels, 0 for no limit) bound the scaling
+	// via CSS min-width and max-width relative to FontSize.
+	Responsive  bool
+	MinFontSize float64
+	MaxFontSize float64
+
+	// TitleTemplate replaces the "Structure" title bar with a template
+	// expanded per resource (see ParseTitleTemplate); empty keeps the
+	// translated "Structure"
+	TitleTemplate string
+
+	// Branding adds an organization logo and name to the title bar and a
+	// repository link to the footer
+	Branding Branding
+
+	// Watermark is drawn diagonally over the diagram, e.g. "DRAFT"; empty
+	// for none (see NormalizeWatermark)
+	Watermark string
+
+	// Whitespace re-formats the SVG markup: WhitespacePretty for reading,
+	// WhitespaceMinified for smaller payloads; empty keeps the default line
+	// breaks
+	Whitespace string
+
+	// Lang selects the translation of titles, labels and the legend (see
+	// Languages); empty or "en" keeps English
+	Lang string
 
-	// Colors
+	// Colors; the defaults keep text at the WCAG AA contrast of 4.5:1 on
+	// every row tint
 	HeaderBgColor   string
 	HeaderTextColor string
 	RowBgColor      string
@@ -48,39 +137,303 @@ type SVGConfig struct {
 	NotUsedColor    string
 	TodoColor       string
 
+	// Must support styling
+	MustSupportColor     string // Fill of the boxed "S" flag
+	MustSupportRowColor  string // Row tint when HighlightMustSupport is set
+	HighlightMustSupport bool   // Tint rows flagged MS
+
+	// AddedRowColor tints slices and elements a profile adds to its base
+	AddedRowColor string
+
+	// Documentation link templates for types and element names, e.g.
+	// "https://hl7.org/fhir/R4/{lower}.html"; explicit typeRef and target
+	// URLs take precedence
+	TypeLinkBase    string
+	ElementLinkBase string
+
+	// FHIRLinks links FHIR data types and resources to their page in the
+	// FHIR specification when TypeLinkBase is not set
+	FHIRLinks bool
+
+	// StrictLinks only links absolute http(s) URLs from definitions;
+	// otherwise relative and mailto: links are kept too. Schemes that can
+	// run script are always dropped.
+	StrictLinks bool
+
+	// TargetRowColor highlights the row addressed by the URI fragment
+	TargetRowColor string
+
+	// HoverRows highlights the row under the pointer with HoverRowColor,
+	// e.g. when the SVG is inlined in an HTML page
+	HoverRows     bool
+	HoverRowColor string
+
+	// ShowLegend appends a key explaining icons, flags and usage styling
+	ShowLegend bool
+
+	// RowNumbers adds a leading column numbering the rows, so reviewers can
+	// refer to "row 17"; each number links to the row's anchor
+	RowNumbers bool
+
+	// Coverage colors rows by usage (UsedRowColor, TodoRowColor,
+	// NotUsedRowColor) and adds a bar summarizing the implemented and TODO
+	// elements below the rows. Composite diagrams get the row colors only.
+	Coverage        bool
+	UsedRowColor    string
+	TodoRowColor    string
+	NotUsedRowColor string
+
+	// RollupUsage greys out parents whose children are all not used and
+	// appends "(no children used)" to their description, so coverage views
+	// do not show them as implemented
+	RollupUsage bool
+
+	// GroupHeaders draws a band naming each top-level element with children
+	// above its row, so long resources like Claim read as sections;
+	// GroupHeaderHeight is the height of the band
+	GroupHeaders      bool
+	GroupHeaderHeight float64
+
+	// Metadata footer showing resource version and generation details
+	ShowMetadataFooter   bool
+	MetadataFooterHeight float64
+	GeneratedAt          time.Time `json:"-"` // Excluded so ETags stay stable across requests
+
+	// Deterministic omits content that varies between runs or deployments
+	// (generation time and renderer version) so identical input always
+	// produces byte-identical output
+	Deterministic bool
+
+	// Custom font used for measurement and rendering; nil uses Go Regular
+	// for measurement. EmbedFont inlines it as an @font-face data URI.
+	Font      *Font
+	EmbedFont bool
+
+	// CompositeSpacing is the vertical gap between resources in a composite SVG
+	CompositeSpacing float64
+
+	// MaxRowsPerPage splits taller structure diagrams into pages that repeat
+	// the title bar and header row, stacked in one SVG or rendered one SVG
+	// per page by RenderPagesContext; 0 disables paging. Composite diagrams
+	// and the layout are not paged.
+	MaxRowsPerPage int
+
 	// Text measurer (initialized during render)
 	textMeasurer *TextMeasurer
 
+	// showChanges adds the profile comparison key to the legend; set during
+	// layout when elements carry a change
+	showChanges bool
+
+	// showAnnotations adds the margin note column; set during layout when
+	// annotations carry notes
+	showAnnotations bool
+
+	// showSample adds the sample value column; set during layout when the
+	// definition has an example instance
+	showSample bool
+
+	// fhirVersion selects the FHIR types linked to the specification; set
+	// during layout from the definition (see models.FHIRVersionOf)
+	fhirVersion string
+
+	// hideCoverageSummary leaves out the coverage bar on all but the last
+	// page of a paged diagram
+	hideCoverageSummary bool
+
+	// rowNumberColWidth fits the largest row number; set during layout when
+	// RowNumbers is on
+	rowNumberColWidth float64
+
+	// thumbnailWidth scales the drawing to this many pixels wide; set by
+	// RenderThumbnailToContext
+	thumbnailWidth float64
+
 	// CompressedResource is the Brotli+Base64URL encoded resource for footer links
 	CompressedResource string
+
+	// EmbedSource writes CompressedResource into the SVG's <metadata> so
+	// the definition can be recovered from the image (see ExtractSource)
+	EmbedSource bool
+
+	// ShareID is the short id of a stored resource; it takes precedence over
+	// CompressedResource for footer links
+	ShareID string
+}
+
+// editorURL returns the link that opens the resource in the editor, or ""
+func (c SVGConfig) editorURL() string {
+	if c.ShareID != "" {
+		return "/editor?id=" + c.ShareID
+	}
+	if c.CompressedResource != "" {
+		return "/editor?resource=" + c.CompressedResource
+	}
+	return ""
 }
 
-// DefaultConfig returns sensible default configuration
+// sourceURL returns the link to the resource JSON, or ""
+func (c SVGConfig) sourceURL() string {
+	if c.ShareID != "" {
+		return "/share/" + c.ShareID
+	}
+	if c.CompressedResource != "" {
+		return "/source?resource=" + c.CompressedResource
+	}
+	return ""
+}
+
+// DefaultConfig returns sensible default configuration, with the default
+// theme applied
 func DefaultConfig() SVGConfig {
-	return SVGConfig{
-		FontFamily:          "Arial, sans-serif",
-		FontSize:            12,
-		HeaderFontSize:      13,
-		MinRowHeight:        26, // topMargin(4) + lineHeight(16) + bottomMargin(6)
-		LineHeight:          16,
-		HeaderHeight:        28,
-		TitleHeight:         32,
-		IconSize:            14,
-		Padding:             8,
-		TreeStyle:           DefaultTreeStyle(),
-		NameColWidth:        180,
-		FlagsColWidth:       50,
-		CardinalityColWidth: 55,
-		TypeColWidth:        220,
-		DescriptionColWidth: 400,
-		HeaderBgColor:       "#F0F0F0",
-		HeaderTextColor:     "#333333",
-		RowBgColor:          "#FFFFFF",
-		AltRowBgColor:       "#F8F8F8",
-		BorderColor:         "#CCCCCC",
-		LinkColor:           "#005EB8",
-		TextColor:           "#333333",
-		NotUsedColor:        "#999999",
-		TodoColor:           "#FF6600",
+	config := SVGConfig{
+		FontFamily:           "Arial, sans-serif",
+		FontSize:             12,
+		HeaderFontSize:       13,
+		MinRowHeight:         26, // topMargin(4) + lineHeight(16) + bottomMargin(6)
+		LineHeight:           16,
+		HeaderHeight:         28,
+		TitleHeight:          32,
+		IconSize:             14,
+		Padding:              8,
+		TreeStyle:            DefaultTreeStyle(),
+		NameColWidth:         180,
+		FlagsColWidth:        50,
+		CardinalityColWidth:  55,
+		TypeColWidth:         220,
+		DescriptionColWidth:  400,
+		MappingsColWidth:     200,
+		HeaderBgColor:        "#F0F0F0",
+		HeaderTextColor:      "#333333",
+		RowBgColor:           "#FFFFFF",
+		AltRowBgColor:        "#F8F8F8",
+		BorderColor:          "#CCCCCC",
+		LinkColor:            "#005EB8",
+		TextColor:            "#333333",
+		NotUsedColor:         "#666666",
+		TodoColor:            "#B34700",
+		MustSupportColor:     "#CC0000",
+		MustSupportRowColor:  "#FFF0F0",
+		AddedRowColor:        "#E8F5E9",
+		TargetRowColor:       "#FFF3B0",
+		HoverRowColor:        "#EAF2FB",
+		UsedRowColor:         "#E6F4EA",
+		TodoRowColor:         "#FFF3E0",
+		NotUsedRowColor:      "#EEEEEE",
+		GroupHeaderHeight:    22,
+		MetadataFooterHeight: 22,
+		CompositeSpacing:     16,
+		Columns:              defaultColumns,
+		ExtraColumns:         defaultExtraColumns,
+		Flags:                defaultFlags,
+		Icons:                defaultIcons,
+		IconRules:            defaultIconRules,
+		TitleTemplate:        defaultTitleTemplate,
+		Watermark:            defaultWatermark,
+		Whitespace:           defaultWhitespace,
+		StrictLinks:          defaultStrictLinks,
+		FHIRLinks:            true,
+		Branding:             defaultBranding,
+	}
+	defaultTheme.apply(&config)
+	return config
+}
+
+// Theme overrides the default font family and colors, e.g. for branding.
+// Empty fields keep the built-in values.
+type Theme struct {
+	FontFamily          string
+	HeaderBgColor       string
+	HeaderTextColor     string
+	RowBgColor          string
+	AltRowBgColor       string
+	BorderColor         string
+	LinkColor           string
+	TextColor           string
+	NotUsedColor        string
+	TodoColor           string
+	MustSupportColor    string
+	MustSupportRowColor string
+	AddedRowColor       string
+	TargetRowColor      string
+	HoverRowColor       string
+	UsedRowColor        string
+	TodoRowColor        string
+	NotUsedRowColor     string
+
+	// Tree lines; zero widths keep the built-in values
+	TreeLineColor string
+	TreeLineWidth float64
+	TreeIndent    float64
+}
+
+// defaultTheme is applied by DefaultConfig
+var defaultTheme Theme
+
+// SetDefaultTheme configures the theme applied by DefaultConfig
+func SetDefaultTheme(t Theme) {
+	defaultTheme = t
+}
+
+// themes are the named themes selectable per request; see RegisterTheme
+var themes = make(map[string]Theme)
+
+// RegisterTheme makes a theme selectable by name with UseTheme, e.g. a
+// customer's brand palette loaded from a theme file
+func RegisterTheme(name string, t Theme) {
+	themes[name] = t
+}
+
+// ThemeNames returns the names of the registered themes, sorted
+func ThemeNames() []string {
+	return slices.Sorted(maps.Keys(themes))
+}
+
+// UseTheme applies the named theme over the config's font family, colors
+// and tree style; it reports false, leaving the config unchanged, for
+// unknown names
+func (c *SVGConfig) UseTheme(name string) bool {
+	t, ok := themes[name]
+	if ok {
+		t.apply(c)
+	}
+	return ok
+}
+
+// apply copies the non-empty theme fields into config
+func (t Theme) apply(config *SVGConfig) {
+	for _, field := range []struct {
+		value string
+		dst   *string
+	}{
+		{t.FontFamily, &config.FontFamily},
+		{t.HeaderBgColor, &config.HeaderBgColor},
+		{t.HeaderTextColor, &config.HeaderTextColor},
+		{t.RowBgColor, &config.RowBgColor},
+		{t.AltRowBgColor, &config.AltRowBgColor},
+		{t.BorderColor, &config.BorderColor},
+		{t.LinkColor, &config.LinkColor},
+		{t.TextColor, &config.TextColor},
+		{t.NotUsedColor, &config.NotUsedColor},
+		{t.TodoColor, &config.TodoColor},
+		{t.MustSupportColor, &config.MustSupportColor},
+		{t.MustSupportRowColor, &config.MustSupportRowColor},
+		{t.AddedRowColor, &config.AddedRowColor},
+		{t.TargetRowColor, &config.TargetRowColor},
+		{t.HoverRowColor, &config.HoverRowColor},
+		{t.UsedRowColor, &config.UsedRowColor},
+		{t.TodoRowColor, &config.TodoRowColor},
+		{t.NotUsedRowColor, &config.NotUsedRowColor},
+		{t.TreeLineColor, &config.TreeStyle.Color},
+	} {
+		if field.value != "" {
+			*field.dst = field.value
+		}
+	}
+	if t.TreeLineWidth > 0 {
+		config.TreeStyle.Width = t.TreeLineWidth
+	}
+	if t.TreeIndent > 0 {
+		config.TreeStyle.IndentPx = t.TreeIndent
 	}
 }
@@ -8,6 +8,10 @@ const (
 	// BoldTextWidthFactor is width multiplier for estimating bold text width
 	BoldTextWidthFactor = 0.90
 
+	// EmojiWidthFactor is the width of an emoji in ems; browsers draw them
+	// from a color emoji font whose glyphs are wider than letters
+	EmojiWidthFactor = 1.25
+
 	// HeaderTextMarginY is vertical margin for header text positioning
 	HeaderTextMarginY = 6.0
 
@@ -35,8 +39,8 @@ const (
 
 // Flag rendering constants
 const (
-	// FlagCharWidth is estimated width per character in flag text
-	FlagCharWidth = 7.0
+	// FlagFontSize is the font size of flag glyphs, as in the .flag-box style
+	FlagFontSize = 10.0
 
 	// FlagBoxPadding is horizontal padding inside flag boxes
 	FlagBoxPadding = 6.0
@@ -48,6 +52,18 @@ const (
 	FlagBoxTextOffset = 3.0
 )
 
+// Code chip constants
+const (
+	// CodeChipPadding is horizontal padding inside a code chip
+	CodeChipPadding = 8.0
+
+	// CodeChipGap is space between code chips and after the "Codes:" label
+	CodeChipGap = 4.0
+
+	// CodeChipRadius is the corner radius of code chips
+	CodeChipRadius = 7.0
+)
+
 // Tree line constants
 const (
 	// TreeHorizontalGap is gap between horizontal tree line and icon
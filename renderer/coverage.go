@@ -0,0 +1,129 @@
+package renderer
+
+import (
+	"fmt"
+
+	"fhir_renderer/models"
+)
+
+// Coverage summary bar dimensions
+const (
+	CoverageBarHeight   = 28.0
+	CoverageMeterWidth  = 160.0
+	CoverageMeterHeight = 10.0
+	CoverageFontSize    = 11.0
+)
+
+// coverageMeterColors fill the implemented, TODO and unused parts of the
+// meter in the summary bar
+var coverageMeterColors = struct{ used, todo, notUsed string }{"#2E7D32", "#B34700", "#999999"}
+
+// coverage counts the element rows by usage; the root and truncation
+// placeholders are not elements
+type coverage struct {
+	total, used, todo, notUsed int
+}
+
+// countCoverage counts the usage of rows
+func countCoverage(rows []RowData) coverage {
+	var c coverage
+	for _, row := range rows {
+		usage := row.Element.Element.Usage
+		if row.IsRoot || usage == models.UsageTruncated {
+			continue
+		}
+		c.total++
+		switch usage {
+		case models.UsageUsed:
+			c.used++
+		case models.UsageTodo:
+			c.todo++
+		case models.UsageNotUsed:
+			c.notUsed++
+		}
+	}
+	return c
+}
+
+// coverageText returns the translated summary, e.g. "42/77 elements
+// implemented, 12 TODO"
+func coverageText(c coverage, config SVGConfig) string {
+	return fmt.Sprintf(config.text("%d/%d elements implemented, %d TODO"), c.used, c.total, c.todo)
+}
+
+// coverageRowColor returns the row tint of a usage in the coverage view, or
+// "" for usages without one
+func coverageRowColor(usage string, config SVGConfig) string {
+	switch usage {
+	case models.UsageUsed:
+		return config.UsedRowColor
+	case models.UsageTodo:
+		return config.TodoRowColor
+	case models.UsageNotUsed:
+		return config.NotUsedRowColor
+	}
+	return ""
+}
+
+// coverageBarHeight returns the height of the coverage summary bar below the
+// rows, 0 when it is not shown
+func coverageBarHeight(config SVGConfig) float64 {
+	if !config.Coverage || config.hideCoverageSummary {
+		return 0
+	}
+	return CoverageBarHeight
+}
+
+// buildCoverageBar draws the summary bar at y: a meter split by usage and
+// the implemented and TODO counts
+func buildCoverageBar(rows []RowData, y, totalWidth float64, config SVGConfig) string {
+	if coverageBarHeight(config) == 0 {
+		return ""
+	}
+	c := countCoverage(rows)
+	meterX := config.Padding
+	meterY := y + (CoverageBarHeight-CoverageMeterHeight)/2
+
+	meter := newNode("g").attr("class", "coverage-meter").append(
+		newNode("rect").fixed("x", meterX, 0).fixed("y", meterY, 0).fixed("width", CoverageMeterWidth, 0).
+			fixed("height", CoverageMeterHeight, 0).attr("fill", config.RowBgColor).attr("stroke", config.BorderColor),
+	)
+	x := meterX
+	for _, part := range []struct {
+		count int
+		fill  string
+	}{
+		{c.used, coverageMeterColors.used},
+		{c.todo, coverageMeterColors.todo},
+		{c.notUsed, coverageMeterColors.notUsed},
+	} {
+		if part.count == 0 {
+			continue
+		}
+		width := CoverageMeterWidth * float64(part.count) / float64(c.total)
+		meter.append(newNode("rect").attr("x", num(x)).fixed("y", meterY, 0).attr("width", num(width)).
+			fixed("height", CoverageMeterHeight, 0).attr("fill", part.fill))
+		x += width
+	}
+
+	return newNode("g").attr("class", "coverage").append(
+		newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", CoverageBarHeight, 0).
+			attr("fill", config.HeaderBgColor).attr("stroke", config.BorderColor),
+		meter,
+		newNode("text").fixed("x", meterX+CoverageMeterWidth+config.Padding, 0).fixed("y", y+CoverageBarHeight/2+TextVerticalOffset, 0).
+			attr("font-family", config.FontFamily).attr("font-size", px(CoverageFontSize)).attr("fill", config.TextColor).
+			content(coverageText(c, config)),
+	).String()
+}
+
+// htmlCoverageSummary returns the summary line below the HTML table, or ""
+func htmlCoverageSummary(flat []models.FlatElement, config SVGConfig) string {
+	if !config.Coverage {
+		return ""
+	}
+	rows := make([]RowData, len(flat))
+	for i, fe := range flat {
+		rows[i] = RowData{Element: fe, IsRoot: i == 0}
+	}
+	return fmt.Sprintf("<p class=\"coverage\">%s</p>\n", escapeXML(coverageText(countCoverage(rows), config)))
+}
@@ -0,0 +1,79 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// extensionURL returns the url shown below an extension row's name, or ""
+// when there is none or it only repeats the name, as the slice name urls of
+// sub-extensions do
+func extensionURL(fe models.FlatElement) string {
+	if fe.Extension == nil || fe.Extension.URL == fe.Extension.Name {
+		return ""
+	}
+	return fe.Extension.URL
+}
+
+// extensionContext returns the "Context: …" line of an extension row, or ""
+func extensionContext(fe models.FlatElement, config SVGConfig) string {
+	if fe.Extension == nil || fe.Extension.Context == "" {
+		return ""
+	}
+	return config.text(ExtensionContextLabel) + " " + fe.Extension.Context
+}
+
+// extensionLink returns the link target of an extension url: absolute urls
+// with a safe scheme, or "" for relative ones such as slice names
+func (c SVGConfig) extensionLink(url string) string {
+	if !strings.Contains(url, "://") {
+		return ""
+	}
+	return c.safeLink(url)
+}
+
+// renderExtensionLines renders the url, linked, and the context of an
+// extension row below its name
+func renderExtensionLines(row RowData, nameX, baseTextY float64, config SVGConfig) []*node {
+	var nodes []*node
+	y := baseTextY + float64(len(row.NameLines))*config.LineHeight
+	link := config.extensionLink(extensionURL(row.Element))
+	class := "cell-text"
+	if link != "" {
+		class = "link-text"
+	}
+	var urlLines []*node
+	for _, line := range row.URLLines {
+		urlLines = append(urlLines, newNode("text").fixed("x", nameX, 0).fixed("y", y, 0).attr("class", class).content(line))
+		y += config.LineHeight
+	}
+	if link != "" && len(urlLines) > 0 {
+		nodes = append(nodes, newNode("a").attr("xlink:href", link).attr("target", "_blank").append(urlLines...))
+	} else {
+		nodes = append(nodes, urlLines...)
+	}
+	for _, line := range row.ContextLines {
+		nodes = append(nodes, newNode("text").fixed("x", nameX, 0).fixed("y", y, 0).attr("class", "cell-text").content(line))
+		y += config.LineHeight
+	}
+	return nodes
+}
+
+// htmlExtensionDetails renders the url and context of an extension row for
+// the HTML name cell, or ""
+func htmlExtensionDetails(fe models.FlatElement, config SVGConfig) string {
+	var sb strings.Builder
+	if url := extensionURL(fe); url != "" {
+		if link := config.extensionLink(url); link != "" {
+			sb.WriteString(fmt.Sprintf(`<br><a href="%s" target="_blank" rel="noopener">%s</a>`, escapeXML(link), escapeXML(url)))
+		} else {
+			sb.WriteString("<br>" + escapeXML(url))
+		}
+	}
+	if context := extensionContext(fe, config); context != "" {
+		sb.WriteString("<br>" + escapeXML(context))
+	}
+	return sb.String()
+}
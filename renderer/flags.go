@@ -2,45 +2,203 @@ package renderer
 
 import (
 	"fmt"
-	"strings"
+	"math"
+	"regexp"
+	"slices"
+
+	"fhir_renderer/models"
 )
 
-func renderFlags(flags []string, config SVGConfig) string {
+// FlagStyle describes how a flag code is drawn and explained
+type FlagStyle struct {
+	Code        string // Flag code as listed in element flags, e.g. "MS"
+	Text        string // Displayed glyphs; empty shows the code
+	Boxed       bool   // Draw a box around the text
+	Fill        string // Box fill color; empty for an outline-only box
+	TextColor   string // Text color override; empty for the default
+	Description string // Tooltip, accessible label and legend text
+}
+
+// BuiltinFlags are the FHIR flags in legend order. The must support box
+// is filled with SVGConfig.MustSupportColor.
+var BuiltinFlags = []FlagStyle{
+	{Code: models.FlagSummary, Text: "\u03A3", Description: "Summary element"},
+	{Code: models.FlagModifier, Text: "?!\u03A3", Description: "Modifier element"},
+	{Code: models.FlagConstraint, Description: "Has constraint"},
+	{Code: models.FlagTrialUse, Boxed: true, Description: "Trial use"},
+	{Code: models.FlagNormative, Boxed: true, Description: "Normative"},
+	// White "S" on a solid box, matching the FHIR IG publisher
+	{Code: models.FlagMustSupport, Text: "S", Boxed: true, TextColor: "#FFFFFF", Description: "Must support"},
+}
+
+// flagCodePattern restricts custom flag codes to what fits in a flags list
+var flagCodePattern = regexp.MustCompile(`^[A-Za-z0-9?!_-]+$`)
+
+// defaultFlags are the custom flags of DefaultConfig; see SetDefaultFlags
+var defaultFlags []FlagStyle
+
+// SetDefaultFlags sets the custom flags DefaultConfig uses, e.g. from the
+// server configuration. The list must pass ValidateFlags.
+func SetDefaultFlags(flags []FlagStyle) {
+	defaultFlags = slices.Clone(flags)
+}
+
+// ValidateFlags checks that custom flag codes are unique and use only
+// letters, digits and "?!_-", and that colors are hex colors
+func ValidateFlags(flags []FlagStyle) error {
+	seen := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		switch {
+		case !flagCodePattern.MatchString(flag.Code):
+			return fmt.Errorf("invalid flag code %q (use letters, digits and '?!_-')", flag.Code)
+		case seen[flag.Code]:
+			return fmt.Errorf("flag %q listed twice", flag.Code)
+		case flag.Fill != "" && !models.IsHexColor(flag.Fill):
+			return fmt.Errorf("flag %q has an invalid fill %q (expected a hex color)", flag.Code, flag.Fill)
+		case flag.TextColor != "" && !models.IsHexColor(flag.TextColor):
+			return fmt.Errorf("flag %q has an invalid text color %q (expected a hex color)", flag.Code, flag.TextColor)
+		}
+		seen[flag.Code] = true
+	}
+	return nil
+}
+
+// flagStyles returns the flag registry: the built-in flags, replaced by
+// custom flags with the same code, followed by the other custom flags
+func (c SVGConfig) flagStyles() []FlagStyle {
+	styles := slices.Clone(BuiltinFlags)
+	for i := range styles {
+		if styles[i].Code == models.FlagMustSupport {
+			styles[i].Fill = c.MustSupportColor
+		}
+	}
+	for _, flag := range c.Flags {
+		if i := slices.IndexFunc(styles, func(s FlagStyle) bool { return s.Code == flag.Code }); i >= 0 {
+			styles[i] = flag
+		} else {
+			styles = append(styles, flag)
+		}
+	}
+	return styles
+}
+
+// flagStyleFor returns the display style for a flag code. Unregistered
+// codes are drawn as plain text without a description. It runs for every
+// flag of every row, so it looks the code up instead of building flagStyles.
+func flagStyleFor(flag string, config SVGConfig) FlagStyle {
+	hasCode := func(s FlagStyle) bool { return s.Code == flag }
+	style := FlagStyle{Code: flag}
+	if i := slices.IndexFunc(config.Flags, hasCode); i >= 0 {
+		style = config.Flags[i]
+	} else if i := slices.IndexFunc(BuiltinFlags, hasCode); i >= 0 {
+		style = BuiltinFlags[i]
+		if flag == models.FlagMustSupport {
+			style.Fill = config.MustSupportColor
+		}
+	}
+	if style.Text == "" {
+		style.Text = style.Code
+	}
+	return style
+}
+
+// flagLabel returns the translated description of a flag, or its code
+func flagLabel(style FlagStyle, config SVGConfig) string {
+	if style.Description == "" {
+		return style.Code
+	}
+	return config.text(style.Description)
+}
+
+// flagWidth returns the drawn width of a flag: its measured glyphs, plus
+// the padding of its box when boxed
+func flagWidth(style FlagStyle, config SVGConfig) float64 {
+	width := math.Ceil(config.textMeasurer.MeasureString(style.Text) * FlagFontSize / config.FontSize)
+	if style.Boxed {
+		width += FlagBoxPadding
+	}
+	return width
+}
+
+// flagsWidth returns the width of flags drawn on one line
+func flagsWidth(flags []string, config SVGConfig) float64 {
+	width := 0.0
+	for i, flag := range flags {
+		if i > 0 {
+			width += FlagGap
+		}
+		width += flagWidth(flagStyleFor(flag, config), config)
+	}
+	return width
+}
+
+// wrapFlags breaks flags into lines no wider than maxWidth. A flag wider
+// than maxWidth gets a line of its own, which renderFlags shrinks to fit.
+func wrapFlags(flags []string, maxWidth float64, config SVGConfig) [][]string {
+	var lines [][]string
+	start, width := 0, 0.0 // The current line is flags[start:i], width wide
+	for i, flag := range flags {
+		w := flagWidth(flagStyleFor(flag, config), config)
+		if i > start {
+			if width+FlagGap+w <= maxWidth {
+				width += FlagGap + w
+				continue
+			}
+			lines = append(lines, flags[start:i:i])
+			start = i
+		}
+		width = w
+	}
+	if start < len(flags) {
+		lines = append(lines, flags[start:])
+	}
+	return lines
+}
+
+// calculateFlagsColumnWidth widens the flags column so the widest row's
+// flags fit on one line, within MinFlagsColWidth and MaxFlagsColWidth
+func calculateFlagsColumnWidth(flat []models.FlatElement, tm *TextMeasurer, config SVGConfig) float64 {
+	if !config.showsColumn(ColumnFlags) {
+		return 0
+	}
+	width := columnHeaderWidth(ColumnFlags, tm, config)
+	for _, fe := range flat {
+		width = max(width, flagsWidth(fe.Element.Flags, config)+config.Padding*2)
+	}
+	return math.Ceil(min(max(width, MinFlagsColWidth), MaxFlagsColWidth))
+}
+
+// renderFlags draws flags on one line starting at x 0, shrinking them
+// when they are wider than maxWidth
+func renderFlags(flags []string, maxWidth float64, config SVGConfig) []*node {
 	if len(flags) == 0 {
-		return ""
+		return nil
 	}
 
-	var sb strings.Builder
+	var nodes []*node
 	x := 0.0
 
 	for _, flag := range flags {
-		displayFlag := flag
-		needsBox := false
-
-		switch flag {
-		case "S":
-			displayFlag = "\u03A3"
-		case "?!":
-			displayFlag = "?!\u03A3"
-		case "I":
-			displayFlag = "I"
-		case "TU", "N":
-			needsBox = true
-		}
-
-		if needsBox {
-			boxWidth := float64(len(displayFlag))*FlagCharWidth + FlagBoxPadding
-			sb.WriteString(fmt.Sprintf(`<rect x="%.0f" y="-8" width="%.0f" height="14" fill="none" stroke="%s" rx="2"/>`,
-				x, boxWidth, config.BorderColor))
-			sb.WriteString(fmt.Sprintf(`<text x="%.0f" y="2" class="flag-box">%s</text>`,
-				x+FlagBoxTextOffset, escapeXML(displayFlag)))
-			x += boxWidth + FlagGap
-		} else {
-			sb.WriteString(fmt.Sprintf(`<text x="%.0f" y="2" class="flag-box">%s</text>`,
-				x, escapeXML(displayFlag)))
-			x += float64(len(displayFlag))*FlagCharWidth + FlagGap
+		style := flagStyleFor(flag, config)
+		width := flagWidth(style, config)
+		group := newNode("g").append(newNode("title").content(flagLabel(style, config)))
+		textX := x
+		if style.Boxed {
+			fill, stroke := "none", config.BorderColor
+			if style.Fill != "" {
+				fill, stroke = style.Fill, style.Fill
+			}
+			group.append(newNode("rect").fixed("x", x, 0).attr("y", "-8").fixed("width", width, 0).attr("height", "14").
+				attr("fill", fill).attr("stroke", stroke).attr("rx", "2"))
+			textX += FlagBoxTextOffset
+		}
+		text := newNode("text").fixed("x", textX, 0).attr("y", "2").attr("class", "flag-box")
+		if style.TextColor != "" {
+			text.attr("style", "fill: "+style.TextColor)
 		}
+		nodes = append(nodes, group.append(text.content(style.Text)))
+		x += width + FlagGap
 	}
 
-	return sb.String()
+	return scaleToFit(nodes, x-FlagGap, maxWidth)
 }
@@ -0,0 +1,94 @@
+package renderer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+// Font is a custom TrueType or OpenType font used for both text measurement
+// and rendering, so wrapping matches what the viewer draws
+type Font struct {
+	Family string `json:"family"` // Family name from the font's name table
+	Format string `json:"format"` // "truetype" or "opentype"
+	SHA256 string `json:"sha256"` // Identifies the font data in ETags
+
+	data   []byte
+	parsed *opentype.Font
+}
+
+// ParseFont parses TTF or OTF font data
+func ParseFont(data []byte) (*Font, error) {
+	parsed, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid font: %w", err)
+	}
+
+	family, err := parsed.Name(nil, sfnt.NameIDFamily)
+	if err != nil || strings.TrimSpace(family) == "" {
+		return nil, errors.New("invalid font: missing family name")
+	}
+
+	format := "truetype"
+	if bytes.HasPrefix(data, []byte("OTTO")) {
+		format = "opentype"
+	}
+
+	sum := sha256.Sum256(data)
+	return &Font{
+		Family: strings.TrimSpace(family),
+		Format: format,
+		SHA256: hex.EncodeToString(sum[:]),
+		data:   data,
+		parsed: parsed,
+	}, nil
+}
+
+// UseFont measures and renders text with f. The font's family is put in
+// front of the configured FontFamily so viewers without it fall back.
+func (c *SVGConfig) UseFont(f *Font) {
+	c.Font = f
+	c.FontFamily = fmt.Sprintf("'%s', %s", cssEscapeFamily(f.Family), c.FontFamily)
+}
+
+// cssEscapeFamily makes a family name safe inside a quoted CSS string and
+// an XML attribute
+func cssEscapeFamily(family string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\'', '"', '\\', '<', '>', '&', ';', '{', '}':
+			return -1
+		}
+		return r
+	}, family)
+}
+
+// fontFaceCSS returns an @font-face rule embedding the custom font as a data
+// URI, or "" when no font is set or embedding is disabled
+func fontFaceCSS(config SVGConfig) string {
+	if config.Font == nil || !config.EmbedFont {
+		return ""
+	}
+	mime := "font/ttf"
+	if config.Font.Format == "opentype" {
+		mime = "font/otf"
+	}
+	return fmt.Sprintf("        @font-face { font-family: '%s'; src: url(data:%s;base64,%s) format('%s'); }\n",
+		cssEscapeFamily(config.Font.Family), mime,
+		base64.StdEncoding.EncodeToString(config.Font.data), config.Font.Format)
+}
+
+// newTextMeasurer creates the text measurer for the configured font
+func newTextMeasurer(config SVGConfig) (*TextMeasurer, error) {
+	if config.Font != nil {
+		return newTextMeasurerForFont(config.Font.parsed, config.FontSize)
+	}
+	return NewTextMeasurer(config.FontSize)
+}
@@ -0,0 +1,14 @@
+//go:build !js
+
+package renderer
+
+import "os"
+
+// LoadFontFile reads and parses a TTF or OTF font file
+func LoadFontFile(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFont(data)
+}
@@ -0,0 +1,84 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fhir_renderer/models"
+)
+
+// Run "go test ./renderer -run TestGolden -update" to rewrite the expected
+// SVGs after an intended change, then review the diff before committing.
+var updateGolden = flag.Bool("update", false, "rewrite testdata/golden/*.svg from the current output")
+
+// TestGolden renders every testdata/golden/*.json with the default config and
+// compares the output with the .svg file of the same name
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/golden/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden inputs in testdata/golden")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var resource models.ResourceDefinition
+			if err := json.Unmarshal(data, &resource); err != nil {
+				t.Fatal(err)
+			}
+
+			var got bytes.Buffer
+			if err := RenderTo(&got, &resource, DefaultConfig()); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := strings.TrimSuffix(input, ".json") + ".svg"
+			if *updateGolden {
+				if err := os.WriteFile(golden, got.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				line, gotLine, wantLine := firstDiff(got.String(), string(want))
+				t.Errorf("output differs from %s at line %d:\n got: %s\nwant: %s\n(run with -update if the change is intended)",
+					golden, line, gotLine, wantLine)
+			}
+		})
+	}
+}
+
+// firstDiff returns the first line number (1-based) where got and want
+// differ, with both lines
+func firstDiff(got, want string) (int, string, string) {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return i + 1, g, w
+		}
+	}
+	return 0, "", ""
+}
@@ -0,0 +1,65 @@
+package renderer
+
+import "unicode"
+
+// zeroWidthJoiner joins emoji into a single glyph, e.g. 👩‍💻
+const zeroWidthJoiner = '\u200D'
+
+// graphemes splits s into user-perceived characters, approximating the
+// grapheme clusters of UAX #29: a base character keeps its combining marks,
+// variation selectors, emoji modifiers and zero-width-joined successors, and
+// regional indicators pair up into flags. Text is never broken inside one.
+func graphemes(s string) []string {
+	var clusters []string
+	start := 0
+	prev := rune(-1)
+	indicators := 0 // Regional indicators in the current cluster
+	for i, r := range s {
+		if i > start && !extendsGrapheme(prev, r, indicators) {
+			clusters = append(clusters, s[start:i])
+			start = i
+			indicators = 0
+		}
+		if isRegionalIndicator(r) {
+			indicators++
+		}
+		prev = r
+	}
+	if start < len(s) {
+		clusters = append(clusters, s[start:])
+	}
+	return clusters
+}
+
+// extendsGrapheme reports whether r continues the cluster ending in prev
+func extendsGrapheme(prev, r rune, indicators int) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return true
+	case prev == zeroWidthJoiner, r == zeroWidthJoiner:
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case isGraphemeExtender(r):
+		return true
+	case isRegionalIndicator(prev) && isRegionalIndicator(r):
+		return indicators%2 == 1
+	}
+	return false
+}
+
+// isGraphemeExtender reports whether r modifies the preceding character
+// without being a combining mark: variation selectors, emoji skin tones and
+// the tag characters of subdivision flags
+func isGraphemeExtender(r rune) bool {
+	return (r >= 0xFE00 && r <= 0xFE0F) ||
+		(r >= 0xE0100 && r <= 0xE01EF) ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) ||
+		(r >= 0xE0020 && r <= 0xE007F)
+}
+
+// isRegionalIndicator reports whether r is one of the letters that form
+// flag emoji in pairs, e.g. 🇩🇪
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
@@ -0,0 +1,66 @@
+package renderer
+
+import (
+	"fmt"
+
+	"fhir_renderer/models"
+)
+
+// GroupMarker precedes the name in group header bands
+const GroupMarker = "\u25BE" // ▾
+
+// isGroupRow reports whether a row opens a group: a top-level element with
+// children, like Claim.item or ExplanationOfBenefit.adjudication
+func isGroupRow(fe models.FlatElement) bool {
+	return fe.Depth == 1 && len(fe.Element.Elements) > 0
+}
+
+// groupHeaderHeight returns the height of the band drawn above the row, 0
+// when GroupHeaders is off or the row opens no group
+func groupHeaderHeight(fe models.FlatElement, config SVGConfig) float64 {
+	if !config.GroupHeaders || !isGroupRow(fe) {
+		return 0
+	}
+	return config.GroupHeaderHeight
+}
+
+// groupLabel returns the text of a group header band
+func groupLabel(fe models.FlatElement) string {
+	return GroupMarker + " " + fe.Element.Name
+}
+
+// renderGroupHeader draws the band naming the group a row opens. The root's
+// tree line continues through the band so the tree stays connected.
+func renderGroupHeader(row RowData, y, totalWidth float64, config SVGConfig) *node {
+	height := row.GroupHeight
+	band := newNode("g").attr("class", "group-header").attr("aria-hidden", "true").append(
+		newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", height, 0).
+			attr("fill", config.HeaderBgColor),
+		newNode("line").attr("x1", "0").fixed("y1", y+height, 0).fixed("x2", totalWidth, 0).fixed("y2", y+height, 0).
+			attr("stroke", config.BorderColor).fixed("stroke-width", BorderStrokeWidth, 1),
+	)
+
+	x := config.Padding
+	for _, col := range config.columnSpans() {
+		if col.key == ColumnName {
+			x += col.x
+		}
+	}
+	trunkX := x + config.TreeStyle.IndentPx/2
+	band.append(
+		newNode("line").attr("x1", num(trunkX)).attr("y1", num(y)).attr("x2", num(trunkX)).attr("y2", num(y+height)).
+			attr("stroke", config.TreeStyle.Color).attr("stroke-width", num(config.TreeStyle.Width)),
+		newNode("text").fixed("x", x+config.TreeStyle.IndentPx, 0).fixed("y", y+height/2+TextVerticalOffset, 0).
+			attr("class", "header-text").content(groupLabel(row.Element)),
+	)
+	return band
+}
+
+// htmlGroupHeader returns the table row naming the group a row opens, or ""
+func htmlGroupHeader(fe models.FlatElement, config SVGConfig) string {
+	if !config.GroupHeaders || !isGroupRow(fe) {
+		return ""
+	}
+	return fmt.Sprintf(`<tr class="group-header" style="background: %s;"><th colspan="%d" scope="rowgroup" style="padding-left: %.0fpx;">%s</th></tr>
+`, config.HeaderBgColor, len(config.columns()), config.TreeStyle.IndentPx+8, escapeXML(groupLabel(fe)))
+}
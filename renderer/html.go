@@ -0,0 +1,273 @@
+package renderer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// htmlStyles is the embedded stylesheet for the HTML table output
+const htmlStyles = `
+        .fhir-structure { overflow-x: auto; font-family: %s; font-size: %.0fpx; color: %s; }
+        .fhir-structure table { border-collapse: collapse; width: 100%%; min-width: 640px; }
+        .fhir-structure caption { text-align: left; font-weight: bold; font-size: 14px; padding: 8px; background: %s; border: 1px solid %s; border-bottom: none; }
+        .fhir-structure thead th { text-align: left; font-size: %.0fpx; background: %s; border: 1px solid %s; padding: 6px 8px; }
+        .fhir-structure td, .fhir-structure tbody th { vertical-align: top; text-align: left; border-left: 1px solid %s; border-right: 1px solid %s; border-bottom: %.1fpx solid %s; padding: 4px 8px; }
+        .fhir-structure tbody tr:nth-child(even) { background: %s; }
+        .fhir-structure .name { font-weight: normal; white-space: nowrap; padding-left: calc(var(--depth) * %.0fpx + 8px); }
+        .fhir-structure .name svg { vertical-align: middle; margin-right: %.0fpx; }
+        .fhir-structure a { color: %s; }
+        .fhir-structure .not-used, .fhir-structure .not-used a { color: %s; font-style: italic; }
+        .fhir-structure tbody tr.must-support { background: %s; }
+        .fhir-structure tbody tr:target { background: %s; }
+        .fhir-structure .todo { color: %s; font-weight: bold; }
+        .fhir-structure .flag-box { border: 1px solid %s; border-radius: 2px; padding: 0 2px; font-size: 10px; }
+        .fhir-structure .flag { margin-right: %.0fpx; }
+        @media (max-width: 640px) {
+            .fhir-structure .name { white-space: normal; }
+        }
+`
+
+// RenderHTML generates an accessible HTML table with the same columns as the SVG
+func RenderHTML(resource *models.ResourceDefinition, config SVGConfig) string {
+	var sb strings.Builder
+	config.showAnnotations = resource.HasAnnotationNotes()
+	config.showSample = resource.HasExample()
+	config.fhirVersion = resource.FHIRVersionOf()
+
+	lang := config.Lang
+	if lang == "" {
+		lang = DefaultLang
+	}
+	sb.WriteString(fmt.Sprintf(`<!DOCTYPE html>
+<html lang="%s">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+`, lang))
+	sb.WriteString(fmt.Sprintf("    <title>%s</title>\n", escapeXML(structureTitle(resource, config))))
+	sb.WriteString("    <style>")
+	if css := fontFaceCSS(config); css != "" {
+		sb.WriteString("\n" + strings.TrimRight(css, "\n"))
+	}
+	sb.WriteString(fmt.Sprintf(htmlStyles,
+		config.FontFamily, config.FontSize, config.TextColor,
+		config.HeaderBgColor, config.BorderColor,
+		config.HeaderFontSize, config.HeaderBgColor, config.BorderColor,
+		config.BorderColor, config.BorderColor, BorderStrokeWidth, config.BorderColor,
+		config.AltRowBgColor,
+		config.TreeStyle.IndentPx, IconTextGap,
+		config.LinkColor,
+		config.NotUsedColor,
+		config.MustSupportRowColor,
+		config.TargetRowColor,
+		config.TodoColor,
+		config.BorderColor,
+		FlagGap))
+	sb.WriteString(htmlHoverCSS(config))
+	sb.WriteString("    </style>\n</head>\n<body>\n")
+
+	wrapperStyle := ""
+	if config.Watermark != "" {
+		wrapperStyle = ` style="position: relative;"`
+	}
+	sb.WriteString(fmt.Sprintf(`<div class="fhir-structure"%s>
+<table>
+<caption>%s</caption>
+<thead>
+<tr>`, wrapperStyle, escapeXML(structureTitleBar(resource, config))+htmlStatusBadge(resource.Status, config)+htmlBranding(config)))
+	for _, key := range config.columns() {
+		sb.WriteString(fmt.Sprintf(`<th scope="col">%s</th>`, escapeXML(config.columnTitle(key))))
+	}
+	sb.WriteString(`</tr>
+</thead>
+<tbody>
+`)
+	seen := make(map[string]int)
+	flat := config.flatten(resource)
+	for i, fe := range flat {
+		sb.WriteString(htmlGroupHeader(fe, config))
+		sb.WriteString(renderHTMLRow(fe, anchorID(fe, seen), i+1, config))
+	}
+	sb.WriteString("</tbody>\n</table>\n")
+	sb.WriteString(htmlCoverageSummary(flat, config))
+	sb.WriteString(htmlRepoLink(config))
+	sb.WriteString(htmlWatermark(config))
+	sb.WriteString("</div>\n</body>\n</html>\n")
+
+	return sb.String()
+}
+
+// renderHTMLRow renders one table row for a flattened element; number is the
+// row's 1-based position, the root row being 1
+func renderHTMLRow(fe models.FlatElement, id string, number int, config SVGConfig) string {
+	var sb strings.Builder
+	elem := config.withTypeLinks(fe.Element)
+
+	rowClass := ""
+	if elem.Usage == models.UsageNotUsed || elem.Usage == models.UsageTruncated {
+		rowClass = ` class="not-used"`
+	} else if config.HighlightMustSupport && slices.Contains(elem.Flags, models.FlagMustSupport) {
+		rowClass = ` class="must-support"`
+	}
+	coverageColor := ""
+	if config.Coverage {
+		coverageColor = coverageRowColor(elem.Usage, config)
+	}
+	if fe.Annotation != nil {
+		rowClass += htmlAnnotationStyle(fe)
+	} else if coverageColor != "" {
+		rowClass += fmt.Sprintf(` style="background: %s"`, coverageColor)
+	} else if color, ok := models.HighlightColor(elem.Highlight); ok {
+		rowClass += fmt.Sprintf(` style="background: %s"`, color)
+	} else if elem.Change == models.ChangeAdded {
+		rowClass += fmt.Sprintf(` style="background: %s"`, config.AddedRowColor)
+	}
+	sb.WriteString(fmt.Sprintf(`<tr id="%s"%s data-path="%s" aria-level="%d">`, escapeXML(id), rowClass, escapeXML(fe.Path), fe.Depth+1))
+	for _, key := range config.columns() {
+		if key == ColumnRowNumber {
+			sb.WriteString(fmt.Sprintf(`<td style="text-align: right;"><a href="#%s">%d</a></td>`, escapeXML(id), number))
+			continue
+		}
+		sb.WriteString(renderHTMLCell(key, fe, elem, number == 1, config))
+	}
+	sb.WriteString("</tr>\n")
+	return sb.String()
+}
+
+// renderHTMLCell renders the cell of one column
+func renderHTMLCell(key string, fe models.FlatElement, elem models.Element, isRoot bool, config SVGConfig) string {
+	var sb strings.Builder
+	switch key {
+	case ColumnName:
+		renderHTMLName(&sb, fe, elem, isRoot, config)
+	case ColumnFlags:
+		renderHTMLFlags(&sb, elem, config)
+	case ColumnCardinality:
+		renderHTMLCardinality(&sb, elem, config)
+	case ColumnType:
+		renderHTMLType(&sb, elem)
+	case ColumnDescription:
+		descText, _ := buildDescriptionText(fe, config)
+		descClass := ""
+		if elem.Usage == models.UsageTodo {
+			descClass = ` class="todo"`
+		}
+		if isRTL(descText) {
+			descClass += ` dir="rtl"`
+		}
+		sb.WriteString(fmt.Sprintf("<td%s>%s", descClass, escapeXML(descText)))
+		for i, vc := range valueConstraints(elem, config) {
+			if i > 0 || descText != "" {
+				sb.WriteString("<br>")
+			}
+			sb.WriteString(fmt.Sprintf("<strong>%s</strong> %s", escapeXML(vc.label), escapeXML(vc.value)))
+		}
+		sb.WriteString("</td>")
+	case ColumnMappings:
+		renderHTMLMappings(&sb, elem)
+	case ColumnSample:
+		sb.WriteString(htmlSampleCell(fe))
+	case ColumnAnnotations:
+		sb.WriteString(htmlAnnotationCell(fe))
+	default:
+		sb.WriteString(fmt.Sprintf("<td>%s</td>", escapeXML(elem.Meta[key])))
+	}
+	return sb.String()
+}
+
+// renderHTMLName renders the name with the same icon as the SVG, indented by depth
+func renderHTMLName(sb *strings.Builder, fe models.FlatElement, elem models.Element, isRoot bool, config SVGConfig) {
+	iconType := config.iconFor(elem.Type, isRoot, len(elem.Elements) > 0)
+	sb.WriteString(fmt.Sprintf(`<th scope="row" class="name" style="--depth: %d">`, fe.Depth))
+	if elem.Usage != models.UsageTruncated {
+		sb.WriteString(fmt.Sprintf(`<svg width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" aria-hidden="true">%s</svg>`,
+			config.IconSize, config.IconSize, config.IconSize, config.IconSize,
+			config.renderIcon(iconType, 0, 0, config.IconSize).String()))
+	}
+	if elementURL := config.elementURL(fe.Path); elementURL != "" && elem.Usage != models.UsageTruncated {
+		sb.WriteString(fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener">%s</a>`, escapeXML(elementURL), escapeXML(elem.Name)))
+	} else {
+		sb.WriteString(escapeXML(elem.Name))
+	}
+	sb.WriteString(htmlExtensionDetails(fe, config))
+	sb.WriteString("</th>")
+}
+
+// renderHTMLFlags renders the flags with their meaning as an accessible label
+func renderHTMLFlags(sb *strings.Builder, elem models.Element, config SVGConfig) {
+	sb.WriteString("<td>")
+	for _, flag := range elem.Flags {
+		style := flagStyleFor(flag, config)
+		class := "flag"
+		if style.Boxed {
+			class += " flag-box"
+		}
+		inline := ""
+		if style.Fill != "" {
+			inline = fmt.Sprintf(` style="background: %s; border-color: %s; color: %s"`, style.Fill, style.Fill, style.TextColor)
+		}
+		label := flagLabel(style, config)
+		sb.WriteString(fmt.Sprintf(`<span class="%s"%s title="%s" aria-label="%s">%s</span>`,
+			class, inline, escapeXML(label), escapeXML(label), escapeXML(style.Text)))
+	}
+	sb.WriteString("</td>")
+}
+
+// renderHTMLCardinality renders the cardinality, bold when a profile tightened it
+func renderHTMLCardinality(sb *strings.Builder, elem models.Element, config SVGConfig) {
+	base := config.text("Base:") + " " + elem.BaseCardinality
+	switch {
+	case elem.Change == models.ChangeTightened:
+		sb.WriteString(fmt.Sprintf(`<td><strong title="%s">%s</strong></td>`, escapeXML(base), escapeXML(elem.Cardinality)))
+	case elem.BaseCardinality != "":
+		sb.WriteString(fmt.Sprintf(`<td title="%s">%s</td>`, escapeXML(base), escapeXML(elem.Cardinality)))
+	default:
+		sb.WriteString(fmt.Sprintf("<td>%s</td>", escapeXML(elem.Cardinality)))
+	}
+}
+
+// renderHTMLMappings renders one line per mapping with the identity in bold
+func renderHTMLMappings(sb *strings.Builder, elem models.Element) {
+	sb.WriteString("<td>")
+	for i, mapping := range elem.Mappings {
+		if i > 0 {
+			sb.WriteString("<br>")
+		}
+		sb.WriteString(fmt.Sprintf("<strong>%s:</strong> %s", escapeXML(mapping.Identity), escapeXML(mapping.Map)))
+	}
+	sb.WriteString("</td>")
+}
+
+// renderHTMLType renders the type with links to its definition or targets
+func renderHTMLType(sb *strings.Builder, elem models.Element) {
+	if len(elem.Targets) > 0 {
+		sb.WriteString("<td>")
+		for _, seg := range splitTypeLinks(elem.DisplayType(), elem) {
+			if seg.URL != "" {
+				sb.WriteString(fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener">%s</a>`,
+					escapeXML(seg.URL), escapeXML(seg.Text)))
+			} else {
+				sb.WriteString(escapeXML(seg.Text))
+			}
+		}
+		sb.WriteString("</td>")
+	} else if elem.TypeRef != "" {
+		sb.WriteString(fmt.Sprintf(`<td><a href="%s" target="_blank" rel="noopener">%s</a></td>`,
+			escapeXML(elem.TypeRef), escapeXML(elem.Type)))
+	} else {
+		sb.WriteString(fmt.Sprintf("<td>%s</td>", escapeXML(elem.Type)))
+	}
+}
+
+// htmlStatusBadge returns the publication status badge for the caption, or ""
+func htmlStatusBadge(status string, config SVGConfig) string {
+	label := statusLabel(status, config)
+	if label == "" {
+		return ""
+	}
+	return fmt.Sprintf(` <span class="status" style="background: %s; color: #FFFFFF; border-radius: 9px; padding: 1px 8px; margin-left: 8px; font-size: 10px; font-weight: normal;">%s</span>`,
+		statusColors[strings.ToLower(status)], escapeXML(label))
+}
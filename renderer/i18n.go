@@ -0,0 +1,167 @@
+package renderer
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DefaultLang is the language the renderer's strings are written in
+const DefaultLang = "en"
+
+// translations holds the UI strings of each supported language, keyed by
+// the English text. Missing entries fall back to English.
+var translations = map[string]map[string]string{
+	"de": {
+		"Structure":                     "Struktur",
+		"Card.":                         "Kard.",
+		"Type":                          "Typ",
+		"Description & Constraints":     "Beschreibung & Einschränkungen",
+		"Not used":                      "Nicht verwendet",
+		"Fixed Value:":                  "Fester Wert:",
+		"Context:":                      "Kontext:",
+		"Required Pattern:":             "Erforderliches Muster:",
+		"Codes:":                        "Codes:",
+		"Base:":                         "Basis:",
+		"Legend":                        "Legende",
+		"Icons":                         "Symbole",
+		"Usage":                         "Verwendung",
+		"Profile":                       "Profil",
+		"Resource":                      "Ressource",
+		"Backbone element":              "Backbone-Element",
+		"Primitive data type":           "Primitiver Datentyp",
+		"Complex data type":             "Komplexer Datentyp",
+		"Element of another type":       "Element anderen Typs",
+		"Choice of types [x]":           "Typauswahl [x]",
+		"Reference to another resource": "Referenz auf Ressource",
+		"Summary element":               "Zusammenfassungselement",
+		"Modifier element":              "Modifier-Element",
+		"Has constraint":                "Hat Constraints",
+		"Trial use":                     "Erprobung",
+		"Normative":                     "Normativ",
+		"Used / optional":               "Verwendet / optional",
+		"TODO:":                         "Offen:",
+		"TODO: not yet implemented":     "Offen: noch nicht umgesetzt",
+		"Added slice or element":        "Slice/Element hinzugefügt",
+		"Tightened cardinality":         "Engere Kardinalität",
+		"Removed (max 0)":               "Entfernt (max 0)",
+		"Edit this resource":            "Diese Ressource bearbeiten",
+		"Generated by nuuner/fhir-resource-svg-renderer": "Erstellt mit nuuner/fhir-resource-svg-renderer",
+		"Generated":                           "Erstellt",
+		"View source JSON":                    "Quell-JSON anzeigen",
+		"Display":                             "Anzeige",
+		"Search parameters":                   "Suchparameter",
+		"System interactions:":                "Systeminteraktionen:",
+		"%s with %d elements":                 "%s mit %d Elementen",
+		"%s with %d concepts":                 "%s mit %d Konzepten",
+		"Draft":                               "Entwurf",
+		"Active":                              "Aktiv",
+		"Retired":                             "Zurückgezogen",
+		"Unknown":                             "Unbekannt",
+		"Review notes":                        "Review-Notizen",
+		"Sample value":                        "Beispielwert",
+		"Page %d of %d":                       "Seite %d von %d",
+		"(no children used)":                  "(keine Kindelemente verwendet)",
+		"%d/%d elements implemented, %d TODO": "%d/%d Elemente umgesetzt, %d TODO",
+		"Elements":                            "Elemente",
+		"Extensions":                          "Erweiterungen",
+		"… %d more":                           "… %d weitere",
+	},
+	"fr": {
+		"Name":                          "Nom",
+		"Flags":                         "Drapeaux",
+		"Description & Constraints":     "Description & Contraintes",
+		"Mappings":                      "Correspondances",
+		"Not used":                      "Non utilisé",
+		"Fixed Value:":                  "Valeur fixe :",
+		"Context:":                      "Contexte :",
+		"Required Pattern:":             "Motif requis :",
+		"Codes:":                        "Codes :",
+		"Base:":                         "Base :",
+		"Legend":                        "Légende",
+		"Icons":                         "Icônes",
+		"Usage":                         "Utilisation",
+		"Profile":                       "Profil",
+		"Resource":                      "Ressource",
+		"Backbone element":              "Élément backbone",
+		"Primitive data type":           "Type de données primitif",
+		"Complex data type":             "Type de données complexe",
+		"Element of another type":       "Élément d'un autre type",
+		"Choice of types [x]":           "Choix de types [x]",
+		"Reference to another resource": "Référence à une ressource",
+		"Summary element":               "Élément de résumé",
+		"Modifier element":              "Élément modificateur",
+		"Has constraint":                "A des contraintes",
+		"Trial use":                     "Usage d'essai",
+		"Normative":                     "Normatif",
+		"Used / optional":               "Utilisé / optionnel",
+		"TODO:":                         "À faire :",
+		"TODO: not yet implemented":     "À faire : non implémenté",
+		"Added slice or element":        "Slice ou élément ajouté",
+		"Tightened cardinality":         "Cardinalité restreinte",
+		"Removed (max 0)":               "Supprimé (max 0)",
+		"Edit this resource":            "Modifier cette ressource",
+		"Generated by nuuner/fhir-resource-svg-renderer": "Généré par nuuner/fhir-resource-svg-renderer",
+		"Generated":                           "Généré",
+		"View source JSON":                    "Voir le JSON source",
+		"Display":                             "Affichage",
+		"Definition":                          "Définition",
+		"Search parameters":                   "Paramètres de recherche",
+		"System interactions:":                "Interactions système :",
+		"%s with %d elements":                 "%s avec %d éléments",
+		"%s with %d concepts":                 "%s avec %d concepts",
+		"Draft":                               "Brouillon",
+		"Active":                              "Actif",
+		"Retired":                             "Retiré",
+		"Unknown":                             "Inconnu",
+		"Review notes":                        "Notes de revue",
+		"Sample value":                        "Valeur d'exemple",
+		"Page %d of %d":                       "Page %d sur %d",
+		"(no children used)":                  "(aucun élément enfant utilisé)",
+		"%d/%d elements implemented, %d TODO": "%d/%d éléments implémentés, %d TODO",
+		"Elements":                            "Éléments",
+		"Extensions":                          "Extensions",
+		"… %d more":                           "… %d de plus",
+	},
+}
+
+// Languages returns the supported language codes in sorted order
+func Languages() []string {
+	langs := append(slices.Collect(maps.Keys(translations)), DefaultLang)
+	slices.Sort(langs)
+	return langs
+}
+
+// ParseLang returns the supported language of a tag such as "de" or
+// "de-CH", or false when there is no catalog for it
+func ParseLang(tag string) (string, bool) {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	if _, ok := translations[lang]; ok || lang == DefaultLang {
+		return lang, true
+	}
+	return "", false
+}
+
+// text returns s in the configured language, or s itself when it has no
+// translation
+func (c SVGConfig) text(s string) string {
+	if translated, ok := translations[c.Lang][s]; ok {
+		return translated
+	}
+	return s
+}
+
+// isRTL reports whether text starts with right-to-left script, judged by
+// its first letter
+func isRTL(text string) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko) {
+			return true
+		}
+		if unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return false
+}
@@ -1,15 +1,18 @@
 package renderer
 
 import (
-	"fmt"
 	"strings"
+
+	"fhir_renderer/models"
 )
 
 // Icon types matching HL7 FHIR visual style
 const (
 	IconResource        = "resource"        // Yellow folder - for root resource
 	IconBackboneElement = "backbone"        // Yellow folder with dot - for backbone elements
-	IconElement         = "element"         // Blue diamond - for regular elements
+	IconElement         = "element"         // Blue diamond - for elements of other types
+	IconPrimitive       = "primitive"       // Blue square - for primitive data types
+	IconDatatype        = "datatype"        // Orange block - for complex data types
 	IconExtension       = "extension"       // Orange circle with E - for extensions
 	IconChoice          = "choice"          // Green circle - for choice types
 	IconReference       = "reference"       // Blue arrow - for references
@@ -17,6 +20,12 @@ const (
 
 // RenderIcon returns SVG markup for the specified icon type at the given position
 func RenderIcon(iconType string, x, y float64, size float64) string {
+	return iconNode(iconType, x, y, size).String()
+}
+
+// iconNode returns the icon RenderIcon draws, for rows that write it along
+// with their other nodes
+func iconNode(iconType string, x, y float64, size float64) *node {
 	switch iconType {
 	case IconResource:
 		return renderFolderIcon(x, y, size, "#FDB813", true) // Yellow folder
@@ -24,6 +33,10 @@ func RenderIcon(iconType string, x, y float64, size float64) string {
 		return renderFolderIcon(x, y, size, "#FDB813", false) // Yellow folder with inner mark
 	case IconElement:
 		return renderDiamondIcon(x, y, size, "#005EB8") // Blue diamond
+	case IconPrimitive:
+		return renderPrimitiveIcon(x, y, size, "#3B7DD8") // Blue square
+	case IconDatatype:
+		return renderDatatypeIcon(x, y, size, "#D35400") // Orange block
 	case IconExtension:
 		return renderExtensionIcon(x, y, size, "#FF8C00") // Orange extension
 	case IconChoice:
@@ -36,7 +49,7 @@ func RenderIcon(iconType string, x, y float64, size float64) string {
 }
 
 // renderFolderIcon draws a folder icon (for resources and backbone elements)
-func renderFolderIcon(x, y, size float64, color string, filled bool) string {
+func renderFolderIcon(x, y, size float64, color string, filled bool) *node {
 	// Folder shape
 	w := size * 0.9
 	h := size * 0.7
@@ -48,80 +61,100 @@ func renderFolderIcon(x, y, size float64, color string, filled bool) string {
 		fillColor = "#FFFFFF"
 	}
 
-	svg := fmt.Sprintf(`<g transform="translate(%f,%f)">
-    <path d="M0,%f L0,%f L%f,%f L%f,0 L%f,0 L%f,%f L0,%f Z"
-          fill="%s" stroke="%s" stroke-width="1"/>`,
-		x, y,
-		tabH, h, w, h, w, tabW, tabW, tabH, tabH,
-		fillColor, color)
+	folder := newNode("g").attr("transform", "translate("+num(x)+","+num(y)+")").append(
+		newNode("path").attr("d", "M0,"+num(tabH)+" L0,"+num(h)+" L"+num(w)+","+num(h)+" L"+num(w)+",0 L"+num(tabW)+",0 L"+num(tabW)+","+num(tabH)+" L0,"+num(tabH)+" Z").
+			attr("fill", fillColor).attr("stroke", color).attr("stroke-width", "1"),
+	)
 
 	if !filled {
 		// Add inner dot for backbone element
-		svg += fmt.Sprintf(`<circle cx="%f" cy="%f" r="%f" fill="%s"/>`,
-			w/2, h*0.6, size*0.12, color)
+		folder.append(newNode("circle").attr("cx", num(w/2)).attr("cy", num(h*0.6)).attr("r", num(size*0.12)).attr("fill", color))
 	}
 
-	svg += "</g>"
-	return svg
+	return folder
+}
+
+// points formats x, y pairs as the points of a polygon
+func points(coords ...float64) string {
+	pairs := make([]string, 0, len(coords)/2)
+	for i := 0; i+1 < len(coords); i += 2 {
+		pairs = append(pairs, num(coords[i])+","+num(coords[i+1]))
+	}
+	return strings.Join(pairs, " ")
 }
 
 // renderDiamondIcon draws a diamond icon (for regular elements)
-func renderDiamondIcon(x, y, size float64, color string) string {
+func renderDiamondIcon(x, y, size float64, color string) *node {
 	half := size / 2
-	return fmt.Sprintf(`<polygon points="%f,%f %f,%f %f,%f %f,%f"
-        fill="%s" stroke="%s" stroke-width="0.5"/>`,
-		x+half, y,        // top
-		x+size, y+half,   // right
-		x+half, y+size,   // bottom
-		x, y+half,        // left
-		color, color)
+	return newNode("polygon").attr("points", points(
+		x+half, y, // top
+		x+size, y+half, // right
+		x+half, y+size, // bottom
+		x, y+half, // left
+	)).attr("fill", color).attr("stroke", color).attr("stroke-width", "0.5")
+}
+
+// renderPrimitiveIcon draws a primitive data type icon (small rounded square)
+func renderPrimitiveIcon(x, y, size float64, color string) *node {
+	inset := size * 0.2
+	return newNode("rect").attr("x", num(x+inset)).attr("y", num(y+inset)).attr("width", num(size-inset*2)).
+		attr("height", num(size-inset*2)).attr("rx", num(size*0.12)).attr("fill", color)
+}
+
+// renderDatatypeIcon draws a complex data type icon (square with an inner block)
+func renderDatatypeIcon(x, y, size float64, color string) *node {
+	inset := size * 0.1
+	return newNode("g").append(
+		newNode("rect").attr("x", num(x+inset)).attr("y", num(y+inset)).attr("width", num(size-inset*2)).
+			attr("height", num(size-inset*2)).attr("rx", num(size*0.12)).attr("fill", color),
+		newNode("rect").attr("x", num(x+size*0.35)).attr("y", num(y+size*0.35)).attr("width", num(size*0.3)).
+			attr("height", num(size*0.3)).attr("fill", "white"),
+	)
 }
 
 // renderExtensionIcon draws an extension icon (circle with E)
-func renderExtensionIcon(x, y, size float64, color string) string {
+func renderExtensionIcon(x, y, size float64, color string) *node {
 	cx := x + size/2
 	cy := y + size/2
 	r := size / 2
 
-	return fmt.Sprintf(`<g>
-    <circle cx="%f" cy="%f" r="%f" fill="%s"/>
-    <text x="%f" y="%f" fill="white" font-family="Arial" font-size="%f"
-          text-anchor="middle" dominant-baseline="central" font-weight="bold">E</text>
-</g>`,
-		cx, cy, r, color,
-		cx, cy, size*0.6)
+	return newNode("g").append(
+		newNode("circle").attr("cx", num(cx)).attr("cy", num(cy)).attr("r", num(r)).attr("fill", color),
+		newNode("text").attr("x", num(cx)).attr("y", num(cy)).attr("fill", "white").attr("font-family", "Arial").
+			attr("font-size", num(size*0.6)).attr("text-anchor", "middle").attr("dominant-baseline", "central").
+			attr("font-weight", "bold").content("E"),
+	)
 }
 
 // renderChoiceIcon draws a choice type icon (green circle with split)
-func renderChoiceIcon(x, y, size float64, color string) string {
+func renderChoiceIcon(x, y, size float64, color string) *node {
 	cx := x + size/2
 	cy := y + size/2
 	r := size / 2
 
-	return fmt.Sprintf(`<g>
-    <circle cx="%f" cy="%f" r="%f" fill="%s"/>
-    <line x1="%f" y1="%f" x2="%f" y2="%f" stroke="white" stroke-width="1.5"/>
-</g>`,
-		cx, cy, r, color,
-		cx, cy-r*0.5, cx, cy+r*0.5)
+	return newNode("g").append(
+		newNode("circle").attr("cx", num(cx)).attr("cy", num(cy)).attr("r", num(r)).attr("fill", color),
+		newNode("line").attr("x1", num(cx)).attr("y1", num(cy-r*0.5)).attr("x2", num(cx)).attr("y2", num(cy+r*0.5)).
+			attr("stroke", "white").attr("stroke-width", "1.5"),
+	)
 }
 
 // renderReferenceIcon draws a reference icon (arrow pointing right)
-func renderReferenceIcon(x, y, size float64, color string) string {
+func renderReferenceIcon(x, y, size float64, color string) *node {
 	// Arrow pointing right
 	arrowSize := size * 0.8
 	startX := x + size*0.1
 	midY := y + size/2
 
-	return fmt.Sprintf(`<g>
-    <line x1="%f" y1="%f" x2="%f" y2="%f" stroke="%s" stroke-width="2"/>
-    <polygon points="%f,%f %f,%f %f,%f" fill="%s"/>
-</g>`,
-		startX, midY, startX+arrowSize*0.6, midY, color,
-		startX+arrowSize*0.5, midY-arrowSize*0.3,
-		startX+arrowSize, midY,
-		startX+arrowSize*0.5, midY+arrowSize*0.3,
-		color)
+	return newNode("g").append(
+		newNode("line").attr("x1", num(startX)).attr("y1", num(midY)).attr("x2", num(startX+arrowSize*0.6)).attr("y2", num(midY)).
+			attr("stroke", color).attr("stroke-width", "2"),
+		newNode("polygon").attr("points", points(
+			startX+arrowSize*0.5, midY-arrowSize*0.3,
+			startX+arrowSize, midY,
+			startX+arrowSize*0.5, midY+arrowSize*0.3,
+		)).attr("fill", color),
+	)
 }
 
 // GetIconTypeForElement determines the appropriate icon type based on element properties
@@ -148,6 +181,13 @@ func GetIconTypeForElement(elementType string, isRoot bool, hasChildren bool) st
 		if hasChildren {
 			return IconBackboneElement
 		}
+		// Primitive and complex FHIR data types, as in the FHIR spec
+		switch models.DatatypeKind(elementType) {
+		case models.DatatypePrimitive:
+			return IconPrimitive
+		case models.DatatypeComplex:
+			return IconDatatype
+		}
 		return IconElement
 	}
 }
@@ -156,7 +196,10 @@ func GetIconTypeForElement(elementType string, isRoot bool, hasChildren bool) st
 func RenderGitHubIcon(x, y, size float64, color string) string {
 	// GitHub mark path scaled to fit within size
 	scale := size / 16.0
-	return fmt.Sprintf(`<g transform="translate(%f,%f) scale(%f)">
-    <path fill="%s" d="M8 0C3.58 0 0 3.58 0 8c0 3.54 2.29 6.53 5.47 7.59.4.07.55-.17.55-.38 0-.19-.01-.82-.01-1.49-2.01.37-2.53-.49-2.69-.94-.09-.23-.48-.94-.82-1.13-.28-.15-.68-.52-.01-.53.63-.01 1.08.58 1.23.82.72 1.21 1.87.87 2.33.66.07-.52.28-.87.51-1.07-1.78-.2-3.64-.89-3.64-3.95 0-.87.31-1.59.82-2.15-.08-.2-.36-1.02.08-2.12 0 0 .67-.21 2.2.82.64-.18 1.32-.27 2-.27.68 0 1.36.09 2 .27 1.53-1.04 2.2-.82 2.2-.82.44 1.1.16 1.92.08 2.12.51.56.82 1.27.82 2.15 0 3.07-1.87 3.75-3.65 3.95.29.25.54.73.54 1.48 0 1.07-.01 1.93-.01 2.2 0 .21.15.46.55.38A8.013 8.013 0 0016 8c0-4.42-3.58-8-8-8z"/>
-</g>`, x, y, scale, color)
+	return newNode("g").attr("transform", "translate("+num(x)+","+num(y)+") scale("+num(scale)+")").append(
+		newNode("path").attr("fill", color).attr("d", gitHubMarkPath),
+	).String()
 }
+
+// gitHubMarkPath is the GitHub mark in a 16 x 16 box
+const gitHubMarkPath = "M8 0C3.58 0 0 3.58 0 8c0 3.54 2.29 6.53 5.47 7.59.4.07.55-.17.55-.38 0-.19-.01-.82-.01-1.49-2.01.37-2.53-.49-2.69-.94-.09-.23-.48-.94-.82-1.13-.28-.15-.68-.52-.01-.53.63-.01 1.08.58 1.23.82.72 1.21 1.87.87 2.33.66.07-.52.28-.87.51-1.07-1.78-.2-3.64-.89-3.64-3.95 0-.87.31-1.59.82-2.15-.08-.2-.36-1.02.08-2.12 0 0 .67-.21 2.2.82.64-.18 1.32-.27 2-.27.68 0 1.36.09 2 .27 1.53-1.04 2.2-.82 2.2-.82.44 1.1.16 1.92.08 2.12.51.56.82 1.27.82 2.15 0 3.07-1.87 3.75-3.65 3.95.29.25.54.73.54 1.48 0 1.07-.01 1.93-.01 2.2 0 .21.15.46.55.38A8.013 8.013 0 0016 8c0-4.42-3.58-8-8-8z"
@@ -0,0 +1,144 @@
+package renderer
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// CustomIconSize is the side of the square custom icon paths are drawn in
+// when IconStyle.Size is 0
+const CustomIconSize = 16.0
+
+// IconStyle is an icon drawn from a raw SVG path, e.g. for logical model
+// types such as "Money" that have no FHIR icon
+type IconStyle struct {
+	Name        string  // Icon identifier used by IconRule; a built-in name replaces that icon
+	Path        string  // SVG path data ("d"), drawn in a Size x Size square
+	Fill        string  // Path fill color; empty for the element blue
+	Size        float64 // Side of the square Path is drawn in; 0 for CustomIconSize
+	Description string  // Legend text; icons without one are not listed
+}
+
+// IconRule maps element types to an icon. Type is a type name, where "*"
+// matches any run of characters, e.g. "Money" or "Geo*".
+type IconRule struct {
+	Type string
+	Icon string
+}
+
+// builtinIcons are the icon identifiers RenderIcon draws
+var builtinIcons = []string{IconResource, IconBackboneElement, IconElement, IconPrimitive, IconDatatype, IconExtension, IconChoice, IconReference}
+
+// iconNamePattern restricts custom icon identifiers
+var iconNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// iconPathPattern admits only path commands and numbers, so custom paths
+// cannot inject markup
+var iconPathPattern = regexp.MustCompile(`^[MmLlHhVvCcSsQqTtAaZz0-9eE.,+\-\s]+$`)
+
+// defaultIcons and defaultIconRules are the custom icons and rules of
+// DefaultConfig; see SetDefaultIcons
+var (
+	defaultIcons     []IconStyle
+	defaultIconRules []IconRule
+)
+
+// SetDefaultIcons sets the custom icons and type rules DefaultConfig uses,
+// e.g. from the server configuration. They must pass ValidateIcons.
+func SetDefaultIcons(icons []IconStyle, rules []IconRule) {
+	defaultIcons = slices.Clone(icons)
+	defaultIconRules = slices.Clone(rules)
+}
+
+// ValidateIcons checks that custom icon names are unique, that paths hold
+// only path data and colors are hex colors, and that rules name a type and
+// a built-in or custom icon
+func ValidateIcons(icons []IconStyle, rules []IconRule) error {
+	names := slices.Clone(builtinIcons)
+	seen := make(map[string]bool, len(icons))
+	for _, icon := range icons {
+		switch {
+		case !iconNamePattern.MatchString(icon.Name):
+			return fmt.Errorf("invalid icon name %q (use letters, digits, '-' and '_')", icon.Name)
+		case seen[icon.Name]:
+			return fmt.Errorf("icon %q listed twice", icon.Name)
+		case !iconPathPattern.MatchString(icon.Path):
+			return fmt.Errorf("icon %q has an invalid path (expected SVG path data)", icon.Name)
+		case icon.Fill != "" && !models.IsHexColor(icon.Fill):
+			return fmt.Errorf("icon %q has an invalid fill %q (expected a hex color)", icon.Name, icon.Fill)
+		case icon.Size < 0:
+			return fmt.Errorf("icon %q has a negative size", icon.Name)
+		}
+		seen[icon.Name] = true
+		names = append(names, icon.Name)
+	}
+	for _, rule := range rules {
+		switch {
+		case strings.TrimSpace(rule.Type) == "":
+			return fmt.Errorf("icon rule for %q has no type", rule.Icon)
+		case !slices.Contains(names, rule.Icon):
+			return fmt.Errorf("icon rule for %q uses unknown icon %q", rule.Type, rule.Icon)
+		}
+	}
+	return nil
+}
+
+// iconFor returns the icon of an element: the first icon rule matching its
+// type, else the built-in choice. The root always shows the resource icon.
+func (c SVGConfig) iconFor(elementType string, isRoot, hasChildren bool) string {
+	if !isRoot {
+		for _, rule := range c.IconRules {
+			if matchTypePattern(rule.Type, elementType) {
+				return rule.Icon
+			}
+		}
+	}
+	return GetIconTypeForElement(elementType, isRoot, hasChildren)
+}
+
+// renderIcon draws an icon at x, y, preferring a custom icon of that name
+// over the built-in one
+func (c SVGConfig) renderIcon(name string, x, y, size float64) *node {
+	i := slices.IndexFunc(c.Icons, func(icon IconStyle) bool { return icon.Name == name })
+	if i < 0 {
+		return iconNode(name, x, y, size)
+	}
+	icon := c.Icons[i]
+	box := icon.Size
+	if box == 0 {
+		box = CustomIconSize
+	}
+	fill := icon.Fill
+	if fill == "" {
+		fill = "#005EB8"
+	}
+	return newNode("g").attr("transform", "translate("+num(x)+","+num(y)+") scale("+num(size/box)+")").append(
+		newNode("path").attr("d", icon.Path).attr("fill", fill),
+	)
+}
+
+// matchTypePattern reports whether typ matches pattern, in which "*"
+// matches any run of characters
+func matchTypePattern(pattern, typ string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == typ
+	}
+	rest, ok := strings.CutPrefix(typ, parts[0])
+	if !ok {
+		return false
+	}
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return len(rest) >= len(last) && strings.HasSuffix(rest, last)
+}
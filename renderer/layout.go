@@ -0,0 +1,153 @@
+package renderer
+
+import (
+	"context"
+
+	"fhir_renderer/models"
+)
+
+// Layout describes the computed geometry of a rendered diagram so other
+// tools can build their own renderings or overlay the SVG
+type Layout struct {
+	Width        float64        `json:"width"`
+	Height       float64        `json:"height"`
+	TitleHeight  float64        `json:"titleHeight"`
+	HeaderHeight float64        `json:"headerHeight"`
+	FooterY      float64        `json:"footerY"`
+	FooterHeight float64        `json:"footerHeight"`
+	LineHeight   float64        `json:"lineHeight"`
+	Columns      []LayoutColumn `json:"columns"`
+	Rows         []LayoutRow    `json:"rows"`
+}
+
+// LayoutColumn is the horizontal extent of a table column
+type LayoutColumn struct {
+	Key   string  `json:"key"`
+	Title string  `json:"title"`
+	X     float64 `json:"x"`
+	Width float64 `json:"width"`
+}
+
+// LayoutRow is the position and wrapped text of a single row
+type LayoutRow struct {
+	ID           string   `json:"id"`               // Anchor id of the row's group in the SVG
+	Number       int      `json:"number,omitempty"` // Row number when RowNumbers is set
+	Path         string   `json:"path"`
+	Name         string   `json:"name"`
+	Depth        int      `json:"depth"`
+	Y            float64  `json:"y"`
+	Height       float64  `json:"height"`
+	GroupHeight  float64  `json:"groupHeight,omitempty"` // Group header band at the top of Height
+	IsRoot       bool     `json:"isRoot"`
+	NameLines    []string `json:"nameLines"`
+	URLLines     []string `json:"urlLines,omitempty"`     // Extension url below the name
+	ContextLines []string `json:"contextLines,omitempty"` // Extension context below the url
+	TypeLines    []string `json:"typeLines"`
+	DescLines    []string `json:"descLines"`
+
+	// Flag codes per line of the flags column
+	FlagLines [][]string `json:"flagLines,omitempty"`
+
+	// Fixed value and pattern lines drawn below DescLines, followed by
+	// the lines of binding code chips
+	FixedLines   []string   `json:"fixedLines,omitempty"`
+	PatternLines []string   `json:"patternLines,omitempty"`
+	CodeChips    [][]string `json:"codeChips,omitempty"`
+
+	// Optional columns: the wrapped mappings, the wrapped values of the
+	// extra columns by key, the wrapped example value and the wrapped
+	// annotation note
+	MappingLines []string            `json:"mappingLines,omitempty"`
+	ExtraLines   map[string][]string `json:"extraLines,omitempty"`
+	SampleLines  []string            `json:"sampleLines,omitempty"`
+	NoteLines    []string            `json:"noteLines,omitempty"`
+}
+
+// Size is the size of a rendered diagram, for reserving space before the
+// image is fetched
+type Size struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Rows   int     `json:"rows"`
+}
+
+// MeasureContext runs the layout pass and returns the size of the SVG that
+// RenderToContext would write, the stacked pages of a paged diagram included
+func MeasureContext(ctx context.Context, resource *models.ResourceDefinition, config SVGConfig) (*Size, error) {
+	tm, err := newTextMeasurer(config)
+	if err != nil {
+		return nil, err
+	}
+	defer tm.Close()
+	config.textMeasurer = tm
+
+	rows, colWidths, config, err := layoutRows(ctx, resource, tm, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Size{Width: colWidths.Total(), Height: svgHeight(rows, config), Rows: len(rows)}, nil
+}
+
+// ComputeLayout runs the layout pass without producing SVG
+func ComputeLayout(ctx context.Context, resource *models.ResourceDefinition, config SVGConfig) (*Layout, error) {
+	tm, err := newTextMeasurer(config)
+	if err != nil {
+		return nil, err
+	}
+	defer tm.Close()
+	config.textMeasurer = tm
+
+	rows, colWidths, config, err := layoutRows(ctx, resource, tm, config)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &Layout{
+		Width:        colWidths.Total(),
+		Height:       calculateTotalHeight(rows, config),
+		TitleHeight:  config.TitleHeight,
+		HeaderHeight: config.HeaderHeight,
+		FooterHeight: FooterHeight,
+		LineHeight:   config.LineHeight,
+	}
+
+	for _, col := range config.columnSpans() {
+		layout.Columns = append(layout.Columns, LayoutColumn{Key: col.key, Title: config.columnTitle(col.key), X: col.x, Width: col.width})
+	}
+
+	y := config.TitleHeight + config.HeaderHeight
+	layout.Rows = make([]LayoutRow, len(rows))
+	for i, row := range rows {
+		layout.Rows[i] = LayoutRow{
+			ID:           row.ID,
+			Path:         row.Element.Path,
+			Name:         row.Element.Element.Name,
+			Depth:        row.Element.Depth,
+			Y:            y,
+			Height:       row.RowHeight,
+			GroupHeight:  row.GroupHeight,
+			IsRoot:       row.IsRoot,
+			NameLines:    row.NameLines,
+			FlagLines:    row.FlagLines,
+			URLLines:     row.URLLines,
+			ContextLines: row.ContextLines,
+			TypeLines:    row.TypeLines,
+			DescLines:    row.DescLines,
+
+			FixedLines:   row.FixedLines,
+			PatternLines: row.PatternLines,
+			CodeChips:    row.CodeChips,
+			MappingLines: row.MappingLines,
+			ExtraLines:   row.ExtraLines,
+			SampleLines:  row.SampleLines,
+			NoteLines:    row.NoteLines,
+		}
+		if config.RowNumbers {
+			layout.Rows[i].Number = row.Number
+		}
+		y += row.RowHeight
+	}
+	layout.FooterY = y + coverageBarHeight(config) + legendHeight(config) + metadataFooterHeight(config)
+
+	return layout, nil
+}
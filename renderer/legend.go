@@ -0,0 +1,175 @@
+package renderer
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Legend layout constants
+const (
+	// LegendTitleHeight is the height of the "Legend" heading bar
+	LegendTitleHeight = 24.0
+
+	// LegendLineHeight is the height of one line of legend items
+	LegendLineHeight = 22.0
+
+	// LegendLabelWidth is the width reserved for section labels
+	LegendLabelWidth = 70.0
+
+	// LegendItemWidth is the horizontal space allotted to each legend item
+	LegendItemWidth = 190.0
+
+	// LegendSampleWidth is the space for the sample glyph before the item text
+	LegendSampleWidth = 34.0
+)
+
+// legendItem is a single explained symbol in the legend
+type legendItem struct {
+	icon  string // Icon type, for icon items
+	flag  string // Flag code, for flag items
+	class string // Text class, for usage items
+	text  string // Sample text, for usage items
+	bold  bool   // Sample text is bold
+	fill  string // Swatch color, for row tint items
+	label string
+}
+
+// legendSection groups legend items under a heading
+type legendSection struct {
+	title string
+	items []legendItem
+}
+
+// legendSections mirrors the HL7 legend: icons, flags and usage styling.
+// The flag items come from the flag registry; see sectionsFor.
+var legendSections = []legendSection{
+	{"Icons", []legendItem{
+		{icon: IconResource, label: "Resource"},
+		{icon: IconBackboneElement, label: "Backbone element"},
+		{icon: IconPrimitive, label: "Primitive data type"},
+		{icon: IconDatatype, label: "Complex data type"},
+		{icon: IconElement, label: "Element of another type"},
+		{icon: IconExtension, label: "Extension"},
+		{icon: IconChoice, label: "Choice of types [x]"},
+		{icon: IconReference, label: "Reference to another resource"},
+	}},
+	{"Flags", nil},
+	{"Usage", []legendItem{
+		{class: "cell-text", text: "Aa", label: "Used / optional"},
+		{class: "todo", text: "Aa", label: "TODO: not yet implemented"},
+		{class: "not-used", text: "Aa", label: "Not used"},
+	}},
+}
+
+// sectionsFor returns the legend sections with the described custom
+// icons, the built-in and custom flags, and the profile comparison key
+// when the rendered resource carries changes
+func sectionsFor(config SVGConfig) []legendSection {
+	sections := slices.Clone(legendSections)
+	for i := range sections {
+		switch sections[i].title {
+		case "Icons":
+			sections[i].items = slices.Clone(sections[i].items)
+			for _, icon := range config.Icons {
+				if icon.Description == "" {
+					continue
+				}
+				if j := slices.IndexFunc(sections[i].items, func(item legendItem) bool { return item.icon == icon.Name }); j >= 0 {
+					sections[i].items[j].label = icon.Description
+				} else {
+					sections[i].items = append(sections[i].items, legendItem{icon: icon.Name, label: icon.Description})
+				}
+			}
+		case "Flags":
+			for _, style := range config.flagStyles() {
+				sections[i].items = append(sections[i].items, legendItem{flag: style.Code, label: cmp.Or(style.Description, style.Code)})
+			}
+		}
+	}
+	if !config.showChanges {
+		return sections
+	}
+	return append(sections, legendSection{"Profile", []legendItem{
+		{fill: config.AddedRowColor, label: "Added slice or element"},
+		{class: "cell-text", text: "1..1", bold: true, label: "Tightened cardinality"},
+		{class: "not-used", text: "Aa", label: "Removed (max 0)"},
+	}})
+}
+
+// legendItemsPerLine returns how many legend items fit next to the section label
+func legendItemsPerLine(config SVGConfig) int {
+	n := int((config.tableWidth() - config.Padding*2 - LegendLabelWidth) / LegendItemWidth)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// legendHeight returns the height of the legend section, or 0 when hidden
+func legendHeight(config SVGConfig) float64 {
+	if !config.ShowLegend {
+		return 0
+	}
+	perLine := legendItemsPerLine(config)
+	height := LegendTitleHeight
+	for _, section := range sectionsFor(config) {
+		height += math.Ceil(float64(len(section.items))/float64(perLine)) * LegendLineHeight
+	}
+	return height + config.Padding
+}
+
+// buildLegend renders the legend section starting at y
+func buildLegend(totalWidth, y float64, config SVGConfig) string {
+	var sb strings.Builder
+	height := legendHeight(config)
+	perLine := legendItemsPerLine(config)
+
+	sb.WriteString(newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", height, 0).
+		attr("fill", config.RowBgColor).attr("stroke", config.BorderColor).String())
+	sb.WriteString(newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", LegendTitleHeight, 0).
+		attr("fill", config.HeaderBgColor).attr("stroke", config.BorderColor).String())
+	sb.WriteString(newNode("text").fixed("x", config.Padding, 0).fixed("y", y+LegendTitleHeight/2+TitleVerticalOffset, 0).
+		attr("class", "header-text").content(config.text("Legend")).String())
+
+	lineY := y + LegendTitleHeight
+	for _, section := range sectionsFor(config) {
+		centerY := lineY + LegendLineHeight/2
+		sb.WriteString(newNode("text").fixed("x", config.Padding, 0).fixed("y", centerY+TextVerticalOffset, 0).
+			attr("class", "header-text").content(config.text(section.title)).String())
+
+		for i, item := range section.items {
+			if i > 0 && i%perLine == 0 {
+				lineY += LegendLineHeight
+				centerY = lineY + LegendLineHeight/2
+			}
+			itemX := config.Padding + LegendLabelWidth + float64(i%perLine)*LegendItemWidth
+			sb.WriteString(renderLegendSample(item, itemX, centerY, config).String())
+			sb.WriteString(newNode("text").fixed("x", itemX+LegendSampleWidth, 0).fixed("y", centerY+TextVerticalOffset, 0).
+				attr("class", "cell-text").content(config.text(item.label)).String())
+		}
+		lineY += LegendLineHeight
+	}
+
+	return sb.String()
+}
+
+// renderLegendSample draws the icon, flag or styled text an item explains
+func renderLegendSample(item legendItem, x, centerY float64, config SVGConfig) *node {
+	switch {
+	case item.icon != "":
+		return config.renderIcon(item.icon, x, centerY-config.IconSize/2, config.IconSize)
+	case item.flag != "":
+		return newNode("g").attr("transform", translate(x, centerY)).
+			append(renderFlags([]string{item.flag}, LegendSampleWidth-FlagGap, config)...)
+	case item.fill != "":
+		return newNode("rect").fixed("x", x, 0).fixed("y", centerY-config.IconSize/2, 0).fixed("width", config.IconSize*2, 0).
+			fixed("height", config.IconSize, 0).attr("fill", item.fill).attr("stroke", config.BorderColor)
+	}
+	text := newNode("text").fixed("x", x, 0).fixed("y", centerY+TextVerticalOffset, 0).attr("class", item.class)
+	if item.bold {
+		text.attr("font-weight", "bold")
+	}
+	return text.content(item.text)
+}
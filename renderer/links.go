@@ -0,0 +1,112 @@
+package renderer
+
+import (
+	"cmp"
+	"regexp"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// simpleTypePattern matches a plain type name such as "CodeableConcept"
+var simpleTypePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// expandLinkBase fills a documentation link template. "{name}" is replaced
+// with value, "{lower}" with its lowercase form and "{version}" with the
+// FHIR version, e.g. "R5"; a template without name placeholders has value
+// appended.
+func expandLinkBase(base, value, version string) string {
+	base = strings.ReplaceAll(base, "{version}", version)
+	if !strings.Contains(base, "{name}") && !strings.Contains(base, "{lower}") {
+		return base + value
+	}
+	return strings.NewReplacer("{name}", value, "{lower}", strings.ToLower(value)).Replace(base)
+}
+
+// version returns the FHIR version of the rendered definition
+func (c SVGConfig) version() string {
+	return cmp.Or(c.fhirVersion, models.DefaultFHIRVersion)
+}
+
+// typeURL returns the documentation link for a type name: from
+// TypeLinkBase, else the FHIR specification page of FHIR types when
+// FHIRLinks is set. It returns "" for names that are not plain types.
+func (c SVGConfig) typeURL(typeName string) string {
+	if !simpleTypePattern.MatchString(typeName) {
+		return ""
+	}
+	if c.TypeLinkBase != "" {
+		return expandLinkBase(c.TypeLinkBase, typeName, c.version())
+	}
+	if t, ok := models.LookupType(typeName, c.version()); ok && c.FHIRLinks {
+		return t.URL
+	}
+	return ""
+}
+
+// elementURL returns the documentation link for an element path, or ""
+// when ElementLinkBase is not set
+func (c SVGConfig) elementURL(path string) string {
+	if c.ElementLinkBase == "" || path == "" {
+		return ""
+	}
+	return expandLinkBase(c.ElementLinkBase, path, c.version())
+}
+
+// defaultStrictLinks is the StrictLinks of DefaultConfig; see
+// SetDefaultStrictLinks
+var defaultStrictLinks bool
+
+// SetDefaultStrictLinks sets whether DefaultConfig only links absolute
+// http(s) URLs from definitions
+func SetDefaultStrictLinks(strict bool) {
+	defaultStrictLinks = strict
+}
+
+// safeLink returns url if it may be emitted as a link, or "". URLs with
+// schemes that can run script, such as javascript:, are always dropped;
+// with StrictLinks only absolute http(s) URLs are kept.
+func (c SVGConfig) safeLink(url string) string {
+	if c.StrictLinks {
+		if scheme := models.LinkScheme(url); (scheme == "http" || scheme == "https") && isHTTPURL(url) {
+			return url
+		}
+		return ""
+	}
+	if !models.IsSafeLink(url) {
+		return ""
+	}
+	return url
+}
+
+// withTypeLinks drops unsafe TypeRef and reference target URLs (see
+// safeLink) and fills in missing ones from TypeLinkBase or, for FHIR
+// types, the specification (see typeURL)
+func (c SVGConfig) withTypeLinks(elem models.Element) models.Element {
+	if elem.TypeRef != "" {
+		elem.TypeRef = c.safeLink(elem.TypeRef)
+	}
+	if len(elem.Targets) > 0 {
+		targets := make([]models.Target, len(elem.Targets))
+		for i, t := range elem.Targets {
+			if t.URL != "" {
+				t.URL = c.safeLink(t.URL)
+			}
+			targets[i] = t
+		}
+		elem.Targets = targets
+	}
+	if c.TypeLinkBase == "" && !c.FHIRLinks {
+		return elem
+	}
+	if elem.TypeRef == "" {
+		base, _, _ := strings.Cut(elem.DisplayType(), "(")
+		elem.TypeRef = c.typeURL(strings.TrimSpace(base))
+	}
+	for i, t := range elem.Targets {
+		if t.URL == "" {
+			elem.Targets[i].URL = c.typeURL(t.Type)
+		}
+	}
+	return elem
+}
@@ -0,0 +1,43 @@
+package renderer
+
+import "fmt"
+
+// Media values for UseMedia
+const (
+	MediaScreen = "screen"
+	MediaPrint  = "print"
+)
+
+// printTheme is the print-friendly variant: white rows and header without
+// alternating striping, and black borders
+var printTheme = Theme{
+	HeaderBgColor: "#FFFFFF",
+	RowBgColor:    "#FFFFFF",
+	AltRowBgColor: "#FFFFFF",
+	BorderColor:   "#000000",
+}
+
+// UseMedia switches the config to the variant for media, e.g. MediaPrint
+// for printed implementation guides; other values keep the screen colors
+func (c *SVGConfig) UseMedia(media string) {
+	if media == MediaPrint {
+		printTheme.apply(c)
+	}
+}
+
+// hoverCSS returns the style rule highlighting the row under the pointer,
+// or "" when HoverRows is off
+func hoverCSS(config SVGConfig) string {
+	if !config.HoverRows {
+		return ""
+	}
+	return fmt.Sprintf("        .row:hover > rect:first-child { fill: %s; }\n", config.HoverRowColor)
+}
+
+// htmlHoverCSS is hoverCSS for the rows of the HTML table
+func htmlHoverCSS(config SVGConfig) string {
+	if !config.HoverRows {
+		return ""
+	}
+	return fmt.Sprintf("        .fhir-structure tbody tr:hover { background: %s; }\n", config.HoverRowColor)
+}
@@ -0,0 +1,184 @@
+package renderer
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// node is an SVG element assembled from attributes and children instead of
+// a format string, so markup and arguments cannot get out of step. Values
+// and text are escaped when the node is written. All SVG markup is built
+// with nodes; markup from other builders, such as the style sheet and the
+// GitHub mark, is carried as raw nodes.
+type node struct {
+	name     string // "" for a text run or raw markup
+	attrs    []nodeAttr
+	text     string
+	raw      bool // text is markup, written unescaped
+	children []*node
+	attrBuf  [6]nodeAttr // Backs attrs, so most nodes need one allocation
+}
+
+// nodeAttr is an attribute of a node, written in the order it was added
+type nodeAttr struct {
+	key, value string
+}
+
+// inlineElements hold text whose whitespace is significant, so their
+// children are written without line breaks or indentation; see
+// preservedElements
+var inlineElements = map[string]bool{
+	"text":     true,
+	"title":    true,
+	"desc":     true,
+	"metadata": true,
+}
+
+// newNode starts an element
+func newNode(name string) *node {
+	n := &node{name: name}
+	n.attrs = n.attrBuf[:0]
+	return n
+}
+
+// textNode is a run of text among the children of an element, e.g. the
+// unlinked parts of a type line
+func textNode(text string) *node {
+	return &node{text: text}
+}
+
+// rawNode carries markup that is already built, such as a nested builder's
+// output or a style sheet, and is written unchanged
+func rawNode(markup string) *node {
+	return &node{text: markup, raw: true}
+}
+
+// attr adds an attribute; empty values are written too
+func (n *node) attr(key, value string) *node {
+	n.attrs = append(n.attrs, nodeAttr{key, value})
+	return n
+}
+
+// fixed adds a numeric attribute with the given number of decimals, like
+// the %.Nf verbs of fmt
+func (n *node) fixed(key string, value float64, decimals int) *node {
+	return n.attr(key, strconv.FormatFloat(value, 'f', decimals, 64))
+}
+
+// content sets the element's text
+func (n *node) content(text string) *node {
+	n.text = text
+	return n
+}
+
+// append adds child elements; nil children are skipped so optional parts
+// can be passed directly
+func (n *node) append(children ...*node) *node {
+	for _, child := range children {
+		if child != nil {
+			n.children = append(n.children, child)
+		}
+	}
+	return n
+}
+
+// String returns the element as writeTo writes it
+func (n *node) String() string {
+	var sb strings.Builder
+	n.writeTo(&sb)
+	return sb.String()
+}
+
+// writeTo writes the element followed by a newline; children go on their
+// own lines, indented by four spaces per level, except inside
+// inlineElements. Rows are written straight into the document this way,
+// without building their markup as strings first.
+func (n *node) writeTo(w io.StringWriter) {
+	n.write(w, 0, false)
+}
+
+// open returns the start tag of an element whose children are streamed by
+// the caller, followed by a newline; close ends it
+func (n *node) open() string {
+	var sb strings.Builder
+	n.writeStart(&sb)
+	sb.WriteString(">\n")
+	return sb.String()
+}
+
+// close returns the end tag matching open, followed by a newline
+func (n *node) close() string {
+	return "</" + n.name + ">\n"
+}
+
+func (n *node) write(w io.StringWriter, depth int, inline bool) {
+	if n.name == "" {
+		if n.raw {
+			w.WriteString(n.text)
+		} else {
+			w.WriteString(escapeXML(n.text))
+		}
+		return
+	}
+	if !inline {
+		writeIndent(w, depth)
+	}
+	n.writeStart(w)
+	switch {
+	case len(n.children) > 0:
+		w.WriteString(">")
+		if inline || inlineElements[n.name] {
+			w.WriteString(escapeXML(n.text))
+			for _, child := range n.children {
+				child.write(w, 0, true)
+			}
+		} else {
+			w.WriteString("\n")
+			for _, child := range n.children {
+				child.write(w, depth+1, false)
+			}
+			writeIndent(w, depth)
+		}
+	case n.text != "":
+		w.WriteString(">")
+		w.WriteString(escapeXML(n.text))
+	default:
+		w.WriteString("/>")
+		if !inline {
+			w.WriteString("\n")
+		}
+		return
+	}
+	w.WriteString("</")
+	w.WriteString(n.name)
+	w.WriteString(">")
+	if !inline {
+		w.WriteString("\n")
+	}
+}
+
+// writeIndent writes four spaces per level of depth
+func writeIndent(w io.StringWriter, depth int) {
+	for range depth {
+		w.WriteString("    ")
+	}
+}
+
+// writeStart writes "<name" and the attributes
+func (n *node) writeStart(w io.StringWriter) {
+	w.WriteString("<")
+	w.WriteString(n.name)
+	for _, a := range n.attrs {
+		w.WriteString(" ")
+		w.WriteString(a.key)
+		w.WriteString(`="`)
+		w.WriteString(escapeXML(a.value))
+		w.WriteString(`"`)
+	}
+}
+
+// px formats a font size as whole pixels, e.g. "10px"
+func px(v float64) string {
+	return strconv.FormatFloat(v, 'f', 0, 64) + "px"
+}
@@ -0,0 +1,165 @@
+package renderer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"fhir_renderer/models"
+)
+
+// Paging limits
+const (
+	// MinRowsPerPage is the smallest useful MaxRowsPerPage, so pages hold
+	// more rows than title bar and header
+	MinRowsPerPage = 10
+
+	// PageSpacing is the vertical gap between stacked pages
+	PageSpacing = 24.0
+)
+
+// page is a run of rows drawn below a repeated title bar and header row
+type page struct {
+	rows   []RowData
+	number int // 1-based
+}
+
+// height returns the height of the page's title bar, header and rows
+func (p page) height(config SVGConfig) float64 {
+	height := config.TitleHeight + config.HeaderHeight
+	for _, row := range p.rows {
+		height += row.RowHeight
+	}
+	return height
+}
+
+// pagesHeight returns the height of pages stacked with PageSpacing between
+// them
+func pagesHeight(pages []page, config SVGConfig) float64 {
+	height := 0.0
+	for i, p := range pages {
+		if i > 0 {
+			height += PageSpacing
+		}
+		height += p.height(config)
+	}
+	return height
+}
+
+// paginateRows splits rows into pages of at most perPage rows
+func paginateRows(rows []RowData, perPage int) []page {
+	var pages []page
+	for start := 0; start < len(rows); start += perPage {
+		end := min(start+perPage, len(rows))
+		pages = append(pages, page{rows: rows[start:end], number: len(pages) + 1})
+	}
+	return pages
+}
+
+// pageLabel returns the translated "Page n of total"
+func pageLabel(number, total int, config SVGConfig) string {
+	return fmt.Sprintf(config.text("Page %d of %d"), number, total)
+}
+
+// paged reports whether rows exceed config.MaxRowsPerPage
+func paged(rows []RowData, config SVGConfig) bool {
+	return config.MaxRowsPerPage > 0 && len(rows) > config.MaxRowsPerPage
+}
+
+// buildPagedSVG writes pages stacked in one SVG document, each below its own
+// title bar and header row, with the legend and footers after the last one.
+// rows are all rows of the structure, for the accessible summary; total is
+// the page count for the "Page n of total" labels.
+func buildPagedSVG(w *bufio.Writer, resource *models.ResourceDefinition, rows []RowData, pages []page, total int, colWidths ColumnWidths, config SVGConfig) {
+	totalWidth := colWidths.Total()
+
+	coverageY := pagesHeight(pages, config)
+	legendY := coverageY + coverageBarHeight(config)
+	metadataY := legendY + legendHeight(config)
+	footerY := metadataY + metadataFooterHeight(config)
+	totalHeight := footerY + FooterHeight + SVGHeightPadding
+
+	title := structureTitle(resource, config)
+	if len(pages) == 1 {
+		title += " (" + pageLabel(pages[0].number, total, config) + ")"
+	}
+	w.WriteString(buildSVGHeader(totalWidth, totalHeight, title, structureSummary(resource, rows, config), true, config))
+
+	y := 0.0
+	for _, p := range pages {
+		page := newNode("g").attr("class", "page").attr("data-page", strconv.Itoa(p.number))
+		w.WriteString(page.open())
+		w.WriteString(buildTitleBar(structureTitleBar(resource, config)+" ("+pageLabel(p.number, total, config)+")", y, totalWidth, config))
+		w.WriteString(buildTitleBarExtras(resource.Status, y, totalWidth, config))
+		w.WriteString(renderHeaderRow(config, y+config.TitleHeight, totalWidth))
+		writeDataRows(w, p.rows, y+config.TitleHeight+config.HeaderHeight, totalWidth, config)
+		w.WriteString(page.close())
+		y += p.height(config) + PageSpacing
+	}
+
+	w.WriteString(buildCoverageBar(rows, coverageY, totalWidth, config))
+	if config.ShowLegend {
+		w.WriteString(buildLegend(totalWidth, legendY, config))
+	}
+	if config.ShowMetadataFooter {
+		w.WriteString(buildMetadataFooter(resource, totalWidth, metadataY, config))
+	}
+	w.WriteString(buildFooter(totalWidth, footerY, config))
+	w.WriteString(buildRepoLink(footerY, config))
+	w.WriteString(buildWatermark(totalWidth, totalHeight, config))
+	w.WriteString(svgEnd)
+}
+
+// RenderPagesContext renders each page of at most config.MaxRowsPerPage rows
+// as its own SVG document, for diagrams too tall to rasterize in one piece.
+// Every page repeats the title bar and header row and keeps the column
+// widths, row numbers and anchors of the whole structure; the legend and
+// metadata footer follow the last page. A structure that fits on one page,
+// or a MaxRowsPerPage of 0, yields the regular SVG.
+func RenderPagesContext(ctx context.Context, resource *models.ResourceDefinition, config SVGConfig) ([][]byte, error) {
+	tm, err := newTextMeasurer(config)
+	if err != nil {
+		return nil, err
+	}
+	defer tm.Close()
+	config.textMeasurer = tm
+
+	rows, colWidths, config, err := layoutRows(ctx, resource, tm, config)
+	if err != nil {
+		return nil, err
+	}
+
+	_, span := tracer.Start(ctx, "build SVG")
+	defer span.End()
+	if !paged(rows, config) {
+		var buf bytes.Buffer
+		bw, flush := newSVGWriter(&buf, config)
+		buildSVG(bw, resource, rows, colWidths, calculateTotalHeight(rows, config), config)
+		err := flush()
+		return [][]byte{buf.Bytes()}, err
+	}
+
+	pages := paginateRows(rows, config.MaxRowsPerPage)
+	documents := make([][]byte, len(pages))
+	for i, p := range pages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pageConfig := config
+		if i < len(pages)-1 {
+			pageConfig.ShowLegend = false
+			pageConfig.ShowMetadataFooter = false
+			pageConfig.hideCoverageSummary = true
+		}
+		var buf bytes.Buffer
+		bw, flush := newSVGWriter(&buf, pageConfig)
+		buildPagedSVG(bw, resource, rows, []page{p}, len(pages), colWidths, pageConfig)
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		documents[i] = buf.Bytes()
+	}
+	return documents, nil
+}
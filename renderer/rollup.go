@@ -0,0 +1,48 @@
+package renderer
+
+import (
+	"fhir_renderer/models"
+)
+
+// flatten flattens resource for drawing, with the parents rolled up when
+// RollupUsage is set
+func (c SVGConfig) flatten(resource *models.ResourceDefinition) []models.FlatElement {
+	flat := resource.Flatten()
+	if c.RollupUsage {
+		rollupUsage(flat, c)
+	}
+	return flat
+}
+
+// rollupUsage marks the rows whose children are all not used as not used
+// themselves and notes why in their description. The rows are copies, so
+// the resource is unchanged.
+func rollupUsage(flat []models.FlatElement, config SVGConfig) {
+	for i := range flat {
+		elem := &flat[i].Element
+		if elem.Usage == models.UsageNotUsed || !childrenUnused(*elem) {
+			continue
+		}
+		elem.Usage = models.UsageNotUsed
+		label := config.text(NoChildrenUsedLabel)
+		if elem.Description != "" {
+			label = elem.Description + " " + label
+		}
+		elem.Description = label
+	}
+}
+
+// childrenUnused reports whether an element has children and none of them
+// is used, counting parents whose children are all unused as unused.
+// Extensions count as used.
+func childrenUnused(elem models.Element) bool {
+	if len(elem.Elements) == 0 || len(elem.Extensions) > 0 {
+		return false
+	}
+	for _, child := range elem.Elements {
+		if child.Usage != models.UsageNotUsed && !childrenUnused(child) {
+			return false
+		}
+	}
+	return true
+}
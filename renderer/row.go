@@ -2,6 +2,10 @@ package renderer
 
 import (
 	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"fhir_renderer/models"
@@ -9,192 +13,291 @@ import (
 
 // RowData contains pre-calculated data for a row including wrapped text
 type RowData struct {
-	Element   models.FlatElement
-	NameLines []string
-	TypeLines []string
-	DescLines []string
-	RowHeight float64
-	IsRoot    bool
-	IsAlt     bool
+	Element      models.FlatElement
+	ID           string // Unique anchor id derived from the element path
+	Number       int    // 1-based position in the table, shown when RowNumbers is set
+	NameLines    []string
+	URLLines     []string // Wrapped url of an extension, below the name
+	ContextLines []string // Wrapped context of an extension, below the url
+	TypeLines    []string
+	DescLines    []string
+	FixedLines   []string   // Wrapped "Fixed Value:" line below the description
+	PatternLines []string   // Wrapped "Required Pattern:" line below the fixed value
+	CodeChips    [][]string // Binding codes, wrapped to the description column below the pattern
+	MappingLines []string
+	FlagLines    [][]string          // Flag codes, wrapped to the flags column
+	ExtraLines   map[string][]string // Wrapped Meta values of the extra columns, by key
+	SampleLines  []string            // Wrapped example value
+	NoteLines    []string            // Wrapped annotation note for the margin
+	RowHeight    float64             // Includes GroupHeight
+	GroupHeight  float64             // Group header band drawn above the row, 0 for none
+	IsRoot       bool
+	IsAlt        bool
 }
 
 func renderHeaderRow(config SVGConfig, y, totalWidth float64) string {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf(`<rect x="0" y="%.0f" width="%.0f" height="%.0f" fill="%s" stroke="%s"/>
-`,
-		y, totalWidth, config.HeaderHeight, config.HeaderBgColor, config.BorderColor))
+	sb.WriteString(newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", config.HeaderHeight, 0).
+		attr("fill", config.HeaderBgColor).attr("stroke", config.BorderColor).String())
 
-	x := config.Padding
 	textY := y + config.HeaderHeight/2 + TitleVerticalOffset
-	headers := []struct {
-		name  string
-		width float64
-	}{
-		{"Name", config.NameColWidth},
-		{"Flags", config.FlagsColWidth},
-		{"Card.", config.CardinalityColWidth},
-		{"Type", config.TypeColWidth},
-		{"Description & Constraints", config.DescriptionColWidth},
-	}
-
-	for i, h := range headers {
-		sb.WriteString(fmt.Sprintf(`<text x="%.0f" y="%.0f" class="header-text">%s</text>
-`, x+HeaderTextMarginY, textY, escapeXML(h.name)))
-		x += h.width
-		if i < len(headers)-1 {
-			sb.WriteString(fmt.Sprintf(`<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="%s"/>
-`, x, y, x, y+config.HeaderHeight, config.BorderColor))
+	columns := config.columnSpans()
+	for i, col := range columns {
+		x := col.x + config.Padding
+		sb.WriteString(newNode("text").fixed("x", x+HeaderTextMarginY, 0).fixed("y", textY, 0).
+			attr("class", "header-text").content(config.columnTitle(col.key)).String())
+		if i < len(columns)-1 {
+			x += col.width
+			sb.WriteString(renderColumnSeparator(x, y, config.HeaderHeight, config).String())
 		}
 	}
 
 	return sb.String()
 }
 
-func renderDataRowWrapped(row RowData, config SVGConfig, y, totalWidth float64) string {
-	var sb strings.Builder
-
-	sb.WriteString(renderRowBackground(row, y, totalWidth, config))
-	sb.WriteString(renderRowBorder(y, row.RowHeight, totalWidth, config))
-
-	x := config.Padding
-	baseTextY := y + RowTopMargin + config.FontSize
-	firstLineCenterY := y + RowTopMargin + config.FontSize/2 + IconLineVerticalOffset
-
-	sb.WriteString(renderTreeAndIcon(row, x, y, firstLineCenterY, config))
-	sb.WriteString(renderNameColumn(row, x, baseTextY, config))
+// assignRowIDs sets each row's anchor id from its element path
+func assignRowIDs(rows []RowData, seen map[string]int) {
+	for i := range rows {
+		rows[i].ID = anchorID(rows[i].Element, seen)
+	}
+}
 
-	x += config.NameColWidth
-	sb.WriteString(renderColumnSeparator(x, y, row.RowHeight, config))
+// anchorID derives a row anchor id from the element path. Paths already in
+// seen get a numeric suffix so ids stay unique within the document.
+func anchorID(fe models.FlatElement, seen map[string]int) string {
+	id := fe.Path
+	if id == "" {
+		id = fe.Element.Name
+	}
+	if n := seen[id]; n > 0 {
+		seen[id] = n + 1
+		id = fmt.Sprintf("%s-%d", id, n+1)
+	}
+	seen[id]++
+	return id
+}
 
-	sb.WriteString(renderFlagsColumn(row, x, y, config))
-	x += config.FlagsColWidth
-	sb.WriteString(renderColumnSeparator(x, y, row.RowHeight, config))
+// rowLabel summarizes a row for assistive technology: the element path,
+// cardinality, type and description
+func rowLabel(row RowData, config SVGConfig) string {
+	elem := row.Element.Element
+	path := row.Element.Path
+	if path == "" {
+		path = elem.Name
+	}
+	parts := []string{path}
+	for _, part := range []string{elem.Cardinality, elem.Type} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	label := strings.Join(parts, ", ")
+	if desc, _ := buildDescriptionText(row.Element, config); desc != "" {
+		label += ": " + desc
+	}
+	if a := row.Element.Annotation; a != nil && a.Note != "" {
+		label += " (" + a.Note + ")"
+	}
+	return label
+}
 
-	sb.WriteString(renderCardinalityColumn(row, x, y, config))
-	x += config.CardinalityColWidth
-	sb.WriteString(renderColumnSeparator(x, y, row.RowHeight, config))
+// writeDataRow writes a row's nodes straight to w, as rows make up most of
+// a document
+func writeDataRow(w io.StringWriter, row RowData, config SVGConfig, y, totalWidth float64) {
+	// The group header band goes above the row, outside its group so the
+	// row's :target highlight still applies to the row background
+	if row.GroupHeight > 0 {
+		renderGroupHeader(row, y, totalWidth, config).writeTo(w)
+		y += row.GroupHeight
+		row.RowHeight -= row.GroupHeight
+	}
 
-	sb.WriteString(renderTypeColumn(row, x, baseTextY, config))
-	x += config.TypeColWidth
-	sb.WriteString(renderColumnSeparator(x, y, row.RowHeight, config))
+	// Group the row under its path so pages can deep-link to it
+	group := newNode("g").attr("id", row.ID).attr("class", "row").attr("aria-label", rowLabel(row, config))
+	group.append(renderRowBackground(row, y, totalWidth, config), renderRowBorder(y, row.RowHeight, totalWidth, config))
 
-	sb.WriteString(renderDescriptionColumn(row, x, baseTextY, config))
+	baseTextY := y + RowTopMargin + config.FontSize
+	firstLineCenterY := y + RowTopMargin + config.FontSize/2 + IconLineVerticalOffset
 
-	return sb.String()
+	columns := config.columnSpans()
+	for i, col := range columns {
+		// Cells are drawn one padding to the right of the clip path columns
+		x := col.x + config.Padding
+		switch col.key {
+		case ColumnName:
+			group.append(renderTreeAndIcon(row, x, y, firstLineCenterY, config)...)
+			group.append(renderNameColumn(row, x, baseTextY, config))
+		case ColumnFlags:
+			group.append(renderFlagsColumn(row, x, y, config)...)
+		case ColumnCardinality:
+			group.append(renderCardinalityColumn(row, x, y, config))
+		case ColumnType:
+			group.append(renderTypeColumn(row, x, baseTextY, config))
+		case ColumnDescription:
+			group.append(renderDescriptionColumn(row, x, baseTextY, config))
+		case ColumnMappings:
+			group.append(renderMappingsColumn(row, x, baseTextY, config))
+		case ColumnSample:
+			group.append(renderSampleColumn(row, x, baseTextY, config))
+		case ColumnAnnotations:
+			group.append(renderAnnotationColumn(row, x, y, baseTextY, config))
+		case ColumnRowNumber:
+			group.append(renderRowNumberColumn(row, col.x+col.width-config.Padding, baseTextY))
+		default:
+			group.append(renderExtraColumn(row, col.key, x, baseTextY, config))
+		}
+		if i < len(columns)-1 {
+			group.append(renderColumnSeparator(x+col.width, y, row.RowHeight, config))
+		}
+	}
+	group.writeTo(w)
 }
 
 // renderRowBackground renders the background rectangle for a row
-func renderRowBackground(row RowData, y, totalWidth float64, config SVGConfig) string {
+func renderRowBackground(row RowData, y, totalWidth float64, config SVGConfig) *node {
 	bgColor := config.RowBgColor
 	if row.IsAlt {
 		bgColor = config.AltRowBgColor
 	}
-	return fmt.Sprintf(`<rect x="0" y="%.0f" width="%.0f" height="%.0f" fill="%s"/>
-`,
-		y, totalWidth, row.RowHeight, bgColor)
+	if config.HighlightMustSupport && slices.Contains(row.Element.Element.Flags, models.FlagMustSupport) {
+		bgColor = config.MustSupportRowColor
+	}
+	if row.Element.Element.Change == models.ChangeAdded {
+		bgColor = config.AddedRowColor
+	}
+	if color, ok := models.HighlightColor(row.Element.Element.Highlight); ok {
+		bgColor = color
+	}
+	if config.Coverage {
+		if color := coverageRowColor(row.Element.Element.Usage, config); color != "" {
+			bgColor = color
+		}
+	}
+	if row.Element.Annotation != nil {
+		bgColor = annotationColor(row.Element.Annotation)
+	}
+	return newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", row.RowHeight, 0).
+		attr("fill", bgColor)
 }
 
 // renderRowBorder renders the bottom border of a row
-func renderRowBorder(y, rowHeight, totalWidth float64, config SVGConfig) string {
-	return fmt.Sprintf(`<line x1="0" y1="%.0f" x2="%.0f" y2="%.0f" stroke="%s" stroke-width="%.1f"/>
-`,
-		y+rowHeight, totalWidth, y+rowHeight, config.BorderColor, BorderStrokeWidth)
+func renderRowBorder(y, rowHeight, totalWidth float64, config SVGConfig) *node {
+	return newNode("line").attr("x1", "0").fixed("y1", y+rowHeight, 0).fixed("x2", totalWidth, 0).fixed("y2", y+rowHeight, 0).
+		attr("stroke", config.BorderColor).fixed("stroke-width", BorderStrokeWidth, 1)
 }
 
 // renderColumnSeparator renders a vertical column separator line
-func renderColumnSeparator(x, y, rowHeight float64, config SVGConfig) string {
-	return fmt.Sprintf(`<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="%s"/>
-`,
-		x, y, x, y+rowHeight, config.BorderColor)
+func renderColumnSeparator(x, y, rowHeight float64, config SVGConfig) *node {
+	return newNode("line").fixed("x1", x, 0).fixed("y1", y, 0).fixed("x2", x, 0).fixed("y2", y+rowHeight, 0).
+		attr("stroke", config.BorderColor)
 }
 
 // renderTreeAndIcon renders tree lines and the element icon
-func renderTreeAndIcon(row RowData, x, y, firstLineCenterY float64, config SVGConfig) string {
-	var sb strings.Builder
+func renderTreeAndIcon(row RowData, x, y, firstLineCenterY float64, config SVGConfig) []*node {
 	fe := row.Element
 
 	// Tree lines
-	treeLines := RenderTreeLines(x, y, row.RowHeight, firstLineCenterY, fe.Depth, fe.ParentLasts, fe.IsLast, config.TreeStyle)
-	sb.WriteString(treeLines)
+	nodes := treeLines(x, y, row.RowHeight, firstLineCenterY, fe.Depth, fe.ParentLasts, fe.IsLast, config.TreeStyle)
+
+	// Placeholders for truncated branches have no icon
+	if fe.Element.Usage == models.UsageTruncated {
+		return nodes
+	}
 
 	// Icon
 	iconX := x + float64(fe.Depth)*config.TreeStyle.IndentPx
 	iconY := firstLineCenterY - config.IconSize/2
 	hasChildren := len(fe.Element.Elements) > 0
-	iconType := GetIconTypeForElement(fe.Element.Type, row.IsRoot, hasChildren)
-	sb.WriteString(RenderIcon(iconType, iconX, iconY, config.IconSize))
-
-	return sb.String()
+	iconType := config.iconFor(fe.Element.Type, row.IsRoot, hasChildren)
+	return append(nodes, config.renderIcon(iconType, iconX, iconY, config.IconSize))
 }
 
 // renderNameColumn renders the name column with multi-line support
-func renderNameColumn(row RowData, x, baseTextY float64, config SVGConfig) string {
-	var sb strings.Builder
+func renderNameColumn(row RowData, x, baseTextY float64, config SVGConfig) *node {
 	fe := row.Element
 
 	nameX := x + float64(fe.Depth)*config.TreeStyle.IndentPx + config.IconSize + IconTextGap
 	textClass := "link-text"
-	if fe.Element.Usage == "not-used" {
+	if fe.Element.Usage == "not-used" || fe.Element.Usage == models.UsageTruncated {
 		textClass = "not-used"
 	}
 
-	sb.WriteString(`<g clip-path="url(#clip-name)">
-`)
+	group := newNode("g").attr("clip-path", "url(#clip-name)").append(tooltip(fe.Element.Name, fe.Path))
+	lines := group
+	if fe.Element.Usage != models.UsageTruncated {
+		if elementURL := config.elementURL(fe.Path); elementURL != "" {
+			lines = newNode("a").attr("xlink:href", elementURL).attr("target", "_blank")
+			group.append(lines)
+		}
+	}
 	for i, line := range row.NameLines {
 		lineY := baseTextY + float64(i)*config.LineHeight
-		sb.WriteString(fmt.Sprintf(`<text x="%.0f" y="%.0f" class="%s">%s</text>
-`,
-			nameX, lineY, textClass, escapeXML(line)))
+		lines.append(newNode("text").fixed("x", nameX, 0).fixed("y", lineY, 0).attr("class", textClass).content(line))
 	}
-	sb.WriteString("</g>\n")
-
-	return sb.String()
+	return group.append(renderExtensionLines(row, nameX, baseTextY, config)...)
 }
 
-// renderFlagsColumn renders the flags column
-func renderFlagsColumn(row RowData, x, y float64, config SVGConfig) string {
-	flagsStr := renderFlags(row.Element.Element.Flags, config)
-	flagsY := y + row.RowHeight/2
-	return fmt.Sprintf(`<g clip-path="url(#clip-flags)" transform="translate(%.0f, %.0f)">%s</g>
-`, x+config.Padding, flagsY, flagsStr)
+// renderFlagsColumn renders the flags column, with wrapped lines centered
+// on the row
+func renderFlagsColumn(row RowData, x, y float64, config SVGConfig) []*node {
+	var nodes []*node
+	maxWidth := config.FlagsColWidth - config.Padding*2
+	flagsY := y + row.RowHeight/2 - float64(len(row.FlagLines)-1)*config.LineHeight/2
+	for i, line := range row.FlagLines {
+		nodes = append(nodes, newNode("g").attr("clip-path", "url(#clip-flags)").
+			attr("transform", translate(x+config.Padding, flagsY+float64(i)*config.LineHeight)).
+			append(renderFlags(line, maxWidth, config)...))
+	}
+	return nodes
 }
 
-// renderCardinalityColumn renders the cardinality column
-func renderCardinalityColumn(row RowData, x, y float64, config SVGConfig) string {
+// renderCardinalityColumn renders the cardinality column. Cardinalities a
+// profile tightens are bold, with the base cardinality as tooltip.
+func renderCardinalityColumn(row RowData, x, y float64, config SVGConfig) *node {
+	elem := row.Element.Element
 	cardY := y + row.RowHeight/2 + TextVerticalOffset
-	return fmt.Sprintf(`<g clip-path="url(#clip-card)"><text x="%.0f" y="%.0f" class="cell-text">%s</text></g>
-`,
-		x+config.Padding, cardY, escapeXML(row.Element.Element.Cardinality))
+	group := newNode("g").attr("clip-path", "url(#clip-card)")
+	if elem.BaseCardinality != "" {
+		group.append(tooltip(config.text("Base:")+" "+elem.BaseCardinality, ""))
+	}
+	text := newNode("text").fixed("x", x+config.Padding, 0).fixed("y", cardY, 0).attr("class", "cell-text")
+	if elem.Change == models.ChangeTightened {
+		text.attr("font-weight", "bold")
+	}
+	return group.append(text.content(elem.Cardinality))
 }
 
 // renderTypeColumn renders the type column with multi-line and link support
-func renderTypeColumn(row RowData, x, baseTextY float64, config SVGConfig) string {
-	var sb strings.Builder
+func renderTypeColumn(row RowData, x, baseTextY float64, config SVGConfig) *node {
 	fe := row.Element
 
-	sb.WriteString(`<g clip-path="url(#clip-type)">
-`)
+	group := newNode("g").attr("clip-path", "url(#clip-type)").append(tooltip(fe.Element.DisplayType(), fe.Path))
 	for i, line := range row.TypeLines {
 		lineY := baseTextY + float64(i)*config.LineHeight
-		if fe.Element.TypeRef != "" && i == 0 {
-			sb.WriteString(fmt.Sprintf(`<a xlink:href="%s" target="_blank"><text x="%.0f" y="%.0f" class="link-text">%s</text></a>
-`,
-				escapeXML(fe.Element.TypeRef), x+config.Padding, lineY, escapeXML(line)))
+		text := newNode("text").fixed("x", x+config.Padding, 0).fixed("y", lineY, 0).attr("class", "link-text")
+		if len(fe.Element.Targets) > 0 {
+			// Link each reference target individually within the line
+			for _, seg := range splitTypeLinks(line, fe.Element) {
+				if seg.URL != "" {
+					text.append(newNode("a").attr("xlink:href", seg.URL).attr("target", "_blank").
+						append(newNode("tspan").content(seg.Text)))
+				} else {
+					text.append(textNode(seg.Text))
+				}
+			}
+			group.append(text)
+		} else if fe.Element.TypeRef != "" && i == 0 {
+			group.append(newNode("a").attr("xlink:href", fe.Element.TypeRef).attr("target", "_blank").append(text.content(line)))
 		} else {
-			sb.WriteString(fmt.Sprintf(`<text x="%.0f" y="%.0f" class="link-text">%s</text>
-`,
-				x+config.Padding, lineY, escapeXML(line)))
+			group.append(text.content(line))
 		}
 	}
-	sb.WriteString("</g>\n")
-
-	return sb.String()
+	return group
 }
 
 // renderDescriptionColumn renders the description column with multi-line support
-func renderDescriptionColumn(row RowData, x, baseTextY float64, config SVGConfig) string {
-	var sb strings.Builder
+func renderDescriptionColumn(row RowData, x, baseTextY float64, config SVGConfig) *node {
 	fe := row.Element
 
 	descClass := "cell-text"
@@ -204,12 +307,180 @@ func renderDescriptionColumn(row RowData, x, baseTextY float64, config SVGConfig
 		descClass = "todo"
 	}
 
+	descText, _ := buildDescriptionText(fe, config)
+	var tooltipLines []string
+	if descText != "" {
+		tooltipLines = append(tooltipLines, descText)
+	}
+	for _, vc := range valueConstraints(fe.Element, config) {
+		tooltipLines = append(tooltipLines, vc.label+" "+vc.value)
+	}
+	// Right-to-left descriptions are right-aligned within the column
+	textX, rtl := x+config.Padding, isRTL(descText)
+	if rtl {
+		textX = x + config.DescriptionColWidth - config.Padding
+	}
+	group := newNode("g").append(tooltip(strings.Join(tooltipLines, "\n"), fe.Path))
 	for i, line := range row.DescLines {
 		lineY := baseTextY + float64(i)*config.LineHeight
-		sb.WriteString(fmt.Sprintf(`<text x="%.0f" y="%.0f" class="%s">%s</text>
-`,
-			x+config.Padding, lineY, descClass, escapeXML(line)))
+		text := newNode("text").fixed("x", textX, 0).fixed("y", lineY, 0).attr("class", descClass)
+		if rtl {
+			text.attr("direction", "rtl").attr("text-anchor", "end")
+		}
+		group.append(text.content(line))
+	}
+	// Fixed values and patterns follow the description, with a bold label
+	lineY := baseTextY + float64(len(row.DescLines))*config.LineHeight
+	for _, value := range []struct {
+		label string
+		lines []string
+	}{
+		{config.text(FixedValueLabel), row.FixedLines},
+		{config.text(RequiredPatternLabel), row.PatternLines},
+	} {
+		for i, line := range value.lines {
+			text := newNode("text").fixed("x", x+config.Padding, 0).fixed("y", lineY, 0).attr("class", "cell-text")
+			if rest, ok := strings.CutPrefix(line, value.label); ok && i == 0 {
+				text.append(newNode("tspan").attr("font-weight", "bold").content(value.label), textNode(rest))
+			} else {
+				text.content(line)
+			}
+			group.append(text)
+			lineY += config.LineHeight
+		}
 	}
+	// The codes of the binding follow as chips, after a bold label
+	maxWidth := config.DescriptionColWidth - config.Padding*2
+	for i, line := range row.CodeChips {
+		chipX := x + config.Padding
+		if i == 0 {
+			group.append(newNode("text").fixed("x", chipX, 0).fixed("y", lineY, 0).attr("class", "cell-text").
+				attr("font-weight", "bold").content(config.text(BindingCodesLabel)))
+			chipX += codeLabelWidth(config)
+		}
+		group.append(newNode("g").attr("transform", translate(chipX, lineY-TextVerticalOffset)).
+			append(renderCodeChips(line, maxWidth-(chipX-x-config.Padding), config)...))
+		lineY += config.LineHeight
+	}
+	return group
+}
 
-	return sb.String()
+// renderMappingsColumn renders the element's mappings, one or more lines each
+func renderMappingsColumn(row RowData, x, baseTextY float64, config SVGConfig) *node {
+	if len(row.MappingLines) == 0 {
+		return nil
+	}
+	fe := row.Element
+
+	texts := make([]string, len(fe.Element.Mappings))
+	for i, mapping := range fe.Element.Mappings {
+		texts[i] = mappingText(mapping)
+	}
+	return cellLines("map", row.MappingLines, strings.Join(texts, "\n"), fe.Path, x+config.Padding, baseTextY, config)
+}
+
+// renderRowNumberColumn right-aligns the row number at right, linked to the
+// row's anchor so the link can be copied into a review comment
+func renderRowNumberColumn(row RowData, right, baseTextY float64) *node {
+	return newNode("a").attr("xlink:href", "#"+row.ID).append(
+		newNode("text").fixed("x", right, 0).fixed("y", baseTextY, 0).
+			attr("class", "link-text").attr("text-anchor", "end").content(strconv.Itoa(row.Number)),
+	)
+}
+
+// renderExtraColumn renders the element's Meta value for an extra column
+func renderExtraColumn(row RowData, key string, x, baseTextY float64, config SVGConfig) *node {
+	lines := row.ExtraLines[key]
+	if len(lines) == 0 {
+		return nil
+	}
+	fe := row.Element
+	return cellLines(key, lines, fe.Element.Meta[key], fe.Path, x+config.Padding, baseTextY, config)
+}
+
+// cellLines renders wrapped lines of plain text at x, clipped to the column
+// with the given clip path key and with the full text as tooltip
+func cellLines(clip string, lines []string, full, path string, x, baseTextY float64, config SVGConfig) *node {
+	group := newNode("g").attr("clip-path", "url(#clip-"+clip+")").append(tooltip(full, path))
+	for i, line := range lines {
+		lineY := baseTextY + float64(i)*config.LineHeight
+		group.append(newNode("text").fixed("x", x, 0).fixed("y", lineY, 0).attr("class", "cell-text").content(line))
+	}
+	return group
+}
+
+// valueConstraint is a "Fixed Value:", "Required Pattern:" or "Codes:" line
+type valueConstraint struct {
+	label string
+	value string
+}
+
+// valueConstraints returns the element's fixed value, pattern and bound
+// codes, if set, with labels in the configured language
+func valueConstraints(elem models.Element, config SVGConfig) []valueConstraint {
+	var constraints []valueConstraint
+	if elem.Fixed != "" {
+		constraints = append(constraints, valueConstraint{config.text(FixedValueLabel), elem.Fixed})
+	}
+	if elem.Pattern != "" {
+		constraints = append(constraints, valueConstraint{config.text(RequiredPatternLabel), elem.Pattern})
+	}
+	if codes := bindingCodes(elem.Binding); codes != "" {
+		constraints = append(constraints, valueConstraint{config.text(BindingCodesLabel), codes})
+	}
+	return constraints
+}
+
+// tooltip returns an SVG <title> showing the full, unclipped text and the
+// element path, or nil when there is no text
+func tooltip(text, path string) *node {
+	if text == "" {
+		return nil
+	}
+	if path != "" && path != text {
+		text += "\n" + path
+	}
+	return newNode("title").content(text)
+}
+
+// typeSegment is a piece of a type line, linked when URL is set
+type typeSegment struct {
+	Text string
+	URL  string
+}
+
+// typeTokenPattern matches type and resource names within a type line
+var typeTokenPattern = regexp.MustCompile(`[A-Za-z][A-Za-z0-9_-]*`)
+
+// splitTypeLinks breaks a wrapped type line into plain and linked segments:
+// reference targets link to their own URL and the base type links to TypeRef
+func splitTypeLinks(line string, elem models.Element) []typeSegment {
+	links := make(map[string]string)
+	if elem.TypeRef != "" {
+		base, _, _ := strings.Cut(elem.DisplayType(), "(")
+		links[base] = elem.TypeRef
+	}
+	for _, t := range elem.Targets {
+		if t.URL != "" {
+			links[t.Type] = t.URL
+		}
+	}
+
+	var segments []typeSegment
+	last := 0
+	for _, loc := range typeTokenPattern.FindAllStringIndex(line, -1) {
+		url, ok := links[line[loc[0]:loc[1]]]
+		if !ok {
+			continue
+		}
+		if loc[0] > last {
+			segments = append(segments, typeSegment{Text: line[last:loc[0]]})
+		}
+		segments = append(segments, typeSegment{Text: line[loc[0]:loc[1]], URL: url})
+		last = loc[1]
+	}
+	if last < len(line) {
+		segments = append(segments, typeSegment{Text: line[last:]})
+	}
+	return segments
 }
@@ -0,0 +1,39 @@
+package renderer
+
+import (
+	"fmt"
+
+	"fhir_renderer/models"
+)
+
+// ColumnSample is the column holding values of the definition's example
+// instance. It is appended automatically when a definition has an example
+// and cannot be selected via Columns.
+const ColumnSample = "sample"
+
+// SampleColWidth is the width of the sample value column
+const SampleColWidth = 160.0
+
+// sampleWidth returns the width of the sample value column, or 0
+func (c SVGConfig) sampleWidth() float64 {
+	if !c.showSample {
+		return 0
+	}
+	return SampleColWidth
+}
+
+// renderSampleColumn renders the example value of a row
+func renderSampleColumn(row RowData, x, baseTextY float64, config SVGConfig) *node {
+	if len(row.SampleLines) == 0 {
+		return nil
+	}
+	return cellLines(ColumnSample, row.SampleLines, row.Element.Sample, row.Element.Path, x+config.Padding, baseTextY, config)
+}
+
+// htmlSampleCell renders the sample value cell of an HTML row
+func htmlSampleCell(fe models.FlatElement) string {
+	if fe.Sample == "" {
+		return "<td></td>"
+	}
+	return fmt.Sprintf("<td>%s</td>", escapeXML(fe.Sample))
+}
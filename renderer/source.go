@@ -0,0 +1,55 @@
+package renderer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SourceNamespace is the XML namespace of the element that carries the
+// embedded source inside the SVG's <metadata>
+const SourceNamespace = "https://github.com/nuuner/fhir-resource-svg-renderer#source"
+
+// ErrNoSource is returned by ExtractSource for SVGs without an embedded source
+var ErrNoSource = errors.New("SVG has no embedded source")
+
+// sourceMetadata returns the <metadata> block embedding CompressedResource,
+// or "" unless EmbedSource is set
+func sourceMetadata(config SVGConfig) string {
+	if !config.EmbedSource || config.CompressedResource == "" {
+		return ""
+	}
+	return newNode("metadata").append(
+		newNode("source").attr("xmlns", SourceNamespace).attr("encoding", "brotli-base64url").content(config.CompressedResource),
+	).String()
+}
+
+// ExtractSource returns the Brotli+Base64URL encoded resource embedded in an
+// SVG rendered with EmbedSource. Other prefixes for the namespace, as editors
+// may write on saving, are recognized too.
+func ExtractSource(r io.Reader) (string, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", ErrNoSource
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid SVG: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != SourceNamespace || start.Name.Local != "source" {
+			continue
+		}
+		var encoded string
+		if err := decoder.DecodeElement(&encoded, &start); err != nil {
+			return "", fmt.Errorf("invalid SVG: %w", err)
+		}
+		if encoded = strings.TrimSpace(encoded); encoded == "" {
+			return "", ErrNoSource
+		}
+		return encoded, nil
+	}
+}
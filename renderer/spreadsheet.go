@@ -0,0 +1,155 @@
+package renderer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// spreadsheetHeader names the columns of the CSV and XLSX exports. The
+// headers stay in English so scripts can rely on them.
+var spreadsheetHeader = []string{
+	"Path", "Name", "Depth", "Cardinality", "Type", "Flags",
+	"Binding Strength", "Value Set", "Usage", "Description", "Notes",
+}
+
+// spreadsheetRows returns the header and one row per flattened element
+func spreadsheetRows(resource *models.ResourceDefinition, config SVGConfig) [][]string {
+	rows := [][]string{slices.Clone(spreadsheetHeader)}
+	for _, fe := range config.flatten(resource) {
+		elem := fe.Element
+		path := fe.Path
+		if path == "" {
+			path = elem.Name
+		}
+		var strength, valueSet string
+		if elem.Binding != nil {
+			strength, valueSet = elem.Binding.Strength, elem.Binding.ValueSet
+		}
+		rows = append(rows, []string{
+			path, elem.Name, strconv.Itoa(fe.Depth), elem.Cardinality, elem.DisplayType(),
+			strings.Join(elem.Flags, " "), strength, valueSet, elem.Usage, elem.Description, elem.Notes,
+		})
+	}
+	return rows
+}
+
+// RenderCSV writes the flattened element table as CSV with a header row.
+// Cells that spreadsheets would evaluate as formulas get a leading
+// apostrophe.
+func RenderCSV(w io.Writer, resource *models.ResourceDefinition, config SVGConfig) error {
+	cw := csv.NewWriter(w)
+	for _, row := range spreadsheetRows(resource, config) {
+		for i, cell := range row {
+			row[i] = escapeFormula(cell)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// escapeFormula prefixes cells starting like a formula with an apostrophe
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// xlsxFiles are the fixed parts of a single-sheet workbook; the sheet itself
+// is written by RenderXLSX
+var xlsxFiles = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border/></borders><cellStyleXfs count="1"><xf/></cellStyleXfs><cellXfs count="2"><xf/><xf fontId="1" applyFont="1"/></cellXfs></styleSheet>`},
+}
+
+// xlsxSheetNameReplacer replaces the characters Excel does not allow in
+// sheet names
+var xlsxSheetNameReplacer = strings.NewReplacer(`\`, "_", "/", "_", "?", "_", "*", "_", "[", "_", "]", "_", ":", "_")
+
+// RenderXLSX writes the flattened element table as an Excel workbook with
+// one sheet named after the resource. The header row is bold and frozen,
+// and the columns have auto filters.
+func RenderXLSX(w io.Writer, resource *models.ResourceDefinition, config SVGConfig) error {
+	zw := zip.NewWriter(w)
+	for _, file := range xlsxFiles {
+		if err := writeZipEntry(zw, file.name, file.content); err != nil {
+			return err
+		}
+	}
+
+	sheetName := xlsxSheetNameReplacer.Replace(resource.Name)
+	if sheetName == "" {
+		sheetName = "Structure"
+	}
+	if runes := []rune(sheetName); len(runes) > 31 {
+		sheetName = string(runes[:31])
+	}
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` +
+		escapeXML(sheetName) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	if err := writeZipEntry(zw, "xl/workbook.xml", workbook); err != nil {
+		return err
+	}
+
+	rows := spreadsheetRows(resource, config)
+	lastCell := xlsxCellRef(len(spreadsheetHeader)-1, len(rows)-1)
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			style := ""
+			if r == 0 {
+				style = ` s="1"`
+			}
+			if c == 2 && r > 0 {
+				// Depth is the only numeric column
+				fmt.Fprintf(&sb, `<c r="%s"><v>%s</v></c>`, xlsxCellRef(c, r), cell)
+				continue
+			}
+			fmt.Fprintf(&sb, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, xlsxCellRef(c, r), style, escapeXML(cell))
+		}
+		sb.WriteString("</row>")
+	}
+	fmt.Fprintf(&sb, `</sheetData><autoFilter ref="A1:%s"/></worksheet>`, lastCell)
+	if err := writeZipEntry(zw, "xl/worksheets/sheet1.xml", sb.String()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// xlsxCellRef returns the A1 reference of a 0-based column and row
+func xlsxCellRef(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+// writeZipEntry adds a compressed file to an archive
+func writeZipEntry(zw *zip.Writer, name, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
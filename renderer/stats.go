@@ -0,0 +1,70 @@
+package renderer
+
+import (
+	"context"
+
+	"fhir_renderer/models"
+)
+
+// Stats summarizes how complex a resource definition is and how large its
+// diagram would be, so tooling can flag overly complex profiles without
+// rendering them
+type Stats struct {
+	Elements   int `json:"elements"`   // Rows below the root, extensions included
+	MaxDepth   int `json:"maxDepth"`   // Deepest nesting level; the root's children are at 1
+	Extensions int `json:"extensions"` // Extensions of the resource and its elements
+
+	// Rows by cardinality, e.g. {"0..1": 12, "1..*": 2}; rows without a
+	// cardinality are not counted
+	Cardinalities map[string]int `json:"cardinalities"`
+
+	// Rows carrying each flag, e.g. {"MS": 4, "S": 7}, the root included
+	Flags map[string]int `json:"flags"`
+
+	// Estimated size of the SVG in pixels with the given config
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// AnalyzeContext counts the elements, extensions, cardinalities and flags of
+// resource and runs the layout pass to estimate the diagram size, without
+// producing SVG
+func AnalyzeContext(ctx context.Context, resource *models.ResourceDefinition, config SVGConfig) (*Stats, error) {
+	flat := resource.Flatten()
+	stats := &Stats{
+		Elements:      len(flat) - 1,
+		Extensions:    countExtensions(resource),
+		Cardinalities: make(map[string]int),
+		Flags:         make(map[string]int),
+	}
+	for _, fe := range flat {
+		stats.MaxDepth = max(stats.MaxDepth, fe.Depth)
+		if fe.Element.Cardinality != "" {
+			stats.Cardinalities[fe.Element.Cardinality]++
+		}
+		for _, flag := range fe.Element.Flags {
+			stats.Flags[flag]++
+		}
+	}
+
+	size, err := MeasureContext(ctx, resource, config)
+	if err != nil {
+		return nil, err
+	}
+	stats.Width, stats.Height = size.Width, size.Height
+	return stats, nil
+}
+
+// countExtensions counts the extensions of the resource and of its elements
+// at every level
+func countExtensions(resource *models.ResourceDefinition) int {
+	var count func(elements []models.Element) int
+	count = func(elements []models.Element) int {
+		n := 0
+		for _, e := range elements {
+			n += models.CountExtensions(e.Extensions) + count(e.Elements)
+		}
+		return n
+	}
+	return models.CountExtensions(resource.Extensions) + count(resource.Elements)
+}
@@ -0,0 +1,68 @@
+package renderer
+
+import (
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// Status badge dimensions
+const (
+	StatusBadgeHeight   = 18.0
+	StatusBadgeFontSize = 10.0
+	StatusBadgePadding  = 6.0
+)
+
+// statusColors are the badge fills of the publication statuses; white text
+// keeps the WCAG AA contrast on each
+var statusColors = map[string]string{
+	models.StatusDraft:   "#B34700",
+	models.StatusActive:  "#2E7D32",
+	models.StatusRetired: "#666666",
+	models.StatusUnknown: "#666666",
+}
+
+// statusLabels are the English badge texts, translated via config.text
+var statusLabels = map[string]string{
+	models.StatusDraft:   "Draft",
+	models.StatusActive:  "Active",
+	models.StatusRetired: "Retired",
+	models.StatusUnknown: "Unknown",
+}
+
+// statusLabel returns the translated badge text of a status, or "" when the
+// status is not a FHIR publication status
+func statusLabel(status string, config SVGConfig) string {
+	label, ok := statusLabels[strings.ToLower(status)]
+	if !ok {
+		return ""
+	}
+	return config.text(label)
+}
+
+// statusBadgeWidth returns the width of the badge showing label
+func statusBadgeWidth(label string, config SVGConfig) float64 {
+	return config.textMeasurer.MeasureString(label)*StatusBadgeFontSize/config.FontSize + 2*StatusBadgePadding
+}
+
+// buildStatusBadge draws the publication status as a colored badge at the
+// right of the title bar at y, or returns "" for no or unknown statuses
+func buildStatusBadge(status string, y, totalWidth float64, config SVGConfig) string {
+	label := statusLabel(status, config)
+	if label == "" {
+		return ""
+	}
+	width := statusBadgeWidth(label, config)
+	x := totalWidth - config.Padding - width
+	badgeY := y + (config.TitleHeight-StatusBadgeHeight)/2
+	textY := badgeY + StatusBadgeHeight/2 + 3 // Vertically centered text
+
+	return newNode("g").attr("class", "status-badge").append(
+		newNode("rect").fixed("x", x, 1).fixed("y", badgeY, 1).fixed("width", width, 1).
+			fixed("height", StatusBadgeHeight, 0).fixed("rx", StatusBadgeHeight/2, 0).
+			attr("fill", statusColors[strings.ToLower(status)]),
+		newNode("text").fixed("x", x+StatusBadgePadding, 1).fixed("y", textY, 1).
+			attr("font-family", config.FontFamily).attr("font-size", px(StatusBadgeFontSize)).
+			attr("fill", "#FFFFFF").content(label),
+	).String()
+}
@@ -1,12 +1,22 @@
 package renderer
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"math"
 	"strings"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
 	"fhir_renderer/models"
 )
 
+// tracer records the layout and SVG building spans
+var tracer = otel.Tracer("fhir_renderer/renderer")
+
 // ColumnWidths holds the calculated widths for each column
 type ColumnWidths struct {
 	Name        float64
@@ -14,44 +24,122 @@ type ColumnWidths struct {
 	Cardinality float64
 	Type        float64
 	Description float64
+	Mappings    float64
+	Extra       float64 // Combined width of the extra columns
+	Sample      float64 // Example value column, 0 when hidden
+	Annotations float64 // Margin note column, 0 when hidden
+	RowNumbers  float64 // Leading row number column, 0 when hidden
 }
 
 // Total returns the sum of all column widths
 func (cw ColumnWidths) Total() float64 {
-	return cw.Name + cw.Flags + cw.Cardinality + cw.Type + cw.Description
+	return cw.Name + cw.Flags + cw.Cardinality + cw.Type + cw.Description + cw.Mappings + cw.Extra + cw.Sample + cw.Annotations + cw.RowNumbers
 }
 
 // Render generates SVG for a resource definition
 func Render(resource *models.ResourceDefinition, config SVGConfig) string {
-	tm, err := NewTextMeasurer(config.FontSize)
+	svg, err := RenderContext(context.Background(), resource, config)
 	if err != nil {
 		return renderFallback()
 	}
+	return svg
+}
+
+// RenderContext generates SVG for a resource definition, aborting with the
+// context's error if it is cancelled or its deadline passes mid-render
+func RenderContext(ctx context.Context, resource *models.ResourceDefinition, config SVGConfig) (string, error) {
+	var sb strings.Builder
+	if err := RenderToContext(ctx, &sb, resource, config); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// RenderTo writes the SVG for a resource definition to w
+func RenderTo(w io.Writer, resource *models.ResourceDefinition, config SVGConfig) error {
+	return RenderToContext(context.Background(), w, resource, config)
+}
+
+// RenderToContext writes the SVG for a resource definition to w row by row
+// instead of building the whole document in memory. Layout errors, including
+// cancellation, are returned before anything is written; afterwards only
+// errors from w are reported.
+func RenderToContext(ctx context.Context, w io.Writer, resource *models.ResourceDefinition, config SVGConfig) error {
+	tm, err := newTextMeasurer(config)
+	if err != nil {
+		return err
+	}
 	defer tm.Close()
 	config.textMeasurer = tm
 
-	config.NameColWidth = calculateNameColumnWidth(resource, tm, config)
-	rows := prepareRows(resource.Flatten(), tm, config)
+	rows, colWidths, config, err := layoutRows(ctx, resource, tm, config)
+	if err != nil {
+		return err
+	}
+
+	_, span := tracer.Start(ctx, "build SVG")
+	defer span.End()
+	bw, flush := newSVGWriter(w, config)
+	if paged(rows, config) {
+		pages := paginateRows(rows, config.MaxRowsPerPage)
+		buildPagedSVG(bw, resource, rows, pages, len(pages), colWidths, config)
+		return flush()
+	}
+	totalHeight := calculateTotalHeight(rows, config)
+	buildSVG(bw, resource, rows, colWidths, totalHeight, config)
+	return flush()
+}
+
+// layoutRows sizes the columns and wraps every row. It returns the config
+// updated with the computed column widths.
+func layoutRows(ctx context.Context, resource *models.ResourceDefinition, tm *TextMeasurer, config SVGConfig) ([]RowData, ColumnWidths, SVGConfig, error) {
+	ctx, span := tracer.Start(ctx, "measure text")
+	defer span.End()
+	config = config.withHiddenColumns()
+	config.showAnnotations = resource.HasAnnotationNotes()
+	config.showSample = resource.HasExample()
+	config.fhirVersion = resource.FHIRVersionOf()
+	flatElements := config.flatten(resource)
+	if config.RowNumbers {
+		config.rowNumberColWidth = calculateRowNumberWidth(len(flatElements), tm, config)
+	}
+	config = sizeColumns([]*models.ResourceDefinition{resource}, tm, config)
+	config = fitColumns(config)
+	rows, err := prepareRows(ctx, flatElements, tm, config)
+	if err != nil {
+		return nil, ColumnWidths{}, config, err
+	}
+	span.SetAttributes(attribute.Int("rows", len(rows)))
+	assignRowIDs(rows, make(map[string]int))
+	config.showChanges = resource.HasChanges()
 	colWidths := ColumnWidths{
 		Name:        config.NameColWidth,
 		Flags:       config.FlagsColWidth,
 		Cardinality: config.CardinalityColWidth,
 		Type:        config.TypeColWidth,
 		Description: config.DescriptionColWidth,
+		Mappings:    config.MappingsColWidth,
+		Extra:       config.extraColumnsWidth(),
+		Sample:      config.sampleWidth(),
+		Annotations: config.annotationsWidth(),
+		RowNumbers:  config.rowNumberWidth(),
 	}
-
-	totalHeight := calculateTotalHeight(rows, config)
-	return buildSVG(rows, colWidths, totalHeight, config)
+	return rows, colWidths, config, nil
 }
 
-// calculateNameColumnWidth determines the optimal name column width based on content
-func calculateNameColumnWidth(resource *models.ResourceDefinition, tm *TextMeasurer, config SVGConfig) float64 {
-	flatElements := resource.Flatten()
-	maxNameWidth := tm.MeasureString(resource.Name)
+// calculateNameColumnWidth determines the optimal name column width based on
+// the resource name and its flattened rows
+func calculateNameColumnWidth(name string, flat []models.FlatElement, tm *TextMeasurer, config SVGConfig) float64 {
+	maxNameWidth := tm.MeasureString(name)
 
-	for _, fe := range flatElements {
+	for _, fe := range flat {
 		indentWidth := float64(fe.Depth) * config.TreeStyle.IndentPx
 		nameWidth := indentWidth + config.IconSize + IconSpaceInMeasurement + tm.MeasureString(fe.Element.Name)
+		// Extension urls wrap after their slashes, so fit the widest segment
+		// with the margins prepareRow leaves
+		for _, segment := range strings.SplitAfter(extensionURL(fe), "/") {
+			nameWidth = max(nameWidth, indentWidth+config.IconSize+IconPaddingRight+FontRenderingBuffer+tm.MeasureString(segment))
+		}
 		if nameWidth > maxNameWidth {
 			maxNameWidth = nameWidth
 		}
@@ -67,21 +155,128 @@ func calculateNameColumnWidth(resource *models.ResourceDefinition, tm *TextMeasu
 	return width
 }
 
+// sizeColumns fits the name, flags, cardinality and type columns to the
+// content of the resources and gives the description column the width the
+// flags, cardinality and type columns leave of their configured widths, so
+// short types widen the description and long ones narrow it
+func sizeColumns(resources []*models.ResourceDefinition, tm *TextMeasurer, config SVGConfig) SVGConfig {
+	budget := config.FlagsColWidth + config.CardinalityColWidth + config.TypeColWidth + config.DescriptionColWidth
+	name, flags, card, typ := 0.0, 0.0, 0.0, 0.0
+	for _, resource := range resources {
+		flat := resource.Flatten()
+		name = max(name, calculateNameColumnWidth(resource.Name, flat, tm, config))
+		flags = max(flags, calculateFlagsColumnWidth(flat, tm, config))
+		card = max(card, calculateCardinalityColumnWidth(flat, tm, config))
+		typ = max(typ, calculateTypeColumnWidth(flat, tm, config))
+	}
+	config.NameColWidth = name
+	config.FlagsColWidth, config.CardinalityColWidth, config.TypeColWidth = flags, card, typ
+	if config.showsColumn(ColumnDescription) {
+		config.DescriptionColWidth = max(budget-flags-card-typ, MinDescriptionColWidth)
+	}
+	return config
+}
+
+// calculateCardinalityColumnWidth fits the widest cardinality, within
+// MinCardinalityColWidth and MaxCardinalityColWidth
+func calculateCardinalityColumnWidth(flat []models.FlatElement, tm *TextMeasurer, config SVGConfig) float64 {
+	if !config.showsColumn(ColumnCardinality) {
+		return 0
+	}
+	width := columnHeaderWidth(ColumnCardinality, tm, config)
+	for _, fe := range flat {
+		// Tightened cardinalities are bold
+		width = max(width, tm.MeasureString(fe.Element.Cardinality)/BoldTextWidthFactor+config.Padding*2)
+	}
+	return math.Ceil(min(max(width, MinCardinalityColWidth), MaxCardinalityColWidth))
+}
+
+// calculateTypeColumnWidth fits the widest type without wrapping, within
+// MinTypeColWidth and MaxTypeColWidth
+func calculateTypeColumnWidth(flat []models.FlatElement, tm *TextMeasurer, config SVGConfig) float64 {
+	if !config.showsColumn(ColumnType) {
+		return 0
+	}
+	width := columnHeaderWidth(ColumnType, tm, config)
+	for _, fe := range flat {
+		width = max(width, tm.MeasureString(fe.Element.DisplayType())+config.Padding*2+FontRenderingBuffer)
+	}
+	return math.Ceil(min(max(width, MinTypeColWidth), MaxTypeColWidth))
+}
+
+// columnHeaderWidth returns the width a column needs for its bold header
+func columnHeaderWidth(key string, tm *TextMeasurer, config SVGConfig) float64 {
+	return tm.MeasureString(config.columnTitle(key))*config.HeaderFontSize/config.FontSize/BoldTextWidthFactor + config.Padding*2
+}
+
+// fitColumns shrinks the name, type and description columns so the table
+// fits config.MaxTotalWidth. Each column gives up width in proportion to its
+// room above its minimum, so the widest columns shrink most; when even the
+// minimums do not fit, all three use their minimum. Flags, cardinality and
+// extra columns keep their width.
+func fitColumns(config SVGConfig) SVGConfig {
+	if config.MaxTotalWidth <= 0 {
+		return config
+	}
+	excess := config.tableWidth() - config.MaxTotalWidth
+	if excess <= 0 {
+		return config
+	}
+
+	columns := []struct {
+		width *float64
+		min   float64
+	}{
+		{&config.NameColWidth, MinFitNameColWidth},
+		{&config.TypeColWidth, MinFitTypeColWidth},
+		{&config.DescriptionColWidth, MinFitDescriptionColWidth},
+	}
+	room := 0.0
+	for _, col := range columns {
+		room += max(*col.width-col.min, 0)
+	}
+	if room <= 0 {
+		return config
+	}
+	ratio := min(excess/room, 1)
+	for _, col := range columns {
+		if r := *col.width - col.min; r > 0 {
+			*col.width = math.Floor(*col.width - r*ratio)
+		}
+	}
+	// Give the pixels lost to rounding back to the description, or the name
+	// when the description is hidden
+	if ratio < 1 {
+		if config.showsColumn(ColumnDescription) {
+			config.DescriptionColWidth += config.MaxTotalWidth - config.tableWidth()
+		} else {
+			config.NameColWidth += config.MaxTotalWidth - config.tableWidth()
+		}
+	}
+	return config
+}
+
 // prepareRows creates RowData for each flattened element with text wrapping
-func prepareRows(flatElements []models.FlatElement, tm *TextMeasurer, config SVGConfig) []RowData {
+func prepareRows(ctx context.Context, flatElements []models.FlatElement, tm *TextMeasurer, config SVGConfig) ([]RowData, error) {
 	rows := make([]RowData, len(flatElements))
 
 	for i, fe := range flatElements {
+		// Text measurement dominates render time, so check for cancellation per row
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		rows[i] = prepareRow(fe, i, tm, config)
 	}
 
-	return rows
+	return rows, nil
 }
 
 // prepareRow creates a single RowData with wrapped text and calculated height
 func prepareRow(fe models.FlatElement, index int, tm *TextMeasurer, config SVGConfig) RowData {
+	fe.Element = config.withTypeLinks(fe.Element)
 	row := RowData{
 		Element: fe,
+		Number:  index + 1,
 		IsRoot:  index == 0,
 		IsAlt:   index%2 == 1,
 	}
@@ -98,36 +293,92 @@ func prepareRow(fe models.FlatElement, index int, tm *TextMeasurer, config SVGCo
 		row.NameLines = tm.WrapText(fe.Element.Name, availableNameWidth)
 	}
 
+	// Wrap flags that do not fit the flags column on one line
+	if config.showsColumn(ColumnFlags) {
+		row.FlagLines = wrapFlags(fe.Element.Flags, config.FlagsColWidth-config.Padding*2, config)
+	}
+
+	// Wrap the url and context of extensions below the name
+	if url := extensionURL(fe); url != "" {
+		row.URLLines = tm.WrapText(url, availableNameWidth)
+	}
+	if context := extensionContext(fe, config); context != "" {
+		row.ContextLines = tm.WrapText(context, availableNameWidth)
+	}
+
 	// Wrap type text
-	row.TypeLines = tm.WrapText(fe.Element.Type, availableTypeWidth)
+	if config.showsColumn(ColumnType) {
+		row.TypeLines = tm.WrapText(fe.Element.DisplayType(), availableTypeWidth)
+	}
 
 	// Build and wrap description text
-	descText, isBold := buildDescriptionText(fe)
-	descWidth := availableDescWidth
-	if isBold {
-		descWidth = availableDescWidth * BoldTextWidthFactor
+	if config.showsColumn(ColumnDescription) {
+		descText, isBold := buildDescriptionText(fe, config)
+		descWidth := availableDescWidth
+		if isBold {
+			descWidth = availableDescWidth * BoldTextWidthFactor
+		}
+		row.DescLines = tm.WrapText(descText, descWidth)
+		row.FixedLines = wrapValueLine(config.text(FixedValueLabel), fe.Element.Fixed, availableDescWidth, tm)
+		row.PatternLines = wrapValueLine(config.text(RequiredPatternLabel), fe.Element.Pattern, availableDescWidth, tm)
+		row.CodeChips = wrapCodeChips(fe.Element.Binding, config.DescriptionColWidth-config.Padding*2, config)
+		if descText == "" && (row.FixedLines != nil || row.PatternLines != nil || row.CodeChips != nil) {
+			row.DescLines = nil
+		}
+	}
+
+	// Wrap each mapping as "identity: map"
+	if config.showsColumn(ColumnMappings) {
+		availableMappingsWidth := config.MappingsColWidth - config.Padding*2 - FontRenderingBuffer
+		for _, mapping := range fe.Element.Mappings {
+			row.MappingLines = append(row.MappingLines, tm.WrapText(mappingText(mapping), availableMappingsWidth)...)
+		}
+	}
+
+	// Wrap the values of the extra columns
+	for _, extra := range config.ExtraColumns {
+		value := fe.Element.Meta[extra.Key]
+		if value == "" {
+			continue
+		}
+		if row.ExtraLines == nil {
+			row.ExtraLines = make(map[string][]string, len(config.ExtraColumns))
+		}
+		row.ExtraLines[extra.Key] = tm.WrapText(value, config.columnWidth(extra.Key)-config.Padding*2-FontRenderingBuffer)
 	}
-	row.DescLines = tm.WrapText(descText, descWidth)
 
-	// Calculate row height
-	row.RowHeight = calculateRowHeight(row, config)
+	// Wrap the example value
+	if fe.Sample != "" && config.showsColumn(ColumnSample) {
+		row.SampleLines = tm.WrapText(fe.Sample, SampleColWidth-config.Padding*2-FontRenderingBuffer)
+	}
+
+	// Wrap the annotation note for the margin
+	if fe.Annotation != nil && fe.Annotation.Note != "" && config.showsColumn(ColumnAnnotations) {
+		row.NoteLines = tm.WrapText(fe.Annotation.Note, AnnotationColWidth-config.Padding*2-AnnotationMarkerWidth-FontRenderingBuffer)
+	}
+
+	// Calculate row height, including the group header band above the row
+	row.GroupHeight = groupHeaderHeight(fe, config)
+	row.RowHeight = calculateRowHeight(row, config) + row.GroupHeight
 
 	return row
 }
 
-// buildDescriptionText constructs the description text and returns whether it should be bold
-func buildDescriptionText(fe models.FlatElement) (string, bool) {
+// buildDescriptionText constructs the description text in the configured
+// language and returns whether it should be bold
+func buildDescriptionText(fe models.FlatElement, config SVGConfig) (string, bool) {
 	descText := fe.Element.Description
 	isBold := false
 
 	if fe.Element.Usage == "not-used" {
 		if descText == "" {
-			descText = UnusedElementLabel
+			descText = config.text(UnusedElementLabel)
 		}
 	} else if fe.Element.Usage == "todo" {
 		isBold = true
-		if !strings.HasPrefix(descText, "TODO") {
-			descText = "TODO: " + descText
+		prefix := config.text(TodoPrefix)
+		if !strings.HasPrefix(descText, "TODO") && !strings.HasPrefix(descText, prefix) {
+			descText = prefix + " " + descText
 		}
 	}
 
@@ -141,15 +392,36 @@ func buildDescriptionText(fe models.FlatElement) (string, bool) {
 	return descText, isBold
 }
 
+// wrapValueLine wraps a "Fixed Value:" or "Required Pattern:" line, allowing
+// for the bold label; it returns nil when there is no value
+func wrapValueLine(label, value string, maxWidth float64, tm *TextMeasurer) []string {
+	if value == "" {
+		return nil
+	}
+	return tm.WrapText(label+" "+value, maxWidth*BoldTextWidthFactor)
+}
+
+// mappingText returns a mapping as shown in the mappings column
+func mappingText(mapping models.Mapping) string {
+	return mapping.Identity + ": " + mapping.Map
+}
+
 // calculateRowHeight determines the height of a row based on its content
 func calculateRowHeight(row RowData, config SVGConfig) float64 {
-	maxLines := len(row.NameLines)
+	maxLines := len(row.NameLines) + len(row.URLLines) + len(row.ContextLines)
 	if len(row.TypeLines) > maxLines {
 		maxLines = len(row.TypeLines)
 	}
-	if len(row.DescLines) > maxLines {
-		maxLines = len(row.DescLines)
+	if descLines := len(row.DescLines) + len(row.FixedLines) + len(row.PatternLines) + len(row.CodeChips); descLines > maxLines {
+		maxLines = descLines
+	}
+	if len(row.MappingLines) > maxLines {
+		maxLines = len(row.MappingLines)
+	}
+	for _, lines := range row.ExtraLines {
+		maxLines = max(maxLines, len(lines))
 	}
+	maxLines = max(maxLines, len(row.FlagLines), len(row.SampleLines), len(row.NoteLines))
 
 	height := RowTopMargin + float64(maxLines)*config.LineHeight + RowBottomMargin
 	if height < config.MinRowHeight {
@@ -164,12 +436,30 @@ func calculateTotalHeight(rows []RowData, config SVGConfig) float64 {
 	for _, row := range rows {
 		contentHeight += row.RowHeight
 	}
-	return config.TitleHeight + config.HeaderHeight + contentHeight + FooterHeight + SVGHeightPadding
+	return config.TitleHeight + config.HeaderHeight + contentHeight + coverageBarHeight(config) + legendHeight(config) + metadataFooterHeight(config) + FooterHeight + SVGHeightPadding
 }
 
-// buildSVG constructs the complete SVG string
-func buildSVG(rows []RowData, colWidths ColumnWidths, totalHeight float64, config SVGConfig) string {
-	var sb strings.Builder
+// svgHeight returns the height of the document RenderToContext writes for
+// rows, with the pages stacked when the rows exceed MaxRowsPerPage
+func svgHeight(rows []RowData, config SVGConfig) float64 {
+	if !paged(rows, config) {
+		return calculateTotalHeight(rows, config)
+	}
+	pages := paginateRows(rows, config.MaxRowsPerPage)
+	return pagesHeight(pages, config) + coverageBarHeight(config) + legendHeight(config) + metadataFooterHeight(config) + FooterHeight + SVGHeightPadding
+}
+
+// metadataFooterHeight returns the height of the optional metadata footer row
+func metadataFooterHeight(config SVGConfig) float64 {
+	if !config.ShowMetadataFooter {
+		return 0
+	}
+	return config.MetadataFooterHeight
+}
+
+// buildSVG writes the complete SVG document. Write errors are sticky in
+// bufio.Writer and surface when the caller flushes.
+func buildSVG(w *bufio.Writer, resource *models.ResourceDefinition, rows []RowData, colWidths ColumnWidths, totalHeight float64, config SVGConfig) {
 	totalWidth := colWidths.Total()
 
 	// Calculate footer Y position
@@ -177,108 +467,167 @@ func buildSVG(rows []RowData, colWidths ColumnWidths, totalHeight float64, confi
 	for _, row := range rows {
 		contentHeight += row.RowHeight
 	}
-	footerY := config.TitleHeight + config.HeaderHeight + contentHeight
+	coverageY := config.TitleHeight + config.HeaderHeight + contentHeight
+	legendY := coverageY + coverageBarHeight(config)
+	metadataY := legendY + legendHeight(config)
+	footerY := metadataY + metadataFooterHeight(config)
+
+	w.WriteString(buildSVGHeader(totalWidth, totalHeight, structureTitle(resource, config), structureSummary(resource, rows, config), true, config))
+	w.WriteString(buildTitleBar(structureTitleBar(resource, config), 0, totalWidth, config))
+	w.WriteString(buildTitleBarExtras(resource.Status, 0, totalWidth, config))
+	w.WriteString(renderHeaderRow(config, config.TitleHeight, totalWidth))
+	writeDataRows(w, rows, config.TitleHeight+config.HeaderHeight, totalWidth, config)
+	w.WriteString(buildCoverageBar(rows, coverageY, totalWidth, config))
+	if config.ShowLegend {
+		w.WriteString(buildLegend(totalWidth, legendY, config))
+	}
+	if config.ShowMetadataFooter {
+		w.WriteString(buildMetadataFooter(resource, totalWidth, metadataY, config))
+	}
+	w.WriteString(buildFooter(totalWidth, footerY, config))
+	w.WriteString(buildRepoLink(footerY, config))
+	w.WriteString(buildWatermark(totalWidth, totalHeight, config))
+	w.WriteString(svgEnd)
+}
 
-	sb.WriteString(buildSVGHeader(totalWidth, totalHeight, config))
-	sb.WriteString(buildClipPaths(colWidths, totalHeight, config))
-	sb.WriteString("</defs>\n")
-	sb.WriteString(buildTitleBar(totalWidth, config))
-	sb.WriteString(renderHeaderRow(config, config.TitleHeight, totalWidth))
-	sb.WriteString(buildDataRows(rows, totalWidth, config))
-	sb.WriteString(buildFooter(totalWidth, footerY, config))
-	sb.WriteString("</svg>")
+// structureTitle returns the accessible name of a structure diagram; a
+// title template names it like the title bar
+func structureTitle(resource *models.ResourceDefinition, config SVGConfig) string {
+	if title, err := expandTitle(resource, config.TitleTemplate); err == nil {
+		return title
+	}
+	return resource.Name + " - " + config.text("Structure")
+}
 
-	return sb.String()
+// structureSummary describes a structure diagram for screen readers: the
+// resource type, its number of elements and its description
+func structureSummary(resource *models.ResourceDefinition, rows []RowData, config SVGConfig) string {
+	elements := 0
+	for _, row := range rows {
+		if !row.IsRoot {
+			elements++
+		}
+	}
+	summary := fmt.Sprintf(config.text("%s with %d elements"), resource.Type, elements)
+	if label := statusLabel(resource.Status, config); label != "" {
+		summary += " (" + label + ")"
+	}
+	if resource.Description != "" {
+		summary += ". " + resource.Description
+	}
+	return summary
+}
+
+// svgSizeAttributes adds the size attributes of the root element: a fixed
+// pixel size, or in responsive mode the container's full width with the
+// scaling limited so text stays between MinFontSize and MaxFontSize
+func svgSizeAttributes(root *node, totalWidth, totalHeight float64, config SVGConfig) {
+	viewBox := fmt.Sprintf("0 0 %.0f %.0f", totalWidth, totalHeight)
+	if config.thumbnailWidth > 0 {
+		root.fixed("width", config.thumbnailWidth, 0).fixed("height", math.Ceil(totalHeight*config.thumbnailWidth/totalWidth), 0).
+			attr("viewBox", viewBox)
+		return
+	}
+	if !config.Responsive {
+		root.fixed("width", totalWidth, 0).fixed("height", totalHeight, 0).attr("viewBox", viewBox)
+		return
+	}
+
+	var style []string
+	if config.MinFontSize > 0 {
+		style = append(style, "min-width: "+num(totalWidth*config.MinFontSize/config.FontSize)+"px")
+	}
+	if config.MaxFontSize > 0 {
+		style = append(style, "max-width: "+num(totalWidth*config.MaxFontSize/config.FontSize)+"px")
+	}
+	root.attr("width", "100%").attr("viewBox", viewBox).attr("preserveAspectRatio", "xMinYMin meet")
+	if len(style) > 0 {
+		root.attr("style", strings.Join(style, "; "))
+	}
+}
+
+// xmlDeclaration starts every SVG document
+const xmlDeclaration = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+// svgEnd closes the root element buildSVGHeader opens
+const svgEnd = "</svg>"
+
+// buildSVGHeader opens the SVG document: the root element, the title and
+// description, which name the diagram for screen readers, and the
+// definitions with the styles and, with clipColumns, the clip path of each
+// visible column. The document is closed with svgEnd.
+func buildSVGHeader(totalWidth, totalHeight float64, title, desc string, clipColumns bool, config SVGConfig) string {
+	root := newNode("svg").attr("xmlns", "http://www.w3.org/2000/svg").attr("xmlns:xlink", "http://www.w3.org/1999/xlink")
+	svgSizeAttributes(root, totalWidth, totalHeight, config)
+	root.attr("role", "img").attr("aria-labelledby", "svg-title svg-desc")
+
+	defs := newNode("defs").append(newNode("style").append(rawNode(styleSheet(config))))
+	if clipColumns {
+		for _, col := range config.columnSpans() {
+			defs.append(newNode("clipPath").attr("id", "clip-"+col.key).append(
+				newNode("rect").fixed("x", col.x, 0).attr("y", "0").fixed("width", col.width, 0).fixed("height", totalHeight, 0),
+			))
+		}
+	}
+
+	return xmlDeclaration + root.open() +
+		newNode("title").attr("id", "svg-title").content(title).String() +
+		newNode("desc").attr("id", "svg-desc").content(desc).String() +
+		sourceMetadata(config) +
+		defs.String()
 }
 
-// buildSVGHeader creates the SVG header with styles
-func buildSVGHeader(totalWidth, totalHeight float64, config SVGConfig) string {
-	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"
-     width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">
-<defs>
-    <style>
-        .header-text { font-family: %s; font-size: %.0fpx; font-weight: bold; fill: %s; }
+// styleSheet returns the CSS rules of the diagram classes
+func styleSheet(config SVGConfig) string {
+	return fmt.Sprintf(`%s        .header-text { font-family: %s; font-size: %.0fpx; font-weight: bold; fill: %s; }
         .cell-text { font-family: %s; font-size: %.0fpx; fill: %s; }
         .link-text { font-family: %s; font-size: %.0fpx; fill: %s; cursor: pointer; }
         .not-used { font-family: %s; font-size: %.0fpx; fill: %s; font-style: italic; }
         .todo { font-family: %s; font-size: %.0fpx; fill: %s; font-weight: bold; }
         .flag-box { font-family: %s; font-size: 10px; fill: %s; }
         .title-text { font-family: %s; font-size: 14px; font-weight: bold; fill: %s; }
-    </style>
+%s        .row:target > rect:first-child { fill: %s; }
 `,
-		totalWidth, totalHeight, totalWidth, totalHeight,
+		fontFaceCSS(config),
 		config.FontFamily, config.HeaderFontSize, config.HeaderTextColor,
 		config.FontFamily, config.FontSize, config.TextColor,
 		config.FontFamily, config.FontSize, config.LinkColor,
 		config.FontFamily, config.FontSize, config.NotUsedColor,
 		config.FontFamily, config.FontSize, config.TodoColor,
 		config.FontFamily, config.TextColor,
-		config.FontFamily, config.HeaderTextColor)
+		config.FontFamily, config.HeaderTextColor,
+		hoverCSS(config),
+		config.TargetRowColor)
 }
 
-// buildClipPaths creates clip path definitions for each column
-func buildClipPaths(colWidths ColumnWidths, totalHeight float64, config SVGConfig) string {
-	var sb strings.Builder
-
-	colStarts := []float64{
-		0,
-		colWidths.Name,
-		colWidths.Name + colWidths.Flags,
-		colWidths.Name + colWidths.Flags + colWidths.Cardinality,
-		colWidths.Name + colWidths.Flags + colWidths.Cardinality + colWidths.Type,
-	}
-	widths := []float64{
-		colWidths.Name,
-		colWidths.Flags,
-		colWidths.Cardinality,
-		colWidths.Type,
-		colWidths.Description,
-	}
-	names := []string{"name", "flags", "card", "type", "desc"}
-
-	for i, name := range names {
-		sb.WriteString(fmt.Sprintf(`    <clipPath id="clip-%s"><rect x="%.0f" y="0" width="%.0f" height="%.0f"/></clipPath>
-`,
-			name, colStarts[i], widths[i], totalHeight))
-	}
-
-	return sb.String()
+// buildTitleBar creates the title bar section at y
+func buildTitleBar(title string, y, totalWidth float64, config SVGConfig) string {
+	return newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", config.TitleHeight, 0).
+		attr("fill", config.HeaderBgColor).attr("stroke", config.BorderColor).String() +
+		newNode("text").fixed("x", config.Padding, 0).fixed("y", y+config.TitleHeight/2+TitleVerticalOffset, 0).
+			attr("class", "title-text").content(title).String()
 }
 
-// buildTitleBar creates the title bar section
-func buildTitleBar(totalWidth float64, config SVGConfig) string {
-	return fmt.Sprintf(`<rect x="0" y="0" width="%.0f" height="%.0f" fill="%s" stroke="%s"/>
-<text x="%.0f" y="%.0f" class="title-text">Structure</text>
-`,
-		totalWidth, config.TitleHeight, config.HeaderBgColor, config.BorderColor,
-		config.Padding, config.TitleHeight/2+TitleVerticalOffset)
-}
-
-// buildDataRows renders all data rows
-func buildDataRows(rows []RowData, totalWidth float64, config SVGConfig) string {
-	var sb strings.Builder
-	currentY := config.TitleHeight + config.HeaderHeight
+// writeDataRows writes all data rows starting at startY
+func writeDataRows(w *bufio.Writer, rows []RowData, startY, totalWidth float64, config SVGConfig) {
+	currentY := startY
 
 	for _, row := range rows {
-		sb.WriteString(renderDataRowWrapped(row, config, currentY, totalWidth))
+		writeDataRow(w, row, config, currentY, totalWidth)
 		currentY += row.RowHeight
 	}
-
-	return sb.String()
 }
 
 // buildFooter creates the footer section with edit and attribution links
 func buildFooter(totalWidth, footerY float64, config SVGConfig) string {
 	var sb strings.Builder
-	footerFontSize := 10.0
 	iconSize := 12.0
 	textY := footerY + FooterHeight/2 + 3 // Vertically centered text
 
 	// Footer text components
-	editText := "Edit this resource"
+	editText := config.text("Edit this resource")
 	separator := "|"
-	githubText := "Generated by nuuner/fhir-resource-svg-renderer"
+	githubText := config.text("Generated by nuuner/fhir-resource-svg-renderer")
 
 	// Calculate text widths using the text measurer, scaled for footer font size
 	fontScale := footerFontSize / config.FontSize
@@ -295,40 +644,83 @@ func buildFooter(totalWidth, footerY float64, config SVGConfig) string {
 	editTextX := separatorX - editTextWidth - gap
 
 	// Edit this resource link
-	if config.CompressedResource != "" {
-		editorURL := "/editor?resource=" + config.CompressedResource
-		sb.WriteString(fmt.Sprintf(`<a xlink:href="%s" target="_blank">
-    <text x="%.1f" y="%.1f" font-family="%s" font-size="%.0fpx" fill="%s" style="cursor: pointer;">%s</text>
-</a>
-`,
-			editorURL, editTextX, textY, config.FontFamily, footerFontSize, config.LinkColor, editText))
+	edit := footerText(editText, editTextX, textY, config)
+	if editorURL := config.editorURL(); editorURL != "" {
+		sb.WriteString(footerLink(editorURL, edit.attr("style", "cursor: pointer;")).String())
 	} else {
-		sb.WriteString(fmt.Sprintf(`<text x="%.1f" y="%.1f" font-family="%s" font-size="%.0fpx" fill="%s">%s</text>
-`,
-			editTextX, textY, config.FontFamily, footerFontSize, config.LinkColor, editText))
+		sb.WriteString(edit.String())
 	}
 
 	// Separator
-	sb.WriteString(fmt.Sprintf(`<text x="%.1f" y="%.1f" font-family="%s" font-size="%.0fpx" fill="%s">%s</text>
-`,
-		separatorX, textY, config.FontFamily, footerFontSize, config.LinkColor, separator))
+	sb.WriteString(footerText(separator, separatorX, textY, config).String())
 
 	// GitHub icon and attribution link
 	iconY := textY - iconSize + 2
-	sb.WriteString(fmt.Sprintf(`<a xlink:href="https://github.com/nuuner/fhir-resource-svg-renderer" target="_blank">
-%s
-    <text x="%.1f" y="%.1f" font-family="%s" font-size="%.0fpx" fill="%s" style="cursor: pointer;">%s</text>
-</a>
-`,
-		RenderGitHubIcon(iconX, iconY, iconSize, config.LinkColor),
-		githubTextX, textY, config.FontFamily, footerFontSize, config.LinkColor, githubText))
+	sb.WriteString(footerLink("https://github.com/nuuner/fhir-resource-svg-renderer",
+		rawNode(RenderGitHubIcon(iconX, iconY, iconSize, config.LinkColor)),
+		footerText(githubText, githubTextX, textY, config).attr("style", "cursor: pointer;"),
+	).String())
+
+	return sb.String()
+}
+
+// footerFontSize is the font size of the footer and metadata footer texts
+const footerFontSize = 10.0
+
+// footerText returns a footer text at x, y in the link color
+func footerText(text string, x, y float64, config SVGConfig) *node {
+	return newNode("text").fixed("x", x, 1).fixed("y", y, 1).attr("font-family", config.FontFamily).
+		attr("font-size", px(footerFontSize)).attr("fill", config.LinkColor).content(text)
+}
+
+// footerLink wraps footer content in a link opening in a new tab
+func footerLink(url string, children ...*node) *node {
+	return newNode("a").attr("xlink:href", url).attr("target", "_blank").append(children...)
+}
+
+// buildMetadataFooter creates the footer row with resource and generation details
+func buildMetadataFooter(resource *models.ResourceDefinition, totalWidth, y float64, config SVGConfig) string {
+	var sb strings.Builder
+	textY := y + config.MetadataFooterHeight/2 + 3
+
+	parts := []string{resource.Name}
+	if resource.Version != "" {
+		parts[0] += " v" + resource.Version
+	}
+	if resource.Publisher != "" {
+		parts = append(parts, resource.Publisher)
+	}
+	if resource.Date != "" {
+		parts = append(parts, resource.Date)
+	}
+	if !config.Deterministic {
+		if !config.GeneratedAt.IsZero() {
+			parts = append(parts, config.text("Generated")+" "+config.GeneratedAt.UTC().Format("2006-01-02 15:04 UTC"))
+		}
+		parts = append(parts, config.text("Renderer")+" "+Version)
+	}
+	text := strings.Join(parts, " \u00B7 ")
+
+	sb.WriteString(newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).
+		fixed("height", config.MetadataFooterHeight, 0).attr("fill", config.HeaderBgColor).attr("stroke", config.BorderColor).String())
+	sb.WriteString(newNode("text").fixed("x", config.Padding, 0).fixed("y", textY, 1).attr("font-family", config.FontFamily).
+		attr("font-size", px(footerFontSize)).attr("fill", config.TextColor).content(text).String())
+
+	if sourceURL := config.sourceURL(); sourceURL != "" {
+		sourceText := config.text("View source JSON")
+		sourceWidth := config.textMeasurer.MeasureString(sourceText) * footerFontSize / config.FontSize
+		sb.WriteString(footerLink(sourceURL,
+			footerText(sourceText, totalWidth-config.Padding-sourceWidth, textY, config).attr("style", "cursor: pointer;"),
+		).String())
+	}
 
 	return sb.String()
 }
 
+// renderFallback returns the document Render returns when rendering fails
 func renderFallback() string {
-	return `<?xml version="1.0" encoding="UTF-8"?>
-<svg xmlns="http://www.w3.org/2000/svg" width="400" height="100">
-<text x="10" y="50" font-family="Arial" font-size="14">Error: Could not load font for text measurement</text>
-</svg>`
+	return xmlDeclaration + newNode("svg").attr("xmlns", "http://www.w3.org/2000/svg").attr("width", "400").attr("height", "100").append(
+		newNode("text").attr("x", "10").attr("y", "50").attr("font-family", "Arial").attr("font-size", "14").
+			content("Error: Could not load font for text measurement"),
+	).String()
 }
@@ -0,0 +1,192 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// Terminology table layout
+const (
+	TerminologyMinCodeWidth    = 140.0
+	TerminologyMinDisplayWidth = 120.0
+	TerminologyMaxDisplayWidth = 260.0
+)
+
+// conceptRow is a concept with its wrapped column text
+type conceptRow struct {
+	models.FlatConcept
+	id           string
+	codeLines    []string
+	displayLines []string
+	defLines     []string
+	height       float64
+}
+
+// RenderTerminologyContext renders the concepts of a ValueSet or CodeSystem
+// as a code/display/definition table. Nested concepts are connected with the
+// same tree lines as structure diagrams.
+func RenderTerminologyContext(ctx context.Context, table *models.TerminologyTable, config SVGConfig) (string, error) {
+	tm, err := newTextMeasurer(config)
+	if err != nil {
+		return "", err
+	}
+	defer tm.Close()
+	config.textMeasurer = tm
+
+	flat := table.Flatten()
+	columns := terminologyColumns(flat, tm, config)
+	totalWidth := 0.0
+	for _, col := range columns {
+		totalWidth += col.width
+	}
+
+	rows := make([]conceptRow, len(flat))
+	seen := make(map[string]int)
+	contentHeight := config.TitleHeight + config.HeaderHeight
+	for i, fc := range flat {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		indent := float64(fc.Depth) * config.TreeStyle.IndentPx
+		row := conceptRow{
+			FlatConcept:  fc,
+			id:           conceptAnchorID(fc.Concept, seen),
+			codeLines:    tm.WrapText(fc.Concept.Code, columns[0].width-indent-config.Padding*2-FontRenderingBuffer),
+			displayLines: tm.WrapText(fc.Concept.Display, columns[1].width-config.Padding*2-FontRenderingBuffer),
+			defLines:     tm.WrapText(fc.Concept.Definition, columns[2].width-config.Padding*2-FontRenderingBuffer),
+		}
+		maxLines := max(len(row.codeLines), len(row.displayLines), len(row.defLines), 1)
+		row.height = max(config.MinRowHeight, RowTopMargin+float64(maxLines)*config.LineHeight+RowBottomMargin)
+		rows[i] = row
+		contentHeight += row.height
+	}
+	footerY := contentHeight
+	totalHeight := footerY + FooterHeight + SVGHeightPadding
+
+	title := table.Name
+	if table.Version != "" {
+		title += " v" + table.Version
+	}
+	title += " (" + table.ResourceType + ")"
+
+	var sb strings.Builder
+	sb.WriteString(buildSVGHeader(totalWidth, totalHeight, title, fmt.Sprintf(config.text("%s with %d concepts"), table.ResourceType, len(rows)), false, config))
+	sb.WriteString(buildTitleBar(title, 0, totalWidth, config))
+	sb.WriteString(buildBranding(0, totalWidth-config.Padding, config))
+	sb.WriteString(renderTableHeader(columns, config.TitleHeight, totalWidth, config))
+
+	y := config.TitleHeight + config.HeaderHeight
+	for i, row := range rows {
+		sb.WriteString(renderConceptRow(row, i%2 == 1, columns, y, totalWidth, config))
+		y += row.height
+	}
+
+	sb.WriteString(buildFooter(totalWidth, footerY, config))
+	sb.WriteString(buildRepoLink(footerY, config))
+	sb.WriteString(buildWatermark(totalWidth, totalHeight, config))
+	sb.WriteString(svgEnd)
+	return formatWhitespace(sb.String(), config), nil
+}
+
+// terminologyColumns sizes the code column to the deepest indented code and
+// the display column to the longest display, within limits
+func terminologyColumns(flat []models.FlatConcept, tm *TextMeasurer, config SVGConfig) []tableColumn {
+	codeWidth := TerminologyMinCodeWidth
+	displayWidth := TerminologyMinDisplayWidth
+	for _, fc := range flat {
+		indent := float64(fc.Depth) * config.TreeStyle.IndentPx
+		codeWidth = max(codeWidth, indent+tm.MeasureString(fc.Concept.Code)+config.Padding*2+FontRenderingBuffer)
+		displayWidth = max(displayWidth, tm.MeasureString(fc.Concept.Display)+config.Padding*2+FontRenderingBuffer)
+	}
+
+	return []tableColumn{
+		{title: "Code", width: min(codeWidth, MaxNameColWidth)},
+		{title: "Display", width: min(displayWidth, TerminologyMaxDisplayWidth)},
+		{title: "Definition", width: config.DescriptionColWidth},
+	}
+}
+
+// conceptAnchorID derives a row anchor id from the concept code, or the
+// group label for compose groups, with a numeric suffix for repeats
+func conceptAnchorID(concept models.Concept, seen map[string]int) string {
+	id := concept.Code
+	if concept.Group {
+		id = concept.Display + "-" + concept.Code
+	}
+	if n := seen[id]; n > 0 {
+		seen[id] = n + 1
+		id = fmt.Sprintf("%s-%d", id, n+1)
+	}
+	seen[id]++
+	return id
+}
+
+// renderConceptRow renders one concept. Group rows use the header styling,
+// inactive concepts are greyed out and abstract concepts are not highlighted
+// as selectable codes.
+func renderConceptRow(row conceptRow, isAlt bool, columns []tableColumn, y, totalWidth float64, config SVGConfig) string {
+	concept := row.Concept
+
+	bgColor := config.RowBgColor
+	if concept.Group {
+		bgColor = config.HeaderBgColor
+	} else if isAlt {
+		bgColor = config.AltRowBgColor
+	}
+	label := concept.Display
+	if concept.Code != "" && label != "" {
+		label = concept.Code + ": " + label
+	} else if concept.Code != "" {
+		label = concept.Code
+	}
+	group := newNode("g").attr("id", row.id).attr("class", "row").attr("aria-label", label).append(
+		newNode("rect").attr("x", "0").fixed("y", y, 0).fixed("width", totalWidth, 0).fixed("height", row.height, 0).attr("fill", bgColor),
+		renderRowBorder(y, row.height, totalWidth, config),
+	)
+
+	codeClass, textClass := "link-text", "cell-text"
+	switch {
+	case concept.Group:
+		codeClass = "header-text"
+	case concept.Inactive:
+		codeClass, textClass = "not-used", "not-used"
+	case concept.Abstract:
+		codeClass = "cell-text"
+	}
+
+	baseTextY := y + RowTopMargin + config.FontSize
+	firstLineCenterY := y + RowTopMargin + config.FontSize/2 + IconLineVerticalOffset
+
+	x := 0.0
+	group.append(treeLines(x+config.Padding, y, row.height, firstLineCenterY, row.Depth, row.ParentLasts, row.IsLast, config.TreeStyle)...)
+	codeX := x + config.Padding + float64(row.Depth)*config.TreeStyle.IndentPx
+	group.append(tooltip(concept.Code, concept.System))
+	group.append(renderLines(row.codeLines, codeX, baseTextY, codeClass, config)...)
+
+	x += columns[0].width
+	group.append(renderColumnSeparator(x, y, row.height, config))
+	group.append(renderLines(row.displayLines, x+config.Padding, baseTextY, textClass, config)...)
+
+	x += columns[1].width
+	group.append(renderColumnSeparator(x, y, row.height, config))
+	group.append(renderLines(row.defLines, x+config.Padding, baseTextY, textClass, config)...)
+
+	return group.String()
+}
+
+// renderLines renders wrapped text lines starting at baseTextY, skipping
+// empty lines
+func renderLines(lines []string, x, baseTextY float64, class string, config SVGConfig) []*node {
+	var nodes []*node
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		nodes = append(nodes, newNode("text").fixed("x", x, 0).fixed("y", baseTextY+float64(i)*config.LineHeight, 0).
+			attr("class", class).content(line))
+	}
+	return nodes
+}
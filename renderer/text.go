@@ -2,11 +2,14 @@ package renderer
 
 import (
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/width"
 )
 
 // TextMeasurer handles text measurement and wrapping
@@ -22,7 +25,11 @@ func NewTextMeasurer(fontSize float64) (*TextMeasurer, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newTextMeasurerForFont(f, fontSize)
+}
 
+// newTextMeasurerForFont creates a text measurer for a parsed font
+func newTextMeasurerForFont(f *opentype.Font, fontSize float64) (*TextMeasurer, error) {
 	// Create a font face with the specified size
 	face, err := opentype.NewFace(f, &opentype.FaceOptions{
 		Size:    fontSize,
@@ -39,13 +46,95 @@ func NewTextMeasurer(fontSize float64) (*TextMeasurer, error) {
 	}, nil
 }
 
-// MeasureString returns the width of a string in pixels
+// MeasureString returns the width of a string in pixels. Characters the font
+// has no glyph for are measured as a browser's fallback font would draw
+// them: an emoji, including flags and joined or skin-toned sequences, as one
+// glyph of EmojiWidthFactor ems, a wide East Asian character as one em, and
+// combining marks and other invisible modifiers as nothing.
 func (tm *TextMeasurer) MeasureString(s string) float64 {
-	advance := font.MeasureString(tm.face, s)
+	prev := rune(-1)
+	if isASCII(s) {
+		return fixedToFloat(tm.advance(s, &prev))
+	}
+	var advance fixed.Int26_6
+	for _, g := range graphemes(s) {
+		if tm.isEmoji(g) {
+			advance += fixed.Int26_6(tm.fontSize * EmojiWidthFactor * 64)
+			prev = -1
+			continue
+		}
+		advance += tm.advance(g, &prev)
+	}
 	return fixedToFloat(advance)
 }
 
-// WrapText wraps text to fit within maxWidth, returning multiple lines
+// advance returns the kerned width of the runes of s, continuing after the
+// rune in prev, which it updates
+func (tm *TextMeasurer) advance(s string, prev *rune) fixed.Int26_6 {
+	var advance fixed.Int26_6
+	for _, r := range s {
+		if *prev >= 0 {
+			advance += tm.face.Kern(*prev, r)
+		}
+		a, ok := tm.face.GlyphAdvance(r)
+		if !ok {
+			switch {
+			case isWide(r):
+				a = fixed.Int26_6(tm.fontSize * 64)
+			case isZeroWidth(r):
+				a = 0
+			}
+		}
+		advance += a
+		*prev = r
+	}
+	return advance
+}
+
+// isEmoji reports whether a grapheme is drawn as an emoji the font has no
+// glyph for: a pictograph, a flag, or any sequence using emoji presentation,
+// skin tones or zero-width joiners
+func (tm *TextMeasurer) isEmoji(g string) bool {
+	r, size := utf8.DecodeRuneInString(g)
+	if !isPictographic(r) {
+		return false
+	}
+	if _, ok := tm.face.GlyphAdvance(r); !ok {
+		return true
+	}
+	return size < len(g) && strings.ContainsFunc(g[size:], func(r rune) bool {
+		return r == '\uFE0F' || r == zeroWidthJoiner || (r >= 0x1F3FB && r <= 0x1F3FF)
+	})
+}
+
+// isPictographic reports whether r lies in a block of emoji and pictographic
+// symbols, e.g. ✅, ⭐ or 🔥
+func isPictographic(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) ||
+		(r >= 0x2300 && r <= 0x23FF) ||
+		(r >= 0x2600 && r <= 0x27BF) ||
+		(r >= 0x2B00 && r <= 0x2BFF)
+}
+
+// isZeroWidth reports whether r takes no space of its own: combining marks,
+// format characters such as the zero-width joiner, and variation selectors
+func isZeroWidth(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || isGraphemeExtender(r)
+}
+
+// isWide reports whether r takes a full em in East Asian typography
+func isWide(r rune) bool {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return true
+	}
+	return false
+}
+
+// WrapText wraps text to fit within maxWidth, returning multiple lines.
+// Lines break at spaces and between Chinese or Japanese characters; a word
+// that is wider than maxWidth on its own, such as a long URL, is broken
+// between graphemes instead of overflowing the column.
 func (tm *TextMeasurer) WrapText(text string, maxWidth float64) []string {
 	if text == "" {
 		return []string{""}
@@ -56,27 +145,173 @@ func (tm *TextMeasurer) WrapText(text string, maxWidth float64) []string {
 		return []string{text}
 	}
 
-	var lines []string
-	words := strings.Fields(text)
-	if len(words) == 0 {
+	tokens := wrapTokens(text)
+	if len(tokens) == 0 {
 		return []string{""}
 	}
 
-	currentLine := words[0]
-	for _, word := range words[1:] {
-		testLine := currentLine + " " + word
-		if tm.MeasureString(testLine) <= maxWidth {
-			currentLine = testLine
-		} else {
+	var lines []string
+	currentLine := ""
+	for _, token := range tokens {
+		if currentLine != "" {
+			testLine := currentLine + token.sep + token.text
+			if tm.MeasureString(testLine) <= maxWidth {
+				currentLine = testLine
+				continue
+			}
 			lines = append(lines, currentLine)
-			currentLine = word
 		}
+		pieces := tm.breakWord(token.text, maxWidth)
+		lines = append(lines, pieces[:len(pieces)-1]...)
+		currentLine = pieces[len(pieces)-1]
 	}
 	lines = append(lines, currentLine)
 
 	return lines
 }
 
+// wrapToken is a unit of text that WrapText keeps on one line, with the
+// separator that precedes it within a line
+type wrapToken struct {
+	text string
+	sep  string
+}
+
+// wrapTokens splits text into words, and Chinese or Japanese runs into
+// single characters, which may break without a space. Closing punctuation
+// stays with the character before it.
+func wrapTokens(text string) []wrapToken {
+	words := strings.Fields(text)
+	tokens := make([]wrapToken, 0, len(words))
+	for _, word := range words {
+		if isASCII(word) {
+			tokens = append(tokens, wrapToken{word, " "})
+			continue
+		}
+		sep := " "
+		run := ""
+		for _, g := range graphemes(word) {
+			if !isIdeographic(g) {
+				run += g
+				continue
+			}
+			if run != "" {
+				tokens = append(tokens, wrapToken{run, sep})
+				run, sep = "", ""
+			}
+			if isClosingPunctuation(g) && len(tokens) > 0 && sep == "" {
+				tokens[len(tokens)-1].text += g
+				continue
+			}
+			tokens = append(tokens, wrapToken{g, sep})
+			sep = ""
+		}
+		if run != "" {
+			tokens = append(tokens, wrapToken{run, sep})
+		}
+	}
+	return tokens
+}
+
+// isASCII reports whether s is plain ASCII, which needs no grapheme handling
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdeographic reports whether a grapheme may be broken before and after
+// without a space, as in Chinese and Japanese text
+func isIdeographic(g string) bool {
+	r, _ := utf8.DecodeRuneInString(g)
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK symbols and punctuation
+		(r >= 0xFF01 && r <= 0xFF60) // Fullwidth forms
+}
+
+// isClosingPunctuation reports whether a grapheme must not start a line,
+// e.g. "。" or "）"
+func isClosingPunctuation(g string) bool {
+	r, _ := utf8.DecodeRuneInString(g)
+	return strings.ContainsRune("、。，．：；！？）」』】〕〉》ー々", r)
+}
+
+// breakWord splits a word wider than maxWidth into pieces that fit. It
+// prefers breaking after the punctuation of type names and URLs, then at
+// camelCase boundaries, and only then between graphemes (see breakLevels).
+// Columns too narrow for a single character keep the word whole.
+func (tm *TextMeasurer) breakWord(word string, maxWidth float64) []string {
+	if maxWidth < tm.fontSize || tm.MeasureString(word) <= maxWidth {
+		return []string{word}
+	}
+	return tm.breakSegments(word, maxWidth, breakLevels)
+}
+
+// breakLevels are the ways breakWord splits a word, most preferred first.
+// Segments of one level that are too wide on their own are split by the
+// next. No hyphen is added, since type names and URLs must stay copyable.
+var breakLevels = []func(string) []string{splitAfterPunctuation, splitCamelCase, graphemes}
+
+// breakSegments packs the segments of word into pieces no wider than
+// maxWidth, splitting a segment that is too wide with the next level
+func (tm *TextMeasurer) breakSegments(word string, maxWidth float64, levels []func(string) []string) []string {
+	var pieces []string
+	start, end := 0, 0 // The current piece is word[start:end]
+	for _, segment := range levels[0](word) {
+		next := end + len(segment)
+		if end > start && tm.MeasureString(word[start:next]) > maxWidth {
+			pieces = append(pieces, word[start:end])
+			start = end
+		}
+		if len(levels) == 1 || tm.MeasureString(word[start:next]) <= maxWidth {
+			end = next
+			continue
+		}
+		sub := tm.breakSegments(segment, maxWidth, levels[1:])
+		pieces = append(pieces, sub[:len(sub)-1]...)
+		start, end = next-len(sub[len(sub)-1]), next
+	}
+	return append(pieces, word[start:end])
+}
+
+// softBreakAfter lists the characters a word may break after, as in
+// "Reference(Patient)", "Patient|Group" and canonical URLs
+const softBreakAfter = "/(|.#?&=-_"
+
+// splitAfterPunctuation splits a word after the characters in
+// softBreakAfter
+func splitAfterPunctuation(word string) []string {
+	return splitBefore(word, func(prev, _ rune) bool { return strings.ContainsRune(softBreakAfter, prev) })
+}
+
+// splitCamelCase splits a word between a lower case and an upper case
+// letter, e.g. "Medication|Administration"
+func splitCamelCase(word string) []string {
+	return splitBefore(word, func(prev, r rune) bool { return unicode.IsLower(prev) && unicode.IsUpper(r) })
+}
+
+// splitBefore splits a word before each grapheme whose first rune r
+// satisfies split(prev, r), prev being the first rune of the grapheme before.
+// The segments are slices of word, in order, which breakSegments relies on.
+func splitBefore(word string, split func(prev, r rune) bool) []string {
+	var segments []string
+	start, end := 0, 0
+	prev := rune(-1)
+	for _, g := range graphemes(word) {
+		r, _ := utf8.DecodeRuneInString(g)
+		if end > start && split(prev, r) {
+			segments = append(segments, word[start:end])
+			start = end
+		}
+		end += len(g)
+		prev = r
+	}
+	return append(segments, word[start:])
+}
+
 // TruncateText truncates text to fit within maxWidth, adding ellipsis if needed
 func (tm *TextMeasurer) TruncateText(text string, maxWidth float64) string {
 	if text == "" {
@@ -95,13 +330,14 @@ func (tm *TextMeasurer) TruncateText(text string, maxWidth float64) string {
 		return ellipsis
 	}
 
-	// Binary search for the right length
-	runes := []rune(text)
-	low, high := 0, len(runes)
+	// Binary search for the right number of graphemes, so an emoji or an
+	// accented letter is never cut in half
+	clusters := graphemes(text)
+	low, high := 0, len(clusters)
 
 	for low < high {
 		mid := (low + high + 1) / 2
-		if tm.MeasureString(string(runes[:mid])) <= availableWidth {
+		if tm.MeasureString(strings.Join(clusters[:mid], "")) <= availableWidth {
 			low = mid
 		} else {
 			high = mid - 1
@@ -112,7 +348,7 @@ func (tm *TextMeasurer) TruncateText(text string, maxWidth float64) string {
 		return ellipsis
 	}
 
-	return string(runes[:low]) + ellipsis
+	return strings.Join(clusters[:low], "") + ellipsis
 }
 
 // LineHeight returns the recommended line height for the font
@@ -0,0 +1,156 @@
+package renderer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// diagramClass is a class in a text class diagram: an element with children
+type diagramClass struct {
+	ID         string
+	Label      string
+	Stereotype string
+	Attributes []models.Element
+}
+
+// diagramRelation is a composition between a parent class and a child class
+type diagramRelation struct {
+	From        string
+	To          string
+	Cardinality string
+	Label       string
+}
+
+// classDiagram is the intermediate model shared by the text serializers
+type classDiagram struct {
+	Classes   []*diagramClass
+	Relations []diagramRelation
+}
+
+// identifierPattern matches characters not allowed in diagram identifiers
+var identifierPattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// buildClassDiagram converts the flattened element tree into classes and
+// compositions: the root and every element with children become classes,
+// leaf elements become attributes of their parent class
+func buildClassDiagram(resource *models.ResourceDefinition) classDiagram {
+	flat := resource.Flatten()
+	var diagram classDiagram
+	usedIDs := make(map[string]int)
+
+	newClass := func(id, label, stereotype string) *diagramClass {
+		id = identifierPattern.ReplaceAllString(id, "_")
+		if n := usedIDs[id]; n > 0 {
+			usedIDs[id] = n + 1
+			id = fmt.Sprintf("%s_%d", id, n+1)
+		} else {
+			usedIDs[id] = 1
+		}
+		class := &diagramClass{ID: id, Label: label, Stereotype: stereotype}
+		diagram.Classes = append(diagram.Classes, class)
+		return class
+	}
+
+	// stack[d] is the class that owns elements at depth d+1
+	var stack []*diagramClass
+	for i, fe := range flat {
+		if fe.Depth == 0 {
+			stack = []*diagramClass{newClass(fe.Element.Name, fe.Element.Name, fe.Element.Type)}
+			continue
+		}
+		if fe.Depth > len(stack) {
+			continue
+		}
+		stack = stack[:fe.Depth]
+		parent := stack[fe.Depth-1]
+
+		hasChildren := i+1 < len(flat) && flat[i+1].Depth > fe.Depth
+		if !hasChildren {
+			parent.Attributes = append(parent.Attributes, fe.Element)
+			continue
+		}
+
+		class := newClass(parent.ID+"_"+fe.Element.Name, fe.Element.Name, fe.Element.Type)
+		diagram.Relations = append(diagram.Relations, diagramRelation{
+			From:        parent.ID,
+			To:          class.ID,
+			Cardinality: fe.Element.Cardinality,
+			Label:       fe.Element.Name,
+		})
+		stack = append(stack, class)
+	}
+
+	return diagram
+}
+
+// attributeSuffix formats the cardinality suffix of an attribute
+func attributeSuffix(elem models.Element) string {
+	if elem.Cardinality == "" {
+		return ""
+	}
+	return " [" + elem.Cardinality + "]"
+}
+
+// mermaidTypeReplacer rewrites characters that Mermaid interprets as
+// method or generic syntax
+var mermaidTypeReplacer = strings.NewReplacer("(", "~", ")", "~", "{", "", "}", "", `"`, "'")
+
+// RenderMermaid serializes the element tree as a Mermaid class diagram
+func RenderMermaid(resource *models.ResourceDefinition) string {
+	diagram := buildClassDiagram(resource)
+	var sb strings.Builder
+
+	sb.WriteString("classDiagram\n")
+	for _, class := range diagram.Classes {
+		sb.WriteString(fmt.Sprintf("    class %s[\"%s\"] {\n", class.ID, mermaidTypeReplacer.Replace(class.Label)))
+		if class.Stereotype != "" {
+			sb.WriteString(fmt.Sprintf("        <<%s>>\n", mermaidTypeReplacer.Replace(class.Stereotype)))
+		}
+		for _, attr := range class.Attributes {
+			sb.WriteString(fmt.Sprintf("        +%s %s%s\n",
+				mermaidTypeReplacer.Replace(attr.DisplayType()), attr.Name, attributeSuffix(attr)))
+		}
+		sb.WriteString("    }\n")
+	}
+	for _, rel := range diagram.Relations {
+		cardinality := ""
+		if rel.Cardinality != "" {
+			cardinality = fmt.Sprintf(" \"%s\"", rel.Cardinality)
+		}
+		sb.WriteString(fmt.Sprintf("    %s *--%s %s : %s\n", rel.From, cardinality, rel.To, rel.Label))
+	}
+
+	return sb.String()
+}
+
+// RenderPlantUML serializes the element tree as a PlantUML class diagram
+func RenderPlantUML(resource *models.ResourceDefinition) string {
+	diagram := buildClassDiagram(resource)
+	var sb strings.Builder
+
+	sb.WriteString("@startuml\n")
+	for _, class := range diagram.Classes {
+		stereotype := ""
+		if class.Stereotype != "" {
+			stereotype = fmt.Sprintf(" <<%s>>", class.Stereotype)
+		}
+		sb.WriteString(fmt.Sprintf("class \"%s\" as %s%s {\n", class.Label, class.ID, stereotype))
+		for _, attr := range class.Attributes {
+			sb.WriteString(fmt.Sprintf("  %s : %s%s\n", attr.Name, attr.DisplayType(), attributeSuffix(attr)))
+		}
+		sb.WriteString("}\n")
+	}
+	for _, rel := range diagram.Relations {
+		cardinality := ""
+		if rel.Cardinality != "" {
+			cardinality = fmt.Sprintf(" \"%s\"", rel.Cardinality)
+		}
+		sb.WriteString(fmt.Sprintf("%s *--%s %s : %s\n", rel.From, cardinality, rel.To, rel.Label))
+	}
+	sb.WriteString("@enduml\n")
+
+	return sb.String()
+}
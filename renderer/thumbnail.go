@@ -0,0 +1,55 @@
+package renderer
+
+import (
+	"context"
+	"io"
+
+	"fhir_renderer/models"
+)
+
+// Thumbnail widths in pixels
+const (
+	DefaultThumbnailWidth = 320
+	MinThumbnailWidth     = 64
+	MaxThumbnailWidth     = 1200
+)
+
+// thumbnailColumns are the columns a thumbnail keeps
+var thumbnailColumns = []string{ColumnName, ColumnCardinality, ColumnType}
+
+// RenderThumbnailToContext writes a simplified SVG of a resource definition
+// for gallery and list views: the root and its first-level elements with
+// their cardinality and type, without links, legend or embedded source,
+// drawn width pixels wide. The width is clamped to MinThumbnailWidth and
+// MaxThumbnailWidth.
+func RenderThumbnailToContext(ctx context.Context, w io.Writer, resource *models.ResourceDefinition, width int, config SVGConfig) error {
+	config.Columns = thumbnailColumns
+	config.ExtraColumns = nil
+	config.Responsive = false
+	config.MaxTotalWidth = 0
+	config.ShowLegend = false
+	config.ShowMetadataFooter = false
+	config.RowNumbers = false
+	config.GroupHeaders = false
+	config.Coverage = false
+	config.HoverRows = false
+	config.MaxRowsPerPage = 0
+	config.TypeLinkBase, config.ElementLinkBase, config.FHIRLinks = "", "", false
+	config.CompressedResource, config.ShareID, config.EmbedSource = "", "", false
+	config.thumbnailWidth = float64(min(max(width, MinThumbnailWidth), MaxThumbnailWidth))
+	return RenderToContext(ctx, w, thumbnailResource(resource), config)
+}
+
+// thumbnailResource returns a copy of the resource reduced to its
+// first-level elements and extensions
+func thumbnailResource(resource *models.ResourceDefinition) *models.ResourceDefinition {
+	thumb := *resource
+	thumb.Elements = make([]models.Element, len(resource.Elements))
+	for i, elem := range resource.Elements {
+		elem.Elements, elem.Extensions = nil, nil
+		thumb.Elements[i] = elem
+	}
+	thumb.Annotations = nil
+	thumb.Example = nil
+	return &thumb
+}
@@ -0,0 +1,73 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"fhir_renderer/models"
+)
+
+// MaxTitleTemplateLength limits the size of title bar templates
+const MaxTitleTemplateLength = 512
+
+// defaultTitleTemplate is the TitleTemplate of DefaultConfig; see
+// SetDefaultTitleTemplate
+var defaultTitleTemplate string
+
+// SetDefaultTitleTemplate sets the title bar template DefaultConfig uses. The
+// template must pass ParseTitleTemplate.
+func SetDefaultTitleTemplate(text string) {
+	defaultTitleTemplate = text
+}
+
+// ParseTitleTemplate parses a title bar template. Templates use Go
+// text/template syntax and are executed with the ResourceDefinition, e.g.
+// "Structure: {{.Name}} ({{.Type}}){{with .Version}} v{{.}}{{end}}". Field
+// errors are caught by a trial run against an empty definition.
+func ParseTitleTemplate(text string) (*template.Template, error) {
+	if len(text) > MaxTitleTemplateLength {
+		return nil, fmt.Errorf("title template longer than %d bytes", MaxTitleTemplateLength)
+	}
+	tmpl, err := template.New("title").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, &models.ResourceDefinition{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// structureTitleBar returns the title bar text of a structure diagram: the
+// expanded TitleTemplate, or the translated "Structure" when no template is
+// set or it fails
+func structureTitleBar(resource *models.ResourceDefinition, config SVGConfig) string {
+	if title, err := expandTitle(resource, config.TitleTemplate); err == nil {
+		return title
+	}
+	return config.text("Structure")
+}
+
+// expandTitle executes a title template with resource. Output is limited to
+// one line of MaxTitleTemplateLength bytes.
+func expandTitle(resource *models.ResourceDefinition, text string) (string, error) {
+	if text == "" {
+		return "", errors.New("no title template")
+	}
+	tmpl, err := ParseTitleTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, resource); err != nil {
+		return "", err
+	}
+	title := strings.Join(strings.Fields(sb.String()), " ")
+	if len(title) > MaxTitleTemplateLength {
+		title = strings.ToValidUTF8(title[:MaxTitleTemplateLength], "")
+	}
+	return title, nil
+}
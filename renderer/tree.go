@@ -1,7 +1,6 @@
 package renderer
 
 import (
-	"fmt"
 	"strings"
 )
 
@@ -27,19 +26,31 @@ func DefaultTreeStyle() TreeLineStyle {
 // depth is the current nesting depth (0 = root, 1 = first level children, etc.)
 // firstLineY is the Y position of the first line of text (for horizontal connector alignment)
 func RenderTreeLines(x, y, rowHeight, firstLineY float64, depth int, parentLasts []bool, isLast bool, style TreeLineStyle) string {
+	var sb strings.Builder
+	for _, line := range treeLines(x, y, rowHeight, firstLineY, depth, parentLasts, isLast, style) {
+		line.writeTo(&sb)
+	}
+	return sb.String()
+}
+
+// treeLines returns the lines RenderTreeLines draws, for rows that write
+// them along with their other nodes
+func treeLines(x, y, rowHeight, firstLineY float64, depth int, parentLasts []bool, isLast bool, style TreeLineStyle) []*node {
 	if depth == 0 {
-		return "" // No tree lines for root
+		return nil // No tree lines for root
 	}
 
-	var sb strings.Builder
+	lines := make([]*node, 0, depth+1)
+	line := func(x1, y1, x2, y2 float64) {
+		lines = append(lines, newNode("line").attr("x1", num(x1)).attr("y1", num(y1)).attr("x2", num(x2)).attr("y2", num(y2)).
+			attr("stroke", style.Color).attr("stroke-width", num(style.Width)))
+	}
 
 	// Draw vertical continuation lines for ancestors that weren't last
 	for i := 0; i < depth-1; i++ {
 		if i < len(parentLasts) && !parentLasts[i] {
 			lineX := x + float64(i)*style.IndentPx + style.IndentPx/2
-			sb.WriteString(fmt.Sprintf(
-				`<line x1="%f" y1="%f" x2="%f" y2="%f" stroke="%s" stroke-width="%f"/>`,
-				lineX, y, lineX, y+rowHeight, style.Color, style.Width))
+			line(lineX, y, lineX, y+rowHeight)
 		}
 	}
 
@@ -49,22 +60,16 @@ func RenderTreeLines(x, y, rowHeight, firstLineY float64, depth int, parentLasts
 	if isLast {
 		// L-shaped connector (└──)
 		// Vertical part (from top to first line position)
-		sb.WriteString(fmt.Sprintf(
-			`<line x1="%f" y1="%f" x2="%f" y2="%f" stroke="%s" stroke-width="%f"/>`,
-			connectorX, y, connectorX, firstLineY, style.Color, style.Width))
+		line(connectorX, y, connectorX, firstLineY)
 	} else {
 		// T-shaped connector (├──)
 		// Vertical part (full height to continue for siblings)
-		sb.WriteString(fmt.Sprintf(
-			`<line x1="%f" y1="%f" x2="%f" y2="%f" stroke="%s" stroke-width="%f"/>`,
-			connectorX, y, connectorX, y+rowHeight, style.Color, style.Width))
+		line(connectorX, y, connectorX, y+rowHeight)
 	}
 
 	// Horizontal part (from connector to icon) - aligned with first line
 	horizontalEndX := x + float64(depth)*style.IndentPx - TreeHorizontalGap
-	sb.WriteString(fmt.Sprintf(
-		`<line x1="%f" y1="%f" x2="%f" y2="%f" stroke="%s" stroke-width="%f"/>`,
-		connectorX, firstLineY, horizontalEndX, firstLineY, style.Color, style.Width))
+	line(connectorX, firstLineY, horizontalEndX, firstLineY)
 
-	return sb.String()
+	return lines
 }
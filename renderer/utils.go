@@ -1,6 +1,11 @@
 package renderer
 
-import "strings"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
 // xmlReplacer performs efficient single-pass XML escaping
 var xmlReplacer = strings.NewReplacer(
@@ -14,3 +19,27 @@ var xmlReplacer = strings.NewReplacer(
 func escapeXML(s string) string {
 	return xmlReplacer.Replace(s)
 }
+
+// num formats a coordinate with at most two decimals and no trailing zeros
+// (12 instead of 12.000000), so float noise never reaches the output
+func num(v float64) string {
+	s := strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// translate returns the transform moving a group to x, y in whole pixels
+func translate(x, y float64) string {
+	return fmt.Sprintf("translate(%.0f, %.0f)", x, y)
+}
+
+// scaleToFit wraps content that is width wide in a group shrinking it to
+// maxWidth, or returns it as is when it fits or maxWidth is not set
+func scaleToFit(content []*node, width, maxWidth float64) []*node {
+	if width <= maxWidth || maxWidth <= 0 {
+		return content
+	}
+	return []*node{newNode("g").attr("transform", "scale("+num(maxWidth/width)+")").append(content...)}
+}
@@ -0,0 +1,73 @@
+package renderer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Watermark styling
+const (
+	// MaxWatermarkLength limits the watermark text, in characters
+	MaxWatermarkLength = 40
+
+	// WatermarkColor and WatermarkOpacity keep the table readable below it
+	WatermarkColor   = "#CC0000"
+	WatermarkOpacity = 0.15
+
+	// WatermarkDiagonalShare is the part of the diagonal the text spans
+	WatermarkDiagonalShare = 0.6
+)
+
+// defaultWatermark is the Watermark of DefaultConfig; see SetDefaultWatermark
+var defaultWatermark string
+
+// SetDefaultWatermark sets the watermark DefaultConfig uses, e.g. "DRAFT" on
+// a staging server; "" for none
+func SetDefaultWatermark(text string) {
+	defaultWatermark = NormalizeWatermark(text)
+}
+
+// NormalizeWatermark collapses whitespace and cuts the text to
+// MaxWatermarkLength characters
+func NormalizeWatermark(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > MaxWatermarkLength {
+		text = strings.TrimSpace(string(runes[:MaxWatermarkLength]))
+	}
+	return text
+}
+
+// buildWatermark draws config.Watermark diagonally across a width x height
+// diagram, over the table; "" when no watermark is set
+func buildWatermark(width, height float64, config SVGConfig) string {
+	if config.Watermark == "" {
+		return ""
+	}
+
+	// Size the text so it spans part of the diagonal, but no taller than
+	// half the diagram
+	diagonal := math.Hypot(width, height)
+	ems := config.textMeasurer.MeasureString(config.Watermark) / config.FontSize
+	fontSize := math.Min(diagonal*WatermarkDiagonalShare/math.Max(ems, 1), height/2)
+	angle := -math.Atan2(height, width) * 180 / math.Pi
+	cx, cy := width/2, height/2
+
+	return newNode("text").attr("class", "watermark").fixed("x", cx, 1).fixed("y", cy, 1).
+		attr("transform", fmt.Sprintf("rotate(%.1f %.1f %.1f)", angle, cx, cy)).
+		attr("text-anchor", "middle").attr("dominant-baseline", "middle").
+		attr("font-family", config.FontFamily).attr("font-size", px(fontSize)).attr("font-weight", "bold").
+		attr("fill", WatermarkColor).fixed("fill-opacity", WatermarkOpacity, 2).
+		attr("pointer-events", "none").attr("aria-hidden", "true").
+		content(config.Watermark).String()
+}
+
+// htmlWatermark overlays config.Watermark on the HTML table; "" when no
+// watermark is set
+func htmlWatermark(config SVGConfig) string {
+	if config.Watermark == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<div class="watermark" aria-hidden="true" style="position: absolute; inset: 0; display: flex; align-items: center; justify-content: center; pointer-events: none; overflow: hidden;"><span style="transform: rotate(-30deg); font-size: 96px; font-weight: bold; color: %s; opacity: %.2f; white-space: nowrap;">%s</span></div>
+`, WatermarkColor, WatermarkOpacity, escapeXML(config.Watermark))
+}
@@ -0,0 +1,269 @@
+package renderer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Whitespace modes for SVGConfig.Whitespace
+const (
+	// WhitespaceDefault keeps the line breaks the builders write
+	WhitespaceDefault = ""
+
+	// WhitespacePretty puts every element on its own line, indented by
+	// nesting depth, for reading and diffing the markup
+	WhitespacePretty = "pretty"
+
+	// WhitespaceMinified drops the whitespace between elements for smaller
+	// payloads
+	WhitespaceMinified = "minified"
+)
+
+// prettyIndent indents one nesting level in pretty output
+const prettyIndent = "  "
+
+// whitespaceWriterBufferSize is the output size at which the whitespace
+// writer passes its output on
+const whitespaceWriterBufferSize = 32 * 1024
+
+// preservedElements hold text whose whitespace is significant; markup inside
+// them is copied unchanged
+var preservedElements = map[string]bool{
+	"text":     true,
+	"title":    true,
+	"desc":     true,
+	"style":    true,
+	"metadata": true,
+}
+
+// defaultWhitespace is the Whitespace of DefaultConfig; see
+// SetDefaultWhitespace
+var defaultWhitespace string
+
+// SetDefaultWhitespace sets the whitespace mode DefaultConfig uses, e.g.
+// WhitespaceMinified in production. The mode must be valid for
+// ParseWhitespace.
+func SetDefaultWhitespace(mode string) {
+	defaultWhitespace = mode
+}
+
+// ParseWhitespace validates a whitespace mode: "pretty", "minified" or ""
+// for the default
+func ParseWhitespace(mode string) (string, error) {
+	switch mode {
+	case WhitespaceDefault, WhitespacePretty, WhitespaceMinified:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown whitespace mode %q (expected %s or %s)", mode, WhitespacePretty, WhitespaceMinified)
+}
+
+// whitespaceWriter re-formats the SVG markup written to it: whitespace
+// between elements is dropped or replaced with indentation, and whitespace
+// inside tags collapses to single spaces. Text content and the markup inside
+// preserved elements pass unchanged. Flush must be called after the last
+// write.
+type whitespaceWriter struct {
+	w      io.Writer
+	pretty bool
+	out    []byte
+
+	tag      []byte // Tag being read, from '<' on; nil between tags
+	quote    byte   // Quote character when inside an attribute value
+	text     []byte // Text since the last tag
+	depth    int    // Open elements
+	preserve int    // Open preserved elements
+	afterTag bool   // A tag was written and no text since
+	started  bool
+	err      error
+}
+
+// newWhitespaceWriter returns a writer formatting markup for w in the mode,
+// WhitespacePretty or WhitespaceMinified
+func newWhitespaceWriter(w io.Writer, mode string) *whitespaceWriter {
+	return &whitespaceWriter{w: w, pretty: mode == WhitespacePretty}
+}
+
+func (ww *whitespaceWriter) Write(data []byte) (int, error) {
+	if ww.err != nil {
+		return 0, ww.err
+	}
+	for _, b := range data {
+		if ww.tag == nil {
+			if b == '<' {
+				ww.tag = append(make([]byte, 0, 64), b)
+			} else {
+				ww.text = append(ww.text, b)
+			}
+			continue
+		}
+		ww.tag = append(ww.tag, b)
+		if ww.tagComplete(b) {
+			ww.writeTag()
+		}
+	}
+	if len(ww.out) >= whitespaceWriterBufferSize {
+		ww.flushOutput()
+	}
+	return len(data), ww.err
+}
+
+// tagComplete reports whether b ends the tag being read
+func (ww *whitespaceWriter) tagComplete(b byte) bool {
+	switch {
+	case bytes.HasPrefix(ww.tag, []byte("<!--")):
+		return len(ww.tag) >= 7 && bytes.HasSuffix(ww.tag, []byte("-->"))
+	case bytes.HasPrefix(ww.tag, []byte("<![CDATA[")):
+		return bytes.HasSuffix(ww.tag, []byte("]]>"))
+	case ww.quote != 0:
+		if b == ww.quote {
+			ww.quote = 0
+		}
+		return false
+	case b == '"' || b == '\'':
+		ww.quote = b
+		return false
+	}
+	return b == '>'
+}
+
+// writeTag writes the pending text and the completed tag
+func (ww *whitespaceWriter) writeTag() {
+	tag := ww.tag
+	ww.tag = nil
+	closing := bytes.HasPrefix(tag, []byte("</"))
+	special := bytes.HasPrefix(tag, []byte("<!")) || bytes.HasPrefix(tag, []byte("<?"))
+	selfClosing := !special && bytes.HasSuffix(tag, []byte("/>"))
+
+	// Text inside preserved elements and any non-whitespace text is kept
+	// as written; whitespace between elements is dropped
+	if ww.preserve > 0 || len(bytes.TrimSpace(ww.text)) > 0 {
+		ww.out = append(ww.out, ww.text...)
+		ww.afterTag = false
+	}
+	ww.text = ww.text[:0]
+
+	if ww.preserve > 0 {
+		ww.out = append(ww.out, tag...)
+	} else {
+		depth := ww.depth
+		if closing {
+			depth--
+		}
+		if ww.pretty && ww.started && ww.afterTag {
+			ww.out = append(ww.out, '\n')
+			ww.out = append(ww.out, strings.Repeat(prettyIndent, max(depth, 0))...)
+		}
+		ww.out = appendCollapsed(ww.out, tag)
+	}
+	ww.started = true
+	ww.afterTag = true
+
+	name := tagName(tag)
+	switch {
+	case special || selfClosing:
+	case closing:
+		ww.depth--
+		if ww.preserve > 0 {
+			ww.preserve--
+		}
+	default:
+		ww.depth++
+		if preservedElements[name] || ww.preserve > 0 {
+			ww.preserve++
+		}
+	}
+}
+
+// appendCollapsed appends tag with each run of whitespace outside attribute
+// values collapsed to one space
+func appendCollapsed(out, tag []byte) []byte {
+	var quote byte
+	space := false
+	for _, b := range tag {
+		switch {
+		case quote != 0:
+			if b == quote {
+				quote = 0
+			}
+		case b == '"' || b == '\'':
+			quote = b
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			space = true
+			continue
+		}
+		if space {
+			if b != '>' && !(b == '/' && quote == 0) {
+				out = append(out, ' ')
+			}
+			space = false
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// tagName returns the element name of a start or end tag
+func tagName(tag []byte) string {
+	name := bytes.TrimPrefix(bytes.TrimPrefix(tag, []byte("<")), []byte("/"))
+	if i := bytes.IndexAny(name, " \t\r\n/>"); i >= 0 {
+		name = name[:i]
+	}
+	return string(name)
+}
+
+// flushOutput passes the formatted output on
+func (ww *whitespaceWriter) flushOutput() {
+	if ww.err == nil && len(ww.out) > 0 {
+		_, ww.err = ww.w.Write(ww.out)
+	}
+	ww.out = ww.out[:0]
+}
+
+// Flush writes the remaining output. Whitespace after the last tag is
+// dropped.
+func (ww *whitespaceWriter) Flush() error {
+	if ww.tag != nil {
+		// Unterminated markup is passed on unchanged
+		ww.out = append(ww.out, ww.text...)
+		ww.out = append(ww.out, ww.tag...)
+		ww.tag = nil
+	} else if len(bytes.TrimSpace(ww.text)) > 0 {
+		ww.out = append(ww.out, ww.text...)
+	}
+	ww.text = ww.text[:0]
+	ww.flushOutput()
+	return ww.err
+}
+
+// newSVGWriter returns a buffered writer for SVG markup in config's
+// whitespace mode. The returned flush function must be called once the
+// document is complete.
+func newSVGWriter(w io.Writer, config SVGConfig) (*bufio.Writer, func() error) {
+	if config.Whitespace == WhitespaceDefault {
+		bw := bufio.NewWriter(w)
+		return bw, bw.Flush
+	}
+	ww := newWhitespaceWriter(w, config.Whitespace)
+	bw := bufio.NewWriter(ww)
+	return bw, func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		return ww.Flush()
+	}
+}
+
+// formatWhitespace applies config's whitespace mode to a complete SVG document
+func formatWhitespace(svg string, config SVGConfig) string {
+	if config.Whitespace == WhitespaceDefault {
+		return svg
+	}
+	var sb strings.Builder
+	ww := newWhitespaceWriter(&sb, config.Whitespace)
+	ww.Write([]byte(svg))
+	ww.Flush()
+	return sb.String()
+}
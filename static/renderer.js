@@ -0,0 +1,61 @@
+// Browser wrapper for the WebAssembly renderer built with `make wasm`.
+//
+//   await FHIRRenderer.load();              // fetches wasm_exec.js and renderer.wasm
+//   const result = FHIRRenderer.render(json, { lang: 'de', legend: true });
+//   if (result.svg) { ... } else { console.error(result.error, result.details); }
+//
+// Options use the query parameter names of POST /render (lang, highlightMS,
+// legend, rowNumbers, groupHeaders, rollupUsage, maxRowsPerPage, pretty, deterministic, strict, title, watermark, view, maxDepth, width,
+// columns, include, excludeUsage, onlyFlags); list options are arrays. Results have the shape
+// of the /ws live preview messages: { svg } or { error, details, diagnostics }.
+(function (global) {
+    let loading = null;
+
+    function loadScript(src) {
+        return new Promise((resolve, reject) => {
+            const script = document.createElement('script');
+            script.src = src;
+            script.onload = resolve;
+            script.onerror = () => reject(new Error('Failed to load ' + src));
+            document.head.appendChild(script);
+        });
+    }
+
+    async function instantiate(base) {
+        if (!global.Go) {
+            await loadScript(base + 'wasm_exec.js');
+        }
+        const go = new global.Go();
+        const response = await fetch(base + 'renderer.wasm');
+        if (!response.ok) {
+            throw new Error('renderer.wasm: ' + response.status);
+        }
+        // Servers without the application/wasm type cannot stream
+        const { instance } = response.headers.get('Content-Type') === 'application/wasm'
+            ? await WebAssembly.instantiateStreaming(response, go.importObject)
+            : await WebAssembly.instantiate(await response.arrayBuffer(), go.importObject);
+        go.run(instance);
+        return global.fhirRendererVersion;
+    }
+
+    // load resolves to the renderer version once the module is running. A
+    // failed load can be retried.
+    function load(base = '/static/') {
+        if (!loading) {
+            loading = instantiate(base).catch((e) => {
+                loading = null;
+                throw e;
+            });
+        }
+        return loading;
+    }
+
+    function render(json, options = {}) {
+        if (!global.fhirRendererRender) {
+            throw new Error('FHIRRenderer.load() has not finished');
+        }
+        return JSON.parse(global.fhirRendererRender(json, JSON.stringify(options)));
+    }
+
+    global.FHIRRenderer = { load, render };
+})(window);
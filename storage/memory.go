@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps shared definitions and snippets in process memory. Entries are lost
+// on restart, so it is meant for development and single-instance demos.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	entries  map[string][]byte
+	snippets map[string]Snippet
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:  make(map[string][]byte),
+		snippets: make(map[string]Snippet),
+	}
+}
+
+// Put saves data and returns its content id
+func (s *MemoryStore) Put(ctx context.Context, data []byte) (string, error) {
+	id := ContentID(data)
+	s.mu.Lock()
+	s.entries[id] = append([]byte(nil), data...)
+	s.mu.Unlock()
+	return id, nil
+}
+
+// Get returns the data stored under id
+func (s *MemoryStore) Get(ctx context.Context, id string) ([]byte, error) {
+	s.mu.RLock()
+	data, ok := s.entries[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// Close is a no-op for the in-memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// CreateSnippet saves a new snippet
+func (s *MemoryStore) CreateSnippet(ctx context.Context, snippet Snippet) (Snippet, error) {
+	now := time.Now().UTC()
+	snippet.ID = NewID()
+	snippet.Tags = normalizeTags(snippet.Tags)
+	snippet.CreatedAt = now
+	snippet.UpdatedAt = now
+
+	s.mu.Lock()
+	s.snippets[snippet.ID] = snippet
+	s.mu.Unlock()
+	return snippet, nil
+}
+
+// GetSnippet returns the snippet with id
+func (s *MemoryStore) GetSnippet(ctx context.Context, id string) (Snippet, error) {
+	s.mu.RLock()
+	snippet, ok := s.snippets[id]
+	s.mu.RUnlock()
+	if !ok {
+		return Snippet{}, ErrNotFound
+	}
+	return snippet, nil
+}
+
+// ListSnippets returns the snippets carrying tag (all when tag is empty)
+func (s *MemoryStore) ListSnippets(ctx context.Context, tag string) ([]Snippet, error) {
+	s.mu.RLock()
+	list := make([]Snippet, 0, len(s.snippets))
+	for _, snippet := range s.snippets {
+		if tag != "" && !slices.Contains(snippet.Tags, tag) {
+			continue
+		}
+		snippet.Resource = nil
+		list = append(list, snippet)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// UpdateSnippet replaces an existing snippet's name, tags and resource
+func (s *MemoryStore) UpdateSnippet(ctx context.Context, snippet Snippet) (Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.snippets[snippet.ID]
+	if !ok {
+		return Snippet{}, ErrNotFound
+	}
+	snippet.Tags = normalizeTags(snippet.Tags)
+	snippet.CreatedAt = existing.CreatedAt
+	snippet.UpdatedAt = time.Now().UTC()
+	s.snippets[snippet.ID] = snippet
+	return snippet, nil
+}
+
+// DeleteSnippet removes the snippet with id
+func (s *MemoryStore) DeleteSnippet(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.snippets[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.snippets, id)
+	return nil
+}
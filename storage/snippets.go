@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Snippet is a named, tagged resource definition saved to the library
+type Snippet struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Tags      []string        `json:"tags"`
+	Resource  json.RawMessage `json:"resource,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// SnippetStore persists the snippet library
+type SnippetStore interface {
+	// CreateSnippet saves a new snippet and returns it with its id and timestamps
+	CreateSnippet(ctx context.Context, s Snippet) (Snippet, error)
+	// GetSnippet returns the snippet with id, or ErrNotFound
+	GetSnippet(ctx context.Context, id string) (Snippet, error)
+	// ListSnippets returns all snippets, optionally only those carrying tag,
+	// ordered by name. Resources are omitted from the listing.
+	ListSnippets(ctx context.Context, tag string) ([]Snippet, error)
+	// UpdateSnippet replaces the name, tags and resource of an existing snippet
+	UpdateSnippet(ctx context.Context, s Snippet) (Snippet, error)
+	// DeleteSnippet removes the snippet with id, or returns ErrNotFound
+	DeleteSnippet(ctx context.Context, id string) error
+}
+
+// NewID returns a random id with the same shape as ContentID
+func NewID() string {
+	buf := make([]byte, IDLength)
+	rand.Read(buf)
+	for i, b := range buf {
+		buf[i] = base62Alphabet[int(b)%len(base62Alphabet)]
+	}
+	return string(buf)
+}
+
+// normalizeTags trims tags and drops empty and duplicate entries. The result
+// is never nil so it always serializes as a JSON array.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
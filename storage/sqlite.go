@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore persists shared definitions and snippets in a SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// schema creates the tables used by SQLiteStore
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS shares (
+		id         TEXT PRIMARY KEY,
+		data       BLOB NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS snippets (
+		id         TEXT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		tags       TEXT NOT NULL,
+		resource   BLOB NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+}
+
+// NewSQLiteStore opens (creating if needed) the database at path
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Put saves data and returns its content id
+func (s *SQLiteStore) Put(ctx context.Context, data []byte) (string, error) {
+	id := ContentID(data)
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO shares (id, data, created_at) VALUES (?, ?, ?) ON CONFLICT(id) DO NOTHING`,
+		id, data, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Get returns the data stored under id
+func (s *SQLiteStore) Get(ctx context.Context, id string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM shares WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// CreateSnippet saves a new snippet
+func (s *SQLiteStore) CreateSnippet(ctx context.Context, snippet Snippet) (Snippet, error) {
+	snippet.Tags = normalizeTags(snippet.Tags)
+	tags, err := json.Marshal(snippet.Tags)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	now := time.Now().UTC()
+	snippet.ID = NewID()
+	snippet.CreatedAt = now
+	snippet.UpdatedAt = now
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO snippets (id, name, tags, resource, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		snippet.ID, snippet.Name, string(tags), []byte(snippet.Resource), now, now)
+	if err != nil {
+		return Snippet{}, err
+	}
+	return snippet, nil
+}
+
+// GetSnippet returns the snippet with id
+func (s *SQLiteStore) GetSnippet(ctx context.Context, id string) (Snippet, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, name, tags, resource, created_at, updated_at FROM snippets WHERE id = ?`, id)
+
+	var snippet Snippet
+	var tags string
+	var resource []byte
+	err := row.Scan(&snippet.ID, &snippet.Name, &tags, &resource, &snippet.CreatedAt, &snippet.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Snippet{}, ErrNotFound
+	}
+	if err != nil {
+		return Snippet{}, err
+	}
+	if err := json.Unmarshal([]byte(tags), &snippet.Tags); err != nil {
+		return Snippet{}, err
+	}
+	snippet.Resource = resource
+	return snippet, nil
+}
+
+// ListSnippets returns the snippets carrying tag (all when tag is empty)
+func (s *SQLiteStore) ListSnippets(ctx context.Context, tag string) ([]Snippet, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, tags, created_at, updated_at FROM snippets
+		WHERE ? = '' OR EXISTS (SELECT 1 FROM json_each(snippets.tags) WHERE value = ?)
+		ORDER BY name, id`, tag, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Snippet{}
+	for rows.Next() {
+		var snippet Snippet
+		var tags string
+		if err := rows.Scan(&snippet.ID, &snippet.Name, &tags, &snippet.CreatedAt, &snippet.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(tags), &snippet.Tags); err != nil {
+			return nil, err
+		}
+		list = append(list, snippet)
+	}
+	return list, rows.Err()
+}
+
+// UpdateSnippet replaces an existing snippet's name, tags and resource
+func (s *SQLiteStore) UpdateSnippet(ctx context.Context, snippet Snippet) (Snippet, error) {
+	snippet.Tags = normalizeTags(snippet.Tags)
+	tags, err := json.Marshal(snippet.Tags)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	snippet.UpdatedAt = time.Now().UTC()
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE snippets SET name = ?, tags = ?, resource = ?, updated_at = ? WHERE id = ?`,
+		snippet.Name, string(tags), []byte(snippet.Resource), snippet.UpdatedAt, snippet.ID)
+	if err != nil {
+		return Snippet{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return Snippet{}, ErrNotFound
+	}
+
+	// Reload to pick up the original creation time
+	return s.GetSnippet(ctx, snippet.ID)
+}
+
+// DeleteSnippet removes the snippet with id
+func (s *SQLiteStore) DeleteSnippet(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM snippets WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// ErrNotFound is returned when no entry exists for an id
+var ErrNotFound = errors.New("not found")
+
+// Store persists shared resource definitions under short ids
+type Store interface {
+	// Put saves data and returns its id. Identical data yields the same id.
+	Put(ctx context.Context, data []byte) (string, error)
+	// Get returns the data stored under id, or ErrNotFound
+	Get(ctx context.Context, id string) ([]byte, error)
+	// Close releases the store's resources
+	Close() error
+}
+
+// IDLength is the number of base62 characters in a share id
+const IDLength = 10
+
+// base62Alphabet is used to encode ids so they are URL safe without escaping
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ContentID derives a short, stable id from the content hash
+func ContentID(data []byte) string {
+	sum := sha256.Sum256(data)
+	n := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(base62Alphabet)))
+	mod := new(big.Int)
+
+	id := make([]byte, IDLength)
+	for i := range id {
+		n.DivMod(n, base, mod)
+		id[i] = base62Alphabet[mod.Int64()]
+	}
+	return string(id)
+}
+
+// ValidID reports whether id has the shape produced by ContentID
+func ValidID(id string) bool {
+	if len(id) != IDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,321 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"fhir_renderer/models"
+)
+
+// DecodeError is a JSON decoding error located in the source document,
+// with the raw encoding/json error in Err
+type DecodeError struct {
+	Diagnostic
+	Err error
+
+	offset int // Byte offset in the document
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s at %s (line %d, column %d)", e.Message, e.Path, e.Line, e.Column)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Within relocates an error found in a value of data at path, such as the
+// definition "$[1]" of an array, to data itself
+func (e *DecodeError) Within(data []byte, path string) {
+	walkJSON(data, func(token jsonToken) bool {
+		if token.key || token.delim == '}' || token.delim == ']' || token.path != path {
+			return true
+		}
+		e.offset += token.offset
+		e.Path = path + strings.TrimPrefix(e.Path, "$")
+		e.Line, e.Column = position(data, e.offset)
+		return false
+	})
+}
+
+// DescribeDecodeError turns an error from decoding data into a
+// ResourceDefinition into a *DecodeError with its line, column and JSON
+// path, and a plain message: "trailing comma before '}'", "cardinality
+// must be a string, not a number" or "unknown field "cardinalty" (did you
+// mean "cardinality"?)". Other errors are returned unchanged.
+func DescribeDecodeError(data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return describeSyntaxError(data, syntaxErr)
+	case errors.As(err, &typeErr):
+		return describeTypeError(data, typeErr)
+	}
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if name, unquoteErr := strconv.Unquote(name); unquoteErr == nil {
+			return describeUnknownField(data, name, err)
+		}
+	}
+	return err
+}
+
+// UnknownFields reports the object keys of data that the definition
+// schema does not know, which decoding silently ignores, as warnings
+// suggesting the field they most likely misspell
+func UnknownFields(data []byte) []Diagnostic {
+	var diagnostics []Diagnostic
+	walkJSON(data, func(token jsonToken) bool {
+		if name, ok := unknownKey(token); ok {
+			line, column := position(data, token.offset)
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     CodeUnknownField,
+				Path:     token.path,
+				Message:  unknownFieldMessage(token.keys, name) + " is ignored",
+				Line:     line,
+				Column:   column,
+			})
+		}
+		return true
+	})
+	return diagnostics
+}
+
+func describeSyntaxError(data []byte, err *json.SyntaxError) *DecodeError {
+	message := err.Error()
+	offset := int(err.Offset)
+	if offset >= len(data) {
+		offset = len(data) // Unexpected end of input
+	} else if offset > 0 {
+		offset-- // The offending character was read last
+	}
+	if offset < len(data) && (data[offset] == '}' || data[offset] == ']') {
+		if comma := len(bytes.TrimRight(data[:offset], " \t\r\n")) - 1; comma >= 0 && data[comma] == ',' {
+			message = fmt.Sprintf("trailing comma before '%c'", data[offset])
+			offset = comma
+		}
+	}
+
+	// The path of the innermost object or array open at the error
+	paths := []string{"$"}
+	walkJSON(data, func(token jsonToken) bool {
+		if token.offset >= offset {
+			return false
+		}
+		switch token.delim {
+		case '{', '[':
+			paths = append(paths, token.path)
+		case '}', ']':
+			paths = paths[:len(paths)-1]
+		}
+		return true
+	})
+	path := paths[len(paths)-1]
+	return newDecodeError(data, CodeInvalidJSON, path, message, offset, err)
+}
+
+func describeTypeError(data []byte, err *json.UnmarshalTypeError) *DecodeError {
+	path, subject := "$", "the definition"
+	if err.Field != "" {
+		path = "$" + fieldPath(err.Field)
+		subject = err.Field[strings.LastIndex(err.Field, ".")+1:]
+	}
+	offset := int(err.Offset)
+	walkJSON(data, func(token jsonToken) bool {
+		if !token.key && token.delim != '}' && token.delim != ']' && token.path == path {
+			offset = token.offset
+			return false
+		}
+		return true
+	})
+	value, _, _ := strings.Cut(err.Value, " ")
+	message := fmt.Sprintf("%s must be %s, not %s", subject, typeArticle(jsonKind(err.Type)), typeArticle(value))
+	return newDecodeError(data, CodeInvalidJSON, path, message, offset, err)
+}
+
+func describeUnknownField(data []byte, name string, err error) error {
+	var described *DecodeError
+	walkJSON(data, func(token jsonToken) bool {
+		if key, ok := unknownKey(token); ok && key == name {
+			described = newDecodeError(data, CodeUnknownField, token.path, unknownFieldMessage(token.keys, name), token.offset, err)
+			return false
+		}
+		return true
+	})
+	if described == nil {
+		return err
+	}
+	return described
+}
+
+func newDecodeError(data []byte, code, path, message string, offset int, err error) *DecodeError {
+	line, column := position(data, offset)
+	return &DecodeError{
+		Diagnostic: Diagnostic{
+			Severity: SeverityError,
+			Code:     code,
+			Path:     path,
+			Message:  message,
+			Line:     line,
+			Column:   column,
+		},
+		Err:    err,
+		offset: offset,
+	}
+}
+
+// unknownKey reports the name of an object key the schema lacks. JSON
+// field names match case-insensitively.
+func unknownKey(token jsonToken) (string, bool) {
+	if !token.key {
+		return "", false
+	}
+	name := token.keys[len(token.keys)-1]
+	names := models.FieldNames(token.keys[:len(token.keys)-1])
+	if names == nil || slices.ContainsFunc(names, func(known string) bool { return strings.EqualFold(known, name) }) {
+		return "", false
+	}
+	return name, true
+}
+
+func unknownFieldMessage(keys []string, name string) string {
+	if suggestion := models.SuggestField(keys[:len(keys)-1], name); suggestion != "" {
+		return fmt.Sprintf("unknown field %q (did you mean %q?)", name, suggestion)
+	}
+	return fmt.Sprintf("unknown field %q", name)
+}
+
+// fieldIndexPattern matches the array indices in encoding/json field
+// paths, as in "elements.1.flags"
+var fieldIndexPattern = regexp.MustCompile(`\.(\d+)(\.|$)`)
+
+// fieldPath turns an encoding/json field path into a JSON path suffix,
+// e.g. "elements.1.flags" into ".elements[1].flags"
+func fieldPath(field string) string {
+	path := "." + field
+	for fieldIndexPattern.MatchString(path) {
+		path = fieldIndexPattern.ReplaceAllString(path, "[$1]$2")
+	}
+	return path
+}
+
+// jsonKind names the JSON value a Go type decodes from
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return "number"
+}
+
+// typeArticle names a JSON value kind with its article, e.g. "an array"
+func typeArticle(kind string) string {
+	switch kind {
+	case "array", "object":
+		return "an " + kind
+	case "bool":
+		return "a boolean"
+	}
+	return "a " + kind
+}
+
+// position returns the 1-based line and column of offset in data
+func position(data []byte, offset int) (line, column int) {
+	offset = min(offset, len(data))
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	return bytes.Count(data[:offset], []byte("\n")) + 1, utf8.RuneCount(data[lineStart:offset]) + 1
+}
+
+// jsonToken is an object key, a value or the end of an object or array
+// met while walking a JSON document
+type jsonToken struct {
+	path   string     // JSON path of the value, e.g. $.elements[0].type
+	keys   []string   // Object keys leading to the value, without indices
+	key    bool       // The token is an object key; path and keys end with it
+	delim  json.Delim // '{' or '[' starting the value, '}' or ']' ending it
+	offset int        // Byte offset of the token
+}
+
+// walkFrame is an object or array being walked
+type walkFrame struct {
+	path   string
+	keys   []string
+	object bool
+	key    string // Key of the next object value, once hasKey is set
+	hasKey bool
+	index  int // Index of the next array value
+}
+
+// walkJSON calls visit for every object key, value and object or array end
+// of data in document order until visit returns false or the document
+// turns out malformed
+func walkJSON(data []byte, visit func(jsonToken) bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var stack []*walkFrame
+	for {
+		offset := skipSeparators(data, int(decoder.InputOffset()))
+		token, err := decoder.Token()
+		if err != nil {
+			return
+		}
+		delim, isDelim := token.(json.Delim)
+		if isDelim && (delim == '}' || delim == ']') {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !visit(jsonToken{path: top.path, keys: top.keys, delim: delim, offset: offset}) {
+				return
+			}
+			continue
+		}
+
+		current := jsonToken{path: "$", delim: delim, offset: offset}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			switch {
+			case top.object && !top.hasKey:
+				top.key, top.hasKey = token.(string), true
+				current = jsonToken{path: top.path + "." + top.key, keys: append(slices.Clip(top.keys), top.key), key: true, offset: offset}
+				if !visit(current) {
+					return
+				}
+				continue
+			case top.object:
+				current.path, current.keys = top.path+"."+top.key, append(slices.Clip(top.keys), top.key)
+				top.hasKey = false
+			default:
+				current.path, current.keys = fmt.Sprintf("%s[%d]", top.path, top.index), top.keys
+				top.index++
+			}
+		}
+		if !visit(current) {
+			return
+		}
+		if isDelim {
+			stack = append(stack, &walkFrame{path: current.path, keys: current.keys, object: delim == '{'})
+		}
+	}
+}
+
+// skipSeparators returns the offset of the next token at or after i,
+// skipping whitespace and the commas and colons between tokens
+func skipSeparators(data []byte, i int) int {
+	for i < len(data) && bytes.IndexByte([]byte(" \t\r\n,:"), data[i]) >= 0 {
+		i++
+	}
+	return i
+}
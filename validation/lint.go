@@ -0,0 +1,391 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// Severity levels for diagnostics
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic codes
+const (
+	CodeRequired           = "required"
+	CodeInvalidCardinality = "invalid-cardinality"
+	CodeUnknownFlag        = "unknown-flag"
+	CodeUnknownUsage       = "unknown-usage"
+	CodeUnknownStrength    = "unknown-binding-strength"
+	CodeDuplicateName      = "duplicate-name"
+	CodeMissingType        = "missing-type"
+	CodeSuspiciousDepth    = "suspicious-depth"
+	CodeUnknownStatus      = "unknown-status"
+	CodeUnmatchedPath      = "unmatched-annotation-path"
+	CodeInvalidColor       = "invalid-color"
+	CodeUnsafeLink         = "unsafe-link"
+	CodeInvalidExample     = "invalid-example"
+	CodeUnknownType        = "unknown-type"
+	CodeUnknownFHIRVersion = "unknown-fhir-version"
+	CodeInvalidJSON        = "invalid-json"
+	CodeUnknownField       = "unknown-field"
+)
+
+// MaxSuggestedDepth is the nesting depth above which a warning is reported
+const MaxSuggestedDepth = 6
+
+// CardinalityPattern matches "min..max" where max is a number or "*"
+var CardinalityPattern = regexp.MustCompile(`^\d+\.\.(\d+|\*)$`)
+
+// KnownFlags lists the flag codes understood by the renderer
+var KnownFlags = []string{
+	models.FlagSummary,
+	models.FlagModifier,
+	models.FlagConstraint,
+	models.FlagTrialUse,
+	models.FlagNormative,
+	models.FlagMustSupport,
+}
+
+// RegisterFlags adds custom flag codes to KnownFlags, e.g. those configured
+// for the renderer
+func RegisterFlags(codes ...string) {
+	for _, code := range codes {
+		if !slices.Contains(KnownFlags, code) {
+			KnownFlags = append(KnownFlags, code)
+		}
+	}
+}
+
+// KnownUsages lists the accepted element usage values
+var KnownUsages = []string{
+	models.UsageUsed,
+	models.UsageNotUsed,
+	models.UsageTodo,
+	models.UsageOptional,
+}
+
+// KnownStatuses lists the FHIR publication statuses
+var KnownStatuses = []string{
+	models.StatusDraft,
+	models.StatusActive,
+	models.StatusRetired,
+	models.StatusUnknown,
+}
+
+// KnownBindingStrengths lists the FHIR binding strengths
+var KnownBindingStrengths = []string{"required", "extensible", "preferred", "example"}
+
+// Diagnostic describes a single problem found in a resource definition
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+
+	// Position in the source JSON, for diagnostics found while reading it
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+// Report is the result of linting a resource definition
+type Report struct {
+	Valid       bool         `json:"valid"`
+	Errors      int          `json:"errors"`
+	Warnings    int          `json:"warnings"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// ValidCardinality reports whether s is a well-formed cardinality with min <= max
+func ValidCardinality(s string) bool {
+	if !CardinalityPattern.MatchString(s) {
+		return false
+	}
+	parts := strings.SplitN(s, "..", 2)
+	if parts[1] == "*" {
+		return true
+	}
+	min, _ := strconv.Atoi(parts[0])
+	max, _ := strconv.Atoi(parts[1])
+	return min <= max
+}
+
+// strictCodes are the diagnostic codes whose errors cause a strict render
+// to fail, and strictWarningCodes those that fail it as warnings too
+var (
+	strictCodes        = []string{CodeRequired, CodeInvalidCardinality, CodeUnsafeLink, CodeUnknownStrength, CodeUnknownType}
+	strictWarningCodes = []string{CodeUnknownStrength, CodeUnknownType}
+)
+
+// StrictViolations returns the diagnostics that strict rendering rejects:
+// missing required fields, malformed cardinalities and links with unsafe
+// schemes when they are errors, and invalid binding strengths and
+// misspelled FHIR types, which lint only warns about. Other warnings, such
+// as an extension without a url, do not fail a strict render.
+func StrictViolations(resource *models.ResourceDefinition) []Diagnostic {
+	var violations []Diagnostic
+	for _, d := range Lint(resource).Diagnostics {
+		switch {
+		case d.Severity == SeverityError && slices.Contains(strictCodes, d.Code),
+			d.Severity == SeverityWarning && slices.Contains(strictWarningCodes, d.Code):
+			violations = append(violations, d)
+		}
+	}
+	return violations
+}
+
+// Lint checks a resource definition and returns a report of all diagnostics
+func Lint(resource *models.ResourceDefinition) Report {
+	return newReport(lint(resource))
+}
+
+// LintSource is Lint for a definition decoded from the JSON in data. It
+// also warns about fields decoding ignored, such as misspelled ones.
+func LintSource(data []byte, resource *models.ResourceDefinition) Report {
+	return newReport(append(UnknownFields(data), lint(resource)...))
+}
+
+// lint returns the diagnostics of a resource definition
+func lint(resource *models.ResourceDefinition) []Diagnostic {
+	l := &linter{
+		version:   resource.FHIRVersionOf(),
+		itemTypes: resource.ResourceType == "Questionnaire",
+	}
+
+	if resource.Name == "" {
+		l.add(SeverityError, CodeRequired, "$.name", "missing required field 'name'")
+	}
+	if resource.Type == "" {
+		l.add(SeverityError, CodeRequired, "$.type", "missing required field 'type'")
+	}
+	if _, ok := models.ParseFHIRVersion(resource.FHIRVersion); resource.FHIRVersion != "" && !ok {
+		l.add(SeverityWarning, CodeUnknownFHIRVersion, "$.fhirVersion",
+			fmt.Sprintf("unknown FHIR version %q (expected one of %s or a version number; using %s)",
+				resource.FHIRVersion, strings.Join(models.FHIRVersions, ", "), models.DefaultFHIRVersion))
+	}
+	if resource.Status != "" && !slices.Contains(KnownStatuses, resource.Status) {
+		l.add(SeverityWarning, CodeUnknownStatus, "$.status",
+			fmt.Sprintf("unknown status %q (expected one of %s)", resource.Status, strings.Join(KnownStatuses, ", ")))
+	}
+	l.checkFlags(resource.Flags, "$")
+	l.checkElements(resource.Elements, "$", 1)
+	l.checkExtensions(resource.Extensions, "$", false)
+	l.checkAnnotations(resource)
+	l.checkExample(resource)
+	return l.diagnostics
+}
+
+// newReport counts the errors and warnings among diagnostics
+func newReport(diagnostics []Diagnostic) Report {
+	report := Report{Diagnostics: diagnostics}
+	if report.Diagnostics == nil {
+		report.Diagnostics = []Diagnostic{}
+	}
+	for _, d := range report.Diagnostics {
+		if d.Severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.Valid = report.Errors == 0
+	return report
+}
+
+// linter accumulates diagnostics while walking the definition
+type linter struct {
+	diagnostics []Diagnostic
+	version     string // FHIR version whose types apply
+	itemTypes   bool   // Elements are Questionnaire items typed by item type, such as "group"
+}
+
+func (l *linter) add(severity, code, path, message string) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Path:     path,
+		Message:  message,
+	})
+}
+
+func (l *linter) checkElements(elements []models.Element, parentPath string, depth int) {
+	seen := make(map[string]int)
+
+	for i, elem := range elements {
+		path := fmt.Sprintf("%s.elements[%d]", parentPath, i)
+
+		if elem.Name == "" {
+			l.add(SeverityError, CodeRequired, path+".name", "missing required field 'name'")
+		} else if first, ok := seen[elem.Name]; ok {
+			l.add(SeverityWarning, CodeDuplicateName, path+".name",
+				fmt.Sprintf("duplicate element name %q (first defined at %s.elements[%d])", elem.Name, parentPath, first))
+		} else {
+			seen[elem.Name] = i
+		}
+
+		if elem.Type == "" {
+			l.add(SeverityWarning, CodeMissingType, path+".type", "element has no type")
+		}
+		if !l.itemTypes {
+			l.checkType(elem.Type, path+".type")
+		}
+		if elem.Cardinality != "" && !ValidCardinality(elem.Cardinality) {
+			l.add(SeverityError, CodeInvalidCardinality, path+".cardinality",
+				fmt.Sprintf("invalid cardinality %q (expected min..max, e.g. 0..1 or 1..*)", elem.Cardinality))
+		}
+		if elem.Usage != "" && !slices.Contains(KnownUsages, elem.Usage) {
+			l.add(SeverityWarning, CodeUnknownUsage, path+".usage",
+				fmt.Sprintf("unknown usage %q (expected one of %s)", elem.Usage, strings.Join(KnownUsages, ", ")))
+		}
+		if elem.Binding != nil && elem.Binding.Strength != "" && !slices.Contains(KnownBindingStrengths, elem.Binding.Strength) {
+			l.add(SeverityWarning, CodeUnknownStrength, path+".binding.strength",
+				fmt.Sprintf("unknown binding strength %q (expected one of %s)", elem.Binding.Strength, strings.Join(KnownBindingStrengths, ", ")))
+		}
+		if _, ok := models.HighlightColor(elem.Highlight); elem.Highlight != "" && !ok {
+			l.add(SeverityWarning, CodeInvalidColor, path+".highlight",
+				fmt.Sprintf("invalid highlight %q (expected #RGB, #RRGGBB or one of %s)", elem.Highlight, strings.Join(models.HighlightNames(), ", ")))
+		}
+		l.checkLink(elem.TypeRef, path+".typeRef")
+		if elem.Binding != nil {
+			l.checkLink(elem.Binding.URL, path+".binding.url")
+		}
+		for j, target := range elem.Targets {
+			if target.Type == "" {
+				l.add(SeverityError, CodeRequired, fmt.Sprintf("%s.targets[%d].type", path, j), "missing required field 'type'")
+			}
+			l.checkType(target.Type, fmt.Sprintf("%s.targets[%d].type", path, j))
+			l.checkLink(target.URL, fmt.Sprintf("%s.targets[%d].url", path, j))
+		}
+		for j, mapping := range elem.Mappings {
+			if mapping.Identity == "" {
+				l.add(SeverityError, CodeRequired, fmt.Sprintf("%s.mappings[%d].identity", path, j), "missing required field 'identity'")
+			}
+			if mapping.Map == "" {
+				l.add(SeverityError, CodeRequired, fmt.Sprintf("%s.mappings[%d].map", path, j), "missing required field 'map'")
+			}
+		}
+		if depth == MaxSuggestedDepth+1 {
+			l.add(SeverityWarning, CodeSuspiciousDepth, path,
+				fmt.Sprintf("element is nested %d levels deep; consider flattening the structure", depth))
+		}
+
+		l.checkFlags(elem.Flags, path)
+		l.checkElements(elem.Elements, path, depth+1)
+		l.checkExtensions(elem.Extensions, path, false)
+	}
+}
+
+// checkLink reports URLs whose scheme could run script when the link is
+// followed; the renderer drops such links
+func (l *linter) checkLink(url, path string) {
+	if !models.IsSafeLink(url) {
+		l.add(SeverityError, CodeUnsafeLink, path,
+			fmt.Sprintf("unsafe link scheme %q (expected one of %s or a relative URL)", models.LinkScheme(url), strings.Join(models.SafeLinkSchemes, ", ")))
+	}
+}
+
+// checkAnnotations warns about annotations that match no element or set an
+// invalid color
+func (l *linter) checkAnnotations(resource *models.ResourceDefinition) {
+	if len(resource.Annotations) == 0 {
+		return
+	}
+	matched := make(map[*models.Annotation]bool)
+	for _, fe := range resource.Flatten() {
+		if fe.Annotation != nil {
+			matched[fe.Annotation] = true
+		}
+	}
+	for i := range resource.Annotations {
+		a := &resource.Annotations[i]
+		path := fmt.Sprintf("$.annotations[%d]", i)
+		if !matched[a] {
+			l.add(SeverityWarning, CodeUnmatchedPath, path+".path",
+				fmt.Sprintf("annotation path %q matches no element", a.Path))
+		}
+		if a.Color != "" && !models.IsHexColor(a.Color) {
+			l.add(SeverityWarning, CodeInvalidColor, path+".color",
+				fmt.Sprintf("invalid color %q (expected #RGB or #RRGGBB)", a.Color))
+		}
+	}
+}
+
+// checkExample warns about examples that are no JSON object or whose
+// resourceType is neither the definition's type nor its name
+func (l *linter) checkExample(resource *models.ResourceDefinition) {
+	if !resource.HasExample() {
+		return
+	}
+	instance, ok := resource.ExampleObject()
+	if !ok {
+		l.add(SeverityWarning, CodeInvalidExample, "$.example", "example is not a JSON object")
+		return
+	}
+	if resourceType, _ := instance["resourceType"].(string); resourceType != "" && resourceType != resource.Type && resourceType != resource.Name {
+		l.add(SeverityWarning, CodeInvalidExample, "$.example.resourceType",
+			fmt.Sprintf("example resourceType %q does not match type %q", resourceType, resource.Type))
+	}
+}
+
+// checkType reports FHIR types the definition's FHIR version lacks, such
+// as "Media" in R5, and close misspellings of its types, such as
+// "CodableConcept". The type may list several names, as in
+// "Reference(Patient | Group)"; custom types not resembling a FHIR type
+// are accepted.
+func (l *linter) checkType(typ, path string) {
+	names := strings.FieldsFunc(typ, func(r rune) bool { return r == '(' || r == ')' || r == '|' })
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if _, ok := models.LookupType(name, l.version); ok {
+			continue
+		}
+		if versions := models.TypeVersions(name); len(versions) > 0 {
+			l.add(SeverityWarning, CodeUnknownType, path,
+				fmt.Sprintf("type %q is not defined in FHIR %s (only in %s)", name, l.version, strings.Join(versions, ", ")))
+		} else if suggestion := models.SuggestType(name, l.version); suggestion != "" {
+			l.add(SeverityWarning, CodeUnknownType, path,
+				fmt.Sprintf("unknown type %q (did you mean %q?)", name, suggestion))
+		}
+	}
+}
+
+// checkExtensions checks extensions and their sub-extensions. Sub-extensions
+// need no url, as theirs defaults to the slice name, and complex extensions
+// no value type.
+func (l *linter) checkExtensions(extensions []models.Extension, parentPath string, nested bool) {
+	for i, ext := range extensions {
+		path := fmt.Sprintf("%s.extensions[%d]", parentPath, i)
+
+		if ext.Name == "" {
+			l.add(SeverityError, CodeRequired, path+".name", "missing required field 'name'")
+		}
+		if ext.URL == "" && !nested {
+			l.add(SeverityWarning, CodeRequired, path+".url", "extension has no url")
+		}
+		if ext.Type == "" && len(ext.Extensions) == 0 {
+			l.add(SeverityWarning, CodeMissingType, path+".type", "extension has no type")
+		}
+		l.checkType(ext.Type, path+".type")
+		if ext.Cardinality != "" && !ValidCardinality(ext.Cardinality) {
+			l.add(SeverityError, CodeInvalidCardinality, path+".cardinality",
+				fmt.Sprintf("invalid cardinality %q (expected min..max, e.g. 0..1 or 1..*)", ext.Cardinality))
+		}
+		l.checkFlags(ext.Flags, path)
+		l.checkExtensions(ext.Extensions, path, true)
+	}
+}
+
+func (l *linter) checkFlags(flags []string, parentPath string) {
+	for i, flag := range flags {
+		if !slices.Contains(KnownFlags, flag) {
+			l.add(SeverityWarning, CodeUnknownFlag, fmt.Sprintf("%s.flags[%d]", parentPath, i),
+				fmt.Sprintf("unknown flag %q (expected one of %s)", flag, strings.Join(KnownFlags, ", ")))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package validation
+
+import (
+	"slices"
+	"testing"
+
+	"fhir_renderer/models"
+)
+
+// codes returns the codes of diagnostics, in order
+func codes(diagnostics []Diagnostic) []string {
+	result := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		result[i] = d.Code
+	}
+	return result
+}
+
+func TestStrictViolations(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource models.ResourceDefinition
+		want     []string
+	}{
+		{
+			name:     "valid",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "id", Type: "id", Cardinality: "0..1"}}},
+		},
+		{
+			name: "extension without url is only a warning",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient",
+				Extensions: []models.Extension{{Name: "birthPlace", Type: "Address"}}},
+		},
+		{
+			name:     "missing name",
+			resource: models.ResourceDefinition{Type: "Patient"},
+			want:     []string{CodeRequired},
+		},
+		{
+			name:     "malformed cardinality",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "id", Type: "id", Cardinality: "one"}}},
+			want:     []string{CodeInvalidCardinality},
+		},
+		{
+			name: "unknown binding strength",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient",
+				Elements: []models.Element{{Name: "gender", Type: "code", Binding: &models.Binding{Strength: "mandatory", ValueSet: "male | female"}}}},
+			want: []string{CodeUnknownStrength},
+		},
+		{
+			name:     "misspelled type",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "code", Type: "CodableConcept"}}},
+			want:     []string{CodeUnknownType},
+		},
+		{
+			name: "questionnaire item types",
+			resource: models.ResourceDefinition{ResourceType: "Questionnaire", Name: "Q", Type: "Questionnaire",
+				Elements: []models.Element{{Name: "1", Type: "group", Elements: []models.Element{{Name: "1.1", Type: "quantity"}, {Name: "1.2", Type: "reference"}}}}},
+		},
+		{
+			name:     "unsafe link",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "id", Type: "id", TypeRef: "javascript:alert(1)"}}},
+			want:     []string{CodeUnsafeLink},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codes(StrictViolations(&tt.resource)); !slices.Equal(got, tt.want) {
+				t.Errorf("StrictViolations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource models.ResourceDefinition
+		want     []string
+		valid    bool
+	}{
+		{
+			name:     "valid",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Status: "draft", Elements: []models.Element{{Name: "id", Type: "id", Cardinality: "0..1"}}},
+			valid:    true,
+		},
+		{
+			name:     "missing name and type",
+			resource: models.ResourceDefinition{},
+			want:     []string{CodeRequired, CodeRequired},
+		},
+		{
+			name:     "unknown status and FHIR version",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Status: "published", FHIRVersion: "R9"},
+			want:     []string{CodeUnknownFHIRVersion, CodeUnknownStatus},
+			valid:    true,
+		},
+		{
+			name:     "unknown flag",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "id", Type: "id", Flags: []string{"XX"}}}},
+			want:     []string{CodeUnknownFlag},
+			valid:    true,
+		},
+		{
+			name:     "unknown usage",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "id", Type: "id", Usage: "sometimes"}}},
+			want:     []string{CodeUnknownUsage},
+			valid:    true,
+		},
+		{
+			name:     "duplicate name and missing type",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "id", Type: "id"}, {Name: "id"}}},
+			want:     []string{CodeDuplicateName, CodeMissingType},
+			valid:    true,
+		},
+		{
+			name:     "invalid highlight",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "id", Type: "id", Highlight: "#12"}}},
+			want:     []string{CodeInvalidColor},
+			valid:    true,
+		},
+		{
+			name: "target without type and mapping without identity",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "subject", Type: "Reference",
+				Targets: []models.Target{{URL: "https://hl7.org/fhir/patient.html"}}, Mappings: []models.Mapping{{Map: "PID-3"}}}}},
+			want: []string{CodeRequired, CodeRequired},
+		},
+		{
+			name: "unmatched annotation path",
+			resource: models.ResourceDefinition{Name: "P", Type: "Patient", Elements: []models.Element{{Name: "id", Type: "id"}},
+				Annotations: []models.Annotation{{Path: "gender", Note: "Check"}}},
+			want:  []string{CodeUnmatchedPath},
+			valid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Lint(&tt.resource)
+			if got := codes(report.Diagnostics); !slices.Equal(got, tt.want) {
+				t.Errorf("Lint() codes = %v, want %v", got, tt.want)
+			}
+			if report.Valid != tt.valid {
+				t.Errorf("Lint() valid = %v, want %v", report.Valid, tt.valid)
+			}
+			if report.Errors+report.Warnings != len(report.Diagnostics) {
+				t.Errorf("Lint() counted %d errors and %d warnings for %d diagnostics", report.Errors, report.Warnings, len(report.Diagnostics))
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"fhir_renderer/models"
+)
+
+// Diagnostic codes of schema validation
+const (
+	CodeInvalidType  = "invalid-type"
+	CodeInvalidValue = "invalid-value"
+)
+
+// ValidateSchema checks the JSON document data against a JSON Schema and
+// reports each violation as an error with its path, line and column. It
+// understands the keywords the published ResourceDefinition schema uses:
+// $ref (to #/$defs), allOf, anyOf, type, properties, required,
+// additionalProperties, items, enum and pattern; others are ignored.
+func ValidateSchema(schema map[string]any, data []byte) Report {
+	if err := json.Unmarshal(data, new(any)); err != nil {
+		diagnostic := Diagnostic{Severity: SeverityError, Code: CodeInvalidJSON, Path: "$", Message: err.Error()}
+		if decodeErr, ok := DescribeDecodeError(data, err).(*DecodeError); ok {
+			diagnostic = decodeErr.Diagnostic
+		}
+		return newReport([]Diagnostic{diagnostic})
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Tells integers from other numbers
+	var value any
+	decoder.Decode(&value)
+
+	v := &schemaValidator{root: schema, keyOffsets: map[string]int{}, valueOffsets: map[string]int{}}
+	walkJSON(data, func(token jsonToken) bool {
+		switch {
+		case token.key:
+			v.keyOffsets[token.path] = token.offset
+		case token.delim != '}' && token.delim != ']':
+			v.valueOffsets[token.path] = token.offset
+		}
+		return true
+	})
+	v.check(schema, value, "$")
+
+	// Report in document order
+	sort.SliceStable(v.violations, func(i, j int) bool { return v.violations[i].offset < v.violations[j].offset })
+	diagnostics := make([]Diagnostic, len(v.violations))
+	for i, violation := range v.violations {
+		diagnostics[i] = violation.diagnostic
+		diagnostics[i].Line, diagnostics[i].Column = position(data, violation.offset)
+	}
+	return newReport(diagnostics)
+}
+
+// schemaValidator collects the violations of one document
+type schemaValidator struct {
+	root         map[string]any
+	keyOffsets   map[string]int // Offsets of object keys by the path of their value
+	valueOffsets map[string]int
+	violations   []schemaViolation
+}
+
+// schemaViolation is a diagnostic and the offset of the value it concerns
+type schemaViolation struct {
+	diagnostic Diagnostic
+	offset     int
+}
+
+// add records a violation at path; key points it at the object key
+// rather than the value
+func (v *schemaValidator) add(code, path, message string, key bool) {
+	offset := v.valueOffsets[path]
+	if keyOffset, ok := v.keyOffsets[path]; key && ok {
+		offset = keyOffset
+	}
+	v.violations = append(v.violations, schemaViolation{
+		diagnostic: Diagnostic{Severity: SeverityError, Code: code, Path: path, Message: message},
+		offset:     offset,
+	})
+}
+
+// check validates value at path against schema
+func (v *schemaValidator) check(schema map[string]any, value any, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if resolved := v.resolve(ref); resolved != nil {
+			v.check(resolved, value, path)
+		}
+	}
+	for _, sub := range schemaList(schema["allOf"]) {
+		v.check(sub, value, path)
+	}
+	if anyOf := schemaList(schema["anyOf"]); len(anyOf) > 0 && !slices.ContainsFunc(anyOf, func(sub map[string]any) bool { return v.matches(sub, value, path) }) {
+		v.add(CodeRequired, path, anyOfMessage(anyOf), false)
+	}
+
+	if typ, ok := schema["type"].(string); ok && !matchesType(typ, value) {
+		v.add(CodeInvalidType, path, fmt.Sprintf("%s must be %s, not %s", pathSubject(path), typeArticle(schemaKind(typ)), typeArticle(valueKind(value))), false)
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(allowed any) bool { return reflect.DeepEqual(allowed, value) }) {
+		v.add(CodeInvalidValue, path, enumMessage(path, value, enum), false)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if s, isString := value.(string); isString {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+				v.add(CodeInvalidValue, path, fmt.Sprintf("%s %q does not match the pattern %s", pathSubject(path), s, pattern), false)
+			}
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.checkObject(schema, value, path)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				v.check(items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+}
+
+// checkObject validates the required, known and additional properties of
+// an object
+func (v *schemaValidator) checkObject(schema map[string]any, object map[string]any, path string) {
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := object[name]; !present {
+					v.add(CodeRequired, path, fmt.Sprintf("missing required property %q", name), false)
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childPath := path + "." + name
+		if property, ok := properties[name].(map[string]any); ok {
+			v.check(property, object[name], childPath)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.add(CodeUnknownField, childPath, unknownPropertyMessage(name, properties), true)
+			}
+		case map[string]any:
+			v.check(additional, object[name], childPath)
+		}
+	}
+}
+
+// matches reports whether value satisfies schema, without recording
+// violations
+func (v *schemaValidator) matches(schema map[string]any, value any, path string) bool {
+	probe := &schemaValidator{root: v.root, keyOffsets: v.keyOffsets, valueOffsets: v.valueOffsets}
+	probe.check(schema, value, path)
+	return len(probe.violations) == 0
+}
+
+// resolve returns the schema a local reference such as
+// "#/$defs/Element" points to
+func (v *schemaValidator) resolve(ref string) map[string]any {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil
+	}
+	defs, _ := v.root["$defs"].(map[string]any)
+	resolved, _ := defs[name].(map[string]any)
+	return resolved
+}
+
+// schemaList returns the subschemas of an allOf or anyOf keyword
+func schemaList(value any) []map[string]any {
+	list, _ := value.([]any)
+	schemas := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if schema, ok := item.(map[string]any); ok {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas
+}
+
+// matchesType reports whether value is of the JSON Schema type typ
+func matchesType(typ string, value any) bool {
+	switch value := value.(type) {
+	case map[string]any:
+		return typ == "object"
+	case []any:
+		return typ == "array"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case json.Number:
+		return typ == "number" || typ == "integer" && !strings.ContainsAny(value.String(), ".eE")
+	case nil:
+		return typ == "null"
+	}
+	return false
+}
+
+// valueKind names the JSON kind of a decoded value, as jsonKind does for
+// Go types
+func valueKind(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	}
+	return "number"
+}
+
+// schemaKind maps a JSON Schema type to the kinds typeArticle names
+func schemaKind(typ string) string {
+	switch typ {
+	case "boolean":
+		return "bool"
+	case "integer":
+		return "whole number"
+	}
+	return typ
+}
+
+// pathSubject names the value at path in messages: its property name, or
+// "the definition" for the document
+func pathSubject(path string) string {
+	if path == "$" {
+		return "the definition"
+	}
+	return path[strings.LastIndex(path, ".")+1:]
+}
+
+func enumMessage(path string, value any, enum []any) string {
+	allowed := make([]string, 0, len(enum))
+	for _, item := range enum {
+		allowed = append(allowed, fmt.Sprint(item))
+	}
+	message := fmt.Sprintf("%s %s is not one of %s", pathSubject(path), formatValue(value), strings.Join(allowed, ", "))
+	if s, ok := value.(string); ok {
+		if suggestion := models.ClosestName(s, allowed); suggestion != "" {
+			message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+		}
+	}
+	return message
+}
+
+func unknownPropertyMessage(name string, properties map[string]any) string {
+	known := make([]string, 0, len(properties))
+	for property := range properties {
+		known = append(known, property)
+	}
+	sort.Strings(known)
+	if suggestion := models.ClosestName(name, known); suggestion != "" {
+		return fmt.Sprintf("unknown property %q (did you mean %q?)", name, suggestion)
+	}
+	return fmt.Sprintf("unknown property %q", name)
+}
+
+// anyOfMessage describes an unmatched anyOf: the properties one of which
+// is required, or a generic message for other alternatives
+func anyOfMessage(anyOf []map[string]any) string {
+	var names []string
+	for _, sub := range anyOf {
+		required, _ := sub["required"].([]any)
+		if len(sub) != 1 || len(required) != 1 {
+			return "does not match any of the allowed forms"
+		}
+		names = append(names, fmt.Sprintf("%q", required[0]))
+	}
+	return "missing required property " + strings.Join(names, " or ")
+}
+
+// formatValue renders a decoded value for messages
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
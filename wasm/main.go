@@ -0,0 +1,190 @@
+//go:build js && wasm
+
+// Command wasm exposes the renderer to JavaScript so the editor can render
+// in the browser without a server round trip.
+//
+// Build with: make wasm
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"syscall/js"
+
+	"fhir_renderer/convert"
+	"fhir_renderer/models"
+	"fhir_renderer/renderer"
+	"fhir_renderer/validation"
+)
+
+// options mirrors the query parameters of POST /render
+type options struct {
+	Lang           string   `json:"lang"`
+	Title          string   `json:"title"`
+	Watermark      string   `json:"watermark"`
+	Pretty         *bool    `json:"pretty"`
+	FHIRLinks      *bool    `json:"fhirLinks"`
+	FHIRVersion    string   `json:"fhirVersion"`
+	HighlightMS    bool     `json:"highlightMS"`
+	Hover          bool     `json:"hover"`
+	Media          string   `json:"media"`
+	Legend         bool     `json:"legend"`
+	RowNumbers     bool     `json:"rowNumbers"`
+	GroupHeaders   bool     `json:"groupHeaders"`
+	RollupUsage    bool     `json:"rollupUsage"`
+	MaxRowsPerPage int      `json:"maxRowsPerPage"`
+	Deterministic  bool     `json:"deterministic"`
+	Strict         bool     `json:"strict"`
+	View           string   `json:"view"`
+	MaxDepth       int      `json:"maxDepth"`
+	Width          float64  `json:"width"`
+	Columns        []string `json:"columns"`
+	Include        []string `json:"include"`
+	ExcludeUsage   []string `json:"excludeUsage"`
+	OnlyFlags      []string `json:"onlyFlags"`
+}
+
+// result has the shape of the /ws live preview messages
+type result struct {
+	SVG         string                  `json:"svg,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	Details     string                  `json:"details,omitempty"`
+	Diagnostics []validation.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+func main() {
+	js.Global().Set("fhirRendererRender", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) < 1 {
+			return encode(result{Error: "missing resource JSON"})
+		}
+		var opts options
+		if len(args) > 1 && args[1].Type() == js.TypeString {
+			if err := json.Unmarshal([]byte(args[1].String()), &opts); err != nil {
+				return encode(result{Error: "Invalid options", Details: err.Error()})
+			}
+		}
+		return encode(render([]byte(args[0].String()), opts))
+	}))
+	js.Global().Set("fhirRendererVersion", renderer.Version)
+
+	// Keep the exported functions alive
+	select {}
+}
+
+// render decodes, validates and renders a single definition like POST /render
+func render(data []byte, opts options) result {
+	var resource models.ResourceDefinition
+	if err := decode(data, opts.Strict, &resource); err != nil {
+		r := result{Error: "Invalid JSON", Details: err.Error()}
+		var decodeErr *validation.DecodeError
+		if errors.As(err, &decodeErr) {
+			r.Diagnostics = []validation.Diagnostic{decodeErr.Diagnostic}
+		}
+		return r
+	}
+	if version, ok := models.ParseFHIRVersion(opts.FHIRVersion); ok {
+		resource.FHIRVersion = version
+	}
+	if resource.Name == "" {
+		return result{Error: "missing required field 'name'"}
+	}
+	if resource.Type == "" {
+		return result{Error: "missing required field 'type'"}
+	}
+	if opts.Strict {
+		if diagnostics := validation.StrictViolations(&resource); len(diagnostics) > 0 {
+			return result{Error: "Strict validation failed", Diagnostics: diagnostics}
+		}
+	}
+
+	trimmed := &resource
+	if opts.View == "summary" {
+		trimmed = trimmed.Filter(models.SummaryFilter())
+	}
+	filter := models.ElementFilter{Include: opts.Include, ExcludeUsages: opts.ExcludeUsage, OnlyFlags: opts.OnlyFlags}
+	if !filter.IsEmpty() {
+		trimmed = trimmed.Filter(filter)
+	}
+	if opts.MaxDepth >= 1 {
+		trimmed = trimmed.Truncate(opts.MaxDepth)
+	}
+
+	config := renderer.DefaultConfig()
+	if _, err := renderer.ParseTitleTemplate(opts.Title); opts.Title != "" && err == nil {
+		config.TitleTemplate = opts.Title
+	}
+	if opts.Watermark != "" && opts.Watermark != "none" {
+		config.Watermark = renderer.NormalizeWatermark(opts.Watermark)
+	}
+	if lang, ok := renderer.ParseLang(opts.Lang); ok {
+		config.Lang = lang
+	}
+	if opts.Pretty != nil {
+		config.Whitespace = renderer.WhitespaceMinified
+		if *opts.Pretty {
+			config.Whitespace = renderer.WhitespacePretty
+		}
+	}
+	if opts.FHIRLinks != nil {
+		config.FHIRLinks = *opts.FHIRLinks
+	}
+	config.HighlightMustSupport = opts.HighlightMS
+	config.HoverRows = opts.Hover
+	config.UseMedia(opts.Media)
+	config.ShowLegend = opts.Legend
+	config.RowNumbers = opts.RowNumbers
+	config.GroupHeaders = opts.GroupHeaders
+	config.RollupUsage = opts.RollupUsage
+	config.Coverage = opts.View == "coverage"
+	if opts.MaxRowsPerPage >= renderer.MinRowsPerPage {
+		config.MaxRowsPerPage = opts.MaxRowsPerPage
+	}
+	config.Deterministic = opts.Deterministic
+	if len(opts.Columns) > 0 {
+		if parsed, err := renderer.ParseColumns(opts.Columns); err == nil {
+			config.Columns = parsed
+		}
+	}
+	if opts.Width > 0 {
+		config.MaxTotalWidth = opts.Width
+	}
+
+	var svg strings.Builder
+	if err := renderer.RenderToContext(context.Background(), &svg, trimmed, config); err != nil {
+		return result{Error: "Render failed", Details: err.Error()}
+	}
+	return result{SVG: svg.String()}
+}
+
+// decode unmarshals resource JSON, converting FHIR resources first and
+// nesting elements given as flat paths. JSON errors are located in data.
+func decode(data []byte, strict bool, resource *models.ResourceDefinition) error {
+	if converted, ok, err := convert.FromFHIR(context.Background(), data); ok {
+		if err != nil {
+			return err
+		}
+		*resource = *converted
+		return nil
+	}
+	if !strict {
+		if err := json.Unmarshal(data, resource); err != nil {
+			return validation.DescribeDecodeError(data, err)
+		}
+		return resource.NestPaths()
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(resource); err != nil {
+		return validation.DescribeDecodeError(data, err)
+	}
+	return resource.NestPaths()
+}
+
+// encode returns r as a JSON string for the JavaScript wrapper to parse
+func encode(r result) string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}